@@ -0,0 +1,340 @@
+/*
+账户余额的背书策略与扣款授权
+
+- 开户时通过私有数据验证参数为余额键设置键级背书策略（state-based endorsement），只要求央行peer背书，
+  此后修改余额的交易都必须满足该策略，而不是链码级的背书策略；入账（如其他银行客户转入）不需要收款方银行参与
+- 央行私有集合只分发给央行，银行peer不保存也读不到余额，因此银行不为扣款交易本身背书，
+  而是事先在本行的隐式私有集合 _implicit_org_<银行MSP> 中写入扣款授权 debitauth_<账户> = 扣款交易ID
+- 扣款授权由账户本人或本行admin调用 AuthorizeDebit 提交，调用者须属于该银行的 MSP，由本行peer背书；
+  授权键设置键级背书策略，此后只有本行peer可以修改
+- 余额减少时央行peer以 GetPrivateDataHash 核对账户所属银行隐式集合中的授权是否为本交易ID，不读取银行的数据；
+  授权只对一个交易ID有效，哈希进入读集，授权在扣款提交前被改写时扣款交易验证失败
+- 合约配置 requireBankEndorsement 默认开启，关闭后或账户属于央行时扣款不需要银行授权
+- 账户所属银行记录在账户的 bankMsp 中：开户人与账户同属一个组织时取开户人的 MSP，否则取已记录的组织映射
+- 账户迁移到其他银行、开户早于本功能或修改央行 MSP 后，央行admin通过 SetAccountEndorsementPolicy 重设；
+  修改策略本身须满足键上现有的策略
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/pkg/statebased"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// debitAuthPrefix 扣款授权键前缀，位于账户所属银行的隐式集合：debitauth_<账户> -> 已授权的扣款交易ID
+const debitAuthPrefix = "debitauth_"
+
+// defaultRequireBankEndorsement 扣款默认需要账户所属银行的授权
+const defaultRequireBankEndorsement = true
+
+// accountEndorsementOrgs 扣款需要参与的 MSP（央行背书与账户所属银行的授权），按字母排序
+func accountEndorsementOrgs(config *ContractConfig, bankMSP string) []string {
+	orgs := []string{config.CentralBankMSP}
+	if config.RequireBankEndorsement && bankMSP != "" && bankMSP != config.CentralBankMSP {
+		orgs = append(orgs, bankMSP)
+	}
+	sort.Strings(orgs)
+	return orgs
+}
+
+// setAccountEndorsement 设置账户余额键的背书策略（只要求央行），返回扣款需要参与的 MSP
+func setAccountEndorsement(ctx contractapi.TransactionContextInterface, account string, bankMSP string) ([]string, error) {
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := setKeyEndorsement(ctx, privateCollection(ctx), balancePrefix+account, []string{config.CentralBankMSP}); err != nil {
+		return nil, err
+	}
+	return accountEndorsementOrgs(config, bankMSP), nil
+}
+
+// setKeyEndorsement 为私有集合中的键设置背书策略：所列各 MSP 的peer均须背书
+func setKeyEndorsement(ctx contractapi.TransactionContextInterface, collection string, key string, orgs []string) error {
+	endorsementPolicy, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return fmt.Errorf("failed to create endorsement policy: %v", err)
+	}
+	if err := endorsementPolicy.AddOrgs(statebased.RoleTypePeer, orgs...); err != nil {
+		return fmt.Errorf("failed to add orgs to endorsement policy: %v", err)
+	}
+	policy, err := endorsementPolicy.Policy()
+	if err != nil {
+		return fmt.Errorf("failed to serialize endorsement policy: %v", err)
+	}
+	err = ctx.GetStub().SetPrivateDataValidationParameter(collection, key, policy)
+	if err != nil {
+		return fmt.Errorf("failed to set endorsement policy for %s: %v", key, err)
+	}
+	return nil
+}
+
+// checkDebitAuthorization 扣款前核对账户所属银行是否已授权本交易：只比对隐式集合中授权的哈希
+func checkDebitAuthorization(ctx contractapi.TransactionContextInterface, account *UserBalance) error {
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if !config.RequireBankEndorsement || account.BankMSP == "" || account.BankMSP == config.CentralBankMSP {
+		return nil
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	authHash, err := ctx.GetStub().GetPrivateDataHash(implicitOrgCollection(account.BankMSP), debitAuthPrefix+account.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to read debit authorization of %s: %v", account.UserID, err)
+	}
+	expected := sha256.Sum256([]byte(txID))
+	if !bytes.Equal(authHash, expected[:]) {
+		return fmt.Errorf("debit of %s in transaction %s has not been authorized by %s, call AuthorizeDebit on its peers first", account.UserID, txID, account.BankMSP)
+	}
+	return nil
+}
+
+// getAccountEndorsementOrgs 读取私有集合中键当前背书策略里的 MSP；未设置键级策略时返回空
+func getAccountEndorsementOrgs(ctx contractapi.TransactionContextInterface, key string) ([]string, error) {
+	policy, err := ctx.GetStub().GetPrivateDataValidationParameter(privateCollection(ctx), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read endorsement policy for %s: %v", key, err)
+	}
+	if len(policy) == 0 {
+		return []string{}, nil
+	}
+	endorsementPolicy, err := statebased.NewStateEP(policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse endorsement policy for %s: %v", key, err)
+	}
+	orgs := endorsementPolicy.ListOrgs()
+	sort.Strings(orgs)
+	return orgs, nil
+}
+
+// accountBankMSP 确定开户时账户所属银行的 MSP：开户人与账户同属一个组织时取开户人的 MSP，否则取已记录的组织映射
+func (s *SmartContract) accountBankMSP(ctx contractapi.TransactionContextInterface, callerID string, account string, accountOrg string) (string, error) {
+	callerDomain, err := s.extractDomainFromClientID(callerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract caller domain: %v", err)
+	}
+	if callerDomain == accountOrg {
+		// 同时记录开户人组织的域名到 MSP 映射
+		return s.partyMSP(ctx, callerID)
+	}
+
+	bankMSP, err := s.knownAccountMSP(ctx, account)
+	if err != nil {
+		return "", err
+	}
+	if bankMSP == "" {
+		return "", fmt.Errorf("the MSP of %s is unknown, open the account with an admin of its bank", accountOrg)
+	}
+	return bankMSP, nil
+}
+
+// SetAccountEndorsementPolicy 央行admin重设账户的背书策略，用于账户迁移到其他银行或开户早于键级背书的账户
+// bankMSP 为账户新的所属银行 MSP ID
+func (s *SmartContract) SetAccountEndorsementPolicy(ctx contractapi.TransactionContextInterface, account string, bankMSP string) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", errors.New("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	isCentralBank, err := isCentralBankMSP(ctx, clientMSPID)
+	if err != nil {
+		return "", err
+	}
+	if !isCentralBank || !s.isAdminUser(ctx, callerID) {
+		return "", errors.New("only central bank admins can change account endorsement policies")
+	}
+	if bankMSP == "" {
+		return "", errors.New("bank MSP must not be empty")
+	}
+
+	accountID, err := s.resolveAccount(ctx, account)
+	if err != nil {
+		return "", err
+	}
+	userAccount, err := s.getUserAccountInfo(ctx, accountID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read account %s: %v", account, err)
+	}
+	if err := checkAccountActive(userAccount, "the"); err != nil {
+		return "", err
+	}
+
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	previousBankMSP := userAccount.BankMSP
+	previousOrgs := accountEndorsementOrgs(config, previousBankMSP)
+
+	userAccount.BankMSP = bankMSP
+	if err := s.updateUserAccountInPrivateCollection(ctx, userAccount); err != nil {
+		return "", err
+	}
+	orgs, err := setAccountEndorsement(ctx, accountID, bankMSP)
+	if err != nil {
+		return "", err
+	}
+	creditOrgs, err := getAccountEndorsementOrgs(ctx, balancePrefix+accountID)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.emitEvent(ctx, eventSpec{
+		Type:       eventTypeEndorsementChanged,
+		Attributes: map[string]interface{}{"bankMsp": bankMSP, "previousBankMsp": previousBankMSP, "endorsers": orgs},
+		Parties:    map[string]string{"account": accountID, "changedBy": callerID},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("endorsement policy of %s changed from %v to %v", accountID, previousOrgs, orgs)
+
+	resultJSON, err := json.Marshal(map[string]interface{}{
+		"account":           accountID,
+		"bankMsp":           bankMSP,
+		"previousBankMsp":   previousBankMSP,
+		"endorsers":         orgs,
+		"creditEndorsers":   creditOrgs,
+		"previousEndorsers": previousOrgs,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// GetAccountEndorsementPolicy 查询账户扣款（endorsers）与入账（creditEndorsers）当前要求背书的 MSP；调用者需有权查看该账户余额
+func (s *SmartContract) GetAccountEndorsementPolicy(ctx contractapi.TransactionContextInterface, account string) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", errors.New("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+	accountID, err := s.resolveAccount(ctx, account)
+	if err != nil {
+		return "", err
+	}
+	allowed, err := s.checkBalancePermission(ctx, callerID, accountID)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "", fmt.Errorf("client is not authorized to view account %s", account)
+	}
+
+	userAccount, err := s.getUserAccountInfo(ctx, accountID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read account %s: %v", account, err)
+	}
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	orgs := accountEndorsementOrgs(config, userAccount.BankMSP)
+	creditOrgs, err := getAccountEndorsementOrgs(ctx, balancePrefix+accountID)
+	if err != nil {
+		return "", err
+	}
+
+	resultJSON, err := json.Marshal(map[string]interface{}{
+		"account":         accountID,
+		"bankMsp":         userAccount.BankMSP,
+		"endorsers":       orgs,
+		"creditEndorsers": creditOrgs,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// AuthorizeDebit 账户本人或所属银行admin授权一笔扣款交易，由本行peer背书
+// txId 为随后提交的扣款交易的ID（客户端先构造扣款提案取得交易ID）；授权写入本行的隐式集合，不读取央行私有集合
+func (s *SmartContract) AuthorizeDebit(ctx contractapi.TransactionContextInterface, account string, txID string) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", errors.New("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	if account == "" || txID == "" {
+		return "", errors.New("account and transaction id must not be empty")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+	bankMSP, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	// 只能授权本人账户或本行客户的账户；授权只写入调用者所属 MSP 的隐式集合，对其他银行的账户不起作用
+	if callerID != account {
+		callerDomain, err := s.extractDomainFromClientID(callerID)
+		if err != nil {
+			return "", fmt.Errorf("failed to extract caller domain: %v", err)
+		}
+		accountDomain, err := s.extractDomainFromClientID(account)
+		if err != nil {
+			return "", fmt.Errorf("failed to extract account domain: %v", err)
+		}
+		if !s.isAdminUser(ctx, callerID) || callerDomain != accountDomain {
+			return "", fmt.Errorf("client is not authorized to authorize debits of %s", account)
+		}
+	}
+
+	collection := implicitOrgCollection(bankMSP)
+	key := debitAuthPrefix + account
+	if err := ctx.GetStub().PutPrivateData(collection, key, []byte(txID)); err != nil {
+		return "", fmt.Errorf("failed to store debit authorization of %s: %v", account, err)
+	}
+	if err := setKeyEndorsement(ctx, collection, key, []string{bankMSP}); err != nil {
+		return "", err
+	}
+
+	log.Printf("debit of %s authorized by %s for transaction %s", account, bankMSP, txID)
+
+	resultJSON, err := json.Marshal(map[string]string{
+		"account": account,
+		"bankMsp": bankMSP,
+		"txId":    txID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %v", err)
+	}
+	return string(resultJSON), nil
+}
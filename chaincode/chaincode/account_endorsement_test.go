@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/pkg/statebased"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func endorsementOrgs(t *testing.T, env *testEnv, collection string, key string) []string {
+	t.Helper()
+	policy := env.ledger.ep[collection+":"+key]
+	if policy == nil {
+		t.Fatalf("no endorsement policy set for %s", key)
+	}
+	endorsementPolicy, err := statebased.NewStateEP(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orgs := endorsementPolicy.ListOrgs()
+	sort.Strings(orgs)
+	return orgs
+}
+
+// authorizeDebit 账户本人授权测试账本上的下一笔交易扣款其账户
+func authorizeDebit(t *testing.T, env *testEnv, user testUser) {
+	t.Helper()
+	txID := fmt.Sprintf("tx%04d", env.ledger.txn+2)
+	_, err := env.invoke(user, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.AuthorizeDebit(ctx, user.id, txID)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("AuthorizeDebit failed: %v", err)
+	}
+}
+
+// transfer 转账；付款方为银行客户时先由其授权本次扣款
+func transfer(t *testing.T, env *testEnv, from testUser, to testUser, amount int) *mockStub {
+	t.Helper()
+	if from.msp != CENTRAL_MSP_ID {
+		authorizeDebit(t, env, from)
+	}
+	stub, err := env.invoke(from, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.Transfer(ctx, to.id, amount)
+	})
+	if err != nil {
+		t.Fatalf("Transfer failed: %v", err)
+	}
+	return stub
+}
+
+func TestDebitsRequireBankAuthorization(t *testing.T) {
+	env := newTestEnv(t)
+	env.initialize()
+
+	_, err := env.invoke(bankAAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.OpenAccount(ctx, bankAUser.id, accountTypeIndividual)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+	_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.Mint(ctx, 1000)
+	})
+	if err != nil {
+		t.Fatalf("Mint failed: %v", err)
+	}
+
+	// 余额键只需央行背书；入账不需要收款方银行授权
+	if got := endorsementOrgs(t, env, defaultPrivateCollection, balancePrefix+bankAUser.id); !reflect.DeepEqual(got, []string{CENTRAL_MSP_ID}) {
+		t.Fatalf("balance key endorsers = %v, want only the central bank", got)
+	}
+	transfer(t, env, centralBankAdmin, bankAUser, 100)
+
+	debit := func() error {
+		_, err := env.invoke(bankAUser, nil, func(ctx contractapi.TransactionContextInterface) error {
+			return env.contract.Transfer(ctx, centralBankAdmin.id, 40)
+		})
+		return err
+	}
+	if err := debit(); err == nil || !strings.Contains(err.Error(), "has not been authorized by AMSP") {
+		t.Fatalf("expected an unauthorized debit error, got %v", err)
+	}
+
+	// 授权写入银行的隐式集合，只有本行peer可以修改；扣款交易不读取授权原文
+	authorizeDebit(t, env, bankAUser)
+	collection := implicitOrgCollection("AMSP")
+	if got := endorsementOrgs(t, env, collection, debitAuthPrefix+bankAUser.id); !reflect.DeepEqual(got, []string{"AMSP"}) {
+		t.Fatalf("debit authorization endorsers = %v, want only the bank", got)
+	}
+	stub, err := env.invoke(bankAUser, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.Transfer(ctx, centralBankAdmin.id, 40)
+	})
+	if err != nil {
+		t.Fatalf("authorized debit failed: %v", err)
+	}
+	if stub.preads[debitAuthPrefix+bankAUser.id] || len(stub.pwrites[collection]) != 0 {
+		t.Fatalf("the debit must only compare the authorization hash")
+	}
+
+	// 授权只对一个交易有效
+	if err := debit(); err == nil || !strings.Contains(err.Error(), "has not been authorized") {
+		t.Fatalf("expected the authorization to be spent, got %v", err)
+	}
+
+	// 只能授权本人或本行客户的账户
+	_, err = env.invoke(bankAUser, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.AuthorizeDebit(ctx, centralBankAdmin.id, "tx9999")
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "not authorized to authorize debits") {
+		t.Fatalf("expected an authorization error, got %v", err)
+	}
+	_, err = env.invoke(bankAAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.AuthorizeDebit(ctx, bankAUser.id, "")
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "must not be empty") {
+		t.Fatalf("expected an empty transaction id error, got %v", err)
+	}
+
+	// 关闭 requireBankEndorsement 后扣款不需要银行授权
+	_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.UpdateConfig(ctx, `{"requireBankEndorsement":false}`, "test")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if err := debit(); err != nil {
+		t.Fatalf("debit without bank endorsement requirement failed: %v", err)
+	}
+}
@@ -0,0 +1,324 @@
+/*
+账户生命周期
+
+- 账户由客户所属银行的admin（或央行admin）通过 OpenAccount 显式开立，记录账户类型、状态与开立时间
+- 转账只能在已开立且未销户的账户之间进行，拼写错误的收款账户不再凭空产生新账户
+- CloseAccount 将剩余余额划转到指定账户后销户，并释放账户的别名
+- 开立本功能之前已有余额记录的账户由迁移步骤 balances 记为已开立的个人账户
+- 开户时为余额键设置键级背书策略，扣款另需账户所属银行的授权，见 account_endorsement.go
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// 账户类型
+const (
+	accountTypeIndividual = "individual" // 个人账户
+	accountTypeMerchant   = "merchant"   // 商户账户
+	accountTypeSettlement = "settlement" // 银行结算账户
+	accountTypeSystem     = "system"     // 系统/国库账户，仅央行可开立
+)
+
+// 账户状态；未开立的账户状态为空
+const (
+	accountStatusActive = "active"
+	accountStatusClosed = "closed"
+)
+
+// isValidAccountType 检查账户类型
+func isValidAccountType(accountType string) bool {
+	switch accountType {
+	case accountTypeIndividual, accountTypeMerchant, accountTypeSettlement, accountTypeSystem:
+		return true
+	}
+	return false
+}
+
+// checkAccountActive 检查账户已开立且未销户，role 用于错误信息
+func checkAccountActive(account *UserBalance, role string) error {
+	switch account.Status {
+	case accountStatusActive:
+		return nil
+	case accountStatusClosed:
+		return fmt.Errorf("%s account %s is closed", role, account.UserID)
+	}
+	return fmt.Errorf("%s account %s has not been opened", role, account.UserID)
+}
+
+// canManageAccount 判断调用者能否开立或关闭账户：账户所属银行的admin或央行admin
+func (s *SmartContract) canManageAccount(ctx contractapi.TransactionContextInterface, callerID string, accountOrg string) (bool, error) {
+	if !s.isAdminUser(ctx, callerID) {
+		return false, nil
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return false, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	isCentralBank, err := isCentralBankMSP(ctx, clientMSPID)
+	if err != nil {
+		return false, err
+	}
+	if isCentralBank {
+		return true, nil
+	}
+	callerDomain, err := s.extractDomainFromClientID(callerID)
+	if err != nil {
+		return false, fmt.Errorf("failed to extract caller domain: %v", err)
+	}
+	return callerDomain == accountOrg, nil
+}
+
+// openAccount 在内存中为未开立的账户填写开立信息
+func openAccount(account *UserBalance, accountType string, openedBy string, openedAt int64) {
+	account.AccountType = accountType
+	account.Status = accountStatusActive
+	account.OpenedAt = openedAt
+	account.OpenedBy = openedBy
+}
+
+// OpenAccount 为客户开立账户
+// account 为客户端ID；accountType 为 individual、merchant、settlement 或 system，为空时默认 individual
+func (s *SmartContract) OpenAccount(ctx contractapi.TransactionContextInterface, account string, accountType string) (string, error) {
+	// 检查合约初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+
+	if accountType == "" {
+		accountType = accountTypeIndividual
+	}
+	if !isValidAccountType(accountType) {
+		return "", fmt.Errorf("invalid account type %s: expected individual, merchant, settlement or system", accountType)
+	}
+
+	accountOrg, err := s.extractOrgMSPFromClientID(account)
+	if err != nil {
+		return "", fmt.Errorf("invalid account id: %v", err)
+	}
+
+	// 检查权限
+	allowed, err := s.canManageAccount(ctx, callerID, accountOrg)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "", fmt.Errorf("client is not authorized to open accounts for %s", accountOrg)
+	}
+	if accountType == accountTypeSystem {
+		clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+		if err != nil {
+			return "", fmt.Errorf("failed to get MSPID: %v", err)
+		}
+		isCentralBank, err := isCentralBankMSP(ctx, clientMSPID)
+		if err != nil {
+			return "", err
+		}
+		if !isCentralBank {
+			return "", fmt.Errorf("only the central bank can open system accounts")
+		}
+	}
+
+	userAccount, err := s.getUserAccountInfo(ctx, account)
+	if err != nil {
+		return "", fmt.Errorf("failed to read account %s: %v", account, err)
+	}
+	switch userAccount.Status {
+	case accountStatusActive:
+		return "", fmt.Errorf("account %s is already open", account)
+	case accountStatusClosed:
+		return "", fmt.Errorf("account %s is closed and cannot be reopened", account)
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	openAccount(userAccount, accountType, callerID, timestamp.Seconds)
+
+	// 扣款的背书策略：央行peer，以及按配置要求的账户所属银行peer
+	userAccount.BankMSP, err = s.accountBankMSP(ctx, callerID, account, accountOrg)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.updateUserAccountInPrivateCollection(ctx, userAccount)
+	if err != nil {
+		return "", err
+	}
+	endorsers, err := setAccountEndorsement(ctx, account, userAccount.BankMSP)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.emitEvent(ctx, eventSpec{
+		Type:       eventTypeAccountOpened,
+		Attributes: map[string]interface{}{"accountType": accountType, "endorsers": endorsers},
+		Parties:    map[string]string{"account": account, "openedBy": callerID},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("%s account opened for %s", accountType, account)
+
+	accountJSON, err := json.Marshal(userAccount)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal account: %v", err)
+	}
+	return string(accountJSON), nil
+}
+
+// CloseAccount 关闭账户，剩余余额划转到 sweepTo 后销户
+// 余额为零时 sweepTo 可以为空；账户与 sweepTo 都可以使用别名
+func (s *SmartContract) CloseAccount(ctx contractapi.TransactionContextInterface, account string, sweepTo string) (string, error) {
+	// 检查合约初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+
+	accountID, err := s.resolveAccount(ctx, account)
+	if err != nil {
+		return "", err
+	}
+	userAccount, err := s.getUserAccountInfo(ctx, accountID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read account %s: %v", account, err)
+	}
+	if err := checkAccountActive(userAccount, "the"); err != nil {
+		return "", err
+	}
+
+	// 检查权限
+	allowed, err := s.canManageAccount(ctx, callerID, userAccount.OrgMSP)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "", fmt.Errorf("client is not authorized to close account %s", account)
+	}
+	if err := s.checkNoConfidentialBalance(ctx, accountID); err != nil {
+		return "", err
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	sweptAmount := userAccount.Balance
+	result := map[string]interface{}{
+		"txId":        ctx.GetStub().GetTxID(),
+		"account":     accountID,
+		"sweptAmount": sweptAmount,
+	}
+
+	// 划转剩余余额
+	if userAccount.Balance > 0 {
+		if sweepTo == "" {
+			return "", fmt.Errorf("account %s has a balance of %d, a sweep account is required", account, userAccount.Balance)
+		}
+		sweepID, err := s.resolveAccount(ctx, sweepTo)
+		if err != nil {
+			return "", err
+		}
+		if sweepID == accountID {
+			return "", fmt.Errorf("sweep account must differ from the account being closed")
+		}
+
+		err = s.transferHelperPrivate(ctx, accountID, sweepID, userAccount.Balance)
+		if err != nil {
+			return "", fmt.Errorf("failed to sweep balance: %v", err)
+		}
+
+		record, err := s.newTransactionRecord(ctx, "sweep", accountID, sweepID, userAccount.Balance)
+		if err != nil {
+			return "", err
+		}
+		record.Spender = callerID
+		err = s.bookTransactionRecord(ctx, record)
+		if err != nil {
+			return "", err
+		}
+		err = s.emitTransferEvent(ctx, record)
+		if err != nil {
+			return "", err
+		}
+
+		userAccount.SweepAccount = sweepID
+		result["sweptTo"] = sweepID
+	}
+
+	// 销户；同一交易内对同一键的最后一次写入生效，覆盖划转时写入的余额记录
+	userAccount.Balance = 0
+	userAccount.Status = accountStatusClosed
+	userAccount.ClosedAt = timestamp.Seconds
+	userAccount.ClosedBy = callerID
+	err = s.updateUserAccountInPrivateCollection(ctx, userAccount)
+	if err != nil {
+		return "", err
+	}
+
+	// 释放账户的别名
+	aliasKeys, err := s.accountAliasKeys(ctx, accountID)
+	if err != nil {
+		return "", err
+	}
+	for _, key := range aliasKeys {
+		record, err := s.getAliasRecord(ctx, key)
+		if err != nil {
+			return "", err
+		}
+		if record == nil {
+			continue
+		}
+		if err := s.deleteAliasRecord(ctx, key, record); err != nil {
+			return "", err
+		}
+	}
+	result["releasedAliases"] = len(aliasKeys)
+
+	// 同一信封中跟在划转事件之后
+	err = s.emitEvent(ctx, eventSpec{
+		Type:    eventTypeAccountClosed,
+		Parties: map[string]string{"account": accountID, "closedBy": callerID, "sweepTo": userAccount.SweepAccount},
+		Details: map[string]interface{}{"sweptAmount": sweptAmount},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("account %s closed, swept %d", accountID, sweptAmount)
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %v", err)
+	}
+	return string(resultJSON), nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func openAccountAs(env *testEnv, caller testUser, account string, accountType string) error {
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.OpenAccount(ctx, account, accountType)
+		return err
+	})
+	return err
+}
+
+func closeAccount(env *testEnv, caller testUser, account string, sweepTo string) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		response, err := env.contract.CloseAccount(ctx, account, sweepTo)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &result)
+	})
+	return result, err
+}
+
+// TestCloseAccountSweeps 销户时余额划转到指定账户、别名释放，销户后不能收款或重开
+func TestCloseAccountSweeps(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 100)
+	merchant := newTestUser("Shop1", "a.example.com", "AMSP")
+	if err := openAccountAs(env, bankAAdmin, merchant.id, accountTypeMerchant); err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+	registerAlias(t, env, bankAUser, "@alice", "")
+
+	bankBAdmin := newTestUser("Admin", "b.example.com", "BMSP")
+	if _, err := closeAccount(env, bankBAdmin, bankAUser.id, merchant.id); err == nil || !strings.Contains(err.Error(), "not authorized to close") {
+		t.Fatalf("expected an authorization error, got %v", err)
+	}
+	if _, err := closeAccount(env, bankAAdmin, bankAUser.id, ""); err == nil || !strings.Contains(err.Error(), "a sweep account is required") {
+		t.Fatalf("expected a missing sweep account error, got %v", err)
+	}
+	if _, err := closeAccount(env, bankAAdmin, bankAUser.id, bankAUser.id); err == nil || !strings.Contains(err.Error(), "must differ") {
+		t.Fatalf("expected a self sweep error, got %v", err)
+	}
+
+	// 划转是对本行客户的扣款，由本行admin授权
+	sweepTxID := fmt.Sprintf("tx%04d", env.ledger.txn+2)
+	_, err := env.invoke(bankAAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.AuthorizeDebit(ctx, bankAUser.id, sweepTxID)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("AuthorizeDebit failed: %v", err)
+	}
+	result, err := closeAccount(env, bankAAdmin, bankAUser.id, merchant.id)
+	if err != nil {
+		t.Fatalf("CloseAccount failed: %v", err)
+	}
+	if result["sweptAmount"] != float64(100) || result["sweptTo"] != merchant.id || result["releasedAliases"] != float64(1) {
+		t.Fatalf("unexpected close result: %v", result)
+	}
+	if got := balanceOf(t, env, merchant); got != 100 {
+		t.Fatalf("merchant balance = %d, want 100", got)
+	}
+	if record := getTransactionRecord(t, env, result["txId"].(string)); record.TransactionType != "sweep" || record.Spender != bankAAdmin.id {
+		t.Fatalf("unexpected sweep record: %+v", record)
+	}
+	if _, err := resolveAlias(env, centralBankAdmin, "@alice"); err == nil {
+		t.Fatalf("expected the alias of the closed account to be released")
+	}
+
+	_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.Transfer(ctx, bankAUser.id, 10)
+	})
+	if err == nil || !strings.Contains(err.Error(), "is closed") {
+		t.Fatalf("expected a closed account error, got %v", err)
+	}
+	if err := openAccountAs(env, bankAAdmin, bankAUser.id, ""); err == nil || !strings.Contains(err.Error(), "cannot be reopened") {
+		t.Fatalf("expected a reopen error, got %v", err)
+	}
+	if _, err := closeAccount(env, bankAAdmin, bankAUser.id, merchant.id); err == nil || !strings.Contains(err.Error(), "is closed") {
+		t.Fatalf("expected a closed account error, got %v", err)
+	}
+}
+
+func TestOpenAccountRejects(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 0)
+	newcomer := newTestUser("User2", "a.example.com", "AMSP")
+
+	for _, tc := range []struct {
+		caller      testUser
+		account     string
+		accountType string
+		want        string
+	}{
+		{newTestUser("Admin", "b.example.com", "BMSP"), newcomer.id, "", "not authorized to open accounts"},
+		{bankAUser, newcomer.id, "", "not authorized to open accounts"},
+		{bankAAdmin, newcomer.id, "savings", "invalid account type"},
+		{bankAAdmin, "not-a-client-id", "", "invalid account id"},
+		{bankAAdmin, newcomer.id, accountTypeSystem, "only the central bank"},
+		{bankAAdmin, bankAUser.id, "", "is already open"},
+	} {
+		if err := openAccountAs(env, tc.caller, tc.account, tc.accountType); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("OpenAccount(%q) error = %v, want %q", tc.accountType, err, tc.want)
+		}
+	}
+	if err := openAccountAs(env, centralBankAdmin, newcomer.id, accountTypeSystem); err != nil {
+		t.Fatalf("central bank OpenAccount failed: %v", err)
+	}
+}
@@ -0,0 +1,640 @@
+/*
+账户别名目录
+
+- 用户可注册唯一别名代替 base64 客户端ID：@handle、phone:<号码>、email:<地址>
+- 电话与邮箱只保存加盐哈希与掩码提示，盐值保存在央行私有集合中
+- 别名须由账户所属银行的admin（或央行）验证后才能用于收付款与查询
+- 可见性控制 ResolveAlias 向谁披露账户ID：public、bank（默认）、private
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// aliasPrefix 别名记录的键前缀：alias_<kind>_<handle 或哈希>
+const aliasPrefix = "alias_"
+
+// aliasSaltKey 电话与邮箱别名哈希使用的盐值
+const aliasSaltKey = "aliassalt"
+
+// accountAliasIndex 账户 -> 别名键 的组合键索引
+const accountAliasIndex = "acct~alias"
+
+// maxAliasesPerAccount 每个账户最多注册别名数量的默认值，实际取自合约配置
+const maxAliasesPerAccount = 5
+
+// 别名类型
+const (
+	aliasKindHandle = "handle"
+	aliasKindPhone  = "phone"
+	aliasKindEmail  = "email"
+)
+
+// 别名状态
+const (
+	aliasStatusPending  = "pending"
+	aliasStatusVerified = "verified"
+)
+
+// 别名可见性
+const (
+	aliasVisibilityPublic  = "public"  // 任何调用者都可解析出账户ID
+	aliasVisibilityBank    = "bank"    // 任何调用者可确认别名及所属银行，账户ID仅向同行与央行披露
+	aliasVisibilityPrivate = "private" // 仅账户本人、所属银行admin与央行可解析
+)
+
+var (
+	handlePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{2,31}$`)
+	phonePattern  = regexp.MustCompile(`^\+?[0-9]{6,15}$`)
+)
+
+// AliasRecord 别名记录
+type AliasRecord struct {
+	Alias      string `json:"alias"`                // @handle，电话与邮箱为掩码提示
+	Kind       string `json:"kind"`                 // handle | phone | email
+	AccountID  string `json:"accountId"`            // 别名指向的客户端ID
+	OrgMSP     string `json:"orgMsp"`               // 账户所属组织域名
+	Visibility string `json:"visibility"`           // public | bank | private
+	Status     string `json:"status"`               // pending | verified
+	CreatedAt  int64  `json:"createdAt"`            // 注册时间
+	UpdatedAt  int64  `json:"updatedAt"`            // 最后修改时间
+	VerifiedAt int64  `json:"verifiedAt,omitempty"` // 验证时间
+	VerifiedBy string `json:"verifiedBy,omitempty"` // 验证人客户端ID
+}
+
+// parsedAlias 规范化后的别名
+type parsedAlias struct {
+	Kind  string
+	Value string // handle 名称或规范化的电话/邮箱
+}
+
+// isAliasReference 判断账户引用是否为别名；客户端ID为 base64，不含 @ 与 :
+func isAliasReference(ref string) bool {
+	return strings.HasPrefix(ref, "@") || strings.HasPrefix(ref, aliasKindPhone+":") || strings.HasPrefix(ref, aliasKindEmail+":")
+}
+
+// parseAlias 解析并规范化别名
+func parseAlias(alias string) (*parsedAlias, error) {
+	alias = strings.TrimSpace(alias)
+	switch {
+	case strings.HasPrefix(alias, "@"):
+		handle := strings.ToLower(alias[1:])
+		if !handlePattern.MatchString(handle) {
+			return nil, fmt.Errorf("invalid handle %s: use 3-32 characters a-z, 0-9, '.', '_' or '-'", alias)
+		}
+		return &parsedAlias{Kind: aliasKindHandle, Value: handle}, nil
+	case strings.HasPrefix(alias, aliasKindPhone+":"):
+		phone := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(alias[len(aliasKindPhone)+1:])
+		if !phonePattern.MatchString(phone) {
+			return nil, fmt.Errorf("invalid phone number in alias %s", alias)
+		}
+		return &parsedAlias{Kind: aliasKindPhone, Value: phone}, nil
+	case strings.HasPrefix(alias, aliasKindEmail+":"):
+		email := strings.ToLower(strings.TrimSpace(alias[len(aliasKindEmail)+1:]))
+		at := strings.Index(email, "@")
+		if len(email) > 254 || at <= 0 || at != strings.LastIndex(email, "@") || !strings.Contains(email[at+1:], ".") || strings.ContainsAny(email, " :") {
+			return nil, fmt.Errorf("invalid email address in alias %s", alias)
+		}
+		return &parsedAlias{Kind: aliasKindEmail, Value: email}, nil
+	}
+	return nil, fmt.Errorf("invalid alias %s: expected @handle, phone:<number> or email:<address>", alias)
+}
+
+// hint 返回可展示的别名：handle 原样返回，电话与邮箱做掩码
+func (a *parsedAlias) hint() string {
+	switch a.Kind {
+	case aliasKindPhone:
+		return aliasKindPhone + ":***" + a.Value[len(a.Value)-4:]
+	case aliasKindEmail:
+		at := strings.Index(a.Value, "@")
+		return aliasKindEmail + ":" + a.Value[:1] + "***" + a.Value[at:]
+	}
+	return "@" + a.Value
+}
+
+// aliasKey 返回别名的存储键；电话与邮箱使用加盐哈希，盐值不存在时 create 决定是否生成
+// 返回空字符串表示尚未生成盐值（即不存在任何哈希别名）
+func (s *SmartContract) aliasKey(ctx contractapi.TransactionContextInterface, alias *parsedAlias, create bool) (string, error) {
+	if alias.Kind == aliasKindHandle {
+		return aliasPrefix + aliasKindHandle + "_" + alias.Value, nil
+	}
+
+	stub := ctx.GetStub()
+	salt, err := stub.GetPrivateData(privateCollection(ctx), aliasSaltKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read alias salt: %v", err)
+	}
+	if salt == nil {
+		if !create {
+			return "", nil
+		}
+		// 盐值由首次注册的交易ID派生，各背书节点结果一致
+		digest := sha256.Sum256([]byte(aliasSaltKey + stub.GetChannelID() + stub.GetTxID()))
+		salt = []byte(hex.EncodeToString(digest[:]))
+		if err := stub.PutPrivateData(privateCollection(ctx), aliasSaltKey, salt); err != nil {
+			return "", fmt.Errorf("failed to store alias salt: %v", err)
+		}
+	}
+
+	digest := sha256.Sum256([]byte(string(salt) + "|" + alias.Kind + "|" + alias.Value))
+	return aliasPrefix + alias.Kind + "_" + hex.EncodeToString(digest[:]), nil
+}
+
+// getAliasRecord 读取别名记录，不存在时返回 nil
+func (s *SmartContract) getAliasRecord(ctx contractapi.TransactionContextInterface, key string) (*AliasRecord, error) {
+	if key == "" {
+		return nil, nil
+	}
+	recordBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alias %s: %v", key, err)
+	}
+	if recordBytes == nil {
+		return nil, nil
+	}
+	var record AliasRecord
+	if err := json.Unmarshal(recordBytes, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal alias %s: %v", key, err)
+	}
+	return &record, nil
+}
+
+// lookupAlias 解析别名并读取记录，返回存储键与记录（不存在时为 nil）
+func (s *SmartContract) lookupAlias(ctx contractapi.TransactionContextInterface, alias string) (string, *AliasRecord, error) {
+	parsed, err := parseAlias(alias)
+	if err != nil {
+		return "", nil, err
+	}
+	key, err := s.aliasKey(ctx, parsed, false)
+	if err != nil {
+		return "", nil, err
+	}
+	record, err := s.getAliasRecord(ctx, key)
+	if err != nil {
+		return "", nil, err
+	}
+	return key, record, nil
+}
+
+// putAliasRecord 写入别名记录及账户索引
+func (s *SmartContract) putAliasRecord(ctx contractapi.TransactionContextInterface, key string, record *AliasRecord) error {
+	stub := ctx.GetStub()
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alias: %v", err)
+	}
+	if err := stub.PutPrivateData(privateCollection(ctx), key, recordBytes); err != nil {
+		return fmt.Errorf("failed to store alias: %v", err)
+	}
+
+	indexKey, err := stub.CreateCompositeKey(accountAliasIndex, []string{record.AccountID, key})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", accountAliasIndex, err)
+	}
+	if err := stub.PutPrivateData(privateCollection(ctx), indexKey, indexValue); err != nil {
+		return fmt.Errorf("failed to store alias index: %v", err)
+	}
+	return nil
+}
+
+// deleteAliasRecord 删除别名记录及账户索引
+func (s *SmartContract) deleteAliasRecord(ctx contractapi.TransactionContextInterface, key string, record *AliasRecord) error {
+	stub := ctx.GetStub()
+	if err := stub.DelPrivateData(privateCollection(ctx), key); err != nil {
+		return fmt.Errorf("failed to delete alias: %v", err)
+	}
+
+	indexKey, err := stub.CreateCompositeKey(accountAliasIndex, []string{record.AccountID, key})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", accountAliasIndex, err)
+	}
+	if err := stub.DelPrivateData(privateCollection(ctx), indexKey); err != nil {
+		return fmt.Errorf("failed to delete alias index: %v", err)
+	}
+	return nil
+}
+
+// accountAliasKeys 返回账户已注册的别名存储键
+func (s *SmartContract) accountAliasKeys(ctx contractapi.TransactionContextInterface, account string) ([]string, error) {
+	stub := ctx.GetStub()
+	iterator, err := stub.GetPrivateDataByPartialCompositeKey(privateCollection(ctx), accountAliasIndex, []string{account})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan index %s: %v", accountAliasIndex, err)
+	}
+	defer iterator.Close()
+
+	var keys []string
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate index %s: %v", accountAliasIndex, err)
+		}
+		_, attributes, err := stub.SplitCompositeKey(entry.Key)
+		if err != nil || len(attributes) != 2 {
+			continue
+		}
+		keys = append(keys, attributes[1])
+	}
+	return keys, nil
+}
+
+// resolveAccount 将账户引用解析为客户端ID
+// 别名必须已验证；非别名引用原样返回
+func (s *SmartContract) resolveAccount(ctx contractapi.TransactionContextInterface, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if !isAliasReference(ref) {
+		return ref, nil
+	}
+	_, record, err := s.lookupAlias(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if record == nil || record.Status != aliasStatusVerified {
+		return "", fmt.Errorf("alias %s is not registered or not verified", ref)
+	}
+	return record.AccountID, nil
+}
+
+// canManageAlias 判断调用者是否为别名所属银行的admin或央行
+func (s *SmartContract) canManageAlias(ctx contractapi.TransactionContextInterface, callerID string, record *AliasRecord) (bool, error) {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return false, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	isCentralBank, err := isCentralBankMSP(ctx, clientMSPID)
+	if err != nil {
+		return false, err
+	}
+	if isCentralBank {
+		return true, nil
+	}
+	callerDomain, err := s.extractDomainFromClientID(callerID)
+	if err != nil {
+		return false, fmt.Errorf("failed to extract caller domain: %v", err)
+	}
+	return s.isAdminUser(ctx, callerID) && callerDomain == record.OrgMSP, nil
+}
+
+// checkAliasAccess 检查合约初始化并返回调用者ID
+func checkAliasAccess(ctx contractapi.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+	return callerID, nil
+}
+
+// normalizeAliasVisibility 校验可见性，空字符串返回 fallback
+func normalizeAliasVisibility(visibility string, fallback string) (string, error) {
+	switch visibility {
+	case "":
+		return fallback, nil
+	case aliasVisibilityPublic, aliasVisibilityBank, aliasVisibilityPrivate:
+		return visibility, nil
+	}
+	return "", fmt.Errorf("invalid visibility %s: expected public, bank or private", visibility)
+}
+
+// marshalAliasRecord 序列化别名记录
+func marshalAliasRecord(record *AliasRecord) (string, error) {
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal alias: %v", err)
+	}
+	return string(recordJSON), nil
+}
+
+// RegisterAlias 为调用者账户注册别名，注册后处于 pending 状态，需银行admin验证
+// visibility 为空时默认 bank
+func (s *SmartContract) RegisterAlias(ctx contractapi.TransactionContextInterface, alias string, visibility string) (string, error) {
+	callerID, err := checkAliasAccess(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	parsed, err := parseAlias(alias)
+	if err != nil {
+		return "", err
+	}
+	visibility, err = normalizeAliasVisibility(visibility, aliasVisibilityBank)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := s.aliasKey(ctx, parsed, true)
+	if err != nil {
+		return "", err
+	}
+	existing, err := s.getAliasRecord(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		if existing.AccountID == callerID {
+			return "", fmt.Errorf("alias %s is already registered to this account", parsed.hint())
+		}
+		return "", fmt.Errorf("alias %s is already taken", parsed.hint())
+	}
+
+	keys, err := s.accountAliasKeys(ctx, callerID)
+	if err != nil {
+		return "", err
+	}
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) >= config.Limits.MaxAliasesPerAccount {
+		return "", fmt.Errorf("account already has the maximum of %d aliases", config.Limits.MaxAliasesPerAccount)
+	}
+
+	account, err := s.getUserAccountInfo(ctx, callerID)
+	if err != nil {
+		return "", err
+	}
+	if err := checkAccountActive(account, "caller"); err != nil {
+		return "", err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	record := &AliasRecord{
+		Alias:      parsed.hint(),
+		Kind:       parsed.Kind,
+		AccountID:  callerID,
+		OrgMSP:     account.OrgMSP,
+		Visibility: visibility,
+		Status:     aliasStatusPending,
+		CreatedAt:  timestamp.Seconds,
+		UpdatedAt:  timestamp.Seconds,
+	}
+	if err := s.putAliasRecord(ctx, key, record); err != nil {
+		return "", err
+	}
+
+	log.Printf("Alias %s registered, pending verification", record.Alias)
+
+	return marshalAliasRecord(record)
+}
+
+// VerifyAlias 账户所属银行的admin或央行验证别名，验证后别名可用于收付款
+func (s *SmartContract) VerifyAlias(ctx contractapi.TransactionContextInterface, alias string) (string, error) {
+	callerID, err := checkAliasAccess(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	key, record, err := s.lookupAlias(ctx, alias)
+	if err != nil {
+		return "", err
+	}
+	if record == nil {
+		return "", fmt.Errorf("alias %s does not exist", alias)
+	}
+	allowed, err := s.canManageAlias(ctx, callerID, record)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "", fmt.Errorf("client is not authorized to verify alias %s", record.Alias)
+	}
+	if record.Status == aliasStatusVerified {
+		return "", fmt.Errorf("alias %s is already verified", record.Alias)
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	record.Status = aliasStatusVerified
+	record.VerifiedAt = timestamp.Seconds
+	record.VerifiedBy = callerID
+	record.UpdatedAt = timestamp.Seconds
+	if err := s.putAliasRecord(ctx, key, record); err != nil {
+		return "", err
+	}
+
+	log.Printf("Alias %s verified", record.Alias)
+
+	return marshalAliasRecord(record)
+}
+
+// UpdateAlias 账户本人修改别名或其可见性
+// newAlias 为空时保留原别名，否则改用新别名并重新进入 pending 状态；visibility 为空时保持不变
+func (s *SmartContract) UpdateAlias(ctx contractapi.TransactionContextInterface, alias string, newAlias string, visibility string) (string, error) {
+	callerID, err := checkAliasAccess(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	key, record, err := s.lookupAlias(ctx, alias)
+	if err != nil {
+		return "", err
+	}
+	if record == nil || record.AccountID != callerID {
+		return "", fmt.Errorf("alias %s is not registered to this account", alias)
+	}
+
+	record.Visibility, err = normalizeAliasVisibility(visibility, record.Visibility)
+	if err != nil {
+		return "", err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	record.UpdatedAt = timestamp.Seconds
+
+	if newAlias != "" {
+		parsed, err := parseAlias(newAlias)
+		if err != nil {
+			return "", err
+		}
+		newKey, err := s.aliasKey(ctx, parsed, true)
+		if err != nil {
+			return "", err
+		}
+		if newKey != key {
+			existing, err := s.getAliasRecord(ctx, newKey)
+			if err != nil {
+				return "", err
+			}
+			if existing != nil {
+				return "", fmt.Errorf("alias %s is already taken", parsed.hint())
+			}
+			if err := s.deleteAliasRecord(ctx, key, record); err != nil {
+				return "", err
+			}
+			key = newKey
+		}
+		record.Alias = parsed.hint()
+		record.Kind = parsed.Kind
+		record.Status = aliasStatusPending
+		record.VerifiedAt = 0
+		record.VerifiedBy = ""
+	}
+
+	if err := s.putAliasRecord(ctx, key, record); err != nil {
+		return "", err
+	}
+
+	log.Printf("Alias %s updated", record.Alias)
+
+	return marshalAliasRecord(record)
+}
+
+// DeleteAlias 删除别名；账户本人、所属银行admin与央行可以删除
+func (s *SmartContract) DeleteAlias(ctx contractapi.TransactionContextInterface, alias string) error {
+	callerID, err := checkAliasAccess(ctx)
+	if err != nil {
+		return err
+	}
+
+	key, record, err := s.lookupAlias(ctx, alias)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return fmt.Errorf("alias %s does not exist", alias)
+	}
+	if record.AccountID != callerID {
+		allowed, err := s.canManageAlias(ctx, callerID, record)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("client is not authorized to delete alias %s", record.Alias)
+		}
+	}
+
+	if err := s.deleteAliasRecord(ctx, key, record); err != nil {
+		return err
+	}
+
+	log.Printf("Alias %s deleted", record.Alias)
+
+	return nil
+}
+
+// ResolveAlias 按可见性解析别名
+// 账户本人、所属银行admin与央行总能看到完整记录；其他调用者只能解析已验证的别名：
+// public 返回账户ID，bank 仅向同行披露账户ID，private 视为不存在
+func (s *SmartContract) ResolveAlias(ctx contractapi.TransactionContextInterface, alias string) (string, error) {
+	callerID, err := checkAliasAccess(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	_, record, err := s.lookupAlias(ctx, alias)
+	if err != nil {
+		return "", err
+	}
+	notFound := fmt.Errorf("alias %s does not exist", alias)
+	if record == nil {
+		return "", notFound
+	}
+
+	if record.AccountID == callerID {
+		return marshalAliasRecord(record)
+	}
+	allowed, err := s.canManageAlias(ctx, callerID, record)
+	if err != nil {
+		return "", err
+	}
+	if allowed {
+		return marshalAliasRecord(record)
+	}
+
+	if record.Status != aliasStatusVerified || record.Visibility == aliasVisibilityPrivate {
+		return "", notFound
+	}
+
+	result := map[string]interface{}{
+		"alias":  record.Alias,
+		"kind":   record.Kind,
+		"orgMsp": record.OrgMSP,
+		"status": record.Status,
+	}
+	callerDomain, _ := s.extractDomainFromClientID(callerID)
+	if record.Visibility == aliasVisibilityPublic || callerDomain == record.OrgMSP {
+		result["accountId"] = record.AccountID
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal alias: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// ListAccountAliases 列出账户的全部别名，权限与 GetUserAccountInfo 一致
+// account 为空时列出调用者本人的别名
+func (s *SmartContract) ListAccountAliases(ctx contractapi.TransactionContextInterface, account string) (string, error) {
+	callerID, err := checkAliasAccess(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	target := callerID
+	if account != "" {
+		target, err = s.resolveAccount(ctx, account)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	hasPermission, err := s.checkAccountInfoPermission(ctx, callerID, target)
+	if err != nil {
+		return "", fmt.Errorf("failed to check permission: %v", err)
+	}
+	if !hasPermission {
+		return "", fmt.Errorf("caller does not have permission to view aliases of account %s", account)
+	}
+
+	keys, err := s.accountAliasKeys(ctx, target)
+	if err != nil {
+		return "", err
+	}
+	records := []*AliasRecord{}
+	for _, key := range keys {
+		record, err := s.getAliasRecord(ctx, key)
+		if err != nil {
+			return "", err
+		}
+		if record != nil {
+			records = append(records, record)
+		}
+	}
+
+	result := map[string]interface{}{
+		"account": target,
+		"aliases": records,
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal aliases: %v", err)
+	}
+	return string(resultJSON), nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// aliasCall 以 caller 身份调用返回别名记录的合约函数
+func aliasCall(env *testEnv, caller testUser, fn func(ctx contractapi.TransactionContextInterface) (string, error)) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		response, err := fn(ctx)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &result)
+	})
+	return result, err
+}
+
+func registerAlias(t *testing.T, env *testEnv, caller testUser, alias string, visibility string) map[string]interface{} {
+	t.Helper()
+	record, err := aliasCall(env, caller, func(ctx contractapi.TransactionContextInterface) (string, error) {
+		return env.contract.RegisterAlias(ctx, alias, visibility)
+	})
+	if err != nil {
+		t.Fatalf("RegisterAlias %s failed: %v", alias, err)
+	}
+	return record
+}
+
+func verifyAlias(env *testEnv, caller testUser, alias string) (map[string]interface{}, error) {
+	return aliasCall(env, caller, func(ctx contractapi.TransactionContextInterface) (string, error) {
+		return env.contract.VerifyAlias(ctx, alias)
+	})
+}
+
+func resolveAlias(env *testEnv, caller testUser, alias string) (map[string]interface{}, error) {
+	return aliasCall(env, caller, func(ctx contractapi.TransactionContextInterface) (string, error) {
+		return env.contract.ResolveAlias(ctx, alias)
+	})
+}
+
+func listAliases(env *testEnv, caller testUser, account string) ([]interface{}, error) {
+	result, err := aliasCall(env, caller, func(ctx contractapi.TransactionContextInterface) (string, error) {
+		return env.contract.ListAccountAliases(ctx, account)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result["aliases"].([]interface{}), nil
+}
+
+// TestAliasLifecycle 注册、银行验证、按可见性解析、收款、修改与删除
+func TestAliasLifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 100)
+	outsider := newTestUser("User1", "b.example.com", "BMSP")
+
+	record := registerAlias(t, env, bankAUser, "@Alice", "")
+	if record["alias"] != "@alice" || record["status"] != aliasStatusPending || record["visibility"] != aliasVisibilityBank {
+		t.Fatalf("unexpected alias record: %v", record)
+	}
+	phone := registerAlias(t, env, bankAUser, "phone:+1 555-0100-22", aliasVisibilityPrivate)
+	if phone["alias"] != "phone:***0022" {
+		t.Fatalf("phone alias hint = %v", phone["alias"])
+	}
+
+	// 未验证的别名只对本人、本行admin与央行可见
+	if _, err := resolveAlias(env, outsider, "@alice"); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("expected a pending alias to stay hidden, got %v", err)
+	}
+	if _, err := verifyAlias(env, outsider, "@alice"); err == nil || !strings.Contains(err.Error(), "not authorized to verify") {
+		t.Fatalf("expected an authorization error, got %v", err)
+	}
+	for _, alias := range []string{"@alice", "phone:+15550100 22"} {
+		if _, err := verifyAlias(env, bankAAdmin, alias); err != nil {
+			t.Fatalf("VerifyAlias %s failed: %v", alias, err)
+		}
+	}
+	if _, err := verifyAlias(env, bankAAdmin, "@alice"); err == nil || !strings.Contains(err.Error(), "already verified") {
+		t.Fatalf("expected an already verified error, got %v", err)
+	}
+
+	// bank 可见性只向同行披露账户ID，private 对外视为不存在
+	resolved, err := resolveAlias(env, outsider, "@alice")
+	if err != nil {
+		t.Fatalf("ResolveAlias failed: %v", err)
+	}
+	if resolved["accountId"] != nil || resolved["orgMsp"] != "a.example.com" {
+		t.Fatalf("bank visibility disclosed too much: %v", resolved)
+	}
+	if resolved, err := resolveAlias(env, bankAAdmin, "@alice"); err != nil || resolved["accountId"] != bankAUser.id {
+		t.Fatalf("bank admin resolution = %v, %v", resolved, err)
+	}
+	if _, err := resolveAlias(env, outsider, "phone:+15550100 22"); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("expected a private alias to stay hidden, got %v", err)
+	}
+
+	// 已验证的别名可以代替账户ID收款
+	_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.Transfer(ctx, "@alice", 25)
+	})
+	if err != nil {
+		t.Fatalf("Transfer to alias failed: %v", err)
+	}
+	if got := balanceOf(t, env, bankAUser); got != 125 {
+		t.Fatalf("balance = %d, want 125", got)
+	}
+
+	// 改名后重新进入 pending 状态，旧别名释放
+	updated, err := aliasCall(env, bankAUser, func(ctx contractapi.TransactionContextInterface) (string, error) {
+		return env.contract.UpdateAlias(ctx, "@alice", "@alice.w", aliasVisibilityPublic)
+	})
+	if err != nil {
+		t.Fatalf("UpdateAlias failed: %v", err)
+	}
+	if updated["status"] != aliasStatusPending || updated["visibility"] != aliasVisibilityPublic {
+		t.Fatalf("unexpected updated alias: %v", updated)
+	}
+	if _, err := resolveAlias(env, bankAUser, "@alice"); err == nil {
+		t.Fatalf("expected the old alias to be released")
+	}
+
+	aliases, err := listAliases(env, bankAUser, "")
+	if err != nil || len(aliases) != 2 {
+		t.Fatalf("ListAccountAliases = %v, %v", aliases, err)
+	}
+	if _, err := listAliases(env, outsider, bankAUser.id); err == nil || !strings.Contains(err.Error(), "does not have permission") {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+
+	deleteAlias := func(caller testUser, alias string) error {
+		_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+			return env.contract.DeleteAlias(ctx, alias)
+		})
+		return err
+	}
+	if err := deleteAlias(outsider, "@alice.w"); err == nil || !strings.Contains(err.Error(), "not authorized to delete") {
+		t.Fatalf("expected an authorization error, got %v", err)
+	}
+	if err := deleteAlias(bankAUser, "@alice.w"); err != nil {
+		t.Fatalf("DeleteAlias failed: %v", err)
+	}
+	if aliases, err := listAliases(env, centralBankAdmin, bankAUser.id); err != nil || len(aliases) != 1 {
+		t.Fatalf("ListAccountAliases after delete = %v, %v", aliases, err)
+	}
+}
+
+func TestRegisterAliasRejects(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 0)
+	registerAlias(t, env, bankAUser, "@alice", "")
+
+	for _, tc := range []struct {
+		caller     testUser
+		alias      string
+		visibility string
+		want       string
+	}{
+		{bankAUser, "@a", "", "invalid handle"},
+		{bankAUser, "email:alice", "", "invalid email address"},
+		{bankAUser, "alice", "", "invalid alias"},
+		{bankAUser, "@bob", "secret", "invalid visibility"},
+		{bankAUser, "@ALICE", "", "already registered to this account"},
+		{centralBankAdmin, "@alice", "", "already taken"},
+	} {
+		_, err := aliasCall(env, tc.caller, func(ctx contractapi.TransactionContextInterface) (string, error) {
+			return env.contract.RegisterAlias(ctx, tc.alias, tc.visibility)
+		})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("RegisterAlias(%q, %q) error = %v, want %q", tc.alias, tc.visibility, err, tc.want)
+		}
+	}
+}
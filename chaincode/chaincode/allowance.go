@@ -0,0 +1,442 @@
+/*
+授权额度管理
+
+- Approve 只接受非负额度；ApproveWithExpiry 可设置到期时间（Unix 秒），到期后额度视为零
+- IncreaseAllowance/DecreaseAllowance 在现有额度上增减，避免覆盖式 Approve 的竞态
+- RevokeAllowance 撤销授权，额度为零的授权记录直接删除
+- Allowance 与授权列表查询采用与 BalanceOf 相同的权限模型，授权方或被授权方任一可见即可查询
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// 授权变更操作，随 Approval 事件发出
+const (
+	allowanceActionApprove  = "approve"
+	allowanceActionIncrease = "increase"
+	allowanceActionDecrease = "decrease"
+	allowanceActionRevoke   = "revoke"
+)
+
+// allowanceView 授权列表中的一条授权
+type allowanceView struct {
+	AllowanceRecord
+	Expired bool `json:"expired"`
+}
+
+// expired 判断授权在 now（Unix 秒）时是否已到期
+func (r *AllowanceRecord) expired(now int64) bool {
+	return r.ExpiresAt > 0 && now >= r.ExpiresAt
+}
+
+// createAllowanceKey 创建 owner 授权给 spender 的额度键
+func createAllowanceKey(ctx contractapi.TransactionContextInterface, owner string, spender string) (string, error) {
+	allowanceKey, err := ctx.GetStub().CreateCompositeKey(allowancePrefix, []string{owner, spender})
+	if err != nil {
+		return "", fmt.Errorf("failed to create the composite key for prefix %s: %v", allowancePrefix, err)
+	}
+	return allowanceKey, nil
+}
+
+// upgradeLegacyAllowance 将一条授权记录转换为当前格式，返回是否为只存储额度整数的旧格式
+func upgradeLegacyAllowance(owner string, spender string, data []byte) (*AllowanceRecord, bool, error) {
+	var record AllowanceRecord
+	if err := json.Unmarshal(data, &record); err == nil {
+		record.Owner = owner
+		record.Spender = spender
+		return &record, false, nil
+	}
+	value, err := strconv.Atoi(string(data))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse allowance %s -> %s: %v", owner, spender, err)
+	}
+	return &AllowanceRecord{Owner: owner, Spender: spender, Value: value}, true, nil
+}
+
+// parseAllowanceRecord 解析授权记录；迁移步骤 allowances 完成后旧格式报错
+func parseAllowanceRecord(ctx contractapi.TransactionContextInterface, owner string, spender string, data []byte) (*AllowanceRecord, error) {
+	record, legacy, err := upgradeLegacyAllowance(owner, spender, data)
+	if err != nil {
+		return nil, err
+	}
+	if legacy {
+		done, err := migrationDone(ctx, migrationAllowances)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return nil, legacyFormatError("allowance", owner+" -> "+spender, migrationAllowances)
+		}
+	}
+	return record, nil
+}
+
+// getAllowanceRecord 读取授权记录（含已到期的授权）；不存在时返回额度为零的记录
+func (s *SmartContract) getAllowanceRecord(ctx contractapi.TransactionContextInterface, owner string, spender string) (*AllowanceRecord, error) {
+	allowanceKey, err := createAllowanceKey(ctx, owner, spender)
+	if err != nil {
+		return nil, err
+	}
+
+	allowanceBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), allowanceKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read allowance for %s from private collection: %v", allowanceKey, err)
+	}
+	if allowanceBytes == nil {
+		return &AllowanceRecord{Owner: owner, Spender: spender}, nil
+	}
+	return parseAllowanceRecord(ctx, owner, spender, allowanceBytes)
+}
+
+// putAllowanceRecord 写入授权记录，额度为零时删除记录
+func (s *SmartContract) putAllowanceRecord(ctx contractapi.TransactionContextInterface, record *AllowanceRecord) error {
+	allowanceKey, err := createAllowanceKey(ctx, record.Owner, record.Spender)
+	if err != nil {
+		return err
+	}
+
+	if record.Value == 0 {
+		err = ctx.GetStub().DelPrivateData(privateCollection(ctx), allowanceKey)
+		if err != nil {
+			return fmt.Errorf("failed to delete allowance %s: %v", allowanceKey, err)
+		}
+		return nil
+	}
+
+	allowanceRecordBytes, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal allowance record: %v", err)
+	}
+	err = ctx.GetStub().PutPrivateData(privateCollection(ctx), allowanceKey, allowanceRecordBytes)
+	if err != nil {
+		return fmt.Errorf("failed to update state of smart contract for key %s: %v", allowanceKey, err)
+	}
+	return nil
+}
+
+// currentAllowance 读取 owner 授权给 spender 的有效授权与当前交易时间；已到期的授权视为不存在
+func (s *SmartContract) currentAllowance(ctx contractapi.TransactionContextInterface, owner string, spender string) (*AllowanceRecord, int64, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	record, err := s.getAllowanceRecord(ctx, owner, spender)
+	if err != nil {
+		return nil, 0, err
+	}
+	if record.expired(timestamp.Seconds) {
+		record = &AllowanceRecord{Owner: owner, Spender: spender}
+	}
+	return record, timestamp.Seconds, nil
+}
+
+// checkSpenderActive 检查被授权方是已开立的账户
+func (s *SmartContract) checkSpenderActive(ctx contractapi.TransactionContextInterface, spender string) error {
+	spenderAccount, err := s.getUserAccountInfo(ctx, spender)
+	if err != nil {
+		return fmt.Errorf("failed to read spender account %s: %v", spender, err)
+	}
+	return checkAccountActive(spenderAccount, "spender")
+}
+
+// allowanceCaller 检查合约初始化，返回调用者ID与解析别名后的被授权方
+func (s *SmartContract) allowanceCaller(ctx contractapi.TransactionContextInterface, spender string) (string, string, error) {
+	// 首先检查合约是否已初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	// 获取提交客户端身份的ID
+	owner, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	// 解析被授权方别名
+	spender, err = s.resolveAccount(ctx, spender)
+	if err != nil {
+		return "", "", err
+	}
+	if spender == owner {
+		return "", "", fmt.Errorf("cannot approve an allowance for the owner account itself")
+	}
+	return owner, spender, nil
+}
+
+// setAllowance 写入新的授权额度，记录交易并发出 Approval 事件
+func (s *SmartContract) setAllowance(ctx contractapi.TransactionContextInterface, record *AllowanceRecord, action string, updatedAt int64) error {
+	record.UpdatedAt = updatedAt
+	err := s.putAllowanceRecord(ctx, record)
+	if err != nil {
+		return err
+	}
+
+	// 创建交易记录
+	txRecord, err := s.newTransactionRecord(ctx, "approve", record.Owner, record.Spender, record.Value)
+	if err != nil {
+		return err
+	}
+	txRecord.Spender = record.Spender
+
+	// 央行存储交易记录
+	err = s.bookTransactionRecord(ctx, txRecord)
+	if err != nil {
+		return err
+	}
+
+	// 发出 Approval 事件
+	details := map[string]interface{}{
+		"value":  record.Value,
+		"action": action,
+	}
+	if record.ExpiresAt > 0 {
+		details["expiresAt"] = record.ExpiresAt
+	}
+	err = s.emitEvent(ctx, eventSpec{
+		Type:       eventTypeApproval,
+		Attributes: map[string]interface{}{"action": action},
+		Parties:    map[string]string{"owner": record.Owner, "spender": record.Spender},
+		Details:    details,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("client %s set allowance (%s) of %d tokens for spender %s", record.Owner, action, record.Value, record.Spender)
+
+	// ISO 20022 结构化日志（pacs.008 + camt.053）——非清算授权事件
+	if err := s.logISO20022Pacs008AndCamt053(ctx, "approve", record.Owner, record.Spender, record.Value, record.Spender, nil, nil); err != nil {
+		log.Printf("ISO20022 logging (approve) failed: %v", err)
+	}
+
+	return nil
+}
+
+// approve 以覆盖方式设置授权额度；expiresAt 为 0 表示不过期
+func (s *SmartContract) approve(ctx contractapi.TransactionContextInterface, spender string, value int, expiresAt int64) error {
+	if value < 0 {
+		return fmt.Errorf("allowance value must not be negative")
+	}
+
+	owner, spender, err := s.allowanceCaller(ctx, spender)
+	if err != nil {
+		return err
+	}
+
+	// 被授权方必须是已开立的账户
+	if err := s.checkSpenderActive(ctx, spender); err != nil {
+		return err
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	if expiresAt != 0 && expiresAt <= timestamp.Seconds {
+		return fmt.Errorf("allowance expiry %d must be in the future", expiresAt)
+	}
+
+	record := &AllowanceRecord{
+		Owner:     owner,
+		Spender:   spender,
+		Value:     value,
+		ExpiresAt: expiresAt,
+	}
+	return s.setAllowance(ctx, record, allowanceActionApprove, timestamp.Seconds)
+}
+
+// ApproveWithExpiry 允许 spender 在 expiresAt（Unix 秒）之前从调用客户端账户中提取最多 value 金额
+// 此函数触发 Approval 事件
+func (s *SmartContract) ApproveWithExpiry(ctx contractapi.TransactionContextInterface, spender string, value int, expiresAt int64) error {
+	if expiresAt <= 0 {
+		return fmt.Errorf("allowance expiry must be a positive Unix timestamp")
+	}
+	return s.approve(ctx, spender, value, expiresAt)
+}
+
+// IncreaseAllowance 在现有授权额度上增加 addedValue，保留原到期时间
+// 已到期的授权视为零额度且不再过期
+func (s *SmartContract) IncreaseAllowance(ctx contractapi.TransactionContextInterface, spender string, addedValue int) error {
+	if addedValue <= 0 {
+		return fmt.Errorf("added value must be positive")
+	}
+
+	owner, spender, err := s.allowanceCaller(ctx, spender)
+	if err != nil {
+		return err
+	}
+	if err := s.checkSpenderActive(ctx, spender); err != nil {
+		return err
+	}
+
+	record, now, err := s.currentAllowance(ctx, owner, spender)
+	if err != nil {
+		return err
+	}
+	record.Value, err = add(record.Value, addedValue)
+	if err != nil {
+		return err
+	}
+
+	return s.setAllowance(ctx, record, allowanceActionIncrease, now)
+}
+
+// DecreaseAllowance 在现有授权额度上减少 subtractedValue，额度不能减到零以下
+func (s *SmartContract) DecreaseAllowance(ctx contractapi.TransactionContextInterface, spender string, subtractedValue int) error {
+	if subtractedValue <= 0 {
+		return fmt.Errorf("subtracted value must be positive")
+	}
+
+	owner, spender, err := s.allowanceCaller(ctx, spender)
+	if err != nil {
+		return err
+	}
+
+	record, now, err := s.currentAllowance(ctx, owner, spender)
+	if err != nil {
+		return err
+	}
+	if record.Value < subtractedValue {
+		return fmt.Errorf("decreased allowance below zero: current allowance for spender %s is %d", spender, record.Value)
+	}
+	record.Value -= subtractedValue
+
+	return s.setAllowance(ctx, record, allowanceActionDecrease, now)
+}
+
+// RevokeAllowance 撤销调用者授权给 spender 的额度（含已到期的授权）
+func (s *SmartContract) RevokeAllowance(ctx contractapi.TransactionContextInterface, spender string) error {
+	owner, spender, err := s.allowanceCaller(ctx, spender)
+	if err != nil {
+		return err
+	}
+
+	record, err := s.getAllowanceRecord(ctx, owner, spender)
+	if err != nil {
+		return err
+	}
+	if record.Value == 0 {
+		return fmt.Errorf("no allowance granted to spender %s", spender)
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	record.Value = 0
+	record.ExpiresAt = 0
+	return s.setAllowance(ctx, record, allowanceActionRevoke, timestamp.Seconds)
+}
+
+// ListAllowancesByOwner 列出账户授权给他人的全部额度
+func (s *SmartContract) ListAllowancesByOwner(ctx contractapi.TransactionContextInterface, owner string) (string, error) {
+	return s.listAllowances(ctx, owner, true)
+}
+
+// ListAllowancesBySpender 列出他人授权给账户的全部额度
+func (s *SmartContract) ListAllowancesBySpender(ctx contractapi.TransactionContextInterface, spender string) (string, error) {
+	return s.listAllowances(ctx, spender, false)
+}
+
+// listAllowances 按授权方或被授权方扫描 allowancePrefix 复合键
+// 按授权方查询使用部分复合键；按被授权方查询扫描全部授权后过滤
+func (s *SmartContract) listAllowances(ctx contractapi.TransactionContextInterface, account string, byOwner bool) (string, error) {
+	// 首先检查合约是否已初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	// 获取当前调用者的信息
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+
+	// 解析账户别名
+	accountID, err := s.resolveAccount(ctx, account)
+	if err != nil {
+		return "", err
+	}
+
+	// 检查权限
+	hasPermission, err := s.checkBalancePermission(ctx, callerID, accountID)
+	if err != nil {
+		return "", fmt.Errorf("failed to check permission: %v", err)
+	}
+	if !hasPermission {
+		return "", fmt.Errorf("caller does not have permission to view allowances of account %s", account)
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	var attributes []string
+	if byOwner {
+		attributes = []string{accountID}
+	}
+	iterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(privateCollection(ctx), allowancePrefix, attributes)
+	if err != nil {
+		return "", fmt.Errorf("failed to scan allowances: %v", err)
+	}
+	defer iterator.Close()
+
+	allowances := []allowanceView{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return "", fmt.Errorf("failed to iterate allowances: %v", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil || len(keyParts) != 2 {
+			continue
+		}
+		if !byOwner && keyParts[1] != accountID {
+			continue
+		}
+
+		record, err := parseAllowanceRecord(ctx, keyParts[0], keyParts[1], kv.Value)
+		if err != nil {
+			return "", err
+		}
+		if record.Value == 0 {
+			continue
+		}
+		allowances = append(allowances, allowanceView{
+			AllowanceRecord: *record,
+			Expired:         record.expired(timestamp.Seconds),
+		})
+	}
+
+	result := map[string]interface{}{
+		"account":    accountID,
+		"allowances": allowances,
+		"count":      len(allowances),
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal allowances: %v", err)
+	}
+	return string(resultJSON), nil
+}
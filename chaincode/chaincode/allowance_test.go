@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+type allowanceList struct {
+	Count      int             `json:"count"`
+	Allowances []allowanceView `json:"allowances"`
+}
+
+func listAllowances(env *testEnv, caller testUser, account string, byOwner bool) (*allowanceList, error) {
+	var list allowanceList
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var response string
+		var err error
+		if byOwner {
+			response, err = env.contract.ListAllowancesByOwner(ctx, account)
+		} else {
+			response, err = env.contract.ListAllowancesBySpender(ctx, account)
+		}
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &list)
+	})
+	return &list, err
+}
+
+// allowanceOf 以授权方身份读取唯一一条授权
+func allowanceOf(t *testing.T, env *testEnv, owner testUser) allowanceView {
+	t.Helper()
+	list, err := listAllowances(env, owner, owner.id, true)
+	if err != nil {
+		t.Fatalf("ListAllowancesByOwner failed: %v", err)
+	}
+	if list.Count != 1 {
+		t.Fatalf("expected one allowance, got %+v", list)
+	}
+	return list.Allowances[0]
+}
+
+// TestAllowanceLifecycle 设置带到期时间的额度、增减、按额度扣款、到期与撤销
+func TestAllowanceLifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 100)
+	merchant := newTestUser("Shop1", "a.example.com", "AMSP")
+	if err := openAccountAs(env, bankAAdmin, merchant.id, accountTypeMerchant); err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+	owner := func(fn func(ctx contractapi.TransactionContextInterface) error) {
+		t.Helper()
+		if _, err := env.invoke(bankAUser, nil, fn); err != nil {
+			t.Fatalf("allowance update failed: %v", err)
+		}
+	}
+
+	expiresAt := env.ledger.now + 1000
+	owner(func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.ApproveWithExpiry(ctx, merchant.id, 50, expiresAt)
+	})
+	owner(func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.IncreaseAllowance(ctx, merchant.id, 20)
+	})
+	owner(func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.DecreaseAllowance(ctx, merchant.id, 30)
+	})
+	if allowance := allowanceOf(t, env, bankAUser); allowance.Value != 40 || allowance.ExpiresAt != expiresAt || allowance.Expired {
+		t.Fatalf("unexpected allowance: %+v", allowance)
+	}
+	list, err := listAllowances(env, merchant, merchant.id, false)
+	if err != nil || list.Count != 1 || list.Allowances[0].Owner != bankAUser.id {
+		t.Fatalf("ListAllowancesBySpender = %+v, %v", list, err)
+	}
+
+	// 被授权方在额度内扣款，扣款同样需要付款方银行授权
+	authorizeDebit(t, env, bankAUser)
+	_, err = env.invoke(merchant, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.TransferFrom(ctx, bankAUser.id, merchant.id, 15)
+	})
+	if err != nil {
+		t.Fatalf("TransferFrom failed: %v", err)
+	}
+	if allowance := allowanceOf(t, env, bankAUser); allowance.Value != 25 {
+		t.Fatalf("allowance after TransferFrom = %d, want 25", allowance.Value)
+	}
+
+	// 到期后列表标记为过期，增加额度时从零开始且不再过期
+	env.ledger.now = expiresAt
+	if allowance := allowanceOf(t, env, bankAUser); !allowance.Expired {
+		t.Fatalf("expected the allowance to be expired: %+v", allowance)
+	}
+	owner(func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.IncreaseAllowance(ctx, merchant.id, 5)
+	})
+	if allowance := allowanceOf(t, env, bankAUser); allowance.Value != 5 || allowance.ExpiresAt != 0 || allowance.Expired {
+		t.Fatalf("unexpected allowance after expiry: %+v", allowance)
+	}
+
+	owner(func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.RevokeAllowance(ctx, merchant.id)
+	})
+	if list, err := listAllowances(env, bankAUser, bankAUser.id, true); err != nil || list.Count != 0 {
+		t.Fatalf("allowances after revoke = %+v, %v", list, err)
+	}
+}
+
+func TestAllowanceRejects(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 100)
+	merchant := newTestUser("Shop1", "a.example.com", "AMSP")
+	if err := openAccountAs(env, bankAAdmin, merchant.id, accountTypeMerchant); err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+	unopened := newTestUser("User2", "a.example.com", "AMSP")
+	now := env.ledger.now
+
+	for _, tc := range []struct {
+		name string
+		fn   func(ctx contractapi.TransactionContextInterface) error
+		want string
+	}{
+		{"zero expiry", func(ctx contractapi.TransactionContextInterface) error {
+			return env.contract.ApproveWithExpiry(ctx, merchant.id, 10, 0)
+		}, "positive Unix timestamp"},
+		{"past expiry", func(ctx contractapi.TransactionContextInterface) error {
+			return env.contract.ApproveWithExpiry(ctx, merchant.id, 10, now)
+		}, "must be in the future"},
+		{"negative value", func(ctx contractapi.TransactionContextInterface) error {
+			return env.contract.ApproveWithExpiry(ctx, merchant.id, -1, now+1000)
+		}, "must not be negative"},
+		{"owner as spender", func(ctx contractapi.TransactionContextInterface) error {
+			return env.contract.IncreaseAllowance(ctx, bankAUser.id, 10)
+		}, "owner account itself"},
+		{"unopened spender", func(ctx contractapi.TransactionContextInterface) error {
+			return env.contract.IncreaseAllowance(ctx, unopened.id, 10)
+		}, "has not been opened"},
+		{"zero increase", func(ctx contractapi.TransactionContextInterface) error {
+			return env.contract.IncreaseAllowance(ctx, merchant.id, 0)
+		}, "must be positive"},
+		{"decrease below zero", func(ctx contractapi.TransactionContextInterface) error {
+			return env.contract.DecreaseAllowance(ctx, merchant.id, 1)
+		}, "below zero"},
+		{"revoke missing", func(ctx contractapi.TransactionContextInterface) error {
+			return env.contract.RevokeAllowance(ctx, merchant.id)
+		}, "no allowance granted"},
+	} {
+		if _, err := env.invoke(bankAUser, nil, tc.fn); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: error = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+
+	outsider := newTestUser("User1", "b.example.com", "BMSP")
+	if _, err := listAllowances(env, outsider, bankAUser.id, true); err == nil || !strings.Contains(err.Error(), "does not have permission") {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+	if _, err := listAllowances(env, outsider, merchant.id, false); err == nil || !strings.Contains(err.Error(), "does not have permission") {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+}
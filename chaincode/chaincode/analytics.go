@@ -0,0 +1,674 @@
+/*
+央行货币统计分析
+
+- 记账时每笔链码交易写入只写不读的统计增量：按日期与交易类型的笔数/金额、每日活跃账户、总供应量变动，
+  并发交易不会因共享的聚合键产生读写冲突
+- 统计查询汇总期间内的增量键，并读取银行汇总账与余额记录，不扫描交易记录
+- 所有查询仅限央行调用，应以 evaluate 方式执行
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// 统计聚合的键前缀
+const transactionStatPrefix = "txstat_" // txstat_<YYYYMMDD>_<交易类型>_<交易ID> -> 该交易的 periodStat 增量
+const activeAccountPrefix = "active_"   // active_<YYYYMMDD>_<账户> -> 当日有记账
+const supplyChangePrefix = "supply_"    // supply_<YYYYMMDD>_<交易ID> -> 该交易的总供应量变动
+
+// analyticsDayLayout 聚合键中的日期格式（UTC）
+const analyticsDayLayout = "20060102"
+
+// maxAnalyticsDays 单次统计查询最多覆盖天数的默认值，实际取自合约配置
+const maxAnalyticsDays = 3660
+
+// maxHoldingsScan 持有集中度统计最多扫描账户数的默认值，实际取自合约配置
+const maxHoldingsScan = 10000
+
+// circulationTypes 在持有人之间流转资金的交易类型（用于计算货币流通速度）
+var circulationTypes = []string{"transfer", "transferFrom", "directDebit", "return", "sweep"}
+
+// periodStat 某一期间内某类交易的笔数与金额
+type periodStat struct {
+	Count  int `json:"count"`
+	Volume int `json:"volume"`
+}
+
+// transactionAggregates 一笔链码交易内累积的统计增量
+// 同一交易的多条记录写入同一个增量键，需要先合并再一次性写入
+type transactionAggregates struct {
+	stats  map[string]*periodStat
+	active map[string]bool
+}
+
+// newTransactionAggregates 创建空的统计增量
+func newTransactionAggregates() *transactionAggregates {
+	return &transactionAggregates{
+		stats:  map[string]*periodStat{},
+		active: map[string]bool{},
+	}
+}
+
+// add 将一条交易记录计入统计增量；授权（approve）只计入笔数，不产生活跃账户
+func (a *transactionAggregates) add(record *TransactionRecord) {
+	day := analyticsDay(record.Timestamp)
+
+	key := transactionStatPrefix + day + "_" + record.TransactionType
+	stat, ok := a.stats[key]
+	if !ok {
+		stat = &periodStat{}
+		a.stats[key] = stat
+	}
+	stat.Count++
+	stat.Volume += record.Amount
+
+	if record.TransactionType == "approve" {
+		return
+	}
+	for _, account := range []string{record.From, record.To} {
+		if account != "" && account != "0x0" {
+			a.active[activeAccountPrefix+day+"_"+account] = true
+		}
+	}
+}
+
+// flushTransactionAggregates 将统计增量写入私有集合
+// 增量键带交易ID，只写不读，不会与并发交易产生读写冲突
+func (s *SmartContract) flushTransactionAggregates(ctx contractapi.TransactionContextInterface, aggregates *transactionAggregates) error {
+	stub := ctx.GetStub()
+	txID := stub.GetTxID()
+
+	for _, key := range sortedStatKeys(aggregates.stats) {
+		statBytes, err := json.Marshal(aggregates.stats[key])
+		if err != nil {
+			return fmt.Errorf("failed to marshal transaction statistics: %v", err)
+		}
+		if err := stub.PutPrivateData(privateCollection(ctx), key+"_"+txID, statBytes); err != nil {
+			return fmt.Errorf("failed to store transaction statistics %s: %v", key, err)
+		}
+	}
+
+	// 活跃账户标记同样只写不读
+	for _, key := range sortedKeys(aggregates.active) {
+		if err := stub.PutPrivateData(privateCollection(ctx), key, indexValue); err != nil {
+			return fmt.Errorf("failed to store active account marker: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// recordSupplyChange 记录本交易的总供应量变动（只写不读）
+func (s *SmartContract) recordSupplyChange(ctx contractapi.TransactionContextInterface, delta int) error {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	key := supplyChangePrefix + analyticsDay(timestamp.Seconds) + "_" + ctx.GetStub().GetTxID()
+	err = ctx.GetStub().PutPrivateData(privateCollection(ctx), key, []byte(strconv.Itoa(delta)))
+	if err != nil {
+		return fmt.Errorf("failed to store supply change: %v", err)
+	}
+	return nil
+}
+
+// GetTransactionStatistics 央行按期间统计各类交易的笔数与金额
+// granularity 为 day、month 或 year
+func (s *SmartContract) GetTransactionStatistics(ctx contractapi.TransactionContextInterface, fromTimestamp int64, toTimestamp int64, granularity string) (string, error) {
+	if err := s.checkAnalyticsAccess(ctx); err != nil {
+		return "", err
+	}
+
+	fromDay, toDay, err := analyticsDayRange(ctx, fromTimestamp, toTimestamp)
+	if err != nil {
+		return "", err
+	}
+	if err := validateGranularity(granularity); err != nil {
+		return "", err
+	}
+
+	periods := map[string]map[string]*periodStat{}
+	err = s.forEachPeriodStat(ctx, fromDay, toDay, func(day string, transactionType string, stat periodStat) {
+		period := analyticsPeriod(day, granularity)
+		if periods[period] == nil {
+			periods[period] = map[string]*periodStat{}
+		}
+		total := periods[period][transactionType]
+		if total == nil {
+			total = &periodStat{}
+			periods[period][transactionType] = total
+		}
+		total.Count += stat.Count
+		total.Volume += stat.Volume
+	})
+	if err != nil {
+		return "", err
+	}
+
+	result := []map[string]interface{}{}
+	for _, period := range sortedPeriods(periods) {
+		total := periodStat{}
+		for _, stat := range periods[period] {
+			total.Count += stat.Count
+			total.Volume += stat.Volume
+		}
+		result = append(result, map[string]interface{}{
+			"period": period,
+			"types":  periods[period],
+			"total":  total,
+		})
+	}
+
+	return marshalAnalytics(map[string]interface{}{
+		"from":        fromTimestamp,
+		"to":          toTimestamp,
+		"granularity": granularity,
+		"periods":     result,
+	})
+}
+
+// GetIssuanceHistory 央行按期间查询发行（mint）、回笼（burn）与期末总供应量
+func (s *SmartContract) GetIssuanceHistory(ctx contractapi.TransactionContextInterface, fromTimestamp int64, toTimestamp int64, granularity string) (string, error) {
+	if err := s.checkAnalyticsAccess(ctx); err != nil {
+		return "", err
+	}
+
+	fromDay, toDay, err := analyticsDayRange(ctx, fromTimestamp, toTimestamp)
+	if err != nil {
+		return "", err
+	}
+	if err := validateGranularity(granularity); err != nil {
+		return "", err
+	}
+
+	issued := map[string]int{}
+	redeemed := map[string]int{}
+	periodSet := map[string]bool{}
+	err = s.forEachPeriodStat(ctx, fromDay, toDay, func(day string, transactionType string, stat periodStat) {
+		period := analyticsPeriod(day, granularity)
+		switch transactionType {
+		case "mint":
+			issued[period] += stat.Volume
+			periodSet[period] = true
+		case "burn":
+			redeemed[period] += stat.Volume
+			periodSet[period] = true
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+
+	openingSupply, supplies, err := s.dailySupply(ctx, fromDay, toDay)
+	if err != nil {
+		return "", err
+	}
+
+	// 期末供应量取期间内最后一天的收盘值
+	closing := map[string]int{}
+	for _, day := range sortedDays(supplies) {
+		closing[analyticsPeriod(day, granularity)] = supplies[day]
+	}
+
+	result := []map[string]interface{}{}
+	for _, period := range sortedKeys(periodSet) {
+		result = append(result, map[string]interface{}{
+			"period":        period,
+			"issued":        issued[period],
+			"redeemed":      redeemed[period],
+			"net":           issued[period] - redeemed[period],
+			"closingSupply": closing[period],
+		})
+	}
+
+	return marshalAnalytics(map[string]interface{}{
+		"from":          fromTimestamp,
+		"to":            toTimestamp,
+		"granularity":   granularity,
+		"openingSupply": openingSupply,
+		"periods":       result,
+	})
+}
+
+// GetMoneyVelocity 央行计算期间内的货币流通速度：持有人之间的流转金额 / 期间平均总供应量
+func (s *SmartContract) GetMoneyVelocity(ctx contractapi.TransactionContextInterface, fromTimestamp int64, toTimestamp int64) (string, error) {
+	if err := s.checkAnalyticsAccess(ctx); err != nil {
+		return "", err
+	}
+
+	fromDay, toDay, err := analyticsDayRange(ctx, fromTimestamp, toTimestamp)
+	if err != nil {
+		return "", err
+	}
+
+	volume := 0
+	count := 0
+	err = s.forEachPeriodStat(ctx, fromDay, toDay, func(day string, transactionType string, stat periodStat) {
+		for _, circulationType := range circulationTypes {
+			if transactionType == circulationType {
+				volume += stat.Volume
+				count += stat.Count
+			}
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+
+	_, supplies, err := s.dailySupply(ctx, fromDay, toDay)
+	if err != nil {
+		return "", err
+	}
+	supplySum := 0
+	for _, supply := range supplies {
+		supplySum += supply
+	}
+	averageSupply := float64(supplySum) / float64(len(supplies))
+
+	velocity := 0.0
+	if averageSupply > 0 {
+		velocity = float64(volume) / averageSupply
+	}
+
+	return marshalAnalytics(map[string]interface{}{
+		"from":              fromTimestamp,
+		"to":                toTimestamp,
+		"days":              len(supplies),
+		"transactionCount":  count,
+		"transactionVolume": volume,
+		"averageSupply":     roundRatio(averageSupply),
+		"velocity":          roundRatio(velocity),
+		"circulationTypes":  circulationTypes,
+	})
+}
+
+// GetActiveAccounts 央行统计期间内有记账的账户数，按组织域名分组
+func (s *SmartContract) GetActiveAccounts(ctx contractapi.TransactionContextInterface, fromTimestamp int64, toTimestamp int64) (string, error) {
+	if err := s.checkAnalyticsAccess(ctx); err != nil {
+		return "", err
+	}
+
+	fromDay, toDay, err := analyticsDayRange(ctx, fromTimestamp, toTimestamp)
+	if err != nil {
+		return "", err
+	}
+
+	iterator, err := ctx.GetStub().GetPrivateDataByRange(privateCollection(ctx), activeAccountPrefix+fromDay, activeAccountPrefix+toDay+"~")
+	if err != nil {
+		return "", fmt.Errorf("failed to scan active accounts: %v", err)
+	}
+	defer iterator.Close()
+
+	accounts := map[string]bool{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return "", fmt.Errorf("failed to get next active account: %v", err)
+		}
+		// active_<YYYYMMDD>_<账户>
+		marker := strings.TrimPrefix(kv.Key, activeAccountPrefix)
+		if len(marker) <= len(analyticsDayLayout)+1 {
+			continue
+		}
+		accounts[marker[len(analyticsDayLayout)+1:]] = true
+	}
+
+	byOrganization := map[string]int{}
+	for account := range accounts {
+		domain, err := s.extractDomainFromClientID(account)
+		if err != nil {
+			domain = "unknown"
+		}
+		byOrganization[domain]++
+	}
+
+	return marshalAnalytics(map[string]interface{}{
+		"from":           fromTimestamp,
+		"to":             toTimestamp,
+		"activeAccounts": len(accounts),
+		"byOrganization": byOrganization,
+	})
+}
+
+// GetSupplyByBank 央行查询总供应量按持有人所属银行（组织域名）的分布，读取银行汇总账
+func (s *SmartContract) GetSupplyByBank(ctx contractapi.TransactionContextInterface) (string, error) {
+	if err := s.checkAnalyticsAccess(ctx); err != nil {
+		return "", err
+	}
+
+	aggregates, err := s.listBankAggregates(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	total := 0
+	for _, aggregate := range aggregates {
+		total += aggregate.Balance
+	}
+
+	result := []map[string]interface{}{}
+	for _, aggregate := range aggregates {
+		share := 0.0
+		if total > 0 {
+			share = float64(aggregate.Balance) / float64(total)
+		}
+		result = append(result, map[string]interface{}{
+			"organization": aggregate.OrgMSP,
+			"balance":      aggregate.Balance,
+			"share":        roundRatio(share),
+		})
+	}
+
+	totalSupply, err := s.getTotalSupplyFromPrivateCollection(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve total token supply: %v", err)
+	}
+
+	return marshalAnalytics(map[string]interface{}{
+		"totalSupply": totalSupply,
+		"totalHeld":   total,
+		"banks":       result,
+	})
+}
+
+// GetHolderConcentration 央行计算持有集中度：前 topN 名持有人份额与基尼系数
+// includeCentralBank 为 false 时排除央行自身账户，只统计流通中的货币
+// 最多扫描配置的 maxHoldingsScan 个账户，超出时结果只覆盖已扫描的账户并返回 truncated
+func (s *SmartContract) GetHolderConcentration(ctx contractapi.TransactionContextInterface, topN int, includeCentralBank bool) (string, error) {
+	if err := s.checkAnalyticsAccess(ctx); err != nil {
+		return "", err
+	}
+	if topN <= 0 {
+		return "", errors.New("topN must be a positive integer")
+	}
+
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	holdings, truncated, err := s.scanHoldings(ctx, config.Limits.MaxHoldingsScan)
+	if err != nil {
+		return "", err
+	}
+
+	balances := []int{}
+	for _, holding := range holdings {
+		if holding.Balance <= 0 {
+			continue
+		}
+		if !includeCentralBank && holding.OrgMSP == config.CentralBankDomain {
+			continue
+		}
+		balances = append(balances, holding.Balance)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(balances)))
+
+	total := 0
+	for _, balance := range balances {
+		total += balance
+	}
+	top := 0
+	for i := 0; i < topN && i < len(balances); i++ {
+		top += balances[i]
+	}
+	topShare := 0.0
+	if total > 0 {
+		topShare = float64(top) / float64(total)
+	}
+
+	return marshalAnalytics(map[string]interface{}{
+		"holders":            len(balances),
+		"totalHeld":          total,
+		"topN":               topN,
+		"topNBalance":        top,
+		"topNShare":          roundRatio(topShare),
+		"gini":               roundRatio(giniCoefficient(balances)),
+		"includeCentralBank": includeCentralBank,
+		"accountsScanned":    len(holdings),
+		"truncated":          truncated,
+	})
+}
+
+// checkAnalyticsAccess 检查合约已初始化且调用者属于央行
+func (s *SmartContract) checkAnalyticsAccess(ctx contractapi.TransactionContextInterface) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return errors.New("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	isCentralBank, err := isCentralBankMSP(ctx, clientMSPID)
+	if err != nil {
+		return err
+	}
+	if !isCentralBank {
+		return errors.New("client is not authorized to query monetary analytics")
+	}
+	return nil
+}
+
+// forEachPeriodStat 按日期顺序遍历 [fromDay, toDay] 内每笔交易的统计增量
+func (s *SmartContract) forEachPeriodStat(ctx contractapi.TransactionContextInterface, fromDay string, toDay string, fn func(day string, transactionType string, stat periodStat)) error {
+	iterator, err := ctx.GetStub().GetPrivateDataByRange(privateCollection(ctx), transactionStatPrefix+fromDay, transactionStatPrefix+toDay+"~")
+	if err != nil {
+		return fmt.Errorf("failed to scan transaction statistics: %v", err)
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return fmt.Errorf("failed to get next transaction statistics: %v", err)
+		}
+		// txstat_<YYYYMMDD>_<交易类型>_<交易ID>
+		day, rest, found := strings.Cut(strings.TrimPrefix(kv.Key, transactionStatPrefix), "_")
+		if !found {
+			continue
+		}
+		transactionType, _, found := strings.Cut(rest, "_")
+		if !found {
+			continue
+		}
+		var stat periodStat
+		if err := json.Unmarshal(kv.Value, &stat); err != nil {
+			return fmt.Errorf("failed to unmarshal transaction statistics %s: %v", kv.Key, err)
+		}
+		fn(day, transactionType, stat)
+	}
+	return nil
+}
+
+// dailySupply 返回 fromDay 之前的收盘供应量，以及 [fromDay, toDay] 内每天的收盘供应量
+// 收盘供应量由截至当天的全部供应量变动累加；没有发行或回笼的日期沿用前一天的值
+func (s *SmartContract) dailySupply(ctx contractapi.TransactionContextInterface, fromDay string, toDay string) (int, map[string]int, error) {
+	iterator, err := ctx.GetStub().GetPrivateDataByRange(privateCollection(ctx), supplyChangePrefix, supplyChangePrefix+toDay+"~")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to scan supply changes: %v", err)
+	}
+	defer iterator.Close()
+
+	opening := 0
+	changes := map[string]int{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to get next supply change: %v", err)
+		}
+		delta, err := strconv.Atoi(string(kv.Value))
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to parse supply change %s: %v", kv.Key, err)
+		}
+		// supply_<YYYYMMDD>_<交易ID>
+		day, _, found := strings.Cut(strings.TrimPrefix(kv.Key, supplyChangePrefix), "_")
+		if !found {
+			continue
+		}
+		if day < fromDay {
+			opening += delta
+			continue
+		}
+		changes[day] += delta
+	}
+
+	supplies := map[string]int{}
+	current := opening
+	start, _ := time.Parse(analyticsDayLayout, fromDay)
+	end, _ := time.Parse(analyticsDayLayout, toDay)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		key := day.Format(analyticsDayLayout)
+		current += changes[key]
+		supplies[key] = current
+	}
+
+	return opening, supplies, nil
+}
+
+// scanHoldings 按键顺序读取账户余额，limit 大于 0 时最多读取 limit 个账户并返回是否还有未读取的账户
+func (s *SmartContract) scanHoldings(ctx contractapi.TransactionContextInterface, limit int) ([]*UserBalance, bool, error) {
+	iterator, err := ctx.GetStub().GetPrivateDataByRange(privateCollection(ctx), balancePrefix, balancePrefix[:len(balancePrefix)-1]+"`")
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to scan balances: %v", err)
+	}
+	defer iterator.Close()
+
+	holdings := []*UserBalance{}
+	for iterator.HasNext() {
+		if limit > 0 && len(holdings) >= limit {
+			return holdings, true, nil
+		}
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get next balance: %v", err)
+		}
+		holding, err := s.decodeBalanceRecord(ctx, kv.Key[len(balancePrefix):], kv.Value)
+		if err != nil {
+			return nil, false, err
+		}
+		holdings = append(holdings, holding)
+	}
+	return holdings, false, nil
+}
+
+// analyticsDay 将时间戳转换为聚合键中的日期（UTC）
+func analyticsDay(timestamp int64) string {
+	return time.Unix(timestamp, 0).UTC().Format(analyticsDayLayout)
+}
+
+// analyticsDayRange 校验时间范围并转换为日期
+func analyticsDayRange(ctx contractapi.TransactionContextInterface, fromTimestamp int64, toTimestamp int64) (string, string, error) {
+	if fromTimestamp < 0 || toTimestamp < fromTimestamp {
+		return "", "", fmt.Errorf("invalid time range %d - %d", fromTimestamp, toTimestamp)
+	}
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	if (toTimestamp-fromTimestamp)/86400 >= int64(config.Limits.MaxAnalyticsDays) {
+		return "", "", fmt.Errorf("time range exceeds %d days", config.Limits.MaxAnalyticsDays)
+	}
+	return analyticsDay(fromTimestamp), analyticsDay(toTimestamp), nil
+}
+
+// validateGranularity 校验统计粒度
+func validateGranularity(granularity string) error {
+	switch granularity {
+	case "day", "month", "year":
+		return nil
+	}
+	return fmt.Errorf("granularity must be day, month or year, got %s", granularity)
+}
+
+// analyticsPeriod 将日期归入统计期间：day -> YYYYMMDD，month -> YYYYMM，year -> YYYY
+func analyticsPeriod(day string, granularity string) string {
+	switch granularity {
+	case "month":
+		return day[:6]
+	case "year":
+		return day[:4]
+	}
+	return day
+}
+
+// giniCoefficient 计算基尼系数，balances 不要求有序
+func giniCoefficient(balances []int) float64 {
+	n := len(balances)
+	if n == 0 {
+		return 0
+	}
+	sorted := append([]int(nil), balances...)
+	sort.Ints(sorted)
+
+	weighted, total := 0.0, 0.0
+	for i, balance := range sorted {
+		weighted += float64(i+1) * float64(balance)
+		total += float64(balance)
+	}
+	if total == 0 {
+		return 0
+	}
+	return 2*weighted/(float64(n)*total) - float64(n+1)/float64(n)
+}
+
+// roundRatio 比率保留 6 位小数
+func roundRatio(value float64) float64 {
+	return math.Round(value*1e6) / 1e6
+}
+
+// sortedStatKeys 返回按字典序排列的统计键，保证写集顺序确定
+func sortedStatKeys(stats map[string]*periodStat) []string {
+	keys := make([]string, 0, len(stats))
+	for key := range stats {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedDays 返回按时间排列的日期
+func sortedDays(supplies map[string]int) []string {
+	days := make([]string, 0, len(supplies))
+	for day := range supplies {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	return days
+}
+
+// sortedPeriods 返回按时间排列的统计期间
+func sortedPeriods(periods map[string]map[string]*periodStat) []string {
+	keys := make([]string, 0, len(periods))
+	for key := range periods {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// marshalAnalytics 序列化统计结果
+func marshalAnalytics(result map[string]interface{}) (string, error) {
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal analytics: %v", err)
+	}
+	return string(resultJSON), nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func queryAnalytics(env *testEnv, caller testUser, query func(ctx contractapi.TransactionContextInterface) (string, error)) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		response, err := query(ctx)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &result)
+	})
+	return result, err
+}
+
+// TestAnalyticsAggregatesPerTransaction 统计增量按交易写入，不读取共享的聚合键，查询时汇总
+func TestAnalyticsAggregatesPerTransaction(t *testing.T) {
+	env := newTestEnv(t)
+	env.initialize()
+
+	for _, amount := range []int{1000, 300} {
+		stub, err := env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+			return env.contract.Mint(ctx, amount)
+		})
+		if err != nil {
+			t.Fatalf("Mint failed: %v", err)
+		}
+		for key := range stub.preads {
+			if strings.HasPrefix(key, transactionStatPrefix) || strings.HasPrefix(key, supplyChangePrefix) {
+				t.Fatalf("Mint read the aggregate key %s", key)
+			}
+		}
+	}
+	_, err := env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.Burn(ctx, 200)
+	})
+	if err != nil {
+		t.Fatalf("Burn failed: %v", err)
+	}
+
+	from, to := env.ledger.now-3600, env.ledger.now+3600
+	history, err := queryAnalytics(env, centralBankAdmin, func(ctx contractapi.TransactionContextInterface) (string, error) {
+		return env.contract.GetIssuanceHistory(ctx, from, to, "year")
+	})
+	if err != nil {
+		t.Fatalf("GetIssuanceHistory failed: %v", err)
+	}
+	periods := history["periods"].([]interface{})
+	if len(periods) != 1 {
+		t.Fatalf("expected one period, got %v", periods)
+	}
+	period := periods[0].(map[string]interface{})
+	if period["issued"] != float64(1300) || period["redeemed"] != float64(200) || period["closingSupply"] != float64(1100) {
+		t.Fatalf("unexpected issuance history %v", period)
+	}
+
+	statistics, err := queryAnalytics(env, centralBankAdmin, func(ctx contractapi.TransactionContextInterface) (string, error) {
+		return env.contract.GetTransactionStatistics(ctx, from, to, "day")
+	})
+	if err != nil {
+		t.Fatalf("GetTransactionStatistics failed: %v", err)
+	}
+	types := statistics["periods"].([]interface{})[0].(map[string]interface{})["types"].(map[string]interface{})
+	mint := types["mint"].(map[string]interface{})
+	if mint["count"] != float64(2) || mint["volume"] != float64(1300) {
+		t.Fatalf("unexpected mint statistics %v", mint)
+	}
+
+	_, err = queryAnalytics(env, bankAAdmin, func(ctx contractapi.TransactionContextInterface) (string, error) {
+		return env.contract.GetIssuanceHistory(ctx, from, to, "year")
+	})
+	if err == nil || !strings.Contains(err.Error(), "not authorized to query monetary analytics") {
+		t.Fatalf("expected an authorization error, got %v", err)
+	}
+	_, err = queryAnalytics(env, centralBankAdmin, func(ctx contractapi.TransactionContextInterface) (string, error) {
+		return env.contract.GetTransactionStatistics(ctx, from, to, "week")
+	})
+	if err == nil || !strings.Contains(err.Error(), "granularity must be day, month or year") {
+		t.Fatalf("expected a granularity error, got %v", err)
+	}
+}
+
+func TestHolderConcentrationScanLimit(t *testing.T) {
+	env := newTestEnv(t)
+	env.initialize()
+	_, err := env.invoke(bankAAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.OpenAccount(ctx, bankAUser.id, accountTypeIndividual)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+	_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.Mint(ctx, 1000)
+	})
+	if err != nil {
+		t.Fatalf("Mint failed: %v", err)
+	}
+	transfer(t, env, centralBankAdmin, bankAUser, 250)
+
+	concentration := func() map[string]interface{} {
+		t.Helper()
+		result, err := queryAnalytics(env, centralBankAdmin, func(ctx contractapi.TransactionContextInterface) (string, error) {
+			return env.contract.GetHolderConcentration(ctx, 1, true)
+		})
+		if err != nil {
+			t.Fatalf("GetHolderConcentration failed: %v", err)
+		}
+		return result
+	}
+
+	result := concentration()
+	if result["truncated"] != false || result["holders"] != float64(2) || result["totalHeld"] != float64(1000) {
+		t.Fatalf("unexpected concentration %v", result)
+	}
+
+	_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.UpdateConfig(ctx, `{"limits":{"maxHoldingsScan":1}}`, "test")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	result = concentration()
+	if result["truncated"] != true || result["accountsScanned"] != float64(1) {
+		t.Fatalf("expected a truncated scan, got %v", result)
+	}
+
+	_, err = queryAnalytics(env, centralBankAdmin, func(ctx contractapi.TransactionContextInterface) (string, error) {
+		return env.contract.GetHolderConcentration(ctx, 0, true)
+	})
+	if err == nil || !strings.Contains(err.Error(), "topN must be a positive integer") {
+		t.Fatalf("expected a topN error, got %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+/*
+账户月末余额与按月分桶的记账索引
+
+- 记账索引另按月分桶（acct~month~ts~tx），对账单只扫描期间所涉月份的索引，不再从账户的第一笔分录开始
+- 每次余额变动写入账户当月的月末余额（acct~month），同月后续变动直接覆盖，不读取旧值
+- 期初余额 = 期初所在月之前最近的月末余额 + 当月期初之前的分录；期末余额 = 期初余额 + 期间分录，
+  与当前余额及期末之后的分录无关
+- 账户记录的 firstBookedAt 为首次余额变动时间，向前查找月末余额到该月为止，更早的余额为 0
+- 本功能之前的账户由迁移步骤 balanceHistory 按交易索引补写；迁移完成前对账单仍由当前余额倒推
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// 组合键对象类型
+const accountMonthTxIndex = "acct~month~ts~tx" // 账户 -> 月份 -> 时间戳 -> 交易ID
+const monthBalanceIndex = "acct~month"         // 账户 -> 月份，值为该月最后一次变动后的余额
+
+// balanceMonthLayout 月份分桶的格式（UTC）
+const balanceMonthLayout = "200601"
+
+// accountPeriod 账户在一个期间内的分录与期初、期末余额
+type accountPeriod struct {
+	Entries        []accountEntry
+	OpeningBalance int
+	ClosingBalance int
+}
+
+// monthOf 返回时间戳所在月（UTC）的第一天
+func monthOf(timestamp int64) time.Time {
+	t := time.Unix(timestamp, 0).UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// formatIndexMonth 时间戳所在月的分桶键
+func formatIndexMonth(timestamp int64) string {
+	return monthOf(timestamp).Format(balanceMonthLayout)
+}
+
+// putMonthBalance 写入账户在 month 的月末余额
+func putMonthBalance(ctx contractapi.TransactionContextInterface, account string, month string, balance int) error {
+	key, err := ctx.GetStub().CreateCompositeKey(monthBalanceIndex, []string{account, month})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", monthBalanceIndex, err)
+	}
+	if err := ctx.GetStub().PutPrivateData(privateCollection(ctx), key, []byte(strconv.Itoa(balance))); err != nil {
+		return fmt.Errorf("failed to store month balance of %s: %v", account, err)
+	}
+	return nil
+}
+
+// recordMonthBalance 余额变动时写入当月月末余额，并补全账户的首次变动时间
+func recordMonthBalance(ctx contractapi.TransactionContextInterface, account *UserBalance) error {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	if account.FirstBookedAt == 0 {
+		account.FirstBookedAt = timestamp.Seconds
+	}
+	return putMonthBalance(ctx, account.UserID, formatIndexMonth(timestamp.Seconds), account.Balance)
+}
+
+// balanceBeforeMonth 返回账户在 timestamp 所在月之前最近的月末余额；首次余额变动之前为 0
+func balanceBeforeMonth(ctx contractapi.TransactionContextInterface, account *UserBalance, timestamp int64) (int, error) {
+	if account.FirstBookedAt == 0 {
+		return 0, nil
+	}
+	stub := ctx.GetStub()
+	firstMonth := monthOf(account.FirstBookedAt)
+	for month := monthOf(timestamp).AddDate(0, -1, 0); !month.Before(firstMonth); month = month.AddDate(0, -1, 0) {
+		key, err := stub.CreateCompositeKey(monthBalanceIndex, []string{account.UserID, month.Format(balanceMonthLayout)})
+		if err != nil {
+			return 0, fmt.Errorf("failed to create the composite key for prefix %s: %v", monthBalanceIndex, err)
+		}
+		value, err := stub.GetPrivateData(privateCollection(ctx), key)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read month balance of %s: %v", account.UserID, err)
+		}
+		if value == nil {
+			continue
+		}
+		balance, err := strconv.Atoi(string(value))
+		if err != nil {
+			return 0, fmt.Errorf("malformed month balance of %s: %v", account.UserID, err)
+		}
+		return balance, nil
+	}
+	return 0, nil
+}
+
+// queryAccountPeriod 查询账户在 [from, to] 期间的分录与期初、期末余额
+// 授权（approve）不影响余额，不产生分录
+func (s *SmartContract) queryAccountPeriod(ctx contractapi.TransactionContextInterface, account string, from int64, to int64) (*accountPeriod, error) {
+	done, err := migrationDone(ctx, migrationBalanceHistory)
+	if err != nil {
+		return nil, err
+	}
+	if !done {
+		return s.queryLegacyAccountPeriod(ctx, account, from, to)
+	}
+
+	userAccount, err := s.getUserAccountInfo(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	opening, err := balanceBeforeMonth(ctx, userAccount, from)
+	if err != nil {
+		return nil, err
+	}
+
+	period := &accountPeriod{}
+	lastMonth := monthOf(to)
+	for month := monthOf(from); !month.After(lastMonth); month = month.AddDate(0, 1, 0) {
+		attributes := []string{account, month.Format(balanceMonthLayout)}
+		err := s.forEachIndexedTransaction(ctx, accountMonthTxIndex, attributes, entryCursor{}, func(position entryCursor) (bool, error) {
+			if position.Timestamp > to {
+				return false, nil
+			}
+			record, err := s.getTransactionRecord(ctx, position.TxID)
+			if err != nil {
+				return false, err
+			}
+			if record == nil || record.TransactionType == "approve" {
+				return true, nil
+			}
+
+			entries := recordEntries(record, account)
+			if position.Timestamp < from {
+				// 期初所在月中期初之前的分录计入期初余额
+				for _, entry := range entries {
+					opening += entry.signedAmount()
+				}
+				return true, nil
+			}
+			period.Entries = append(period.Entries, entries...)
+			return true, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	period.OpeningBalance = opening
+	period.ClosingBalance = opening
+	for _, entry := range period.Entries {
+		period.ClosingBalance += entry.signedAmount()
+	}
+	return period, nil
+}
+
+// queryLegacyAccountPeriod 迁移步骤 balanceHistory 完成前的查询：扫描账户的全部索引，由当前余额倒推期末与期初余额
+func (s *SmartContract) queryLegacyAccountPeriod(ctx contractapi.TransactionContextInterface, account string, from int64, to int64) (*accountPeriod, error) {
+	currentBalance, err := s.getBalanceFromPrivateCollection(ctx, account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read account %s from private collection: %v", account, err)
+	}
+
+	period := &accountPeriod{ClosingBalance: currentBalance}
+	err = s.forEachIndexedTransaction(ctx, accountTxIndex, []string{account}, entryCursor{}, func(position entryCursor) (bool, error) {
+		if position.Timestamp < from {
+			return true, nil
+		}
+		record, err := s.getTransactionRecord(ctx, position.TxID)
+		if err != nil {
+			return false, err
+		}
+		if record == nil || record.TransactionType == "approve" {
+			return true, nil
+		}
+
+		entries := recordEntries(record, account)
+		if position.Timestamp > to {
+			for _, entry := range entries {
+				period.ClosingBalance -= entry.signedAmount()
+			}
+			return true, nil
+		}
+		period.Entries = append(period.Entries, entries...)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	period.OpeningBalance = period.ClosingBalance
+	for _, entry := range period.Entries {
+		period.OpeningBalance -= entry.signedAmount()
+	}
+	return period, nil
+}
+
+// migrateBalanceHistoryBatch 迁移步骤 balanceHistory：按账户补写月份索引、月末余额与首次变动时间
+// 每个账户的月末余额由当前余额按交易索引倒推；书签为最后处理的余额键
+func (s *SmartContract) migrateBalanceHistoryBatch(ctx contractapi.TransactionContextInterface, bookmark string, batchSize int) (*migrationBatch, error) {
+	// 旧格式交易记录迁移后才有账户索引
+	indexed, err := migrationDone(ctx, migrationTransactionRecords)
+	if err != nil {
+		return nil, err
+	}
+	if !indexed {
+		return nil, fmt.Errorf("run RunMigration %s before %s", migrationTransactionRecords, migrationBalanceHistory)
+	}
+
+	startKey := balancePrefix
+	if bookmark != "" {
+		if !strings.HasPrefix(bookmark, balancePrefix) {
+			return nil, fmt.Errorf("invalid bookmark %s", bookmark)
+		}
+		startKey = bookmark + "\x00"
+	}
+	iterator, err := ctx.GetStub().GetPrivateDataByRange(privateCollection(ctx), startKey, balancePrefix[:len(balancePrefix)-1]+"`")
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan balances: %v", err)
+	}
+	defer iterator.Close()
+
+	batch := &migrationBatch{}
+	for batch.Scanned < batchSize && iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next balance: %v", err)
+		}
+		batch.Scanned++
+		batch.Bookmark = kv.Key
+
+		account, _, err := s.upgradeLegacyBalance(kv.Key[len(balancePrefix):], kv.Value)
+		if err != nil {
+			return nil, err
+		}
+		migrated, err := s.backfillBalanceHistory(ctx, account)
+		if err != nil {
+			return nil, err
+		}
+		if migrated {
+			batch.Migrated++
+		}
+	}
+	if !iterator.HasNext() {
+		batch.Bookmark = ""
+		batch.Done = true
+	}
+	return batch, nil
+}
+
+// backfillBalanceHistory 为一个账户补写月份索引与月末余额，返回账户是否有记账
+func (s *SmartContract) backfillBalanceHistory(ctx contractapi.TransactionContextInterface, account *UserBalance) (bool, error) {
+	type booking struct {
+		position entryCursor
+		amount   int
+	}
+	var bookings []booking
+	total := 0
+	err := s.forEachIndexedTransaction(ctx, accountTxIndex, []string{account.UserID}, entryCursor{}, func(position entryCursor) (bool, error) {
+		record, err := s.getTransactionRecord(ctx, position.TxID)
+		if err != nil {
+			return false, err
+		}
+		if record == nil || record.TransactionType == "approve" {
+			return true, nil
+		}
+		amount := 0
+		for _, entry := range recordEntries(record, account.UserID) {
+			amount += entry.signedAmount()
+		}
+		bookings = append(bookings, booking{position: position, amount: amount})
+		total += amount
+		return true, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if len(bookings) == 0 {
+		return false, nil
+	}
+
+	// 同一月份的月末余额按时间顺序覆盖，最后写入的为该月最后一笔记账后的余额
+	balance := account.Balance - total
+	for _, b := range bookings {
+		balance += b.amount
+		month := formatIndexMonth(b.position.Timestamp)
+		if err := putIndexEntry(ctx, accountMonthTxIndex, account.UserID, month, formatIndexTimestamp(b.position.Timestamp), b.position.TxID); err != nil {
+			return false, err
+		}
+		if err := putMonthBalance(ctx, account.UserID, month, balance); err != nil {
+			return false, err
+		}
+	}
+
+	first := bookings[0].position.Timestamp
+	if account.FirstBookedAt != 0 && account.FirstBookedAt <= first {
+		return true, nil
+	}
+	account.FirstBookedAt = first
+	return true, s.putUserAccount(ctx, account)
+}
@@ -0,0 +1,448 @@
+/*
+银行汇总账
+
+- 按账户记录中的 OrgMSP 维护各银行客户余额合计、发行/回笼累计与跨行资金流入流出
+- 在 transferHelperPrivate、Mint、Burn 与保密余额存取中随余额变动同步更新，行内转账不改动汇总账
+- 每笔链码交易为涉及的银行写入一条增量（bankagg~org~tx），只写不读，并发交易不会在汇总账上产生读写冲突
+- GetBankAggregate / ListBankAggregates 汇总各银行的增量，无需扫描账户或交易记录；
+  RebuildBankAggregates 按账户余额校正余额合计，并将已有增量合并为一条
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// bankAggregateIndex 银行汇总账增量的组合键对象类型：组织 -> 交易ID，值为该交易对汇总账的增量
+const bankAggregateIndex = "bankagg~org~tx"
+
+// unknownBankOrg 无法识别所属组织的账户归入的汇总账
+const unknownBankOrg = "unknown"
+
+// BankAggregate 银行汇总账
+type BankAggregate struct {
+	OrgMSP   string              `json:"orgMsp"`          // 账户记录中的 OrgMSP（组织域名）
+	MSPID    string              `json:"mspId,omitempty"` // 已记录的 MSP ID，读取时补充
+	Balance  int                 `json:"balance"`         // 本行客户余额合计
+	Issued   int                 `json:"issued"`          // 铸币累计
+	Redeemed int                 `json:"redeemed"`        // 销毁累计
+	Inflow   int                 `json:"inflow"`          // 他行转入累计
+	Outflow  int                 `json:"outflow"`         // 转往他行累计
+	Shielded int                 `json:"shielded"`        // 本行客户存入保密余额的净额，保密转账不再调整
+	Flows    map[string]bankFlow `json:"flows,omitempty"` // 按对手行统计的跨行流量
+}
+
+// bankFlow 与某一对手行之间的跨行流量
+type bankFlow struct {
+	In  int `json:"in"`
+	Out int `json:"out"`
+}
+
+// bankAggregateOrg 返回账户记录中的组织，缺失时归入 unknown
+func bankAggregateOrg(orgMSP string) string {
+	if orgMSP == "" {
+		return unknownBankOrg
+	}
+	return orgMSP
+}
+
+// addBankAggregate 将增量累加到汇总账
+func addBankAggregate(total *BankAggregate, delta *BankAggregate) {
+	total.Balance += delta.Balance
+	total.Issued += delta.Issued
+	total.Redeemed += delta.Redeemed
+	total.Inflow += delta.Inflow
+	total.Outflow += delta.Outflow
+	total.Shielded += delta.Shielded
+	for counterparty, flow := range delta.Flows {
+		addBankFlow(total, counterparty, flow.In, flow.Out)
+	}
+}
+
+// forEachBankAggregateDelta 按组织与交易ID顺序遍历汇总账增量；attributes 为空时遍历所有银行
+func (s *SmartContract) forEachBankAggregateDelta(ctx contractapi.TransactionContextInterface, attributes []string, fn func(key string, org string, delta *BankAggregate)) error {
+	stub := ctx.GetStub()
+	iterator, err := stub.GetPrivateDataByPartialCompositeKey(privateCollection(ctx), bankAggregateIndex, attributes)
+	if err != nil {
+		return fmt.Errorf("failed to scan bank aggregates: %v", err)
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return fmt.Errorf("failed to get next bank aggregate: %v", err)
+		}
+		_, parts, err := stub.SplitCompositeKey(kv.Key)
+		if err != nil || len(parts) != 2 {
+			return fmt.Errorf("malformed bank aggregate key %q", kv.Key)
+		}
+		delta := &BankAggregate{}
+		if err := json.Unmarshal(kv.Value, delta); err != nil {
+			return fmt.Errorf("failed to unmarshal bank aggregate %s: %v", parts[0], err)
+		}
+		fn(kv.Key, parts[0], delta)
+	}
+	return nil
+}
+
+// getBankAggregate 汇总银行的全部增量，没有增量时返回零值
+func (s *SmartContract) getBankAggregate(ctx contractapi.TransactionContextInterface, org string) (*BankAggregate, error) {
+	aggregate := &BankAggregate{OrgMSP: org}
+	err := s.forEachBankAggregateDelta(ctx, []string{org}, func(key string, org string, delta *BankAggregate) {
+		addBankAggregate(aggregate, delta)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return aggregate, nil
+}
+
+// putBankAggregateDelta 写入本交易对一个银行汇总账的增量
+// 每笔链码交易对同一银行只写入一次，多处变动需先在内存中合并
+func (s *SmartContract) putBankAggregateDelta(ctx contractapi.TransactionContextInterface, delta *BankAggregate) error {
+	stored := *delta
+	stored.MSPID = ""
+
+	deltaBytes, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bank aggregate: %v", err)
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(bankAggregateIndex, []string{delta.OrgMSP, ctx.GetStub().GetTxID()})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", bankAggregateIndex, err)
+	}
+	if err := ctx.GetStub().PutPrivateData(privateCollection(ctx), key, deltaBytes); err != nil {
+		return fmt.Errorf("failed to store bank aggregate %s: %v", delta.OrgMSP, err)
+	}
+	return nil
+}
+
+// recordBankIssuance 铸币（amount 为正）或销毁（amount 为负）时更新铸币者所属银行的汇总账
+func (s *SmartContract) recordBankIssuance(ctx contractapi.TransactionContextInterface, orgMSP string, amount int) error {
+	delta := &BankAggregate{OrgMSP: bankAggregateOrg(orgMSP), Balance: amount}
+	if amount > 0 {
+		delta.Issued = amount
+	} else {
+		delta.Redeemed = -amount
+	}
+	return s.putBankAggregateDelta(ctx, delta)
+}
+
+// recordBankShielding 客户存入（amount 为正）或取出（amount 为负）保密余额时更新其所属银行的汇总账
+// 汇总账余额只统计明文余额，存入保密余额的部分转入 Shielded
+func (s *SmartContract) recordBankShielding(ctx contractapi.TransactionContextInterface, orgMSP string, amount int) error {
+	return s.putBankAggregateDelta(ctx, &BankAggregate{OrgMSP: bankAggregateOrg(orgMSP), Balance: -amount, Shielded: amount})
+}
+
+// bankTransfer 一笔跨行资金划转
+type bankTransfer struct {
+	FromOrgMSP string
+	ToOrgMSP   string
+	Value      int
+}
+
+// recordBankTransfers 转账时更新双方银行的汇总账，行内转账不产生变动
+// 多笔划转涉及同一银行时先在内存中累计，每个银行写入一条增量
+func (s *SmartContract) recordBankTransfers(ctx contractapi.TransactionContextInterface, transfers []bankTransfer) error {
+	aggregates := map[string]*BankAggregate{}
+	var orgs []string
+	load := func(org string) *BankAggregate {
+		if aggregate, ok := aggregates[org]; ok {
+			return aggregate
+		}
+		aggregate := &BankAggregate{OrgMSP: org}
+		aggregates[org] = aggregate
+		orgs = append(orgs, org)
+		return aggregate
+	}
+
+	for _, transfer := range transfers {
+		fromOrg := bankAggregateOrg(transfer.FromOrgMSP)
+		toOrg := bankAggregateOrg(transfer.ToOrgMSP)
+		if fromOrg == toOrg || transfer.Value == 0 {
+			continue
+		}
+
+		fromAggregate := load(fromOrg)
+		toAggregate := load(toOrg)
+
+		fromAggregate.Balance -= transfer.Value
+		fromAggregate.Outflow += transfer.Value
+		addBankFlow(fromAggregate, toOrg, 0, transfer.Value)
+
+		toAggregate.Balance += transfer.Value
+		toAggregate.Inflow += transfer.Value
+		addBankFlow(toAggregate, fromOrg, transfer.Value, 0)
+	}
+
+	sort.Strings(orgs)
+	for _, org := range orgs {
+		if err := s.putBankAggregateDelta(ctx, aggregates[org]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addBankFlow 累加与对手行之间的流量
+func addBankFlow(aggregate *BankAggregate, counterparty string, in int, out int) {
+	if aggregate.Flows == nil {
+		aggregate.Flows = map[string]bankFlow{}
+	}
+	flow := aggregate.Flows[counterparty]
+	flow.In += in
+	flow.Out += out
+	aggregate.Flows[counterparty] = flow
+}
+
+// GetBankAggregate 查询单个银行的汇总账
+// msp 可以是 MSP ID 或账户记录中的组织域名；央行可查询所有银行，银行admin只能查询本行
+func (s *SmartContract) GetBankAggregate(ctx contractapi.TransactionContextInterface, msp string) (string, error) {
+	// 检查合约初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", errors.New("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	org, err := s.resolveBankOrg(ctx, msp)
+	if err != nil {
+		return "", err
+	}
+
+	// 检查权限
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	isCentralBank, err := isCentralBankMSP(ctx, clientMSPID)
+	if err != nil {
+		return "", err
+	}
+	if !isCentralBank {
+		callerID, err := ctx.GetClientIdentity().GetID()
+		if err != nil {
+			return "", fmt.Errorf("failed to get caller id: %v", err)
+		}
+		callerDomain, err := s.extractDomainFromClientID(callerID)
+		if err != nil {
+			return "", fmt.Errorf("failed to extract caller domain: %v", err)
+		}
+		if !s.isAdminUser(ctx, callerID) || callerDomain != org {
+			return "", fmt.Errorf("client is not authorized to read bank aggregate %s", msp)
+		}
+	}
+
+	aggregate, err := s.getBankAggregate(ctx, org)
+	if err != nil {
+		return "", err
+	}
+	if err := s.fillBankMSPID(ctx, aggregate); err != nil {
+		return "", err
+	}
+
+	aggregateJSON, err := json.Marshal(aggregate)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal bank aggregate: %v", err)
+	}
+	return string(aggregateJSON), nil
+}
+
+// ListBankAggregates 央行查询所有银行的汇总账及其占流通总量的份额
+func (s *SmartContract) ListBankAggregates(ctx contractapi.TransactionContextInterface) (string, error) {
+	if err := s.checkAnalyticsAccess(ctx); err != nil {
+		return "", err
+	}
+
+	aggregates, err := s.listBankAggregates(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	totalSupply, err := s.getTotalSupplyFromPrivateCollection(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve total token supply: %v", err)
+	}
+
+	banks := []map[string]interface{}{}
+	totalHeld := 0
+	for _, aggregate := range aggregates {
+		if err := s.fillBankMSPID(ctx, aggregate); err != nil {
+			return "", err
+		}
+		share := 0.0
+		if totalSupply > 0 {
+			share = float64(aggregate.Balance) / float64(totalSupply)
+		}
+		banks = append(banks, map[string]interface{}{
+			"aggregate": aggregate,
+			"share":     roundRatio(share),
+		})
+		totalHeld += aggregate.Balance
+	}
+
+	return marshalAnalytics(map[string]interface{}{
+		"totalSupply": totalSupply,
+		"totalHeld":   totalHeld,
+		"banks":       banks,
+	})
+}
+
+// RebuildBankAggregates 央行管理员按账户余额重算各银行的余额合计，并将每个银行的增量合并为一条
+// 用于汇总账启用前已有余额的账本；发行、回笼与跨行流量累计保持不变
+// 只删除本次读到的增量，并发交易新写入的增量保留，合并后的汇总结果不变
+func (s *SmartContract) RebuildBankAggregates(ctx contractapi.TransactionContextInterface) (string, error) {
+	if err := s.checkAnalyticsAccess(ctx); err != nil {
+		return "", err
+	}
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+	if !s.isAdminUser(ctx, callerID) {
+		return "", errors.New("client is not authorized to rebuild bank aggregates")
+	}
+
+	// 校正余额合计需要全部账户，维护操作不限制扫描数量
+	holdings, _, err := s.scanHoldings(ctx, 0)
+	if err != nil {
+		return "", err
+	}
+	balances := map[string]int{}
+	for _, holding := range holdings {
+		balances[bankAggregateOrg(holding.OrgMSP)] += holding.Balance
+	}
+
+	aggregates, deltaKeys, err := s.scanBankAggregates(ctx)
+	if err != nil {
+		return "", err
+	}
+	byOrg := map[string]*BankAggregate{}
+	for _, aggregate := range aggregates {
+		byOrg[aggregate.OrgMSP] = aggregate
+	}
+	for org := range balances {
+		if byOrg[org] == nil {
+			byOrg[org] = &BankAggregate{OrgMSP: org}
+		}
+	}
+
+	orgs := make([]string, 0, len(byOrg))
+	for org := range byOrg {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+
+	corrected := map[string]int{}
+	compacted := 0
+	for _, org := range orgs {
+		aggregate := byOrg[org]
+		if aggregate.Balance != balances[org] {
+			corrected[org] = balances[org] - aggregate.Balance
+			aggregate.Balance = balances[org]
+		} else if len(deltaKeys[org]) <= 1 {
+			continue
+		}
+		for _, key := range deltaKeys[org] {
+			if err := ctx.GetStub().DelPrivateData(privateCollection(ctx), key); err != nil {
+				return "", fmt.Errorf("failed to delete bank aggregate %s: %v", org, err)
+			}
+		}
+		compacted += len(deltaKeys[org])
+		if err := s.putBankAggregateDelta(ctx, aggregate); err != nil {
+			return "", err
+		}
+	}
+
+	log.Printf("bank aggregates rebuilt: %d banks, %d corrected, %d deltas compacted", len(orgs), len(corrected), compacted)
+
+	return marshalAnalytics(map[string]interface{}{
+		"banks":     len(orgs),
+		"corrected": corrected,
+		"compacted": compacted,
+	})
+}
+
+// listBankAggregates 汇总全部银行的增量，按组织排序
+func (s *SmartContract) listBankAggregates(ctx contractapi.TransactionContextInterface) ([]*BankAggregate, error) {
+	aggregates, _, err := s.scanBankAggregates(ctx)
+	return aggregates, err
+}
+
+// scanBankAggregates 汇总全部银行的增量，同时返回各银行的增量键
+func (s *SmartContract) scanBankAggregates(ctx contractapi.TransactionContextInterface) ([]*BankAggregate, map[string][]string, error) {
+	aggregates := []*BankAggregate{}
+	keys := map[string][]string{}
+	var current *BankAggregate
+	err := s.forEachBankAggregateDelta(ctx, []string{}, func(key string, org string, delta *BankAggregate) {
+		if current == nil || current.OrgMSP != org {
+			current = &BankAggregate{OrgMSP: org}
+			aggregates = append(aggregates, current)
+		}
+		addBankAggregate(current, delta)
+		keys[org] = append(keys[org], key)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return aggregates, keys, nil
+}
+
+// resolveBankOrg 将 MSP ID 或组织域名解析为汇总账使用的组织
+func (s *SmartContract) resolveBankOrg(ctx contractapi.TransactionContextInterface, msp string) (string, error) {
+	if msp == "" {
+		return "", errors.New("msp must not be empty")
+	}
+
+	existing, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(privateCollection(ctx), bankAggregateIndex, []string{msp})
+	if err != nil {
+		return "", fmt.Errorf("failed to read bank aggregate %s: %v", msp, err)
+	}
+	found := existing.HasNext()
+	existing.Close()
+	if found {
+		return msp, nil
+	}
+
+	// 按已记录的组织域名到 MSP ID 映射反查
+	iterator, err := ctx.GetStub().GetPrivateDataByRange(privateCollection(ctx), orgMSPPrefix, orgMSPPrefix[:len(orgMSPPrefix)-1]+"`")
+	if err != nil {
+		return "", fmt.Errorf("failed to scan org msp mappings: %v", err)
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return "", fmt.Errorf("failed to get next org msp mapping: %v", err)
+		}
+		if string(kv.Value) == msp {
+			return strings.TrimPrefix(kv.Key, orgMSPPrefix), nil
+		}
+	}
+
+	return msp, nil
+}
+
+// fillBankMSPID 按组织域名补充已记录的 MSP ID
+func (s *SmartContract) fillBankMSPID(ctx contractapi.TransactionContextInterface, aggregate *BankAggregate) error {
+	mspBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), orgMSPPrefix+aggregate.OrgMSP)
+	if err != nil {
+		return fmt.Errorf("failed to read org msp for %s: %v", aggregate.OrgMSP, err)
+	}
+	aggregate.MSPID = string(mspBytes)
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func getBankAggregate(env *testEnv, caller testUser, msp string) (*BankAggregate, error) {
+	aggregate := &BankAggregate{}
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		result, err := env.contract.GetBankAggregate(ctx, msp)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(result), aggregate)
+	})
+	return aggregate, err
+}
+
+// bankAggregateKeys 账本上各银行的汇总账增量条数
+func bankAggregateKeys(env *testEnv) map[string]int {
+	counts := map[string]int{}
+	stub := env.ledger.newStub()
+	for key := range env.ledger.private[defaultPrivateCollection] {
+		objectType, parts, err := stub.SplitCompositeKey(key)
+		if err == nil && objectType == bankAggregateIndex {
+			counts[parts[0]]++
+		}
+	}
+	return counts
+}
+
+func TestBankAggregateDeltas(t *testing.T) {
+	env := newTestEnv(t)
+	env.initialize()
+	_, err := env.invoke(bankAAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.OpenAccount(ctx, bankAUser.id, accountTypeIndividual)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+	_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.Mint(ctx, 1000)
+	})
+	if err != nil {
+		t.Fatalf("Mint failed: %v", err)
+	}
+
+	// 跨行转账只写入增量，不读取汇总账
+	for _, stub := range []*mockStub{
+		transfer(t, env, centralBankAdmin, bankAUser, 500),
+		transfer(t, env, bankAUser, centralBankAdmin, 100),
+	} {
+		for key := range stub.preads {
+			if strings.HasPrefix(key, "\x00"+bankAggregateIndex+"\x00") {
+				t.Fatalf("transfer read the bank aggregate key %q", key)
+			}
+		}
+	}
+
+	aggregate, err := getBankAggregate(env, bankAAdmin, "a.example.com")
+	if err != nil {
+		t.Fatalf("GetBankAggregate failed: %v", err)
+	}
+	if aggregate.Balance != 400 || aggregate.Inflow != 500 || aggregate.Outflow != 100 || aggregate.Flows[CENTRAL_BANK_DOMAIN] != (bankFlow{In: 500, Out: 100}) {
+		t.Fatalf("unexpected aggregate %+v", aggregate)
+	}
+	central, err := getBankAggregate(env, centralBankAdmin, CENTRAL_BANK_DOMAIN)
+	if err != nil {
+		t.Fatalf("GetBankAggregate failed: %v", err)
+	}
+	if central.Balance != 600 || central.Issued != 1000 {
+		t.Fatalf("unexpected central bank aggregate %+v", central)
+	}
+
+	// 重算后每个银行只剩一条合并的增量，汇总结果不变
+	_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.RebuildBankAggregates(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("RebuildBankAggregates failed: %v", err)
+	}
+	if counts := bankAggregateKeys(env); counts["a.example.com"] != 1 || counts[CENTRAL_BANK_DOMAIN] != 1 {
+		t.Fatalf("expected one delta per bank after rebuild, got %v", counts)
+	}
+	rebuilt, err := getBankAggregate(env, bankAAdmin, "a.example.com")
+	if err != nil {
+		t.Fatalf("GetBankAggregate failed: %v", err)
+	}
+	if rebuilt.Balance != aggregate.Balance || rebuilt.Inflow != aggregate.Inflow || rebuilt.Outflow != aggregate.Outflow {
+		t.Fatalf("rebuild changed the aggregate: %+v, was %+v", rebuilt, aggregate)
+	}
+
+	if _, err := getBankAggregate(env, bankAUser, "a.example.com"); err == nil || !strings.Contains(err.Error(), "not authorized to read bank aggregate") {
+		t.Fatalf("expected an authorization error, got %v", err)
+	}
+	if _, err := getBankAggregate(env, centralBankAdmin, ""); err == nil || !strings.Contains(err.Error(), "msp must not be empty") {
+		t.Fatalf("expected an empty msp error, got %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+/*
+链码即服务（CCaaS）运行模式
+
+- 设置 CHAINCODE_SERVER_ADDRESS 后链码作为外部服务运行，由peer按 connection.json 主动连接；
+  未设置时保持peer启动链码进程的默认模式
+- CHAINCODE_ID 为 peer lifecycle 计算出的 package ID，未设置时取 CORE_CHAINCODE_ID_NAME
+- TLS 默认关闭（与 deployCCAAS.sh 生成的 connection.json 一致）；CHAINCODE_TLS_DISABLED=false 时
+  从 CHAINCODE_TLS_KEY、CHAINCODE_TLS_CERT 读取服务端私钥与证书，CHAINCODE_CLIENT_CA_CERT 可选，设置后校验peer的客户端证书
+- 健康检查：CHAINCODE_HEALTH_ADDRESS（默认 0.0.0.0:9998，设为 disabled 关闭）上的 GET /healthz，
+  服务中返回 200，关闭中或链码服务异常退出后返回 503
+- 先绑定链码服务监听端口再对外报告服务中；端口绑定失败时直接退出
+- 收到 SIGTERM/SIGINT 时先把健康状态置为关闭中，停止链码 gRPC 服务（等待进行中的调用，超时后强制关闭），
+  再关闭健康检查服务并退出
+- gRPC 服务参数（keepalive、消息大小、TLS）与 shim.ChaincodeServer 一致
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	defaultHealthAddress = "0.0.0.0:9998"
+	healthDisabled       = "disabled"
+	healthPath           = "/healthz"
+	shutdownTimeout      = 5 * time.Second
+
+	// 与 shim.ChaincodeServer 一致的 gRPC 参数
+	maxGRPCMessageSize    = 100 * 1024 * 1024
+	grpcKeepaliveTime     = time.Minute
+	grpcKeepaliveTimeout  = 20 * time.Second
+	grpcKeepaliveMinTime  = time.Minute
+	grpcConnectionTimeout = 5 * time.Second
+)
+
+// 健康状态
+const (
+	healthStarting int32 = iota
+	healthServing
+	healthStopping
+	healthFailed
+)
+
+var healthStatusNames = map[int32]string{
+	healthStarting: "starting",
+	healthServing:  "serving",
+	healthStopping: "stopping",
+	healthFailed:   "failed",
+}
+
+// serverConfig 外部服务模式的配置
+type serverConfig struct {
+	CCID          string
+	Address       string
+	HealthAddress string
+	TLSProps      shim.TLSProperties
+}
+
+// loadServerConfig 从环境变量读取外部服务配置；未设置 CHAINCODE_SERVER_ADDRESS 时返回 nil，表示由peer启动
+func loadServerConfig() (*serverConfig, error) {
+	address := os.Getenv("CHAINCODE_SERVER_ADDRESS")
+	if address == "" {
+		return nil, nil
+	}
+
+	ccid := os.Getenv("CHAINCODE_ID")
+	if ccid == "" {
+		ccid = os.Getenv("CORE_CHAINCODE_ID_NAME")
+	}
+	if ccid == "" {
+		return nil, errors.New("CHAINCODE_ID must be set when CHAINCODE_SERVER_ADDRESS is set")
+	}
+
+	healthAddress := os.Getenv("CHAINCODE_HEALTH_ADDRESS")
+	if healthAddress == "" {
+		healthAddress = defaultHealthAddress
+	}
+
+	tlsProps, err := loadTLSProperties()
+	if err != nil {
+		return nil, err
+	}
+
+	return &serverConfig{
+		CCID:          ccid,
+		Address:       address,
+		HealthAddress: healthAddress,
+		TLSProps:      tlsProps,
+	}, nil
+}
+
+// loadTLSProperties 读取 TLS 配置，证书与私钥均为 PEM 文件路径
+func loadTLSProperties() (shim.TLSProperties, error) {
+	disabled := true
+	if value := os.Getenv("CHAINCODE_TLS_DISABLED"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return shim.TLSProperties{}, fmt.Errorf("invalid CHAINCODE_TLS_DISABLED %q: %v", value, err)
+		}
+		disabled = parsed
+	}
+	if disabled {
+		return shim.TLSProperties{Disabled: true}, nil
+	}
+
+	key, err := readPEMFile("CHAINCODE_TLS_KEY", true)
+	if err != nil {
+		return shim.TLSProperties{}, err
+	}
+	cert, err := readPEMFile("CHAINCODE_TLS_CERT", true)
+	if err != nil {
+		return shim.TLSProperties{}, err
+	}
+	clientCACerts, err := readPEMFile("CHAINCODE_CLIENT_CA_CERT", false)
+	if err != nil {
+		return shim.TLSProperties{}, err
+	}
+
+	return shim.TLSProperties{
+		Disabled:      false,
+		Key:           key,
+		Cert:          cert,
+		ClientCACerts: clientCACerts,
+	}, nil
+}
+
+// readPEMFile 读取环境变量 name 指向的文件
+func readPEMFile(name string, required bool) ([]byte, error) {
+	path := os.Getenv(name)
+	if path == "" {
+		if required {
+			return nil, fmt.Errorf("%s must be set when TLS is enabled", name)
+		}
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", name, err)
+	}
+	return content, nil
+}
+
+// newHealthServer 健康检查服务，状态来自 status
+func newHealthServer(address string, status *atomic.Int32) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(healthPath, func(w http.ResponseWriter, r *http.Request) {
+		current := status.Load()
+		w.Header().Set("Content-Type", "application/json")
+		if current != healthServing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": healthStatusNames[current]})
+	})
+	return &http.Server{Addr: address, Handler: mux, ReadHeaderTimeout: shutdownTimeout}
+}
+
+// newChaincodeGRPCServer 创建链码 gRPC 服务
+func newChaincodeGRPCServer(tlsProps shim.TLSProperties) (*grpc.Server, error) {
+	serverOpts := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{Time: grpcKeepaliveTime, Timeout: grpcKeepaliveTimeout}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{MinTime: grpcKeepaliveMinTime, PermitWithoutStream: true}),
+		grpc.MaxSendMsgSize(maxGRPCMessageSize),
+		grpc.MaxRecvMsgSize(maxGRPCMessageSize),
+		grpc.ConnectionTimeout(grpcConnectionTimeout),
+	}
+	if !tlsProps.Disabled {
+		tlsConfig, err := newServerTLSConfig(tlsProps)
+		if err != nil {
+			return nil, err
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	return grpc.NewServer(serverOpts...), nil
+}
+
+// newServerTLSConfig 服务端 TLS 配置；设置了客户端 CA 时要求并校验peer的客户端证书
+func newServerTLSConfig(tlsProps shim.TLSProperties) (*tls.Config, error) {
+	certificate, err := tls.X509KeyPair(tlsProps.Cert, tlsProps.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse TLS key pair: %v", err)
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:             tls.VersionTLS12,
+		Certificates:           []tls.Certificate{certificate},
+		SessionTicketsDisabled: true,
+	}
+	if tlsProps.ClientCACerts != nil {
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(tlsProps.ClientCACerts) {
+			return nil, errors.New("failed to load CHAINCODE_CLIENT_CA_CERT")
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// stopGRPCServer 等待进行中的调用结束，超时后强制关闭（peer的连接为长连接流）
+func stopGRPCServer(server *grpc.Server, timeout time.Duration) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		server.Stop()
+	}
+}
+
+// runChaincodeServer 以外部服务模式运行链码，直到收到终止信号或链码服务出错
+func runChaincodeServer(cc shim.Chaincode, config *serverConfig) error {
+	grpcServer, err := newChaincodeGRPCServer(config.TLSProps)
+	if err != nil {
+		return err
+	}
+	// shim.ChaincodeServer 处理peer的连接，监听与服务生命周期由本函数管理
+	peer.RegisterChaincodeServer(grpcServer, &shim.ChaincodeServer{CCID: config.CCID, CC: cc})
+
+	var status atomic.Int32
+	status.Store(healthStarting)
+
+	var healthServer *http.Server
+	healthErrors := make(chan error, 1)
+	if config.HealthAddress != healthDisabled {
+		healthListener, err := net.Listen("tcp", config.HealthAddress)
+		if err != nil {
+			return fmt.Errorf("failed to listen on health address %s: %v", config.HealthAddress, err)
+		}
+		healthServer = newHealthServer(config.HealthAddress, &status)
+		go func() {
+			if err := healthServer.Serve(healthListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				healthErrors <- err
+			}
+		}()
+		log.Printf("health endpoint listening on %s%s", healthListener.Addr(), healthPath)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(signals)
+
+	var result error
+	listener, err := net.Listen("tcp", config.Address)
+	if err != nil {
+		status.Store(healthFailed)
+		result = fmt.Errorf("failed to listen on %s: %v", config.Address, err)
+	} else {
+		serverErrors := make(chan error, 1)
+		go func() {
+			serverErrors <- grpcServer.Serve(listener)
+		}()
+		status.Store(healthServing)
+		log.Printf("chaincode server %s listening on %s (TLS disabled: %t)", config.CCID, listener.Addr(), config.TLSProps.Disabled)
+
+		select {
+		case sig := <-signals:
+			log.Printf("received %s, shutting down chaincode server", sig)
+			status.Store(healthStopping)
+			stopGRPCServer(grpcServer, shutdownTimeout)
+		case err := <-serverErrors:
+			status.Store(healthFailed)
+			if err == nil {
+				err = errors.New("chaincode server stopped unexpectedly")
+			}
+			result = fmt.Errorf("chaincode server failed: %v", err)
+		case err := <-healthErrors:
+			status.Store(healthFailed)
+			stopGRPCServer(grpcServer, shutdownTimeout)
+			result = fmt.Errorf("health endpoint failed: %v", err)
+		}
+	}
+
+	if healthServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := healthServer.Shutdown(ctx); err != nil {
+			log.Printf("failed to shut down health endpoint: %v", err)
+		}
+	}
+	return result
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// selfSignedPEM 生成同时用于服务端与客户端认证的自签名证书与私钥
+func selfSignedPEM(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "cbdc-chaincode"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// freeAddress 返回一个当前空闲的本地端口
+func freeAddress(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func TestLoadServerConfig(t *testing.T) {
+	for _, name := range []string{"CHAINCODE_SERVER_ADDRESS", "CHAINCODE_ID", "CORE_CHAINCODE_ID_NAME", "CHAINCODE_HEALTH_ADDRESS",
+		"CHAINCODE_TLS_DISABLED", "CHAINCODE_TLS_KEY", "CHAINCODE_TLS_CERT", "CHAINCODE_CLIENT_CA_CERT"} {
+		t.Setenv(name, "")
+	}
+
+	// 未设置服务地址时由peer启动链码
+	if config, err := loadServerConfig(); config != nil || err != nil {
+		t.Fatalf("expected peer-launched mode, got %+v, %v", config, err)
+	}
+
+	t.Setenv("CHAINCODE_SERVER_ADDRESS", "0.0.0.0:9999")
+	if _, err := loadServerConfig(); err == nil || !strings.Contains(err.Error(), "CHAINCODE_ID must be set") {
+		t.Fatalf("expected a missing package id error, got %v", err)
+	}
+	t.Setenv("CORE_CHAINCODE_ID_NAME", "cbdc:abc")
+	config, err := loadServerConfig()
+	if err != nil {
+		t.Fatalf("loadServerConfig failed: %v", err)
+	}
+	if config.CCID != "cbdc:abc" || config.HealthAddress != defaultHealthAddress || !config.TLSProps.Disabled {
+		t.Fatalf("unexpected config: %+v", config)
+	}
+
+	t.Setenv("CHAINCODE_TLS_DISABLED", "maybe")
+	if _, err := loadServerConfig(); err == nil || !strings.Contains(err.Error(), "invalid CHAINCODE_TLS_DISABLED") {
+		t.Fatalf("expected an invalid TLS flag error, got %v", err)
+	}
+	t.Setenv("CHAINCODE_TLS_DISABLED", "false")
+	if _, err := loadServerConfig(); err == nil || !strings.Contains(err.Error(), "CHAINCODE_TLS_KEY must be set") {
+		t.Fatalf("expected a missing TLS key error, got %v", err)
+	}
+
+	dir := t.TempDir()
+	certPEM, keyPEM := selfSignedPEM(t)
+	for name, content := range map[string][]byte{"CHAINCODE_TLS_CERT": certPEM, "CHAINCODE_TLS_KEY": keyPEM, "CHAINCODE_CLIENT_CA_CERT": certPEM} {
+		path := filepath.Join(dir, name+".pem")
+		if err := os.WriteFile(path, content, 0600); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv(name, path)
+	}
+	t.Setenv("CHAINCODE_ID", "cbdc:def")
+	config, err = loadServerConfig()
+	if err != nil {
+		t.Fatalf("loadServerConfig with TLS failed: %v", err)
+	}
+	if config.CCID != "cbdc:def" || config.TLSProps.Disabled || string(config.TLSProps.Key) != string(keyPEM) || config.TLSProps.ClientCACerts == nil {
+		t.Fatalf("unexpected TLS config: %+v", config)
+	}
+
+	t.Setenv("CHAINCODE_CLIENT_CA_CERT", filepath.Join(dir, "missing.pem"))
+	if _, err := loadServerConfig(); err == nil || !strings.Contains(err.Error(), "failed to read CHAINCODE_CLIENT_CA_CERT") {
+		t.Fatalf("expected a missing file error, got %v", err)
+	}
+}
+
+// TestServerTLSRequiresClientCertificate 设置客户端 CA 后没有客户端证书的连接在握手时被拒绝
+func TestServerTLSRequiresClientCertificate(t *testing.T) {
+	certPEM, keyPEM := selfSignedPEM(t)
+	if _, err := newServerTLSConfig(shim.TLSProperties{Key: keyPEM, Cert: []byte("not a certificate")}); err == nil || !strings.Contains(err.Error(), "failed to parse TLS key pair") {
+		t.Fatalf("expected a key pair error, got %v", err)
+	}
+	if _, err := newServerTLSConfig(shim.TLSProperties{Key: keyPEM, Cert: certPEM, ClientCACerts: []byte("not a certificate")}); err == nil || !strings.Contains(err.Error(), "CHAINCODE_CLIENT_CA_CERT") {
+		t.Fatalf("expected a client CA error, got %v", err)
+	}
+
+	tlsConfig, err := newServerTLSConfig(shim.TLSProperties{Key: keyPEM, Cert: certPEM, ClientCACerts: certPEM})
+	if err != nil {
+		t.Fatalf("newServerTLSConfig failed: %v", err)
+	}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	handshakes := make(chan error)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			handshakes <- conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(certPEM)
+	clientCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dial := func(certificates []tls.Certificate) error {
+		conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{RootCAs: roots, Certificates: certificates})
+		if err == nil {
+			defer conn.Close()
+			conn.Write([]byte("ping"))
+		}
+		return <-handshakes
+	}
+
+	if err := dial([]tls.Certificate{clientCert}); err != nil {
+		t.Fatalf("handshake with a client certificate failed: %v", err)
+	}
+	if err := dial(nil); err == nil {
+		t.Fatalf("expected a handshake without a client certificate to be rejected")
+	}
+}
+
+// TestRunChaincodeServer 端口绑定后健康检查返回服务中，收到 SIGTERM 后正常退出
+func TestRunChaincodeServer(t *testing.T) {
+	cc, err := contractapi.NewChaincode(&SmartContract{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 服务端口被占用时直接返回错误
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = runChaincodeServer(cc, &serverConfig{CCID: "cbdc:abc", Address: busy.Addr().String(), HealthAddress: healthDisabled, TLSProps: shim.TLSProperties{Disabled: true}})
+	busy.Close()
+	if err == nil || !strings.Contains(err.Error(), "failed to listen on") {
+		t.Fatalf("expected a bind error, got %v", err)
+	}
+
+	healthAddress := freeAddress(t)
+	result := make(chan error, 1)
+	go func() {
+		result <- runChaincodeServer(cc, &serverConfig{CCID: "cbdc:abc", Address: "127.0.0.1:0", HealthAddress: healthAddress, TLSProps: shim.TLSProperties{Disabled: true}})
+	}()
+
+	status := ""
+	for deadline := time.Now().Add(5 * time.Second); status != "serving"; time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("health endpoint did not report serving, last status %q", status)
+		}
+		response, err := http.Get("http://" + healthAddress + healthPath)
+		if err != nil {
+			continue
+		}
+		var body map[string]string
+		json.NewDecoder(response.Body).Decode(&body)
+		response.Body.Close()
+		status = body["status"]
+		if status == "serving" && response.StatusCode != http.StatusOK {
+			t.Fatalf("serving status returned HTTP %d", response.StatusCode)
+		}
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("runChaincodeServer returned %v after SIGTERM", err)
+		}
+	case <-time.After(2 * shutdownTimeout):
+		t.Fatalf("chaincode server did not stop after SIGTERM")
+	}
+	if _, err := http.Get("http://" + healthAddress + healthPath); err == nil {
+		t.Fatalf("expected the health endpoint to be closed")
+	}
+}
@@ -0,0 +1,629 @@
+/*
+保密金额模式
+
+- 与明文私有数据余额并存的可选模式：账户把明文余额存入保密余额后，公共账本上只保存其 Pedersen 承诺
+- 承诺的打开值（金额与盲化因子）只保存在钱包中，合约既不保存也不接收保密转账的打开值
+- ConfidentialTransfer 的参数只有承诺与范围证明：背书节点校验 余额承诺 = 金额承诺 + 找零承诺（守恒）
+  以及金额与找零均在 [0, 2^64) 内（非负），不需要知道金额
+- 收款方与央行所需的打开值由钱包加密后附在请求中（recipientOpening、auditOpening），合约原样转发和保存，不解读
+- 承诺与证明由钱包生成（参考实现见 confidential_wallet.go），链码不读取随机数，各背书节点的执行结果一致
+- ConfidentialDeposit / ConfidentialWithdraw 在明文余额与保密余额之间划转，金额本就记入明文余额，通过 transient 提交；
+  明文侧按 0x0 记账，保密余额合计与全部承诺的盲化因子之和单独记录在央行私有集合
+- 账本不变量按 明文余额合计 + 保密余额合计 = 总供应量 核对，AuditConfidentialLedger 核对 承诺之和 = commit(合计, 盲化因子之和)
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// confidentialAccountPrefix 保密余额承诺的键前缀，存储在公共状态中
+const confidentialAccountPrefix = "cacct_"
+
+// confidentialTransferPrefix 保密转账监管记录的键前缀，存储在央行私有集合中
+const confidentialTransferPrefix = "ctransfer_"
+
+// confidentialSupplyKey 保密余额合计，存储在央行私有集合中
+const confidentialSupplyKey = "confidentialSupply"
+
+// confidentialBlindingKey 全部保密余额承诺的盲化因子之和，存储在央行私有集合中
+const confidentialBlindingKey = "confidentialBlinding"
+
+// confidentialTransientField 存入、取出金额的 transient 字段
+const confidentialTransientField = "confidential"
+
+// ConfidentialAccount 公共状态中的保密余额承诺
+type ConfidentialAccount struct {
+	Account    string `json:"account"`
+	Commitment string `json:"commitment"`
+	Sequence   int    `json:"sequence"` // 每次更新加一，钱包据此判断本地打开值是否过期
+	UpdatedAt  int64  `json:"updatedAt"`
+}
+
+// ConfidentialTransferRequest ConfidentialTransfer 的参数
+type ConfidentialTransferRequest struct {
+	Recipient        string      `json:"recipient"`
+	AmountCommitment string      `json:"amountCommitment"`
+	ChangeCommitment string      `json:"changeCommitment"` // 转账后付款方的余额承诺
+	AmountProof      *RangeProof `json:"amountProof"`
+	ChangeProof      *RangeProof `json:"changeProof"`
+	RecipientOpening string      `json:"recipientOpening,omitempty"` // 钱包加密给收款方的金额与盲化因子，随事件发出
+	AuditOpening     string      `json:"auditOpening,omitempty"`     // 钱包加密给央行的金额与盲化因子，保存在监管记录中
+}
+
+// ConfidentialWithdrawRequest ConfidentialWithdraw 的参数；剩余承诺为空表示取出全部保密余额并注销保密账户
+type ConfidentialWithdrawRequest struct {
+	RemainingCommitment string      `json:"remainingCommitment,omitempty"`
+	RemainingProof      *RangeProof `json:"remainingProof,omitempty"`
+}
+
+// confidentialAmount 存入、取出时的 transient 参数；blinding 为存入或取出部分承诺的盲化因子
+type confidentialAmount struct {
+	Amount   int    `json:"amount"`
+	Blinding string `json:"blinding"`
+}
+
+// ConfidentialTransferRecord 保密转账监管记录
+type ConfidentialTransferRecord struct {
+	TxID             string `json:"txId"`
+	From             string `json:"from"`
+	To               string `json:"to"`
+	AmountCommitment string `json:"amountCommitment"`
+	AuditOpening     string `json:"auditOpening,omitempty"`
+	Timestamp        int64  `json:"timestamp"`
+}
+
+// readConfidentialTransient 解析 transient 字段 confidential
+func readConfidentialTransient(ctx contractapi.TransactionContextInterface, value interface{}) error {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("failed to get transient data: %v", err)
+	}
+	payload, ok := transient[confidentialTransientField]
+	if !ok || len(payload) == 0 {
+		return fmt.Errorf("transient field %s is required", confidentialTransientField)
+	}
+	if err := json.Unmarshal(payload, value); err != nil {
+		return fmt.Errorf("invalid transient field %s: %v", confidentialTransientField, err)
+	}
+	return nil
+}
+
+// decodeBlinding 解码盲化因子，零值会使承诺失去隐藏性
+func decodeBlinding(encoded string) (*big.Int, error) {
+	blinding, err := decodeScalar(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blinding: %v", err)
+	}
+	if blinding.Sign() == 0 {
+		return nil, errors.New("invalid blinding: must not be zero")
+	}
+	return blinding, nil
+}
+
+// getConfidentialAccount 读取保密余额承诺，不存在时返回 nil
+func getConfidentialAccount(ctx contractapi.TransactionContextInterface, account string) (*ConfidentialAccount, error) {
+	accountBytes, err := ctx.GetStub().GetState(confidentialAccountPrefix + account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read confidential account %s: %v", account, err)
+	}
+	if accountBytes == nil {
+		return nil, nil
+	}
+	var confidential ConfidentialAccount
+	if err := json.Unmarshal(accountBytes, &confidential); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal confidential account %s: %v", account, err)
+	}
+	return &confidential, nil
+}
+
+// loadConfidentialCommitment 读取并解码保密余额承诺；账户没有保密余额时返回无穷远点
+func loadConfidentialCommitment(ctx contractapi.TransactionContextInterface, account string) (*ConfidentialAccount, ecPoint, error) {
+	identity := ecPoint{X: new(big.Int), Y: new(big.Int)}
+	confidential, err := getConfidentialAccount(ctx, account)
+	if err != nil || confidential == nil {
+		return nil, identity, err
+	}
+	commitment, err := decodePoint(confidential.Commitment)
+	if err != nil {
+		return nil, identity, fmt.Errorf("confidential account %s: %v", account, err)
+	}
+	return confidential, commitment, nil
+}
+
+// putConfidentialAccount 写入新的承诺，序号加一
+func putConfidentialAccount(ctx contractapi.TransactionContextInterface, account string, previous *ConfidentialAccount, commitment ecPoint) (*ConfidentialAccount, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	sequence := 1
+	if previous != nil {
+		sequence = previous.Sequence + 1
+	}
+
+	confidential := &ConfidentialAccount{
+		Account:    account,
+		Commitment: encodePoint(commitment),
+		Sequence:   sequence,
+		UpdatedAt:  timestamp.Seconds,
+	}
+	accountJSON, err := json.Marshal(confidential)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal confidential account: %v", err)
+	}
+	if err := ctx.GetStub().PutState(confidentialAccountPrefix+account, accountJSON); err != nil {
+		return nil, fmt.Errorf("failed to store confidential account %s: %v", account, err)
+	}
+	return confidential, nil
+}
+
+// marshalConfidentialAccount 返回本交易写入的承诺；同一交易内读不到自身写入，不能再查询一次
+func marshalConfidentialAccount(confidential *ConfidentialAccount) (string, error) {
+	accountJSON, err := json.Marshal(confidential)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal confidential account: %v", err)
+	}
+	return string(accountJSON), nil
+}
+
+// getConfidentialSupply 读取保密余额合计
+func (s *SmartContract) getConfidentialSupply(ctx contractapi.TransactionContextInterface) (int, error) {
+	supplyBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), confidentialSupplyKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read confidential supply: %v", err)
+	}
+	if supplyBytes == nil {
+		return 0, nil
+	}
+	supply, err := strconv.Atoi(string(supplyBytes))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse confidential supply: %v", err)
+	}
+	return supply, nil
+}
+
+// getConfidentialBlinding 读取全部承诺的盲化因子之和
+func getConfidentialBlinding(ctx contractapi.TransactionContextInterface) (*big.Int, error) {
+	blindingBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), confidentialBlindingKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read confidential blinding: %v", err)
+	}
+	if blindingBytes == nil {
+		return new(big.Int), nil
+	}
+	blinding, err := decodeScalar(string(blindingBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse confidential blinding: %v", err)
+	}
+	return blinding, nil
+}
+
+// updateConfidentialTotals 按 delta 调整保密余额合计，按 blindingDelta 调整盲化因子之和
+func (s *SmartContract) updateConfidentialTotals(ctx contractapi.TransactionContextInterface, delta int, blindingDelta *big.Int) error {
+	supply, err := s.getConfidentialSupply(ctx)
+	if err != nil {
+		return err
+	}
+	supply, err = add(supply, delta)
+	if err != nil {
+		return err
+	}
+	if supply < 0 {
+		return errors.New("confidential supply cannot be negative")
+	}
+	if err := ctx.GetStub().PutPrivateData(privateCollection(ctx), confidentialSupplyKey, []byte(strconv.Itoa(supply))); err != nil {
+		return fmt.Errorf("failed to store confidential supply: %v", err)
+	}
+
+	blinding, err := getConfidentialBlinding(ctx)
+	if err != nil {
+		return err
+	}
+	blinding.Add(blinding, blindingDelta)
+	blinding.Mod(blinding, curveOrder)
+	if err := ctx.GetStub().PutPrivateData(privateCollection(ctx), confidentialBlindingKey, []byte(encodeScalar(blinding))); err != nil {
+		return fmt.Errorf("failed to store confidential blinding: %v", err)
+	}
+	return nil
+}
+
+// confidentialCaller 检查初始化并返回调用者已开立的账户
+func (s *SmartContract) confidentialCaller(ctx contractapi.TransactionContextInterface) (*UserBalance, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, errors.New("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+	account, err := s.getUserAccountInfo(ctx, callerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read account %s: %v", callerID, err)
+	}
+	if err := checkAccountActive(account, "the"); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// bookConfidentialMovement 记录明文余额与保密余额之间的划转并发出事件
+func (s *SmartContract) bookConfidentialMovement(ctx contractapi.TransactionContextInterface, transactionType string, from string, to string, amount int) error {
+	record, err := s.newTransactionRecord(ctx, transactionType, from, to, amount)
+	if err != nil {
+		return err
+	}
+	if err := s.bookTransactionRecord(ctx, record); err != nil {
+		return err
+	}
+	return s.emitTransferEvent(ctx, record)
+}
+
+// ConfidentialDeposit 将调用者的明文余额存入保密余额
+// transient 字段 confidential：{"amount":100,"blinding":"<新增承诺的盲化因子>"}，钱包把盲化因子加到本地打开值上
+func (s *SmartContract) ConfidentialDeposit(ctx contractapi.TransactionContextInterface) (string, error) {
+	account, err := s.confidentialCaller(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var deposit confidentialAmount
+	if err := readConfidentialTransient(ctx, &deposit); err != nil {
+		return "", err
+	}
+	if deposit.Amount <= 0 {
+		return "", errors.New("deposit amount must be positive")
+	}
+	blinding, err := decodeBlinding(deposit.Blinding)
+	if err != nil {
+		return "", err
+	}
+	if account.Balance < deposit.Amount {
+		return "", fmt.Errorf("account %s has insufficient funds", account.UserID)
+	}
+
+	confidential, commitment, err := loadConfidentialCommitment(ctx, account.UserID)
+	if err != nil {
+		return "", err
+	}
+	newCommitment := commitment.add(pedersenCommit(big.NewInt(int64(deposit.Amount)), blinding))
+	updated, err := putConfidentialAccount(ctx, account.UserID, confidential, newCommitment)
+	if err != nil {
+		return "", err
+	}
+
+	account.Balance -= deposit.Amount
+	if err := s.updateUserAccountInPrivateCollection(ctx, account); err != nil {
+		return "", err
+	}
+	if err := s.updateConfidentialTotals(ctx, deposit.Amount, blinding); err != nil {
+		return "", err
+	}
+	if err := s.recordBankShielding(ctx, account.OrgMSP, deposit.Amount); err != nil {
+		return "", err
+	}
+	if err := s.bookConfidentialMovement(ctx, "confidentialDeposit", account.UserID, "0x0", deposit.Amount); err != nil {
+		return "", err
+	}
+
+	log.Printf("confidential deposit booked for %s", account.UserID)
+	return marshalConfidentialAccount(updated)
+}
+
+// ConfidentialWithdraw 将调用者的保密余额取回明文余额
+// withdrawalJSON 见 ConfidentialWithdrawRequest：余额承诺 = commit(金额, 盲化因子) + 剩余承诺，剩余承诺附范围证明；
+// transient 字段 confidential：{"amount":100,"blinding":"<取出部分承诺的盲化因子>"}；取出全部时金额可以为 0
+func (s *SmartContract) ConfidentialWithdraw(ctx contractapi.TransactionContextInterface, withdrawalJSON string) (string, error) {
+	account, err := s.confidentialCaller(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var request ConfidentialWithdrawRequest
+	if err := json.Unmarshal([]byte(withdrawalJSON), &request); err != nil {
+		return "", fmt.Errorf("invalid confidential withdrawal: %v", err)
+	}
+	var withdrawal confidentialAmount
+	if err := readConfidentialTransient(ctx, &withdrawal); err != nil {
+		return "", err
+	}
+	closing := request.RemainingCommitment == ""
+	if withdrawal.Amount < 0 || (withdrawal.Amount == 0 && !closing) {
+		return "", errors.New("withdrawal amount must be positive")
+	}
+	blinding, err := decodeBlinding(withdrawal.Blinding)
+	if err != nil {
+		return "", err
+	}
+
+	confidential, commitment, err := loadConfidentialCommitment(ctx, account.UserID)
+	if err != nil {
+		return "", err
+	}
+	if confidential == nil {
+		return "", fmt.Errorf("account %s has no confidential balance", account.UserID)
+	}
+
+	// 取出部分的承诺与剩余承诺之和须等于当前承诺，剩余金额非负
+	withdrawn := pedersenCommit(big.NewInt(int64(withdrawal.Amount)), blinding)
+	var updated *ConfidentialAccount
+	if closing {
+		if !withdrawn.equal(commitment) {
+			return "", errors.New("withdrawal does not open the whole confidential balance")
+		}
+		if err := ctx.GetStub().DelState(confidentialAccountPrefix + account.UserID); err != nil {
+			return "", fmt.Errorf("failed to delete confidential account %s: %v", account.UserID, err)
+		}
+	} else {
+		remaining, err := decodePoint(request.RemainingCommitment)
+		if err != nil {
+			return "", fmt.Errorf("remaining commitment: %v", err)
+		}
+		if !withdrawn.add(remaining).equal(commitment) {
+			return "", errors.New("withdrawal and remaining commitments do not add up to the balance commitment")
+		}
+		if err := verifyRange(remaining, request.RemainingProof); err != nil {
+			return "", fmt.Errorf("invalid remaining range proof: %v", err)
+		}
+		updated, err = putConfidentialAccount(ctx, account.UserID, confidential, remaining)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	account.Balance, err = add(account.Balance, withdrawal.Amount)
+	if err != nil {
+		return "", err
+	}
+	if err := s.updateUserAccountInPrivateCollection(ctx, account); err != nil {
+		return "", err
+	}
+	if err := s.updateConfidentialTotals(ctx, -withdrawal.Amount, new(big.Int).Neg(blinding)); err != nil {
+		return "", err
+	}
+	if err := s.recordBankShielding(ctx, account.OrgMSP, -withdrawal.Amount); err != nil {
+		return "", err
+	}
+	if withdrawal.Amount > 0 {
+		if err := s.bookConfidentialMovement(ctx, "confidentialWithdraw", "0x0", account.UserID, withdrawal.Amount); err != nil {
+			return "", err
+		}
+	}
+
+	log.Printf("confidential withdrawal booked for %s", account.UserID)
+	if updated == nil {
+		resultJSON, err := json.Marshal(map[string]interface{}{"account": account.UserID, "closed": true})
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal result: %v", err)
+		}
+		return string(resultJSON), nil
+	}
+	return marshalConfidentialAccount(updated)
+}
+
+// ConfidentialTransfer 保密转账，金额只以承诺形式出现在交易中，背书节点只校验守恒与范围证明
+// transferJSON 见 ConfidentialTransferRequest，由钱包生成
+func (s *SmartContract) ConfidentialTransfer(ctx contractapi.TransactionContextInterface, transferJSON string) (string, error) {
+	senderAccount, err := s.confidentialCaller(ctx)
+	if err != nil {
+		return "", err
+	}
+	sender := senderAccount.UserID
+
+	var request ConfidentialTransferRequest
+	if err := json.Unmarshal([]byte(transferJSON), &request); err != nil {
+		return "", fmt.Errorf("invalid confidential transfer: %v", err)
+	}
+	recipient, err := s.resolveAccount(ctx, request.Recipient)
+	if err != nil {
+		return "", err
+	}
+	if recipient == "" || recipient == sender {
+		return "", errors.New("recipient must be another account")
+	}
+	recipientAccount, err := s.getUserAccountInfo(ctx, recipient)
+	if err != nil {
+		return "", fmt.Errorf("failed to read recipient account %s: %v", recipient, err)
+	}
+	if err := checkAccountActive(recipientAccount, "recipient"); err != nil {
+		return "", err
+	}
+
+	// 公开校验：守恒与非负，不依赖打开值；金额为零的转账无法识别，但不影响守恒
+	amountCommitment, err := decodePoint(request.AmountCommitment)
+	if err != nil {
+		return "", fmt.Errorf("amount commitment: %v", err)
+	}
+	changeCommitment, err := decodePoint(request.ChangeCommitment)
+	if err != nil {
+		return "", fmt.Errorf("change commitment: %v", err)
+	}
+	senderConfidential, senderCommitment, err := loadConfidentialCommitment(ctx, sender)
+	if err != nil {
+		return "", err
+	}
+	if senderConfidential == nil {
+		return "", fmt.Errorf("account %s has no confidential balance", sender)
+	}
+	if !amountCommitment.add(changeCommitment).equal(senderCommitment) {
+		return "", errors.New("amount and change commitments do not add up to the sender's balance commitment")
+	}
+	if err := verifyRange(amountCommitment, request.AmountProof); err != nil {
+		return "", fmt.Errorf("invalid amount range proof: %v", err)
+	}
+	if err := verifyRange(changeCommitment, request.ChangeProof); err != nil {
+		return "", fmt.Errorf("invalid change range proof: %v", err)
+	}
+
+	recipientConfidential, recipientCommitment, err := loadConfidentialCommitment(ctx, recipient)
+	if err != nil {
+		return "", err
+	}
+	if _, err := putConfidentialAccount(ctx, sender, senderConfidential, changeCommitment); err != nil {
+		return "", err
+	}
+	if _, err := putConfidentialAccount(ctx, recipient, recipientConfidential, recipientCommitment.add(amountCommitment)); err != nil {
+		return "", err
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	txID := ctx.GetStub().GetTxID()
+	recordJSON, err := json.Marshal(ConfidentialTransferRecord{
+		TxID:             txID,
+		From:             sender,
+		To:               recipient,
+		AmountCommitment: request.AmountCommitment,
+		AuditOpening:     request.AuditOpening,
+		Timestamp:        timestamp.Seconds,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal confidential transfer record: %v", err)
+	}
+	if err := ctx.GetStub().PutPrivateData(privateCollection(ctx), confidentialTransferPrefix+txID, recordJSON); err != nil {
+		return "", fmt.Errorf("failed to store confidential transfer record: %v", err)
+	}
+
+	attributes := map[string]interface{}{"amountCommitment": request.AmountCommitment}
+	if request.RecipientOpening != "" {
+		attributes["recipientOpening"] = request.RecipientOpening
+	}
+	err = s.emitEvent(ctx, eventSpec{
+		Type:       eventTypeConfidentialTransfer,
+		Attributes: attributes,
+		Parties:    map[string]string{"from": sender, "to": recipient},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("confidential transfer %s booked", txID)
+
+	resultJSON, err := json.Marshal(map[string]interface{}{
+		"txId":             txID,
+		"amountCommitment": request.AmountCommitment,
+		"changeCommitment": request.ChangeCommitment,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// GetConfidentialAccount 查询保密余额承诺；金额与盲化因子只在钱包中
+func (s *SmartContract) GetConfidentialAccount(ctx contractapi.TransactionContextInterface, account string) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", errors.New("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	accountID, err := s.resolveAccount(ctx, account)
+	if err != nil {
+		return "", err
+	}
+	confidential, err := getConfidentialAccount(ctx, accountID)
+	if err != nil {
+		return "", err
+	}
+	if confidential == nil {
+		return "", fmt.Errorf("account %s has no confidential balance", account)
+	}
+	return marshalConfidentialAccount(confidential)
+}
+
+// GetConfidentialTransfer 央行查询保密转账的监管记录
+func (s *SmartContract) GetConfidentialTransfer(ctx contractapi.TransactionContextInterface, txID string) (string, error) {
+	if err := s.checkAnalyticsAccess(ctx); err != nil {
+		return "", err
+	}
+	recordJSON, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), confidentialTransferPrefix+txID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read confidential transfer %s: %v", txID, err)
+	}
+	if recordJSON == nil {
+		return "", fmt.Errorf("confidential transfer %s not found", txID)
+	}
+	return string(recordJSON), nil
+}
+
+// AuditConfidentialLedger 央行核对全部保密余额：承诺之和等于 commit(保密余额合计, 盲化因子之和)
+// 转账只移动承诺，存入与取出按明文金额与盲化因子调整两个合计，任何不守恒的更新都会使等式不成立
+func (s *SmartContract) AuditConfidentialLedger(ctx contractapi.TransactionContextInterface) (string, error) {
+	if err := s.checkAnalyticsAccess(ctx); err != nil {
+		return "", err
+	}
+
+	iterator, err := ctx.GetStub().GetStateByRange(confidentialAccountPrefix, confidentialAccountPrefix[:len(confidentialAccountPrefix)-1]+"`")
+	if err != nil {
+		return "", fmt.Errorf("failed to read confidential accounts: %v", err)
+	}
+	defer iterator.Close()
+
+	issues := []ledgerIssue{}
+	accounts := 0
+	commitmentSum := ecPoint{X: new(big.Int), Y: new(big.Int)}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return "", fmt.Errorf("failed to iterate confidential accounts: %v", err)
+		}
+		_, commitment, err := loadConfidentialCommitment(ctx, kv.Key[len(confidentialAccountPrefix):])
+		if err != nil {
+			issues = append(issues, ledgerIssue{Type: issueConfidentialCommitment, Key: kv.Key, Detail: err.Error()})
+			continue
+		}
+		accounts++
+		commitmentSum = commitmentSum.add(commitment)
+	}
+
+	supply, err := s.getConfidentialSupply(ctx)
+	if err != nil {
+		return "", err
+	}
+	blinding, err := getConfidentialBlinding(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(issues) == 0 && !commitmentSum.equal(pedersenCommit(big.NewInt(int64(supply)), blinding)) {
+		issues = append(issues, ledgerIssue{Type: issueSupplyMismatch, Key: confidentialSupplyKey, Detail: "sum of commitments does not open to confidential supply"})
+	}
+
+	return marshalAnalytics(map[string]interface{}{
+		"accounts":           accounts,
+		"confidentialSupply": supply,
+		"consistent":         len(issues) == 0,
+		"issues":             issues,
+	})
+}
+
+// checkNoConfidentialBalance 销户前要求以 ConfidentialWithdraw 取出全部保密余额并注销保密账户
+func (s *SmartContract) checkNoConfidentialBalance(ctx contractapi.TransactionContextInterface, account string) error {
+	confidential, err := getConfidentialAccount(ctx, account)
+	if err != nil {
+		return err
+	}
+	if confidential != nil {
+		return fmt.Errorf("account %s still holds a confidential balance, withdraw it first", account)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func TestRangeProof(t *testing.T) {
+	blinding, err := randomScalar(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prove := func(value uint64) *RangeProof {
+		proof, err := proveRange(value, blinding, rand.Reader)
+		if err != nil {
+			t.Fatalf("proveRange(%d) failed: %v", value, err)
+		}
+		return proof
+	}
+	commit := func(value *big.Int) ecPoint { return pedersenCommit(value, blinding) }
+	twoTo64 := new(big.Int).Lsh(big.NewInt(1), 64)
+
+	tests := []struct {
+		name       string
+		commitment ecPoint
+		proof      func() *RangeProof
+		wantErr    string
+	}{
+		{name: "zero", commitment: commit(big.NewInt(0)), proof: func() *RangeProof { return prove(0) }},
+		{name: "small value", commitment: commit(big.NewInt(12345)), proof: func() *RangeProof { return prove(12345) }},
+		{
+			name:       "largest value",
+			commitment: commit(new(big.Int).Sub(twoTo64, big.NewInt(1))),
+			proof:      func() *RangeProof { return prove(^uint64(0)) },
+		},
+		{
+			// 负数金额按 uint64 回绕后证明的是 2^64-5，与 commit(-5) 不同；挑战绑定整体承诺，第一位即不成立
+			name:       "negative value",
+			commitment: commit(big.NewInt(-5)),
+			proof:      func() *RangeProof { return prove(uint64(1<<64 - 5)) },
+			wantErr:    "bit 0: proof does not verify",
+		},
+		{
+			name:       "value of 2^64",
+			commitment: commit(new(big.Int).Add(twoTo64, big.NewInt(3))),
+			proof:      func() *RangeProof { return prove(3) },
+			wantErr:    "bit 0: proof does not verify",
+		},
+		{
+			name:       "proof for another value",
+			commitment: commit(big.NewInt(100)),
+			proof:      func() *RangeProof { return prove(101) },
+			wantErr:    "proof does not verify",
+		},
+		{
+			name:       "tampered response",
+			commitment: commit(big.NewInt(100)),
+			proof: func() *RangeProof {
+				proof := prove(100)
+				s0, _ := decodeScalar(proof.Bits[5].S0)
+				proof.Bits[5].S0 = encodeScalar(s0.Add(s0, big.NewInt(1)))
+				return proof
+			},
+			wantErr: "bit 5: proof does not verify",
+		},
+		{
+			name:       "swapped bit commitments",
+			commitment: commit(big.NewInt(100)),
+			proof: func() *RangeProof {
+				proof := prove(100)
+				proof.Bits[2].Commitment, proof.Bits[3].Commitment = proof.Bits[3].Commitment, proof.Bits[2].Commitment
+				return proof
+			},
+			wantErr: "proof does not verify",
+		},
+		{
+			name:       "truncated proof",
+			commitment: commit(big.NewInt(100)),
+			proof: func() *RangeProof {
+				proof := prove(100)
+				proof.Bits = proof.Bits[:32]
+				return proof
+			},
+			wantErr: "must contain 64 bit proofs",
+		},
+		{name: "missing proof", commitment: commit(big.NewInt(100)), proof: func() *RangeProof { return nil }, wantErr: "must contain 64 bit proofs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyRange(tt.commitment, tt.proof())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// confidentialWallet 测试用钱包：本地保存保密余额的打开值
+type confidentialWallet struct {
+	balance  int
+	blinding *big.Int
+}
+
+func confidentialCall(env *testEnv, caller testUser, transient interface{}, fn func(ctx contractapi.TransactionContextInterface) (string, error)) (string, error) {
+	var transientMap map[string][]byte
+	if transient != nil {
+		payload, err := json.Marshal(transient)
+		if err != nil {
+			return "", err
+		}
+		transientMap = map[string][]byte{confidentialTransientField: payload}
+	}
+	var result string
+	_, err := env.invoke(caller, transientMap, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+	return result, err
+}
+
+func TestConfidentialTransfer(t *testing.T) {
+	env := newTestEnv(t)
+	env.initialize()
+	_, err := env.invoke(bankAAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.OpenAccount(ctx, bankAUser.id, accountTypeIndividual)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+	_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.Mint(ctx, 1000)
+	})
+	if err != nil {
+		t.Fatalf("Mint failed: %v", err)
+	}
+	transfer(t, env, centralBankAdmin, bankAUser, 500)
+
+	sender := confidentialWallet{}
+	depositBlinding, _ := randomScalar(rand.Reader)
+	authorizeDebit(t, env, bankAUser)
+	_, err = confidentialCall(env, bankAUser, confidentialAmount{Amount: 300, Blinding: encodeScalar(depositBlinding)}, func(ctx contractapi.TransactionContextInterface) (string, error) {
+		return env.contract.ConfidentialDeposit(ctx)
+	})
+	if err != nil {
+		t.Fatalf("ConfidentialDeposit failed: %v", err)
+	}
+	sender.balance, sender.blinding = 300, depositBlinding
+
+	submit := func(request *ConfidentialTransferRequest) error {
+		requestJSON, err := json.Marshal(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = confidentialCall(env, bankAUser, nil, func(ctx contractapi.TransactionContextInterface) (string, error) {
+			return env.contract.ConfidentialTransfer(ctx, string(requestJSON))
+		})
+		return err
+	}
+
+	// 超额转账：找零为负，其范围证明不成立
+	overspendBlinding, _ := randomScalar(rand.Reader)
+	changeBlinding := new(big.Int).Sub(sender.blinding, overspendBlinding)
+	changeBlinding.Mod(changeBlinding, curveOrder)
+	amountProof, _ := proveRange(400, overspendBlinding, rand.Reader)
+	changeProof, _ := proveRange(uint64(1<<64-100), changeBlinding, rand.Reader)
+	err = submit(&ConfidentialTransferRequest{
+		Recipient:        centralBankAdmin.id,
+		AmountCommitment: encodePoint(pedersenCommit(big.NewInt(400), overspendBlinding)),
+		ChangeCommitment: encodePoint(pedersenCommit(big.NewInt(-100), changeBlinding)),
+		AmountProof:      amountProof,
+		ChangeProof:      changeProof,
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid change range proof") {
+		t.Fatalf("expected overspending to be rejected, got %v", err)
+	}
+
+	request, openings, err := buildConfidentialTransfer(centralBankAdmin.id, 120, sender.balance, sender.blinding, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := *request
+	tampered.ChangeCommitment = request.AmountCommitment
+	if err := submit(&tampered); err == nil || !strings.Contains(err.Error(), "do not add up") {
+		t.Fatalf("expected unbalanced commitments to be rejected, got %v", err)
+	}
+	if err := submit(request); err != nil {
+		t.Fatalf("ConfidentialTransfer failed: %v", err)
+	}
+	sender.balance = openings.Change
+	sender.blinding, _ = decodeScalar(openings.ChangeBlinding)
+
+	// 背书节点只看到承诺，私有集合中不保存转账金额
+	for key, value := range env.ledger.private[defaultPrivateCollection] {
+		if strings.HasPrefix(key, confidentialTransferPrefix) && strings.Contains(string(value), `"amount":`) {
+			t.Fatalf("confidential transfer record must not contain the amount: %s", value)
+		}
+	}
+
+	audit := func() {
+		t.Helper()
+		result, err := confidentialCall(env, centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) (string, error) {
+			return env.contract.AuditConfidentialLedger(ctx)
+		})
+		if err != nil {
+			t.Fatalf("AuditConfidentialLedger failed: %v", err)
+		}
+		if !strings.Contains(result, `"consistent":true`) {
+			t.Fatalf("confidential ledger is inconsistent: %s", result)
+		}
+	}
+	audit()
+
+	withdraw := func(amount int) {
+		t.Helper()
+		request, transient, err := buildConfidentialWithdrawal(amount, sender.balance, sender.blinding, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		requestJSON, _ := json.Marshal(request)
+		_, err = confidentialCall(env, bankAUser, transient, func(ctx contractapi.TransactionContextInterface) (string, error) {
+			return env.contract.ConfidentialWithdraw(ctx, string(requestJSON))
+		})
+		if err != nil {
+			t.Fatalf("ConfidentialWithdraw(%d) failed: %v", amount, err)
+		}
+		withdrawn, _ := decodeScalar(transient.Blinding)
+		sender.balance -= amount
+		sender.blinding.Sub(sender.blinding, withdrawn).Mod(sender.blinding, curveOrder)
+	}
+	withdraw(80)
+	audit()
+	withdraw(sender.balance)
+	audit()
+
+	if env.ledger.state[confidentialAccountPrefix+bankAUser.id] != nil {
+		t.Fatalf("withdrawing everything must remove the confidential account")
+	}
+	balance, _, err := readBalanceAndAllowance(env)
+	if err != nil || balance != 500-120 {
+		t.Fatalf("plaintext balance = %d, %v; want %d", balance, err, 500-120)
+	}
+}
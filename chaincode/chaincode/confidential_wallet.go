@@ -0,0 +1,103 @@
+/*
+保密金额模式的钱包侧参考实现
+
+- 钱包在本地保存自己保密余额的打开值（金额与盲化因子），据此生成承诺与范围证明，随机数只在钱包中读取
+- 以下函数不是链码函数，不会被背书节点执行；链码只调用 verifyRange 校验
+- 转账后付款方的打开值变为 (找零, 找零盲化因子)，收款方把收到的 (金额, 金额盲化因子) 加到自己的打开值上
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// confidentialTransferOpenings 一笔保密转账的打开值，由钱包加密后分别交给收款方与央行
+type confidentialTransferOpenings struct {
+	Amount         int    `json:"amount"`
+	AmountBlinding string `json:"amountBlinding"`
+	Change         int    `json:"change"`
+	ChangeBlinding string `json:"changeBlinding"`
+}
+
+// buildConfidentialTransfer 由付款方的打开值生成 ConfidentialTransfer 的参数
+// 金额与找零的盲化因子之和等于余额的盲化因子，使两个承诺之和恰好等于余额承诺
+func buildConfidentialTransfer(recipient string, amount int, balance int, balanceBlinding *big.Int, rand io.Reader) (*ConfidentialTransferRequest, *confidentialTransferOpenings, error) {
+	if amount <= 0 {
+		return nil, nil, errors.New("transfer amount must be positive")
+	}
+	if balance < amount {
+		return nil, nil, errors.New("insufficient confidential funds")
+	}
+	change := balance - amount
+
+	amountBlinding, err := randomScalar(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	changeBlinding := new(big.Int).Sub(balanceBlinding, amountBlinding)
+	changeBlinding.Mod(changeBlinding, curveOrder)
+	if changeBlinding.Sign() == 0 {
+		return nil, nil, errors.New("degenerate blinding, retry")
+	}
+
+	amountProof, err := proveRange(uint64(amount), amountBlinding, rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	changeProof, err := proveRange(uint64(change), changeBlinding, rand)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request := &ConfidentialTransferRequest{
+		Recipient:        recipient,
+		AmountCommitment: encodePoint(pedersenCommit(big.NewInt(int64(amount)), amountBlinding)),
+		ChangeCommitment: encodePoint(pedersenCommit(big.NewInt(int64(change)), changeBlinding)),
+		AmountProof:      amountProof,
+		ChangeProof:      changeProof,
+	}
+	openings := &confidentialTransferOpenings{
+		Amount:         amount,
+		AmountBlinding: encodeScalar(amountBlinding),
+		Change:         change,
+		ChangeBlinding: encodeScalar(changeBlinding),
+	}
+	return request, openings, nil
+}
+
+// buildConfidentialWithdrawal 由打开值生成 ConfidentialWithdraw 的参数与 transient 字段 confidential
+// 取出全部余额时只需打开整个承诺，不生成剩余承诺
+func buildConfidentialWithdrawal(amount int, balance int, balanceBlinding *big.Int, rand io.Reader) (*ConfidentialWithdrawRequest, *confidentialAmount, error) {
+	if amount < 0 || balance < amount {
+		return nil, nil, fmt.Errorf("cannot withdraw %d from a confidential balance of %d", amount, balance)
+	}
+	if amount == balance {
+		return &ConfidentialWithdrawRequest{}, &confidentialAmount{Amount: amount, Blinding: encodeScalar(balanceBlinding)}, nil
+	}
+
+	withdrawnBlinding, err := randomScalar(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	remainingBlinding := new(big.Int).Sub(balanceBlinding, withdrawnBlinding)
+	remainingBlinding.Mod(remainingBlinding, curveOrder)
+	if remainingBlinding.Sign() == 0 {
+		return nil, nil, errors.New("degenerate blinding, retry")
+	}
+	remainingProof, err := proveRange(uint64(balance-amount), remainingBlinding, rand)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request := &ConfidentialWithdrawRequest{
+		RemainingCommitment: encodePoint(pedersenCommit(big.NewInt(int64(balance-amount)), remainingBlinding)),
+		RemainingProof:      remainingProof,
+	}
+	return request, &confidentialAmount{Amount: amount, Blinding: encodeScalar(withdrawnBlinding)}, nil
+}
@@ -0,0 +1,432 @@
+/*
+合约配置
+
+- 央行 MSP ID、央行域名、管理员角色、扣款背书要求、私有集合名称、事件明文明细开关与各项查询上限保存在账本上的配置文档中，
+  所有权限检查读取当前生效的配置，修改配置不需要重新打包链码
+- Initialize 时写入第 1 版配置：默认值来自 config.go 中的常量，可通过 transient 字段 "config" 覆盖部分设置
+- 央行admin通过 UpdateConfig 修改配置，每次修改版本号加 1，并以 confighist_<版本号> 保存完整的历史版本与修改人、原因
+- 早于本功能初始化的合约没有配置文档，按默认值（版本 0）运行，直到第一次 UpdateConfig
+- 配置在交易开始时读取并缓存在交易上下文中
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// contractConfigKey 当前生效配置的存储键（公共状态）
+const contractConfigKey = "contractconfig"
+
+// configHistoryPrefix 历史配置：confighist_<10 位版本号> -> ConfigHistoryEntry
+const configHistoryPrefix = "confighist_"
+
+// configTransientField Initialize 时覆盖默认配置的 transient 字段
+const configTransientField = "config"
+
+// defaultAdminRoleKeyword 证书 CN 包含该关键字（不区分大小写）的用户视为管理员
+const defaultAdminRoleKeyword = "admin"
+
+// ConfigLimits 各项查询与批处理上限
+type ConfigLimits struct {
+	MaxAliasesPerAccount  int `json:"maxAliasesPerAccount"`  // 每个账户最多注册的别名数量
+	MaxQueryPageSize      int `json:"maxQueryPageSize"`      // 交易查询的最大页大小
+	MaxVerifyPageSize     int `json:"maxVerifyPageSize"`     // 账本一致性校验的最大页大小
+	MaxMigrationBatchSize int `json:"maxMigrationBatchSize"` // 数据迁移的最大批大小
+	MaxAnalyticsDays      int `json:"maxAnalyticsDays"`      // 单次统计查询最多覆盖的天数
+	MaxHoldingsScan       int `json:"maxHoldingsScan"`       // 持有集中度统计最多扫描的账户数
+}
+
+// ConfigSettings 可修改的配置项
+type ConfigSettings struct {
+	CentralBankMSP         string       `json:"centralBankMsp"`         // 央行 MSP ID
+	CentralBankDomain      string       `json:"centralBankDomain"`      // 央行组织域名
+	AdminRoleKeyword       string       `json:"adminRoleKeyword"`       // 管理员证书 CN 关键字
+	RequireBankEndorsement bool         `json:"requireBankEndorsement"` // 扣款是否还需要账户所属银行的授权（AuthorizeDebit）
+	PrivateCollection      string       `json:"privateCollection"`      // 央行私有数据集合名称
+	PlaintextEventDetails  bool         `json:"plaintextEventDetails"`  // 未配置事件盐值时是否以明文发出事件明细
+	Limits                 ConfigLimits `json:"limits"`
+}
+
+// ContractConfig 账本上的合约配置
+type ContractConfig struct {
+	ConfigSettings
+	Version   int    `json:"version"`
+	UpdatedAt int64  `json:"updatedAt,omitempty"`
+	UpdatedBy string `json:"updatedBy,omitempty"`
+	TxID      string `json:"txId,omitempty"`
+}
+
+// ConfigHistoryEntry 配置的一个历史版本
+type ConfigHistoryEntry struct {
+	Config          ContractConfig `json:"config"`
+	PreviousVersion int            `json:"previousVersion"`
+	ChangedFields   []string       `json:"changedFields"`
+	Reason          string         `json:"reason,omitempty"`
+}
+
+// defaultContractConfig 默认配置（版本 0），取自 config.go 与各模块的默认上限
+func defaultContractConfig() *ContractConfig {
+	return &ContractConfig{
+		ConfigSettings: ConfigSettings{
+			CentralBankMSP:         CENTRAL_MSP_ID,
+			CentralBankDomain:      CENTRAL_BANK_DOMAIN,
+			AdminRoleKeyword:       defaultAdminRoleKeyword,
+			RequireBankEndorsement: defaultRequireBankEndorsement,
+			PrivateCollection:      defaultPrivateCollection,
+			Limits: ConfigLimits{
+				MaxAliasesPerAccount:  maxAliasesPerAccount,
+				MaxQueryPageSize:      maxQueryPageSize,
+				MaxVerifyPageSize:     maxVerifyPageSize,
+				MaxMigrationBatchSize: maxMigrationBatchSize,
+				MaxAnalyticsDays:      maxAnalyticsDays,
+				MaxHoldingsScan:       maxHoldingsScan,
+			},
+		},
+	}
+}
+
+// validate 检查配置项
+func (c *ConfigSettings) validate() error {
+	if c.CentralBankMSP == "" {
+		return errors.New("centralBankMsp must not be empty")
+	}
+	if c.CentralBankDomain == "" {
+		return errors.New("centralBankDomain must not be empty")
+	}
+	if c.AdminRoleKeyword == "" {
+		return errors.New("adminRoleKeyword must not be empty")
+	}
+	if c.PrivateCollection == "" {
+		return errors.New("privateCollection must not be empty")
+	}
+	limits := reflect.ValueOf(c.Limits)
+	for i := 0; i < limits.NumField(); i++ {
+		if limits.Field(i).Int() <= 0 {
+			return fmt.Errorf("limits.%s must be positive", limits.Type().Field(i).Tag.Get("json"))
+		}
+	}
+	return nil
+}
+
+// applyConfigPatch 将 JSON 中出现的配置项覆盖到 settings 的副本上，未出现的项保持不变
+func applyConfigPatch(settings ConfigSettings, patchJSON []byte) (ConfigSettings, error) {
+	decoder := json.NewDecoder(bytes.NewReader(patchJSON))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&settings); err != nil {
+		return ConfigSettings{}, fmt.Errorf("invalid config: %v", err)
+	}
+	if err := settings.validate(); err != nil {
+		return ConfigSettings{}, fmt.Errorf("invalid config: %v", err)
+	}
+	return settings, nil
+}
+
+// changedConfigFields 列出两份配置中值不同的字段（JSON 名称，上限以 limits.<名称> 表示）
+func changedConfigFields(previous ConfigSettings, next ConfigSettings) []string {
+	changed := []string{}
+	compare := func(prefix string, a reflect.Value, b reflect.Value) {
+		for i := 0; i < a.NumField(); i++ {
+			name := prefix + a.Type().Field(i).Tag.Get("json")
+			if a.Field(i).Kind() == reflect.Struct {
+				continue
+			}
+			if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+				changed = append(changed, name)
+			}
+		}
+	}
+	compare("", reflect.ValueOf(previous), reflect.ValueOf(next))
+	compare("limits.", reflect.ValueOf(previous.Limits), reflect.ValueOf(next.Limits))
+	return changed
+}
+
+// readContractConfig 从账本读取配置，尚未写入时返回默认配置
+func readContractConfig(ctx contractapi.TransactionContextInterface) (*ContractConfig, error) {
+	configBytes, err := ctx.GetStub().GetState(contractConfigKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contract config: %v", err)
+	}
+	if configBytes == nil {
+		return defaultContractConfig(), nil
+	}
+	// 早于某项配置写入的文档缺少该项，取默认值
+	config := defaultContractConfig()
+	if err := json.Unmarshal(configBytes, config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal contract config: %v", err)
+	}
+	return config, nil
+}
+
+// activeConfig 返回本交易生效的配置；eventContext 上下文中每个交易只读取一次
+func activeConfig(ctx contractapi.TransactionContextInterface) (*ContractConfig, error) {
+	eventCtx, ok := ctx.(*eventContext)
+	if ok && eventCtx.config != nil {
+		return eventCtx.config, nil
+	}
+	config, err := readContractConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		eventCtx.config = config
+	}
+	return config, nil
+}
+
+// loadActiveConfig 交易开始前读取配置，读取失败时交易直接失败
+func loadActiveConfig(ctx contractapi.TransactionContextInterface) error {
+	_, err := activeConfig(ctx)
+	return err
+}
+
+// privateCollection 当前配置的央行私有集合名称
+// 配置已在交易开始时读取；未经 eventContext 调用且读取失败时退回默认集合
+func privateCollection(ctx contractapi.TransactionContextInterface) string {
+	config, err := activeConfig(ctx)
+	if err != nil {
+		log.Printf("failed to load contract config, using default collection: %v", err)
+		return defaultPrivateCollection
+	}
+	return config.PrivateCollection
+}
+
+// implicitOrgCollection 组织的隐式私有数据集合，只分发给该组织的peer
+func implicitOrgCollection(mspID string) string {
+	return "_implicit_org_" + mspID
+}
+
+// isCentralBankMSP 检查 MSP ID 是否为当前配置的央行
+func isCentralBankMSP(ctx contractapi.TransactionContextInterface, mspID string) (bool, error) {
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return false, err
+	}
+	return mspID == config.CentralBankMSP, nil
+}
+
+// isCentralBankDomain 检查组织域名是否为当前配置的央行域名
+func isCentralBankDomain(ctx contractapi.TransactionContextInterface, domain string) (bool, error) {
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return false, err
+	}
+	return domain == config.CentralBankDomain, nil
+}
+
+// callerIsCentralBank 检查调用者是否属于当前配置的央行 MSP
+func callerIsCentralBank(ctx contractapi.TransactionContextInterface) (bool, error) {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return false, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	return isCentralBankMSP(ctx, clientMSPID)
+}
+
+// writeContractConfig 写入新版本配置与对应的历史记录
+func writeContractConfig(ctx contractapi.TransactionContextInterface, previous *ContractConfig, settings ConfigSettings, callerID string, reason string) (*ContractConfig, error) {
+	stub := ctx.GetStub()
+	timestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	config := &ContractConfig{
+		ConfigSettings: settings,
+		Version:        previous.Version + 1,
+		UpdatedAt:      timestamp.Seconds,
+		UpdatedBy:      callerID,
+		TxID:           stub.GetTxID(),
+	}
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal contract config: %v", err)
+	}
+	if err := stub.PutState(contractConfigKey, configBytes); err != nil {
+		return nil, fmt.Errorf("failed to store contract config: %v", err)
+	}
+
+	entryBytes, err := json.Marshal(ConfigHistoryEntry{
+		Config:          *config,
+		PreviousVersion: previous.Version,
+		ChangedFields:   changedConfigFields(previous.ConfigSettings, settings),
+		Reason:          reason,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config history: %v", err)
+	}
+	if err := stub.PutState(configHistoryKey(config.Version), entryBytes); err != nil {
+		return nil, fmt.Errorf("failed to store config history: %v", err)
+	}
+
+	if eventCtx, ok := ctx.(*eventContext); ok {
+		eventCtx.config = config
+	}
+	return config, nil
+}
+
+func configHistoryKey(version int) string {
+	return fmt.Sprintf("%s%010d", configHistoryPrefix, version)
+}
+
+// initialConfigSettings Initialize 时的第 1 版配置：默认值，transient 字段 "config" 中的设置覆盖默认值
+// 央行 MSP 决定谁可以初始化合约，只能取内置值，之后通过 UpdateConfig 修改
+func initialConfigSettings(ctx contractapi.TransactionContextInterface) (ConfigSettings, error) {
+	defaults := defaultContractConfig().ConfigSettings
+	settings := defaults
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return ConfigSettings{}, fmt.Errorf("failed to get transient data: %v", err)
+	}
+	if patch, ok := transientMap[configTransientField]; ok {
+		settings, err = applyConfigPatch(settings, patch)
+		if err != nil {
+			return ConfigSettings{}, err
+		}
+		if settings.CentralBankMSP != defaults.CentralBankMSP {
+			return ConfigSettings{}, errors.New("invalid config: centralBankMsp cannot be set at initialization, call UpdateConfig after Initialize")
+		}
+	}
+	return settings, nil
+}
+
+// initContractConfig Initialize 时写入第 1 版配置
+func initContractConfig(ctx contractapi.TransactionContextInterface, settings ConfigSettings, callerID string) (*ContractConfig, error) {
+	return writeContractConfig(ctx, &ContractConfig{ConfigSettings: settings}, settings, callerID, "initialize")
+}
+
+// UpdateConfig 央行admin修改合约配置
+// configJSON 只需包含要修改的配置项，例如 {"limits":{"maxQueryPageSize":200}}；reason 记入审计历史
+// 修改私有集合名称前须先以新集合升级链码定义并迁移数据；修改央行 MSP 后，原央行将失去全部管理权限
+func (s *SmartContract) UpdateConfig(ctx contractapi.TransactionContextInterface, configJSON string, reason string) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", errors.New("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+	isCentralBank, err := callerIsCentralBank(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !isCentralBank || !s.isAdminUser(ctx, callerID) {
+		return "", errors.New("only central bank admins can update the contract config")
+	}
+	if reason == "" {
+		return "", errors.New("reason must not be empty")
+	}
+
+	current, err := activeConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	settings, err := applyConfigPatch(current.ConfigSettings, []byte(configJSON))
+	if err != nil {
+		return "", err
+	}
+	changed := changedConfigFields(current.ConfigSettings, settings)
+	if len(changed) == 0 {
+		return "", errors.New("config is unchanged")
+	}
+
+	// 新集合必须已在链码定义中声明
+	if settings.PrivateCollection != current.PrivateCollection {
+		if _, err := ctx.GetStub().GetPrivateDataHash(settings.PrivateCollection, totalSupplyKey); err != nil {
+			return "", fmt.Errorf("collection %s is not usable: %v", settings.PrivateCollection, err)
+		}
+	}
+
+	config, err := writeContractConfig(ctx, current, settings, callerID, reason)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.emitEvent(ctx, eventSpec{
+		Type:       eventTypeConfigUpdated,
+		Attributes: map[string]interface{}{"version": config.Version, "previousVersion": current.Version, "changedFields": changed},
+		Parties:    map[string]string{"updatedBy": callerID},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("contract config updated to version %d: %v", config.Version, changed)
+
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal contract config: %v", err)
+	}
+	return string(configBytes), nil
+}
+
+// GetConfig 返回当前生效的合约配置
+func (s *SmartContract) GetConfig(ctx contractapi.TransactionContextInterface) (string, error) {
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal contract config: %v", err)
+	}
+	return string(configBytes), nil
+}
+
+// GetConfigHistory 按版本升序返回配置的修改历史，仅央行可查询
+func (s *SmartContract) GetConfigHistory(ctx contractapi.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", errors.New("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	isCentralBank, err := callerIsCentralBank(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !isCentralBank {
+		return "", errors.New("client is not authorized to view the config history")
+	}
+
+	iterator, err := ctx.GetStub().GetStateByRange(configHistoryPrefix, configHistoryPrefix+"\xff")
+	if err != nil {
+		return "", fmt.Errorf("failed to read config history: %v", err)
+	}
+	defer iterator.Close()
+
+	history := []ConfigHistoryEntry{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return "", fmt.Errorf("failed to iterate config history: %v", err)
+		}
+		var entry ConfigHistoryEntry
+		if err := json.Unmarshal(kv.Value, &entry); err != nil {
+			return "", fmt.Errorf("failed to unmarshal config history %s: %v", kv.Key, err)
+		}
+		history = append(history, entry)
+	}
+
+	historyBytes, err := json.Marshal(history)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config history: %v", err)
+	}
+	return string(historyBytes), nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func updateConfig(env *testEnv, caller testUser, configJSON string, reason string) (*ContractConfig, error) {
+	var config ContractConfig
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		response, err := env.contract.UpdateConfig(ctx, configJSON, reason)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &config)
+	})
+	return &config, err
+}
+
+func getConfigHistory(env *testEnv, caller testUser) ([]ConfigHistoryEntry, error) {
+	var history []ConfigHistoryEntry
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		response, err := env.contract.GetConfigHistory(ctx)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &history)
+	})
+	return history, err
+}
+
+func initializeWithConfig(env *testEnv, configJSON string) error {
+	_, err := env.invoke(centralBankAdmin, map[string][]byte{configTransientField: []byte(configJSON)}, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.Initialize(ctx, "CBDC", "DCEP", "2")
+		return err
+	})
+	return err
+}
+
+// TestContractConfigUpdates 初始化时覆盖默认配置，修改后立即生效并记录历史
+func TestContractConfigUpdates(t *testing.T) {
+	env := newTestEnv(t)
+	if err := initializeWithConfig(env, `{"limits":{"maxAliasesPerAccount":2}}`); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if err := openAccountAs(env, bankAAdmin, bankAUser.id, ""); err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+
+	var config ContractConfig
+	_, err := env.invoke(bankAUser, nil, func(ctx contractapi.TransactionContextInterface) error {
+		response, err := env.contract.GetConfig(ctx)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &config)
+	})
+	if err != nil {
+		t.Fatalf("GetConfig failed: %v", err)
+	}
+	if config.Version != 1 || config.Limits.MaxAliasesPerAccount != 2 || config.Limits.MaxQueryPageSize != maxQueryPageSize || config.UpdatedBy != centralBankAdmin.id {
+		t.Fatalf("unexpected initial config: %+v", config)
+	}
+
+	updated, err := updateConfig(env, centralBankAdmin, `{"adminRoleKeyword":"ops","limits":{"maxAliasesPerAccount":1}}`, "tighten alias limit")
+	if err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if updated.Version != 2 || updated.AdminRoleKeyword != "ops" || updated.Limits.MaxAliasesPerAccount != 1 || updated.Limits.MaxQueryPageSize != maxQueryPageSize {
+		t.Fatalf("unexpected updated config: %+v", updated)
+	}
+
+	// 新上限在下一笔交易生效
+	registerAlias(t, env, bankAUser, "@alice", "")
+	_, err = env.invoke(bankAUser, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.RegisterAlias(ctx, "@alice2", "")
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "maximum of 1 aliases") {
+		t.Fatalf("expected the new alias limit to apply, got %v", err)
+	}
+
+	// 管理员关键字改为 ops 后原admin证书失去管理权限
+	if _, err := updateConfig(env, centralBankAdmin, `{"limits":{"maxAliasesPerAccount":3}}`, "relax"); err == nil || !strings.Contains(err.Error(), "only central bank admins") {
+		t.Fatalf("expected the admin keyword change to apply, got %v", err)
+	}
+	operator := newTestUser("Ops1", CENTRAL_BANK_DOMAIN, CENTRAL_MSP_ID)
+	if _, err := updateConfig(env, operator, `{"limits":{"maxAliasesPerAccount":3}}`, "relax"); err != nil {
+		t.Fatalf("UpdateConfig by the new admin failed: %v", err)
+	}
+
+	history, err := getConfigHistory(env, centralBankAdmin)
+	if err != nil {
+		t.Fatalf("GetConfigHistory failed: %v", err)
+	}
+	if len(history) != 3 || history[0].Reason != "initialize" || history[2].Reason != "relax" || history[2].Config.UpdatedBy != operator.id {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+	if got := strings.Join(history[1].ChangedFields, ","); got != "adminRoleKeyword,limits.maxAliasesPerAccount" || history[1].PreviousVersion != 1 {
+		t.Fatalf("unexpected changed fields of version 2: %s", got)
+	}
+}
+
+func TestContractConfigRejects(t *testing.T) {
+	env := newTestEnv(t)
+	if err := initializeWithConfig(env, `{"centralBankMsp":"OtherMSP"}`); err == nil || !strings.Contains(err.Error(), "cannot be set at initialization") {
+		t.Fatalf("expected the central bank MSP to be fixed at initialization, got %v", err)
+	}
+	env.initialize()
+
+	for _, tc := range []struct {
+		caller testUser
+		config string
+		reason string
+		want   string
+	}{
+		{bankAAdmin, `{"plaintextEventDetails":true}`, "test", "only central bank admins"},
+		{newTestUser("User1", CENTRAL_BANK_DOMAIN, CENTRAL_MSP_ID), `{"plaintextEventDetails":true}`, "test", "only central bank admins"},
+		{centralBankAdmin, `{"plaintextEventDetails":true}`, "", "reason must not be empty"},
+		{centralBankAdmin, `{"plaintextEventDetail":true}`, "test", "unknown field"},
+		{centralBankAdmin, `{"limits":{"maxQueryPageSize":0}}`, "test", "limits.maxQueryPageSize must be positive"},
+		{centralBankAdmin, `{"privateCollection":""}`, "test", "privateCollection must not be empty"},
+		{centralBankAdmin, `{"limits":`, "test", "invalid config"},
+		{centralBankAdmin, `{"requireBankEndorsement":true}`, "test", "config is unchanged"},
+	} {
+		if _, err := updateConfig(env, tc.caller, tc.config, tc.reason); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("UpdateConfig(%s) error = %v, want %q", tc.config, err, tc.want)
+		}
+	}
+
+	if _, err := getConfigHistory(env, bankAAdmin); err == nil || !strings.Contains(err.Error(), "not authorized to view the config history") {
+		t.Fatalf("expected an authorization error, got %v", err)
+	}
+}
@@ -0,0 +1,696 @@
+/*
+直接借记授权（Direct Debit Mandate）
+
+- 付款方为商户或公用事业机构（收款方）签发授权，约定单笔上限、月度上限、扣款频率、起止日期与收款方参考号
+- 收款方通过 CollectDirectDebit 按授权扣款，月度额度按 UTC 自然月累计
+- 付款方可暂停、恢复或撤销授权，授权双方可查询扣款历史
+- 扣款复用 TransferFrom 的校验、商户手续费与记账流程，交易类型为 directDebit
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const mandatePrefix = "mandate_"
+
+// accountMandateIndex 账户（付款方或收款方） -> 授权ID 的组合键索引
+const accountMandateIndex = "acct~mandate"
+
+// mandateCollectionIndex 授权ID -> 扣款记录 的组合键索引，按扣款时间排序
+const mandateCollectionIndex = "mandate~collection"
+
+// mandatePeriodLayout 月度额度的统计期间（UTC 自然月）
+const mandatePeriodLayout = "200601"
+
+// 扣款频率
+const (
+	mandateFrequencyAdhoc   = "adhoc"   // 不限次数，仅受额度约束
+	mandateFrequencyOnce    = "once"    // 只能扣款一次
+	mandateFrequencyWeekly  = "weekly"  // 两次扣款至少间隔 7 天
+	mandateFrequencyMonthly = "monthly" // 每个自然月最多扣款一次
+)
+
+// 授权状态
+const (
+	mandateStatusActive    = "active"
+	mandateStatusSuspended = "suspended"
+	mandateStatusCancelled = "cancelled"
+)
+
+// mandateWeekSeconds 每周扣款的最小间隔
+const mandateWeekSeconds = 7 * 24 * 60 * 60
+
+// DirectDebitMandate 直接借记授权
+type DirectDebitMandate struct {
+	MandateID           string `json:"mandateId"`
+	Payer               string `json:"payer"`
+	Creditor            string `json:"creditor"`
+	CreditorReference   string `json:"creditorReference"`
+	MaxPerDebit         int    `json:"maxPerDebit"`
+	MaxPerMonth         int    `json:"maxPerMonth,omitempty"` // 0 表示不限月度额度
+	Frequency           string `json:"frequency"`
+	StartDate           int64  `json:"startDate"`
+	EndDate             int64  `json:"endDate,omitempty"` // 0 表示长期有效
+	Status              string `json:"status"`
+	CollectionPeriod    string `json:"collectionPeriod,omitempty"` // 当前统计期间 YYYYMM
+	CollectedInPeriod   int    `json:"collectedInPeriod"`
+	CollectionsInPeriod int    `json:"collectionsInPeriod"`
+	CollectionCount     int    `json:"collectionCount"`
+	TotalCollected      int    `json:"totalCollected"`
+	LastCollectedAt     int64  `json:"lastCollectedAt,omitempty"`
+	CreatedAt           int64  `json:"createdAt"`
+	UpdatedAt           int64  `json:"updatedAt"`
+	UpdatedBy           string `json:"updatedBy"`
+}
+
+// DirectDebitCollection 一次扣款记录
+type DirectDebitCollection struct {
+	MandateID string `json:"mandateId"`
+	TxID      string `json:"txId"`
+	Amount    int    `json:"amount"`
+	Fee       int    `json:"fee,omitempty"`
+	NetAmount int    `json:"netAmount,omitempty"`
+	Reference string `json:"reference,omitempty"`
+	Period    string `json:"period"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// isValidMandateFrequency 检查扣款频率
+func isValidMandateFrequency(frequency string) bool {
+	switch frequency {
+	case mandateFrequencyAdhoc, mandateFrequencyOnce, mandateFrequencyWeekly, mandateFrequencyMonthly:
+		return true
+	}
+	return false
+}
+
+// mandatePeriod 返回时间戳所在的统计期间
+func mandatePeriod(timestamp int64) string {
+	return time.Unix(timestamp, 0).UTC().Format(mandatePeriodLayout)
+}
+
+// rollPeriod 进入新的自然月时清零月度累计
+func (m *DirectDebitMandate) rollPeriod(period string) {
+	if m.CollectionPeriod != period {
+		m.CollectionPeriod = period
+		m.CollectedInPeriod = 0
+		m.CollectionsInPeriod = 0
+	}
+}
+
+// checkCollection 按授权约定校验一次扣款，now 为交易时间；进入新的自然月时先清零月度累计
+func (m *DirectDebitMandate) checkCollection(amount int, now int64) error {
+	switch m.Status {
+	case mandateStatusActive:
+	case mandateStatusSuspended:
+		return rejectPayment(reasonTransactionForbidden, "mandate %s is suspended", m.MandateID)
+	default:
+		return rejectPayment(reasonNoMandate, "mandate %s is cancelled", m.MandateID)
+	}
+	if now < m.StartDate {
+		return rejectPayment(reasonTransactionForbidden, "mandate %s is not effective until %d", m.MandateID, m.StartDate)
+	}
+	if m.EndDate > 0 && now > m.EndDate {
+		return rejectPayment(reasonNoMandate, "mandate %s expired at %d", m.MandateID, m.EndDate)
+	}
+	if amount <= 0 {
+		return rejectPayment(reasonInvalidAmount, "collection amount must be positive")
+	}
+	if amount > m.MaxPerDebit {
+		return rejectPayment(reasonAmountExceedsLimit, "amount %d exceeds the per-debit limit %d of mandate %s", amount, m.MaxPerDebit, m.MandateID)
+	}
+
+	m.rollPeriod(mandatePeriod(now))
+	if m.MaxPerMonth > 0 && m.CollectedInPeriod+amount > m.MaxPerMonth {
+		return rejectPayment(reasonAmountExceedsLimit, "amount %d exceeds the remaining monthly limit %d of mandate %s", amount, m.MaxPerMonth-m.CollectedInPeriod, m.MandateID)
+	}
+
+	switch m.Frequency {
+	case mandateFrequencyOnce:
+		if m.CollectionCount > 0 {
+			return rejectPayment(reasonTransactionForbidden, "one-off mandate %s has already been collected", m.MandateID)
+		}
+	case mandateFrequencyWeekly:
+		if m.LastCollectedAt > 0 && now-m.LastCollectedAt < mandateWeekSeconds {
+			return rejectPayment(reasonTransactionForbidden, "weekly mandate %s was collected less than 7 days ago", m.MandateID)
+		}
+	case mandateFrequencyMonthly:
+		if m.CollectionsInPeriod > 0 {
+			return rejectPayment(reasonTransactionForbidden, "monthly mandate %s has already been collected in %s", m.MandateID, m.CollectionPeriod)
+		}
+	}
+	return nil
+}
+
+// getMandate 读取授权，不存在时返回 nil
+func (s *SmartContract) getMandate(ctx contractapi.TransactionContextInterface, mandateID string) (*DirectDebitMandate, error) {
+	mandateBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), mandatePrefix+mandateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mandate %s: %v", mandateID, err)
+	}
+	if mandateBytes == nil {
+		return nil, nil
+	}
+	var mandate DirectDebitMandate
+	if err := json.Unmarshal(mandateBytes, &mandate); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal mandate %s: %v", mandateID, err)
+	}
+	return &mandate, nil
+}
+
+// putMandate 写入授权
+func (s *SmartContract) putMandate(ctx contractapi.TransactionContextInterface, mandate *DirectDebitMandate) error {
+	mandateBytes, err := json.Marshal(mandate)
+	if err != nil {
+		return fmt.Errorf("failed to marshal mandate: %v", err)
+	}
+	if err := ctx.GetStub().PutPrivateData(privateCollection(ctx), mandatePrefix+mandate.MandateID, mandateBytes); err != nil {
+		return fmt.Errorf("failed to store mandate %s: %v", mandate.MandateID, err)
+	}
+	return nil
+}
+
+// indexMandate 为付款方与收款方建立授权索引
+func (s *SmartContract) indexMandate(ctx contractapi.TransactionContextInterface, mandate *DirectDebitMandate) error {
+	stub := ctx.GetStub()
+	for _, account := range []string{mandate.Payer, mandate.Creditor} {
+		indexKey, err := stub.CreateCompositeKey(accountMandateIndex, []string{account, mandate.MandateID})
+		if err != nil {
+			return fmt.Errorf("failed to create the composite key for prefix %s: %v", accountMandateIndex, err)
+		}
+		if err := stub.PutPrivateData(privateCollection(ctx), indexKey, []byte{0x00}); err != nil {
+			return fmt.Errorf("failed to store mandate index: %v", err)
+		}
+	}
+	return nil
+}
+
+// loadMandateForCaller 读取授权并检查调用者有权查看：授权双方或可查看其中一方余额的用户
+func (s *SmartContract) loadMandateForCaller(ctx contractapi.TransactionContextInterface, mandateID string) (*DirectDebitMandate, error) {
+	// 首先检查合约是否已初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller id: %v", err)
+	}
+
+	mandate, err := s.getMandate(ctx, mandateID)
+	if err != nil {
+		return nil, err
+	}
+	if mandate == nil {
+		return nil, fmt.Errorf("mandate %s does not exist", mandateID)
+	}
+
+	for _, party := range []string{mandate.Payer, mandate.Creditor} {
+		hasPermission, err := s.checkBalancePermission(ctx, callerID, party)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check permission: %v", err)
+		}
+		if hasPermission {
+			return mandate, nil
+		}
+	}
+	return nil, fmt.Errorf("caller does not have permission to view mandate %s", mandateID)
+}
+
+// marshalMandate 序列化授权
+func marshalMandate(mandate *DirectDebitMandate) (string, error) {
+	mandateJSON, err := json.Marshal(mandate)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal mandate: %v", err)
+	}
+	return string(mandateJSON), nil
+}
+
+// CreateDirectDebitMandate 付款方（调用者）为收款方签发直接借记授权，授权ID为本交易ID
+// 示例：{"creditor":"<clientID或别名>","creditorReference":"ELEC-2024-001","maxPerDebit":50000,"maxPerMonth":50000,"frequency":"monthly","startDate":1704067200,"endDate":0}
+func (s *SmartContract) CreateDirectDebitMandate(ctx contractapi.TransactionContextInterface, mandateJSON string) (string, error) {
+	// 首先检查合约是否已初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	payer, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	var mandate DirectDebitMandate
+	if err := json.Unmarshal([]byte(mandateJSON), &mandate); err != nil {
+		return "", fmt.Errorf("invalid mandate: %v", err)
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	now := timestamp.Seconds
+
+	// 校验授权条款
+	mandate.CreditorReference = strings.TrimSpace(mandate.CreditorReference)
+	if mandate.CreditorReference == "" {
+		return "", fmt.Errorf("creditor reference is required")
+	}
+	if len(mandate.CreditorReference) > maxISOTextLength {
+		return "", fmt.Errorf("creditor reference exceeds %d characters", maxISOTextLength)
+	}
+	if mandate.MaxPerDebit <= 0 {
+		return "", fmt.Errorf("maximum per debit must be positive")
+	}
+	if mandate.MaxPerMonth < 0 {
+		return "", fmt.Errorf("maximum per month must not be negative")
+	}
+	if mandate.MaxPerMonth > 0 && mandate.MaxPerMonth < mandate.MaxPerDebit {
+		return "", fmt.Errorf("maximum per month %d is lower than maximum per debit %d", mandate.MaxPerMonth, mandate.MaxPerDebit)
+	}
+	if mandate.Frequency == "" {
+		mandate.Frequency = mandateFrequencyAdhoc
+	}
+	if !isValidMandateFrequency(mandate.Frequency) {
+		return "", fmt.Errorf("invalid frequency %s: expected adhoc, once, weekly or monthly", mandate.Frequency)
+	}
+	if mandate.StartDate <= 0 {
+		mandate.StartDate = now
+	}
+	if mandate.EndDate < 0 || (mandate.EndDate > 0 && mandate.EndDate <= mandate.StartDate) {
+		return "", fmt.Errorf("end date must be after the start date")
+	}
+	if mandate.EndDate > 0 && mandate.EndDate <= now {
+		return "", fmt.Errorf("end date must be in the future")
+	}
+
+	// 收款方必须是已开立的其他账户
+	creditor, err := s.resolveAccount(ctx, mandate.Creditor)
+	if err != nil {
+		return "", err
+	}
+	if creditor == payer {
+		return "", fmt.Errorf("creditor must differ from the payer")
+	}
+	creditorAccount, err := s.getUserAccountInfo(ctx, creditor)
+	if err != nil {
+		return "", fmt.Errorf("failed to read creditor account %s: %v", creditor, err)
+	}
+	if err := checkAccountActive(creditorAccount, "creditor"); err != nil {
+		return "", err
+	}
+	payerAccount, err := s.getUserAccountInfo(ctx, payer)
+	if err != nil {
+		return "", fmt.Errorf("failed to read payer account %s: %v", payer, err)
+	}
+	if err := checkAccountActive(payerAccount, "payer"); err != nil {
+		return "", err
+	}
+
+	mandate = DirectDebitMandate{
+		MandateID:         ctx.GetStub().GetTxID(),
+		Payer:             payer,
+		Creditor:          creditor,
+		CreditorReference: mandate.CreditorReference,
+		MaxPerDebit:       mandate.MaxPerDebit,
+		MaxPerMonth:       mandate.MaxPerMonth,
+		Frequency:         mandate.Frequency,
+		StartDate:         mandate.StartDate,
+		EndDate:           mandate.EndDate,
+		Status:            mandateStatusActive,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		UpdatedBy:         payer,
+	}
+	if err := s.putMandate(ctx, &mandate); err != nil {
+		return "", err
+	}
+	if err := s.indexMandate(ctx, &mandate); err != nil {
+		return "", err
+	}
+
+	err = s.emitEvent(ctx, eventSpec{
+		Type:       eventTypeMandateCreated,
+		Attributes: map[string]interface{}{"mandateId": mandate.MandateID, "frequency": mandate.Frequency},
+		Parties:    map[string]string{"payer": payer, "creditor": creditor},
+		Details: map[string]interface{}{
+			"creditorReference": mandate.CreditorReference,
+			"maxPerDebit":       mandate.MaxPerDebit,
+			"maxPerMonth":       mandate.MaxPerMonth,
+			"startDate":         mandate.StartDate,
+			"endDate":           mandate.EndDate,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("direct debit mandate %s created: %s -> %s, max per debit %d", mandate.MandateID, payer, creditor, mandate.MaxPerDebit)
+
+	return marshalMandate(&mandate)
+}
+
+// CollectDirectDebit 收款方（调用者）按授权从付款方扣款，reference 写入附言
+// 扣款按授权约定与付款方账户状态校验，失败时返回带 ISO 20022 原因代码的错误
+func (s *SmartContract) CollectDirectDebit(ctx contractapi.TransactionContextInterface, mandateID string, amount int, reference string) (string, error) {
+	// 首先检查合约是否已初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	creditor, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	reference = strings.TrimSpace(reference)
+	if len(reference) > maxMemoLength {
+		return "", rejectPayment(reasonInvalidFileFormat, "reference exceeds %d characters", maxMemoLength)
+	}
+
+	mandate, err := s.getMandate(ctx, mandateID)
+	if err != nil {
+		return "", err
+	}
+	if mandate == nil || mandate.Creditor != creditor {
+		return "", rejectPayment(reasonNoMandate, "no mandate %s for creditor", mandateID)
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	now := timestamp.Seconds
+
+	// 校验授权约定，再按普通转账校验付款方与收款方账户
+	if err := mandate.checkCollection(amount, now); err != nil {
+		return "", err
+	}
+	if err := s.assessTransfer(ctx, mandate.Payer, mandate.Payer, creditor, amount); err != nil {
+		return "", err
+	}
+
+	// 收款方为商户时计算手续费
+	legs, charge, err := s.paymentLegs(ctx, mandate.Payer, creditor, amount)
+	if err != nil {
+		return "", err
+	}
+	err = s.postTransferLegs(ctx, legs)
+	if err != nil {
+		return "", fmt.Errorf("failed to transfer: %v", err)
+	}
+
+	pmtID, err := defaultPaymentIdentification(ctx)
+	if err != nil {
+		return "", err
+	}
+	pmtID.Memo = reference
+
+	// 创建交易记录
+	record, err := s.newTransactionRecord(ctx, "directDebit", mandate.Payer, creditor, amount)
+	if err != nil {
+		return "", err
+	}
+	record.Spender = creditor
+	record.EndToEndID = pmtID.EndToEndID
+	record.UETR = pmtID.UETR
+	record.Memo = reference
+	charge.applyTo(record)
+
+	// 央行存储交易记录
+	err = s.bookTransactionRecord(ctx, record)
+	if err != nil {
+		return "", err
+	}
+
+	// 存储 ACSC 支付状态
+	err = s.recordPaymentSettled(ctx, pmtID, mandate.Payer, creditor, amount)
+	if err != nil {
+		return "", err
+	}
+
+	// 发出 Transfer 事件（附带 camt.054 借贷记通知）
+	err = s.emitTransferEvent(ctx, record)
+	if err != nil {
+		return "", err
+	}
+
+	// 更新授权累计
+	mandate.CollectedInPeriod += amount
+	mandate.CollectionsInPeriod++
+	mandate.CollectionCount++
+	mandate.TotalCollected, err = add(mandate.TotalCollected, amount)
+	if err != nil {
+		return "", err
+	}
+	mandate.LastCollectedAt = now
+	mandate.UpdatedAt = now
+	mandate.UpdatedBy = creditor
+	if err := s.putMandate(ctx, mandate); err != nil {
+		return "", err
+	}
+
+	// 记录扣款历史
+	collection := DirectDebitCollection{
+		MandateID: mandate.MandateID,
+		TxID:      record.TxID,
+		Amount:    amount,
+		Fee:       record.Fee,
+		NetAmount: record.NetAmount,
+		Reference: reference,
+		Period:    mandate.CollectionPeriod,
+		Timestamp: now,
+	}
+	collectionBytes, err := json.Marshal(collection)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal collection: %v", err)
+	}
+	collectionKey, err := ctx.GetStub().CreateCompositeKey(mandateCollectionIndex, []string{mandate.MandateID, fmt.Sprintf("%020d", now), record.TxID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create the composite key for prefix %s: %v", mandateCollectionIndex, err)
+	}
+	if err := ctx.GetStub().PutPrivateData(privateCollection(ctx), collectionKey, collectionBytes); err != nil {
+		return "", fmt.Errorf("failed to store collection: %v", err)
+	}
+
+	log.Printf("direct debit collected under mandate %s: %s -> %s, amount: %d", mandate.MandateID, mandate.Payer, creditor, amount)
+
+	// ISO 20022 结构化日志（pacs.008 + camt.053）
+	if err := s.logISO20022Pacs008AndCamt053(ctx, "directDebit", mandate.Payer, creditor, amount, creditor, pmtID, charge); err != nil {
+		log.Printf("ISO20022 logging (directDebit) failed: %v", err)
+	}
+
+	collectionJSON, err := json.Marshal(collection)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal collection: %v", err)
+	}
+	return string(collectionJSON), nil
+}
+
+// setMandateStatus 付款方变更授权状态
+func (s *SmartContract) setMandateStatus(ctx contractapi.TransactionContextInterface, mandateID string, from []string, to string) (string, error) {
+	// 首先检查合约是否已初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	mandate, err := s.getMandate(ctx, mandateID)
+	if err != nil {
+		return "", err
+	}
+	if mandate == nil || mandate.Payer != callerID {
+		return "", fmt.Errorf("mandate %s does not exist or is not owned by the caller", mandateID)
+	}
+
+	allowed := false
+	for _, status := range from {
+		if mandate.Status == status {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return "", fmt.Errorf("mandate %s is %s and cannot be changed to %s", mandateID, mandate.Status, to)
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	mandate.Status = to
+	mandate.UpdatedAt = timestamp.Seconds
+	mandate.UpdatedBy = callerID
+	if err := s.putMandate(ctx, mandate); err != nil {
+		return "", err
+	}
+
+	err = s.emitEvent(ctx, eventSpec{
+		Type:       eventTypeMandateStatusChanged,
+		Attributes: map[string]interface{}{"mandateId": mandateID, "status": to},
+		Parties:    map[string]string{"payer": mandate.Payer, "creditor": mandate.Creditor},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("direct debit mandate %s is now %s", mandateID, to)
+
+	return marshalMandate(mandate)
+}
+
+// SuspendDirectDebitMandate 付款方暂停授权，暂停期间不能扣款
+func (s *SmartContract) SuspendDirectDebitMandate(ctx contractapi.TransactionContextInterface, mandateID string) (string, error) {
+	return s.setMandateStatus(ctx, mandateID, []string{mandateStatusActive}, mandateStatusSuspended)
+}
+
+// ResumeDirectDebitMandate 付款方恢复已暂停的授权
+func (s *SmartContract) ResumeDirectDebitMandate(ctx contractapi.TransactionContextInterface, mandateID string) (string, error) {
+	return s.setMandateStatus(ctx, mandateID, []string{mandateStatusSuspended}, mandateStatusActive)
+}
+
+// CancelDirectDebitMandate 付款方撤销授权，撤销后不能恢复
+func (s *SmartContract) CancelDirectDebitMandate(ctx contractapi.TransactionContextInterface, mandateID string) (string, error) {
+	return s.setMandateStatus(ctx, mandateID, []string{mandateStatusActive, mandateStatusSuspended}, mandateStatusCancelled)
+}
+
+// GetDirectDebitMandate 查询授权
+func (s *SmartContract) GetDirectDebitMandate(ctx contractapi.TransactionContextInterface, mandateID string) (string, error) {
+	mandate, err := s.loadMandateForCaller(ctx, mandateID)
+	if err != nil {
+		return "", err
+	}
+	return marshalMandate(mandate)
+}
+
+// GetDirectDebitHistory 按时间顺序查询授权下的扣款记录
+func (s *SmartContract) GetDirectDebitHistory(ctx contractapi.TransactionContextInterface, mandateID string) (string, error) {
+	mandate, err := s.loadMandateForCaller(ctx, mandateID)
+	if err != nil {
+		return "", err
+	}
+
+	iterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(privateCollection(ctx), mandateCollectionIndex, []string{mandate.MandateID})
+	if err != nil {
+		return "", fmt.Errorf("failed to scan index %s: %v", mandateCollectionIndex, err)
+	}
+	defer iterator.Close()
+
+	collections := []DirectDebitCollection{}
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return "", fmt.Errorf("failed to iterate index %s: %v", mandateCollectionIndex, err)
+		}
+		var collection DirectDebitCollection
+		if err := json.Unmarshal(entry.Value, &collection); err != nil {
+			return "", fmt.Errorf("failed to unmarshal collection: %v", err)
+		}
+		collections = append(collections, collection)
+	}
+
+	result := map[string]interface{}{
+		"mandate":     mandate,
+		"collections": collections,
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal collections: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// ListDirectDebitMandates 列出账户作为付款方或收款方的全部授权
+func (s *SmartContract) ListDirectDebitMandates(ctx contractapi.TransactionContextInterface, account string) (string, error) {
+	// 首先检查合约是否已初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+
+	accountID, err := s.resolveAccount(ctx, account)
+	if err != nil {
+		return "", err
+	}
+
+	// 检查权限
+	hasPermission, err := s.checkBalancePermission(ctx, callerID, accountID)
+	if err != nil {
+		return "", fmt.Errorf("failed to check permission: %v", err)
+	}
+	if !hasPermission {
+		return "", fmt.Errorf("caller does not have permission to view mandates of account %s", account)
+	}
+
+	stub := ctx.GetStub()
+	iterator, err := stub.GetPrivateDataByPartialCompositeKey(privateCollection(ctx), accountMandateIndex, []string{accountID})
+	if err != nil {
+		return "", fmt.Errorf("failed to scan index %s: %v", accountMandateIndex, err)
+	}
+	defer iterator.Close()
+
+	mandates := []*DirectDebitMandate{}
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return "", fmt.Errorf("failed to iterate index %s: %v", accountMandateIndex, err)
+		}
+		_, attributes, err := stub.SplitCompositeKey(entry.Key)
+		if err != nil || len(attributes) != 2 {
+			continue
+		}
+		mandate, err := s.getMandate(ctx, attributes[1])
+		if err != nil {
+			return "", err
+		}
+		if mandate != nil {
+			mandates = append(mandates, mandate)
+		}
+	}
+
+	result := map[string]interface{}{
+		"account":  accountID,
+		"mandates": mandates,
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal mandates: %v", err)
+	}
+	return string(resultJSON), nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func createMandate(env *testEnv, caller testUser, mandateJSON string) (*DirectDebitMandate, error) {
+	var mandate DirectDebitMandate
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		response, err := env.contract.CreateDirectDebitMandate(ctx, mandateJSON)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &mandate)
+	})
+	return &mandate, err
+}
+
+func collectDirectDebit(env *testEnv, caller testUser, mandateID string, amount int) error {
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.CollectDirectDebit(ctx, mandateID, amount, "INV-1")
+		return err
+	})
+	return err
+}
+
+func setMandateStatus(env *testEnv, caller testUser, fn func(ctx contractapi.TransactionContextInterface, mandateID string) (string, error), mandateID string) error {
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := fn(ctx, mandateID)
+		return err
+	})
+	return err
+}
+
+// TestDirectDebitMandate 签发月度授权，按约定扣款，暂停、恢复与撤销
+func TestDirectDebitMandate(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 200)
+	utility := newTestUser("Utility1", "a.example.com", "AMSP")
+	if err := openAccountAs(env, bankAAdmin, utility.id, accountTypeMerchant); err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+
+	mandate, err := createMandate(env, bankAUser, `{"creditor":"`+utility.id+`","creditorReference":" ELEC-001 ","maxPerDebit":50,"maxPerMonth":80,"frequency":"monthly"}`)
+	if err != nil {
+		t.Fatalf("CreateDirectDebitMandate failed: %v", err)
+	}
+	if mandate.Status != mandateStatusActive || mandate.CreditorReference != "ELEC-001" || mandate.Payer != bankAUser.id || mandate.StartDate == 0 {
+		t.Fatalf("unexpected mandate: %+v", mandate)
+	}
+	id := mandate.MandateID
+
+	// 只有授权中的收款方能扣款，且不超过单笔上限
+	if err := collectDirectDebit(env, centralBankAdmin, id, 40); err == nil || !strings.Contains(err.Error(), "no mandate") {
+		t.Fatalf("expected a missing mandate error, got %v", err)
+	}
+	if err := collectDirectDebit(env, utility, id, 60); err == nil || !strings.Contains(err.Error(), "exceeds the per-debit limit") {
+		t.Fatalf("expected a per-debit limit error, got %v", err)
+	}
+	authorizeDebit(t, env, bankAUser)
+	if err := collectDirectDebit(env, utility, id, 40); err != nil {
+		t.Fatalf("CollectDirectDebit failed: %v", err)
+	}
+	if got := balanceOf(t, env, utility); got != 40 {
+		t.Fatalf("creditor balance = %d, want 40", got)
+	}
+	if err := collectDirectDebit(env, utility, id, 10); err == nil || !strings.Contains(err.Error(), "already been collected") {
+		t.Fatalf("expected a monthly frequency error, got %v", err)
+	}
+
+	var history struct {
+		Mandate     DirectDebitMandate      `json:"mandate"`
+		Collections []DirectDebitCollection `json:"collections"`
+	}
+	_, err = env.invoke(utility, nil, func(ctx contractapi.TransactionContextInterface) error {
+		response, err := env.contract.GetDirectDebitHistory(ctx, id)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &history)
+	})
+	if err != nil {
+		t.Fatalf("GetDirectDebitHistory failed: %v", err)
+	}
+	if len(history.Collections) != 1 || history.Collections[0].Amount != 40 || history.Collections[0].Reference != "INV-1" || history.Mandate.TotalCollected != 40 {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+	if record := getTransactionRecord(t, env, history.Collections[0].TxID); record.TransactionType != "directDebit" || record.Spender != utility.id {
+		t.Fatalf("unexpected collection record: %+v", record)
+	}
+
+	// 只有付款方能变更状态
+	if err := setMandateStatus(env, utility, env.contract.SuspendDirectDebitMandate, id); err == nil || !strings.Contains(err.Error(), "not owned by the caller") {
+		t.Fatalf("expected an ownership error, got %v", err)
+	}
+	if err := setMandateStatus(env, bankAUser, env.contract.SuspendDirectDebitMandate, id); err != nil {
+		t.Fatalf("SuspendDirectDebitMandate failed: %v", err)
+	}
+	if err := collectDirectDebit(env, utility, id, 10); err == nil || !strings.Contains(err.Error(), "is suspended") {
+		t.Fatalf("expected a suspended mandate error, got %v", err)
+	}
+	if err := setMandateStatus(env, bankAUser, env.contract.ResumeDirectDebitMandate, id); err != nil {
+		t.Fatalf("ResumeDirectDebitMandate failed: %v", err)
+	}
+	if err := setMandateStatus(env, bankAUser, env.contract.CancelDirectDebitMandate, id); err != nil {
+		t.Fatalf("CancelDirectDebitMandate failed: %v", err)
+	}
+	if err := setMandateStatus(env, bankAUser, env.contract.ResumeDirectDebitMandate, id); err == nil || !strings.Contains(err.Error(), "cannot be changed") {
+		t.Fatalf("expected a cancelled mandate to stay cancelled, got %v", err)
+	}
+	if err := collectDirectDebit(env, utility, id, 10); err == nil || !strings.Contains(err.Error(), "is cancelled") {
+		t.Fatalf("expected a cancelled mandate error, got %v", err)
+	}
+
+	var list struct {
+		Mandates []DirectDebitMandate `json:"mandates"`
+	}
+	_, err = env.invoke(utility, nil, func(ctx contractapi.TransactionContextInterface) error {
+		response, err := env.contract.ListDirectDebitMandates(ctx, utility.id)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &list)
+	})
+	if err != nil || len(list.Mandates) != 1 || list.Mandates[0].Status != mandateStatusCancelled {
+		t.Fatalf("ListDirectDebitMandates = %+v, %v", list, err)
+	}
+
+	outsider := newTestUser("User1", "b.example.com", "BMSP")
+	_, err = env.invoke(outsider, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.GetDirectDebitMandate(ctx, id)
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "does not have permission") {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+}
+
+func TestCreateDirectDebitMandateRejects(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 0)
+	creditor := `"creditor":"` + centralBankAdmin.id + `"`
+	past := env.ledger.now
+
+	for _, tc := range []struct {
+		mandate string
+		want    string
+	}{
+		{`not json`, "invalid mandate"},
+		{`{` + creditor + `,"maxPerDebit":50}`, "creditor reference is required"},
+		{`{` + creditor + `,"creditorReference":"R","maxPerDebit":0}`, "maximum per debit must be positive"},
+		{`{` + creditor + `,"creditorReference":"R","maxPerDebit":50,"maxPerMonth":20}`, "is lower than maximum per debit"},
+		{`{` + creditor + `,"creditorReference":"R","maxPerDebit":50,"frequency":"daily"}`, "invalid frequency"},
+		{`{` + creditor + `,"creditorReference":"R","maxPerDebit":50,"startDate":1,"endDate":` + fmt.Sprint(past) + `}`, "end date must be in the future"},
+		{`{` + creditor + `,"creditorReference":"R","maxPerDebit":50,"startDate":` + fmt.Sprint(past+100) + `,"endDate":` + fmt.Sprint(past+50) + `}`, "end date must be after the start date"},
+		{`{"creditor":"` + bankAUser.id + `","creditorReference":"R","maxPerDebit":50}`, "creditor must differ"},
+		{`{"creditor":"` + newTestUser("User2", "a.example.com", "AMSP").id + `","creditorReference":"R","maxPerDebit":50}`, "has not been opened"},
+	} {
+		if _, err := createMandate(env, bankAUser, tc.mandate); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("CreateDirectDebitMandate(%s) error = %v, want %q", tc.mandate, err, tc.want)
+		}
+	}
+}
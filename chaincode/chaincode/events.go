@@ -0,0 +1,627 @@
+/*
+链码事件模型
+
+- 所有链码事件以版本化信封发出，事件名为 CBDCEvents；Fabric 每笔交易只保留最后一次 SetEvent，
+  因此同一交易内的多个逻辑事件累积在同一信封中，每次发出时写入完整信封
+- 每个逻辑事件有独立的事件类型（Transfer、Mint、Approval、AccountClosed 等）
+- 账户等敏感字段以加盐哈希（HMAC-SHA256）代替；金额等明细按接收机构加密：
+  ECDH P-256 协商密钥 + AES-256-GCM，接收机构为央行与事件涉及账户所属的银行
+- 盐值由央行通过 transient 字段 eventSecret 配置，各机构通过 RegisterEventKey 登记接收公钥；
+  配置盐值前央行须先登记自己的接收公钥，保证每个事件至少有央行可以解密的明细
+- 机构只能登记本机构的公钥：调用者的 MSP 须与该域名已记录的 MSP（央行为配置中的央行 MSP）一致
+- 未配置盐值时默认不发出账户与明细（事件标记 withheld），只保留非敏感属性；央行可通过 UpdateConfig
+  设置 plaintextEventDetails 显式开启明文明细（结构与解密后的明细相同，含全部 camt.054 通知），
+  经由 <函数名>Transient 提交的交易始终不发出明文明细
+- 每个密文使用独立的临时密钥，由临时密钥种子、交易ID、事件序号与接收机构派生，各背书节点结果一致；
+  种子由央行通过 transient 字段 eventKeySeed 配置，只保存在央行的隐式私有集合中，
+  调用者可另以 transient 字段 eventEntropy 混入本交易的随机数；盐值与账本数据都推不出临时私钥
+
+解密：shared = ECDH(机构私钥, epk)；key = SHA256(shared || epk || recipient)；
+AES-256-GCM(key, nonce) 解密 ciphertext，附加数据为 "<txId>|<seq>|<type>"
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// eventEnvelopeName 链码事件名
+const eventEnvelopeName = "CBDCEvents"
+
+// eventEnvelopeVersion 事件信封版本
+const eventEnvelopeVersion = 1
+
+// eventSecretKey 事件盐值在央行私有集合中的键
+const eventSecretKey = "eventsecret"
+
+// eventSecretTransientField 配置事件盐值的 transient 字段
+const eventSecretTransientField = "eventSecret"
+
+// eventKeySeedKey 临时密钥种子在央行隐式私有集合中的键
+const eventKeySeedKey = "eventkeyseed"
+
+// eventKeySeedTransientField 配置临时密钥种子的 transient 字段
+const eventKeySeedTransientField = "eventKeySeed"
+
+// eventEntropyTransientField 调用者可选提供的本交易随机数，混入临时密钥的派生
+const eventEntropyTransientField = "eventEntropy"
+
+// eventKeyPrefix 机构事件接收公钥，存储在公共状态中
+const eventKeyPrefix = "eventkey_"
+
+// eventPayloadAlgorithm 明细加密算法
+const eventPayloadAlgorithm = "ECDH-ES-P256+A256GCM"
+
+// 事件类型
+const (
+	eventTypeTransfer             = "Transfer"
+	eventTypeTransferFrom         = "TransferFrom"
+	eventTypeDirectDebit          = "DirectDebit"
+	eventTypeMint                 = "Mint"
+	eventTypeBurn                 = "Burn"
+	eventTypeReturn               = "Return"
+	eventTypeSweep                = "Sweep"
+	eventTypeApproval             = "Approval"
+	eventTypeAccountOpened        = "AccountOpened"
+	eventTypeAccountClosed        = "AccountClosed"
+	eventTypeMandateCreated       = "MandateCreated"
+	eventTypeMandateStatusChanged = "MandateStatusChanged"
+	eventTypeConfidentialDeposit  = "ConfidentialDeposit"
+	eventTypeConfidentialWithdraw = "ConfidentialWithdraw"
+	eventTypeConfidentialTransfer = "ConfidentialTransfer"
+	eventTypeEndorsementChanged   = "EndorsementChanged"
+	eventTypeConfigUpdated        = "ConfigUpdated"
+
+	// 预留给冻结与托管功能
+	eventTypeFreeze        = "Freeze"
+	eventTypeUnfreeze      = "Unfreeze"
+	eventTypeEscrowHold    = "EscrowHold"
+	eventTypeEscrowRelease = "EscrowRelease"
+	eventTypeEscrowRefund  = "EscrowRefund"
+)
+
+// bookingEventTypes 记账交易类型 -> 事件类型
+var bookingEventTypes = map[string]string{
+	"transfer":     eventTypeTransfer,
+	"transferFrom": eventTypeTransferFrom,
+	"directDebit":  eventTypeDirectDebit,
+	"mint":         eventTypeMint,
+	"burn":         eventTypeBurn,
+	"return":       eventTypeReturn,
+	"sweep":        eventTypeSweep,
+
+	"confidentialDeposit":  eventTypeConfidentialDeposit,
+	"confidentialWithdraw": eventTypeConfidentialWithdraw,
+}
+
+// eventContext 交易上下文，缓存本交易内已发出的逻辑事件与生效的合约配置
+type eventContext struct {
+	contractapi.TransactionContext
+	envelope *eventEnvelope
+	config   *ContractConfig
+}
+
+// eventEnvelope 版本化事件信封
+type eventEnvelope struct {
+	Version     int              `json:"version"`
+	TxID        string           `json:"txId"`
+	Timestamp   int64            `json:"timestamp"`
+	Sealed      bool             `json:"sealed"`                // 是否已配置盐值（敏感字段已哈希与加密）
+	SaltVersion int              `json:"saltVersion,omitempty"` // 哈希与加密使用的盐值版本
+	Events      []chaincodeEvent `json:"events"`
+}
+
+// chaincodeEvent 信封中的一个逻辑事件
+type chaincodeEvent struct {
+	Seq        int                    `json:"seq"`
+	Type       string                 `json:"type"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"` // 非敏感属性
+	Parties    map[string]string      `json:"parties,omitempty"`    // 角色 -> 账户加盐哈希
+	Payloads   []sealedPayload        `json:"payloads,omitempty"`   // 按接收机构加密的明细
+	Details    map[string]interface{} `json:"details,omitempty"`    // 未配置盐值且开启明文明细时的明细
+	Withheld   bool                   `json:"withheld,omitempty"`   // 未配置盐值，账户与明细未发出
+}
+
+// sealedPayload 为一个接收机构加密的事件明细
+type sealedPayload struct {
+	Recipient    string `json:"recipient"`
+	KeyID        string `json:"keyId"`
+	Algorithm    string `json:"alg"`
+	EphemeralKey string `json:"epk"`
+	Nonce        string `json:"nonce"`
+	Ciphertext   string `json:"ciphertext"`
+}
+
+// eventSecret 事件盐值
+type eventSecret struct {
+	Version   int    `json:"version"`
+	Secret    string `json:"secret"`
+	UpdatedAt int64  `json:"updatedAt"`
+}
+
+// EventKey 机构登记的事件接收公钥
+type EventKey struct {
+	Org       string `json:"org"`
+	PublicKey string `json:"publicKey"` // PEM 编码的 P-256 公钥
+	KeyID     string `json:"keyId"`
+	UpdatedAt int64  `json:"updatedAt"`
+	UpdatedBy string `json:"updatedBy"`
+}
+
+// eventSpec 待发出的逻辑事件
+// Parties 中的账户以哈希发出，账户所属银行与央行为明细的接收机构
+// Details 为加密明细；PerRecipient 可为每个接收机构补充只属于该机构的明细
+type eventSpec struct {
+	Type         string
+	Attributes   map[string]interface{}
+	Parties      map[string]string
+	Details      map[string]interface{}
+	PerRecipient func(org string) (map[string]interface{}, error)
+}
+
+// getEventSecret 读取事件盐值，未配置时返回 nil
+func getEventSecret(ctx contractapi.TransactionContextInterface) (*eventSecret, error) {
+	secretBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), eventSecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event secret: %v", err)
+	}
+	if secretBytes == nil {
+		return nil, nil
+	}
+	var secret eventSecret
+	if err := json.Unmarshal(secretBytes, &secret); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event secret: %v", err)
+	}
+	return &secret, nil
+}
+
+// hmacParts 以 key 计算各部分（以 0 分隔）的 HMAC-SHA256
+func hmacParts(key []byte, parts ...string) []byte {
+	h := hmac.New(sha256.New, key)
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return h.Sum(nil)
+}
+
+// mac 以盐值计算 HMAC-SHA256
+func (secret *eventSecret) mac(parts ...string) []byte {
+	return hmacParts([]byte(secret.Secret), parts...)
+}
+
+// ephemeralKeyMaterial 派生临时密钥的材料：央行隐式集合中的种子，调用者提供 eventEntropy 时与其混合
+// 种子只在央行peer上，其他机构即使知道盐值也推不出临时私钥
+func ephemeralKeyMaterial(ctx contractapi.TransactionContextInterface, config *ContractConfig) ([]byte, error) {
+	seed, err := ctx.GetStub().GetPrivateData(implicitOrgCollection(config.CentralBankMSP), eventKeySeedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event key seed: %v", err)
+	}
+	if seed == nil {
+		return nil, fmt.Errorf("event key seed is not configured, call ConfigureEventPrivacy with transient field %s", eventKeySeedTransientField)
+	}
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transient data: %v", err)
+	}
+	if entropy := transient[eventEntropyTransientField]; len(entropy) > 0 {
+		return hmacParts(seed, "entropy", string(entropy)), nil
+	}
+	return seed, nil
+}
+
+// partyHash 返回账户的加盐哈希；同一盐值下同一账户的哈希相同
+func (secret *eventSecret) partyHash(account string) string {
+	if account == "0x0" {
+		return account
+	}
+	return hex.EncodeToString(secret.mac("party", account))
+}
+
+// getEventKey 读取机构的事件接收公钥，未登记时返回 nil
+func getEventKey(ctx contractapi.TransactionContextInterface, org string) (*EventKey, error) {
+	keyBytes, err := ctx.GetStub().GetState(eventKeyPrefix + org)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event key of %s: %v", org, err)
+	}
+	if keyBytes == nil {
+		return nil, nil
+	}
+	var key EventKey
+	if err := json.Unmarshal(keyBytes, &key); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event key of %s: %v", org, err)
+	}
+	return &key, nil
+}
+
+// parseEventPublicKey 解析 PEM 编码的 P-256 公钥
+func parseEventPublicKey(publicKeyPEM string) (*ecdh.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return nil, fmt.Errorf("public key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %v", err)
+	}
+	ecdsaKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key must be an EC key")
+	}
+	publicKey, err := ecdsaKey.ECDH()
+	if err != nil || publicKey.Curve() != ecdh.P256() {
+		return nil, fmt.Errorf("public key must be on curve P-256")
+	}
+	return publicKey, nil
+}
+
+// sealPayload 为接收机构加密明细，临时密钥与 nonce 由 material 派生
+func sealPayload(material []byte, key *EventKey, aad string, plaintext []byte) (*sealedPayload, error) {
+	recipientKey, err := parseEventPublicKey(key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// 每个接收机构一个临时密钥，各背书节点一致
+	var ephemeral *ecdh.PrivateKey
+	for counter := 0; ephemeral == nil; counter++ {
+		scalar := hmacParts(material, "epk", aad, key.Org, strconv.Itoa(counter))
+		ephemeral, err = ecdh.P256().NewPrivateKey(scalar)
+		if err != nil && counter > 8 {
+			return nil, fmt.Errorf("failed to derive ephemeral key: %v", err)
+		}
+	}
+	shared, err := ephemeral.ECDH(recipientKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive shared secret: %v", err)
+	}
+	epk := ephemeral.PublicKey().Bytes()
+	digest := sha256.Sum256(append(append(shared, epk...), []byte(key.Org)...))
+
+	block, err := aes.NewCipher(digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	nonce := hmacParts(material, "nonce", aad, key.Org)[:gcm.NonceSize()]
+
+	return &sealedPayload{
+		Recipient:    key.Org,
+		KeyID:        key.KeyID,
+		Algorithm:    eventPayloadAlgorithm,
+		EphemeralKey: base64.StdEncoding.EncodeToString(epk),
+		Nonce:        base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext:   base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, []byte(aad))),
+	}, nil
+}
+
+// emitEvent 将逻辑事件加入本交易的事件信封并发出完整信封
+func (s *SmartContract) emitEvent(ctx contractapi.TransactionContextInterface, spec eventSpec) error {
+	stub := ctx.GetStub()
+
+	// 同一交易的事件累积在上下文中；非 eventContext 的上下文每次发出单事件信封
+	var envelope *eventEnvelope
+	if eventCtx, ok := ctx.(*eventContext); ok {
+		envelope = eventCtx.envelope
+	}
+	if envelope == nil {
+		timestamp, err := stub.GetTxTimestamp()
+		if err != nil {
+			return fmt.Errorf("failed to get transaction timestamp: %v", err)
+		}
+		envelope = &eventEnvelope{
+			Version:   eventEnvelopeVersion,
+			TxID:      stub.GetTxID(),
+			Timestamp: timestamp.Seconds,
+			Events:    []chaincodeEvent{},
+		}
+		if eventCtx, ok := ctx.(*eventContext); ok {
+			eventCtx.envelope = envelope
+		}
+	}
+
+	evt := chaincodeEvent{
+		Seq:        len(envelope.Events),
+		Type:       spec.Type,
+		Attributes: spec.Attributes,
+	}
+
+	secret, err := getEventSecret(ctx)
+	if err != nil {
+		return err
+	}
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	// recipientDetails 接收机构可见的明细
+	recipientDetails := func(org string) (map[string]interface{}, error) {
+		details := map[string]interface{}{"parties": spec.Parties}
+		for field, value := range spec.Details {
+			details[field] = value
+		}
+		if spec.PerRecipient != nil {
+			extra, err := spec.PerRecipient(org)
+			if err != nil {
+				return nil, err
+			}
+			for field, value := range extra {
+				details[field] = value
+			}
+		}
+		return details, nil
+	}
+	hasDetails := len(spec.Parties) > 0 || len(spec.Details) > 0 || spec.PerRecipient != nil
+
+	if secret == nil {
+		// 未启用事件隐私：默认不发出账户与明细，央行显式开启时明文发出央行视角的完整明细；
+		// transient 版本提交的交易不在事件中泄露其隐藏的参数
+		transientRequest, err := isTransientRequest(ctx)
+		if err != nil {
+			return err
+		}
+		if hasDetails && config.PlaintextEventDetails && !transientRequest {
+			evt.Details, err = recipientDetails(config.CentralBankDomain)
+			if err != nil {
+				return err
+			}
+		} else if hasDetails {
+			evt.Withheld = true
+		}
+	} else {
+		envelope.Sealed = true
+		envelope.SaltVersion = secret.Version
+
+		// 账户替换为加盐哈希，并确定接收机构
+		recipients := map[string]bool{config.CentralBankDomain: true}
+		evt.Parties = map[string]string{}
+		for role, account := range spec.Parties {
+			if account == "" {
+				continue
+			}
+			evt.Parties[role] = secret.partyHash(account)
+			if org, err := s.extractDomainFromClientID(account); err == nil && org != "" {
+				recipients[org] = true
+			}
+		}
+		orgs := make([]string, 0, len(recipients))
+		for org := range recipients {
+			orgs = append(orgs, org)
+		}
+		sort.Strings(orgs)
+
+		material, err := ephemeralKeyMaterial(ctx, config)
+		if err != nil {
+			return err
+		}
+
+		// 按接收机构加密明细；未登记公钥的机构不产生密文
+		aad := fmt.Sprintf("%s|%d|%s", envelope.TxID, evt.Seq, evt.Type)
+		for _, org := range orgs {
+			key, err := getEventKey(ctx, org)
+			if err != nil {
+				return err
+			}
+			if key == nil {
+				continue
+			}
+
+			details, err := recipientDetails(org)
+			if err != nil {
+				return err
+			}
+			plaintext, err := json.Marshal(details)
+			if err != nil {
+				return fmt.Errorf("failed to marshal event details: %v", err)
+			}
+
+			payload, err := sealPayload(material, key, aad, plaintext)
+			if err != nil {
+				return fmt.Errorf("failed to seal event for %s: %v", org, err)
+			}
+			evt.Payloads = append(evt.Payloads, *payload)
+		}
+		// 明细不能无声丢失：没有任何接收机构登记公钥时交易失败
+		if hasDetails && len(evt.Payloads) == 0 {
+			return fmt.Errorf("no event key is registered for recipients %v of %s event, call RegisterEventKey", orgs, evt.Type)
+		}
+	}
+
+	envelope.Events = append(envelope.Events, evt)
+
+	envelopeJSON, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = stub.SetEvent(eventEnvelopeName, envelopeJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
+
+// ConfigureEventPrivacy 央行设置或轮换事件盐值与临时密钥种子，
+// 分别通过 transient 字段 eventSecret 与 eventKeySeed 传入（各至少 32 字节）
+func (s *SmartContract) ConfigureEventPrivacy(ctx contractapi.TransactionContextInterface) (string, error) {
+	// 检查合约初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	// 仅央行可以配置
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	isCentralBank, err := isCentralBankMSP(ctx, clientMSPID)
+	if err != nil {
+		return "", err
+	}
+	if !isCentralBank {
+		return "", fmt.Errorf("client is not authorized to configure event privacy")
+	}
+
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transient data: %v", err)
+	}
+	secretValue := transient[eventSecretTransientField]
+	if len(secretValue) < 32 {
+		return "", fmt.Errorf("transient field %s must contain at least 32 bytes", eventSecretTransientField)
+	}
+	keySeed := transient[eventKeySeedTransientField]
+	if len(keySeed) < 32 {
+		return "", fmt.Errorf("transient field %s must contain at least 32 bytes", eventKeySeedTransientField)
+	}
+
+	// 央行须先登记接收公钥，否则启用后事件明细无人可以解密
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	centralBankKey, err := getEventKey(ctx, config.CentralBankDomain)
+	if err != nil {
+		return "", err
+	}
+	if centralBankKey == nil {
+		return "", fmt.Errorf("register the event key of %s with RegisterEventKey before configuring event privacy", config.CentralBankDomain)
+	}
+
+	current, err := getEventSecret(ctx)
+	if err != nil {
+		return "", err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	secret := eventSecret{
+		Version:   1,
+		Secret:    hex.EncodeToString(secretValue),
+		UpdatedAt: timestamp.Seconds,
+	}
+	if current != nil {
+		secret.Version = current.Version + 1
+	}
+	secretBytes, err := json.Marshal(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal event secret: %v", err)
+	}
+	if err := ctx.GetStub().PutPrivateData(privateCollection(ctx), eventSecretKey, secretBytes); err != nil {
+		return "", fmt.Errorf("failed to store event secret: %v", err)
+	}
+	// 种子只写入央行的隐式集合，不分发给其他机构
+	if err := ctx.GetStub().PutPrivateData(implicitOrgCollection(config.CentralBankMSP), eventKeySeedKey, keySeed); err != nil {
+		return "", fmt.Errorf("failed to store event key seed: %v", err)
+	}
+
+	log.Printf("event secret configured, version %d", secret.Version)
+
+	return fmt.Sprintf(`{"saltVersion":%d}`, secret.Version), nil
+}
+
+// RegisterEventKey 机构admin登记本机构的事件接收公钥（PEM 编码的 P-256 公钥）
+// 调用者的 MSP 须与其域名对应的 MSP 一致：央行为配置中的央行 MSP，银行为开户时记录的域名映射
+func (s *SmartContract) RegisterEventKey(ctx contractapi.TransactionContextInterface, publicKeyPEM string) (string, error) {
+	// 检查合约初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+	if !s.isAdminUser(ctx, callerID) {
+		return "", fmt.Errorf("only bank admins can register event keys")
+	}
+	org, err := s.extractDomainFromClientID(callerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract caller domain: %v", err)
+	}
+
+	// 证书中的域名可由任意机构的 CA 签发，须以 MSP 确认调用者确实属于该机构
+	callerMSP, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	orgMSP := config.CentralBankMSP
+	if org != config.CentralBankDomain {
+		orgMSP, err = s.knownAccountMSP(ctx, callerID)
+		if err != nil {
+			return "", err
+		}
+		if orgMSP == "" {
+			return "", fmt.Errorf("the MSP of %s is unknown, open an account with an admin of its bank first", org)
+		}
+	}
+	if callerMSP != orgMSP {
+		return "", fmt.Errorf("client of %s is not authorized to register the event key of %s", callerMSP, org)
+	}
+
+	publicKey, err := parseEventPublicKey(publicKeyPEM)
+	if err != nil {
+		return "", err
+	}
+	fingerprint := sha256.Sum256(publicKey.Bytes())
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	key := EventKey{
+		Org:       org,
+		PublicKey: publicKeyPEM,
+		KeyID:     hex.EncodeToString(fingerprint[:8]),
+		UpdatedAt: timestamp.Seconds,
+		UpdatedBy: callerID,
+	}
+	keyJSON, err := json.Marshal(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal event key: %v", err)
+	}
+	if err := ctx.GetStub().PutState(eventKeyPrefix+org, keyJSON); err != nil {
+		return "", fmt.Errorf("failed to store event key: %v", err)
+	}
+
+	log.Printf("event key %s registered for %s", key.KeyID, org)
+
+	return string(keyJSON), nil
+}
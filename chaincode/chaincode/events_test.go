@@ -0,0 +1,214 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func mintAndReadEnvelope(t *testing.T, env *testEnv) eventEnvelope {
+	t.Helper()
+	stub, err := env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.Mint(ctx, 1000)
+	})
+	if err != nil {
+		t.Fatalf("Mint failed: %v", err)
+	}
+	var envelope eventEnvelope
+	if err := json.Unmarshal(stub.events[eventEnvelopeName], &envelope); err != nil {
+		t.Fatalf("failed to unmarshal event envelope: %v", err)
+	}
+	if len(envelope.Events) != 1 || envelope.Events[0].Type != eventTypeMint {
+		t.Fatalf("unexpected events %+v", envelope.Events)
+	}
+	return envelope
+}
+
+// eventKeyPEM 生成一个 PEM 编码的 P-256 公钥
+func eventKeyPEM(t *testing.T) string {
+	t.Helper()
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER}))
+}
+
+func registerEventKey(env *testEnv, caller testUser, publicKeyPEM string) error {
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.RegisterEventKey(ctx, publicKeyPEM)
+		return err
+	})
+	return err
+}
+
+func TestUnsealedEventWithholdsDetails(t *testing.T) {
+	env := newTestEnv(t)
+	env.initialize()
+
+	envelope := mintAndReadEnvelope(t, env)
+	evt := envelope.Events[0]
+	if envelope.Sealed || !evt.Withheld || evt.Details != nil || evt.Parties != nil {
+		t.Fatalf("details must be withheld before event privacy is configured: %+v", evt)
+	}
+}
+
+func TestUnsealedEventCarriesCamt054(t *testing.T) {
+	env := newTestEnv(t)
+	env.initialize()
+	_, err := env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.UpdateConfig(ctx, `{"plaintextEventDetails":true}`, "test")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+
+	envelope := mintAndReadEnvelope(t, env)
+	if envelope.Sealed || envelope.Events[0].Withheld {
+		t.Fatalf("envelope must carry plaintext details once opted in")
+	}
+	details := envelope.Events[0].Details
+	if details["value"] != float64(1000) {
+		t.Fatalf("unexpected details %v", details)
+	}
+	notification, ok := details["camt054"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("camt054 notification missing from details %v", details)
+	}
+	if entries, _ := notification["Ntfctn"].([]interface{}); len(entries) != 1 {
+		t.Fatalf("expected one credit notification for the minter, got %v", notification["Ntfctn"])
+	}
+}
+
+func TestEventPrivacyRequiresCentralBankKey(t *testing.T) {
+	env := newTestEnv(t)
+	env.initialize()
+
+	secret := map[string][]byte{eventSecretTransientField: []byte(strings.Repeat("s", 32))}
+	configure := func(transient map[string][]byte) error {
+		_, err := env.invoke(centralBankAdmin, transient, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := env.contract.ConfigureEventPrivacy(ctx)
+			return err
+		})
+		return err
+	}
+	if err := configure(secret); err == nil || !strings.Contains(err.Error(), eventKeySeedTransientField) {
+		t.Fatalf("expected missing key seed error, got %v", err)
+	}
+	secret[eventKeySeedTransientField] = []byte(strings.Repeat("k", 32))
+	if err := configure(secret); err == nil || !strings.Contains(err.Error(), "RegisterEventKey") {
+		t.Fatalf("expected missing event key error, got %v", err)
+	}
+
+	if err := registerEventKey(env, centralBankAdmin, eventKeyPEM(t)); err != nil {
+		t.Fatalf("RegisterEventKey failed: %v", err)
+	}
+	if err := configure(secret); err != nil {
+		t.Fatalf("ConfigureEventPrivacy failed: %v", err)
+	}
+	// 临时密钥种子只在央行隐式集合中，不在央行私有集合
+	if env.ledger.private[implicitOrgCollection(CENTRAL_MSP_ID)][eventKeySeedKey] == nil || env.ledger.private[defaultPrivateCollection][eventKeySeedKey] != nil {
+		t.Fatalf("event key seed must be stored only in the central bank implicit collection")
+	}
+
+	envelope := mintAndReadEnvelope(t, env)
+	evt := envelope.Events[0]
+	if !envelope.Sealed || evt.Details != nil {
+		t.Fatalf("sealed envelope must not carry plaintext details: %+v", evt)
+	}
+	if len(evt.Payloads) != 1 || evt.Payloads[0].Recipient != CENTRAL_BANK_DOMAIN {
+		t.Fatalf("expected one payload for the central bank, got %+v", evt.Payloads)
+	}
+}
+
+func TestSealedPayloadEphemeralKeys(t *testing.T) {
+	env := newTestEnv(t)
+	env.initialize()
+	if err := registerEventKey(env, centralBankAdmin, eventKeyPEM(t)); err != nil {
+		t.Fatalf("RegisterEventKey failed: %v", err)
+	}
+	_, err := env.invoke(centralBankAdmin, map[string][]byte{
+		eventSecretTransientField:  []byte(strings.Repeat("s", 32)),
+		eventKeySeedTransientField: []byte(strings.Repeat("k", 32)),
+	}, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.ConfigureEventPrivacy(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("ConfigureEventPrivacy failed: %v", err)
+	}
+
+	// 同一账本状态下，调用者提供的随机数不同，临时密钥也不同
+	seal := func(entropy string) sealedPayload {
+		t.Helper()
+		var payload sealedPayload
+		_, err := env.invoke(centralBankAdmin, map[string][]byte{eventEntropyTransientField: []byte(entropy)}, func(ctx contractapi.TransactionContextInterface) error {
+			if err := env.contract.emitEvent(ctx, eventSpec{Type: eventTypeMint, Details: map[string]interface{}{"value": 1}}); err != nil {
+				return err
+			}
+			var envelope eventEnvelope
+			if err := json.Unmarshal(ctx.GetStub().(*mockStub).events[eventEnvelopeName], &envelope); err != nil {
+				return err
+			}
+			payload = envelope.Events[0].Payloads[0]
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("emitEvent failed: %v", err)
+		}
+		return payload
+	}
+	first, second := seal("a"), seal("b")
+	if first.EphemeralKey == second.EphemeralKey || first.Nonce == second.Nonce {
+		t.Fatalf("payloads must use fresh ephemeral keys: %+v %+v", first, second)
+	}
+}
+
+func TestRegisterEventKeyRequiresMatchingMSP(t *testing.T) {
+	env := newTestEnv(t)
+	env.initialize()
+	publicKeyPEM := eventKeyPEM(t)
+
+	// 域名对应的 MSP 尚未记录
+	if err := registerEventKey(env, bankAAdmin, publicKeyPEM); err == nil || !strings.Contains(err.Error(), "MSP of a.example.com is unknown") {
+		t.Fatalf("expected an unknown MSP error, got %v", err)
+	}
+	_, err := env.invoke(bankAAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.OpenAccount(ctx, bankAUser.id, accountTypeIndividual)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+
+	// 其他 MSP 签发的同域名证书不能登记
+	impostor := newTestUser("Admin", "a.example.com", "BMSP")
+	if err := registerEventKey(env, impostor, publicKeyPEM); err == nil || !strings.Contains(err.Error(), "not authorized to register the event key of a.example.com") {
+		t.Fatalf("expected an MSP mismatch error, got %v", err)
+	}
+	centralImpostor := newTestUser("Admin", CENTRAL_BANK_DOMAIN, "AMSP")
+	if err := registerEventKey(env, centralImpostor, publicKeyPEM); err == nil || !strings.Contains(err.Error(), "not authorized to register the event key") {
+		t.Fatalf("expected an MSP mismatch error, got %v", err)
+	}
+	if err := registerEventKey(env, bankAUser, publicKeyPEM); err == nil || !strings.Contains(err.Error(), "only bank admins") {
+		t.Fatalf("expected an admin error, got %v", err)
+	}
+	if err := registerEventKey(env, bankAAdmin, "not a key"); err == nil || !strings.Contains(err.Error(), "not PEM encoded") {
+		t.Fatalf("expected a malformed key error, got %v", err)
+	}
+	if err := registerEventKey(env, bankAAdmin, publicKeyPEM); err != nil {
+		t.Fatalf("RegisterEventKey failed: %v", err)
+	}
+}
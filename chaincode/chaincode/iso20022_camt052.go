@@ -0,0 +1,189 @@
+/*
+ISO 20022 camt.052 日间账户报告
+
+- 返回自给定游标以来记账的分录
+- 游标为不透明字符串，调用方以返回的 nextCursor 继续获取后续分录
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// secondsPerDay 一天的秒数
+const secondsPerDay = 24 * 60 * 60
+
+// entryCursor 分录游标：最后一笔已报告交易的时间戳与交易ID
+type entryCursor struct {
+	Timestamp int64
+	TxID      string
+}
+
+// encode 将游标编码为不透明字符串
+func (c entryCursor) encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", c.Timestamp, c.TxID)))
+}
+
+// after 判断分录是否位于游标之后
+func (c entryCursor) after(record TransactionRecord) bool {
+	return c.afterPosition(record.Timestamp, record.TxID)
+}
+
+// afterPosition 判断 (timestamp, txID) 是否位于游标之后
+func (c entryCursor) afterPosition(timestamp int64, txID string) bool {
+	if timestamp != c.Timestamp {
+		return timestamp > c.Timestamp
+	}
+	return c.TxID == "" || txID > c.TxID
+}
+
+// decodeEntryCursor 解析不透明游标字符串
+func decodeEntryCursor(cursor string) (entryCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return entryCursor{}, fmt.Errorf("invalid cursor: %v", err)
+	}
+	tsPart, txID, found := strings.Cut(string(raw), ":")
+	if !found {
+		return entryCursor{}, errors.New("invalid cursor format")
+	}
+	ts, err := strconv.ParseInt(tsPart, 10, 64)
+	if err != nil {
+		return entryCursor{}, fmt.Errorf("invalid cursor timestamp: %v", err)
+	}
+	return entryCursor{Timestamp: ts, TxID: txID}, nil
+}
+
+// GetIntradayReport 返回账户自 sinceCursor 以来记账分录的 camt.052 日间报告
+// sinceCursor 为空时从当天（UTC）零点开始；返回结果中的 nextCursor 用于下一次查询
+func (s *SmartContract) GetIntradayReport(ctx contractapi.TransactionContextInterface, account string, sinceCursor string) (string, error) {
+	// 检查合约初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	// 获取当前调用者的信息
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+
+	// 解析账户别名
+	account, err = s.resolveAccount(ctx, account)
+	if err != nil {
+		return "", err
+	}
+
+	// 检查权限
+	hasPermission, err := s.checkTransactionQueryPermission(ctx, callerID, account)
+	if err != nil {
+		return "", fmt.Errorf("failed to check permission: %v", err)
+	}
+	if !hasPermission {
+		return "", fmt.Errorf("caller does not have permission to query transactions for user %s", account)
+	}
+
+	stub := ctx.GetStub()
+	ts, err := stub.GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	cursor := entryCursor{Timestamp: ts.Seconds - ts.Seconds%secondsPerDay}
+	if sinceCursor != "" {
+		cursor, err = decodeEntryCursor(sinceCursor)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	period, err := s.queryAccountPeriod(ctx, account, cursor.Timestamp, ts.Seconds)
+	if err != nil {
+		return "", err
+	}
+
+	var newEntries []accountEntry
+	for _, entry := range period.Entries {
+		if cursor.after(entry.Record) {
+			newEntries = append(newEntries, entry)
+		}
+	}
+
+	nextCursor := cursor
+	if len(newEntries) > 0 {
+		last := newEntries[len(newEntries)-1].Record
+		nextCursor = entryCursor{Timestamp: last.Timestamp, TxID: last.TxID}
+	}
+
+	// 当前余额（日间已记账余额）
+	currentBalance, err := s.getBalanceFromPrivateCollection(ctx, account)
+	if err != nil {
+		return "", fmt.Errorf("failed to read account %s from private collection: %v", account, err)
+	}
+
+	// 构建 camt.052 报文
+	tokenSymbol, tokenDecimals, err := s.getTokenMeta(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get token meta failed: %v", err)
+	}
+	amountJSON := func(amount int) map[string]interface{} {
+		return map[string]interface{}{"Ccy": tokenSymbol, "_text": s.formatAmount(amount, tokenDecimals)}
+	}
+
+	ntry := make([]map[string]interface{}, 0, len(newEntries))
+	for _, entry := range newEntries {
+		ntry = append(ntry, s.buildISOEntry(entry, amountJSON))
+	}
+
+	txID := stub.GetTxID()
+	channelID := stub.GetChannelID()
+
+	report := map[string]interface{}{
+		"_std": "ISO20022",
+		"_msg": "camt.052",
+		"GrpHdr": map[string]interface{}{
+			"MsgId":   fmt.Sprintf("%s@%s", txID, channelID),
+			"CreDtTm": fmt.Sprintf("%d", ts.Seconds),
+		},
+		"Rpt": map[string]interface{}{
+			"Id":      fmt.Sprintf("%s@%s", txID, channelID),
+			"CreDtTm": fmt.Sprintf("%d", ts.Seconds),
+			"FrToDt": map[string]interface{}{
+				"FrDtTm": fmt.Sprintf("%d", cursor.Timestamp),
+				"ToDtTm": fmt.Sprintf("%d", ts.Seconds),
+			},
+			"Acct": s.buildISOAccount(account, tokenSymbol),
+			"Bal": []map[string]interface{}{
+				buildISOBalance("ITBD", currentBalance, ts.Seconds, amountJSON),
+			},
+			"TxsSummry": s.buildISOTransactionsSummary(newEntries, tokenDecimals),
+			"Ntry":      ntry,
+		},
+	}
+
+	response := map[string]interface{}{
+		"report":     report,
+		"nextCursor": nextCursor.encode(),
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal response: %v", err)
+	}
+
+	return string(responseJSON), nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// camt052Response 日间报告中测试关心的字段
+type camt052Response struct {
+	Report struct {
+		Rpt struct {
+			Bal []struct {
+				Amt struct {
+					Text string `json:"_text"`
+				}
+			}
+			Ntry []map[string]interface{}
+		}
+	} `json:"report"`
+	NextCursor string `json:"nextCursor"`
+}
+
+func intradayReport(env *testEnv, caller testUser, account string, cursor string) (*camt052Response, error) {
+	response := &camt052Response{}
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		result, err := env.contract.GetIntradayReport(ctx, account, cursor)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(result), response)
+	})
+	return response, err
+}
+
+func TestIntradayReportCursor(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 0)
+	transfer(t, env, centralBankAdmin, bankAUser, 100)
+	transfer(t, env, bankAUser, centralBankAdmin, 25)
+
+	report, err := intradayReport(env, bankAUser, bankAUser.id, "")
+	if err != nil {
+		t.Fatalf("GetIntradayReport failed: %v", err)
+	}
+	if len(report.Report.Rpt.Ntry) != 2 || report.Report.Rpt.Bal[0].Amt.Text != "0.75" {
+		t.Fatalf("unexpected report %+v", report.Report.Rpt)
+	}
+
+	// 游标之后没有新分录；新记账后只返回新的一笔
+	report, err = intradayReport(env, bankAUser, bankAUser.id, report.NextCursor)
+	if err != nil {
+		t.Fatalf("GetIntradayReport failed: %v", err)
+	}
+	if len(report.Report.Rpt.Ntry) != 0 {
+		t.Fatalf("expected no entries after the cursor, got %v", report.Report.Rpt.Ntry)
+	}
+	transfer(t, env, centralBankAdmin, bankAUser, 5)
+	report, err = intradayReport(env, bankAUser, bankAUser.id, report.NextCursor)
+	if err != nil {
+		t.Fatalf("GetIntradayReport failed: %v", err)
+	}
+	if len(report.Report.Rpt.Ntry) != 1 || report.Report.Rpt.Ntry[0]["CdtDbtInd"] != "CRDT" {
+		t.Fatalf("expected the new credit only, got %v", report.Report.Rpt.Ntry)
+	}
+
+	outsider := newTestUser("User1", "b.example.com", "BMSP")
+	if _, err := intradayReport(env, outsider, bankAUser.id, ""); err == nil || !strings.Contains(err.Error(), "does not have permission") {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+	if _, err := intradayReport(env, bankAUser, bankAUser.id, "not a cursor!"); err == nil || !strings.Contains(err.Error(), "invalid cursor") {
+		t.Fatalf("expected an invalid cursor error, got %v", err)
+	}
+}
+
+func TestCamt054Notifications(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 100)
+	stub := transfer(t, env, bankAUser, centralBankAdmin, 30)
+	record := getTransactionRecord(t, env, stub.txID)
+
+	// 央行收到双方的通知，银行只收到本行账户的通知
+	notifications := func(org string) []interface{} {
+		t.Helper()
+		var notification map[string]interface{}
+		_, err := env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+			built, err := env.contract.buildCamt054(ctx, &record, org)
+			if err != nil {
+				return err
+			}
+			encoded, err := json.Marshal(built)
+			if err != nil {
+				return err
+			}
+			return json.Unmarshal(encoded, &notification)
+		})
+		if err != nil {
+			t.Fatalf("buildCamt054 failed: %v", err)
+		}
+		return notification["Ntfctn"].([]interface{})
+	}
+	if got := notifications(CENTRAL_BANK_DOMAIN); len(got) != 2 {
+		t.Fatalf("central bank expected two notifications, got %v", got)
+	}
+	got := notifications("a.example.com")
+	if len(got) != 1 || !strings.HasSuffix(got[0].(map[string]interface{})["Id"].(string), "/DBIT") {
+		t.Fatalf("bank expected only the debit notification, got %v", got)
+	}
+	if got := notifications("b.example.com"); len(got) != 0 {
+		t.Fatalf("an uninvolved bank must not get notifications, got %v", got)
+	}
+}
@@ -0,0 +1,269 @@
+/*
+ISO 20022 camt.053 账户对账单
+
+- 按任意时间段生成对账单：期初余额、期间分录、期末余额
+- 只扫描期间所涉月份的账户索引，期初余额取自月末余额，见 balance_history.go
+- 分录来自央行私有集合中存储的交易记录
+- 权限与交易查询一致（checkTransactionQueryPermission）
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// accountEntry 账户的一条记账分录
+type accountEntry struct {
+	Record    TransactionRecord
+	Amount    int    // 分录金额（非负）
+	CdtDbtInd string // CRDT 或 DBIT
+	Charge    bool   // 手续费账户的手续费入账
+}
+
+// signedAmount 返回分录对余额的影响
+func (e accountEntry) signedAmount() int {
+	if e.CdtDbtInd == "DBIT" {
+		return -e.Amount
+	}
+	return e.Amount
+}
+
+// GenerateStatement 生成指定账户在 [fromTimestamp, toTimestamp] 期间的 camt.053 对账单
+// 时间戳为 Unix 秒；toTimestamp <= 0 表示截至当前交易时间
+func (s *SmartContract) GenerateStatement(ctx contractapi.TransactionContextInterface, account string, fromTimestamp int64, toTimestamp int64) (string, error) {
+	// 检查合约初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	// 获取当前调用者的信息
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+
+	// 解析账户别名
+	account, err = s.resolveAccount(ctx, account)
+	if err != nil {
+		return "", err
+	}
+
+	// 检查权限
+	hasPermission, err := s.checkTransactionQueryPermission(ctx, callerID, account)
+	if err != nil {
+		return "", fmt.Errorf("failed to check permission: %v", err)
+	}
+	if !hasPermission {
+		return "", fmt.Errorf("caller does not have permission to query transactions for user %s", account)
+	}
+
+	stub := ctx.GetStub()
+	ts, err := stub.GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	if toTimestamp <= 0 || toTimestamp > ts.Seconds {
+		toTimestamp = ts.Seconds
+	}
+	if fromTimestamp < 0 || fromTimestamp > toTimestamp {
+		return "", fmt.Errorf("invalid statement period %d - %d", fromTimestamp, toTimestamp)
+	}
+
+	// 只扫描期间所涉月份的索引，期初余额取自月末余额，不再由当前余额倒推
+	period, err := s.queryAccountPeriod(ctx, account, fromTimestamp, toTimestamp)
+	if err != nil {
+		return "", err
+	}
+	periodEntries := period.Entries
+
+	// 构建 camt.053 报文
+	tokenSymbol, tokenDecimals, err := s.getTokenMeta(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get token meta failed: %v", err)
+	}
+	amountJSON := func(amount int) map[string]interface{} {
+		return map[string]interface{}{"Ccy": tokenSymbol, "_text": s.formatAmount(amount, tokenDecimals)}
+	}
+
+	ntry := make([]map[string]interface{}, 0, len(periodEntries))
+	for _, entry := range periodEntries {
+		ntry = append(ntry, s.buildISOEntry(entry, amountJSON))
+	}
+
+	txID := stub.GetTxID()
+	channelID := stub.GetChannelID()
+
+	statement := map[string]interface{}{
+		"_std": "ISO20022",
+		"_msg": "camt.053",
+		"GrpHdr": map[string]interface{}{
+			"MsgId":   fmt.Sprintf("%s@%s", txID, channelID),
+			"CreDtTm": fmt.Sprintf("%d", ts.Seconds),
+		},
+		"Stmt": map[string]interface{}{
+			"Id":      fmt.Sprintf("%s@%s", txID, channelID),
+			"CreDtTm": fmt.Sprintf("%d", ts.Seconds),
+			"FrToDt": map[string]interface{}{
+				"FrDtTm": fmt.Sprintf("%d", fromTimestamp),
+				"ToDtTm": fmt.Sprintf("%d", toTimestamp),
+			},
+			"Acct": s.buildISOAccount(account, tokenSymbol),
+			"Bal": []map[string]interface{}{
+				buildISOBalance("OPBD", period.OpeningBalance, fromTimestamp, amountJSON),
+				buildISOBalance("CLBD", period.ClosingBalance, toTimestamp, amountJSON),
+			},
+			"TxsSummry": s.buildISOTransactionsSummary(periodEntries, tokenDecimals),
+			"Ntry":      ntry,
+		},
+	}
+
+	statementJSON, err := json.Marshal(statement)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal statement: %v", err)
+	}
+
+	return string(statementJSON), nil
+}
+
+// recordEntries 返回交易在账户上产生的分录
+// 自转账同时产生借贷两条分录；商户按扣除手续费后的净额入账，手续费单独记入手续费账户
+func recordEntries(record *TransactionRecord, account string) []accountEntry {
+	var entries []accountEntry
+	if record.From == account {
+		entries = append(entries, accountEntry{Record: *record, Amount: record.Amount, CdtDbtInd: "DBIT"})
+	}
+	if record.To == account {
+		entries = append(entries, accountEntry{Record: *record, Amount: record.Amount - record.Fee, CdtDbtInd: "CRDT"})
+	}
+	if record.Fee > 0 && record.FeeAccount == account {
+		entries = append(entries, accountEntry{Record: *record, Amount: record.Fee, CdtDbtInd: "CRDT", Charge: true})
+	}
+	return entries
+}
+
+// buildISOEntry 构建 camt.05x 报文中的 Ntry 元素
+func (s *SmartContract) buildISOEntry(entry accountEntry, amountJSON func(int) map[string]interface{}) map[string]interface{} {
+	record := entry.Record
+	fromOrg, _ := s.extractDomainFromClientID(record.From)
+	toOrg, _ := s.extractDomainFromClientID(record.To)
+
+	refs := map[string]interface{}{
+		"AcctSvcrRef": record.TxID,
+		"TxId":        record.TxID,
+	}
+	if record.EndToEndID != "" {
+		refs["EndToEndId"] = record.EndToEndID
+	}
+	if record.UETR != "" {
+		refs["UETR"] = record.UETR
+	}
+
+	txDtls := map[string]interface{}{
+		"Refs":      refs,
+		"RltdPties": map[string]interface{}{"Dbtr": record.From, "Cdtr": record.To},
+	}
+	txType := record.TransactionType
+	if entry.Charge {
+		txType = "charge"
+	}
+	if record.Fee > 0 {
+		// 商户手续费：原始指令金额与手续费明细
+		txDtls["AmtDtls"] = map[string]interface{}{"InstdAmt": map[string]interface{}{"Amt": amountJSON(record.Amount)}}
+		txDtls["Chrgs"] = map[string]interface{}{"Rcrd": s.buildISOCharges(&record, amountJSON)}
+	}
+
+	return map[string]interface{}{
+		"Amt":         amountJSON(entry.Amount),
+		"CdtDbtInd":   entry.CdtDbtInd,
+		"Sts":         map[string]interface{}{"Cd": "BOOK"},
+		"BookgDt":     map[string]interface{}{"DtTm": fmt.Sprintf("%d", record.Timestamp)},
+		"AcctSvcrRef": record.TxID,
+		"BkTxCd":      map[string]interface{}{"Prtry": map[string]interface{}{"Cd": txType}},
+		"NtryDtls": map[string]interface{}{
+			"TxDtls": txDtls,
+		},
+		"AddtlNtryInf": fmt.Sprintf("TxType=%s;FromOrg=%s;ToOrg=%s", record.TransactionType, fromOrg, toOrg),
+	}
+}
+
+// buildISOAccount 构建 camt.05x 报文中的 Acct 元素
+func (s *SmartContract) buildISOAccount(account string, currency string) map[string]interface{} {
+	accountOrg, _ := s.extractDomainFromClientID(account)
+	return map[string]interface{}{
+		"Id":  map[string]interface{}{"Othr": map[string]interface{}{"Id": account}},
+		"Ccy": currency,
+		"Svcr": map[string]interface{}{
+			"FinInstnId": map[string]interface{}{"Othr": map[string]interface{}{"Id": accountOrg}},
+		},
+	}
+}
+
+// buildISOBalance 构建 camt.05x 报文中的 Bal 元素
+func buildISOBalance(code string, balance int, at int64, amountJSON func(int) map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"Tp":        map[string]interface{}{"CdOrPrtry": map[string]interface{}{"Cd": code}},
+		"Amt":       amountJSON(absInt(balance)),
+		"CdtDbtInd": creditDebitIndicator(balance),
+		"Dt":        map[string]interface{}{"DtTm": fmt.Sprintf("%d", at)},
+	}
+}
+
+// buildISOTransactionsSummary 构建 camt.05x 报文中的 TxsSummry 元素（分录笔数与合计）
+func (s *SmartContract) buildISOTransactionsSummary(entries []accountEntry, tokenDecimals int) map[string]interface{} {
+	creditCount, creditSum, debitCount, debitSum := 0, 0, 0, 0
+	for _, entry := range entries {
+		if entry.CdtDbtInd == "CRDT" {
+			creditCount++
+			creditSum += entry.Amount
+		} else {
+			debitCount++
+			debitSum += entry.Amount
+		}
+	}
+	netAmount := creditSum - debitSum
+
+	return map[string]interface{}{
+		"TtlNtries": map[string]interface{}{
+			"NbOfNtries": len(entries),
+			"Sum":        s.formatAmount(creditSum+debitSum, tokenDecimals),
+			"TtlNetNtry": map[string]interface{}{
+				"Amt":       s.formatAmount(absInt(netAmount), tokenDecimals),
+				"CdtDbtInd": creditDebitIndicator(netAmount),
+			},
+		},
+		"TtlCdtNtries": map[string]interface{}{
+			"NbOfNtries": creditCount,
+			"Sum":        s.formatAmount(creditSum, tokenDecimals),
+		},
+		"TtlDbtNtries": map[string]interface{}{
+			"NbOfNtries": debitCount,
+			"Sum":        s.formatAmount(debitSum, tokenDecimals),
+		},
+	}
+}
+
+// creditDebitIndicator 根据金额符号返回 CRDT 或 DBIT
+func creditDebitIndicator(amount int) string {
+	if amount < 0 {
+		return "DBIT"
+	}
+	return "CRDT"
+}
+
+// absInt 返回整数的绝对值
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// 测试账本的月初时间戳（UTC）
+const (
+	december2023 = 1701388800
+	january2024  = 1704067200
+	february2024 = 1706745600
+)
+
+// camt053Statement 对账单中测试关心的字段
+type camt053Statement struct {
+	Stmt struct {
+		Bal []struct {
+			Tp struct {
+				CdOrPrtry struct {
+					Cd string
+				}
+			}
+			Amt struct {
+				Text string `json:"_text"`
+			}
+		}
+		Ntry []map[string]interface{}
+	}
+}
+
+// balances 返回期初与期末余额
+func (s camt053Statement) balances() (string, string) {
+	var opening, closing string
+	for _, bal := range s.Stmt.Bal {
+		switch bal.Tp.CdOrPrtry.Cd {
+		case "OPBD":
+			opening = bal.Amt.Text
+		case "CLBD":
+			closing = bal.Amt.Text
+		}
+	}
+	return opening, closing
+}
+
+func generateStatement(env *testEnv, caller testUser, account string, from int64, to int64) (*camt053Statement, error) {
+	statement := &camt053Statement{}
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		result, err := env.contract.GenerateStatement(ctx, account, from, to)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(result), statement)
+	})
+	return statement, err
+}
+
+// seedStatementLedger bankAUser 在 2023 年 11 月至 2024 年 2 月间的记账：
+// 11 月 +500；12 月 -100；1 月 +50、-20（相隔 10 秒）；2 月 +7
+func seedStatementLedger(t *testing.T, env *testEnv) {
+	t.Helper()
+	env.initialize()
+	_, err := env.invoke(bankAAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.OpenAccount(ctx, bankAUser.id, accountTypeIndividual)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+	_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.Mint(ctx, 1000)
+	})
+	if err != nil {
+		t.Fatalf("Mint failed: %v", err)
+	}
+
+	transfer(t, env, centralBankAdmin, bankAUser, 500)
+	env.ledger.now = december2023 + 3600
+	transfer(t, env, bankAUser, centralBankAdmin, 100)
+	env.ledger.now = january2024 + 3600
+	transfer(t, env, centralBankAdmin, bankAUser, 50)
+	transfer(t, env, bankAUser, centralBankAdmin, 20)
+	env.ledger.now = february2024 + 3600
+	transfer(t, env, centralBankAdmin, bankAUser, 7)
+}
+
+func TestGenerateStatementBalances(t *testing.T) {
+	env := newTestEnv(t)
+	seedStatementLedger(t, env)
+
+	tests := []struct {
+		name        string
+		from        int64
+		to          int64
+		wantOpening string
+		wantClosing string
+		wantEntries int
+	}{
+		{name: "two months", from: december2023, to: february2024 - 1, wantOpening: "5.00", wantClosing: "4.30", wantEntries: 3},
+		{name: "starts mid-month after an entry", from: december2023 + 7200, to: january2024 + 3615, wantOpening: "4.00", wantClosing: "4.50", wantEntries: 1},
+		{name: "no entries in period", from: december2023 + 7200, to: january2024 - 1, wantOpening: "4.00", wantClosing: "4.00"},
+		{name: "until now", from: january2024, wantOpening: "4.00", wantClosing: "4.37", wantEntries: 3},
+		{name: "before the first booking", from: 1600000000, to: 1650000000, wantOpening: "0.00", wantClosing: "0.00"},
+		{name: "whole history", from: 0, wantOpening: "0.00", wantClosing: "4.37", wantEntries: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statement, err := generateStatement(env, bankAUser, bankAUser.id, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("GenerateStatement failed: %v", err)
+			}
+			opening, closing := statement.balances()
+			if opening != tt.wantOpening || closing != tt.wantClosing || len(statement.Stmt.Ntry) != tt.wantEntries {
+				t.Fatalf("got opening %s closing %s with %d entries, want %s %s %d", opening, closing, len(statement.Stmt.Ntry), tt.wantOpening, tt.wantClosing, tt.wantEntries)
+			}
+		})
+	}
+}
+
+func TestGenerateStatementRejects(t *testing.T) {
+	env := newTestEnv(t)
+	seedStatementLedger(t, env)
+
+	_, err := generateStatement(env, bankAUser, centralBankAdmin.id, december2023, 0)
+	if err == nil || !strings.Contains(err.Error(), "does not have permission") {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+	_, err = generateStatement(env, bankAUser, bankAUser.id, january2024, december2023)
+	if err == nil || !strings.Contains(err.Error(), "invalid statement period") {
+		t.Fatalf("expected an invalid period error, got %v", err)
+	}
+}
+
+// TestStatementBalanceHistoryMigration 早于月末余额的账本：迁移前由当前余额倒推，迁移补写后结果一致
+func TestStatementBalanceHistoryMigration(t *testing.T) {
+	env := newTestEnv(t)
+	seedStatementLedger(t, env)
+
+	private := env.ledger.private[defaultPrivateCollection]
+	for key := range private {
+		if strings.HasPrefix(key, "\x00"+accountMonthTxIndex+"\x00") || strings.HasPrefix(key, "\x00"+monthBalanceIndex+"\x00") {
+			delete(private, key)
+		}
+	}
+	for _, user := range []testUser{bankAUser, centralBankAdmin} {
+		var account UserBalance
+		if err := json.Unmarshal(private[balancePrefix+user.id], &account); err != nil {
+			t.Fatal(err)
+		}
+		account.FirstBookedAt = 0
+		accountBytes, _ := json.Marshal(account)
+		private[balancePrefix+user.id] = accountBytes
+	}
+	var state SchemaState
+	if err := json.Unmarshal(private[schemaStateKey], &state); err != nil {
+		t.Fatal(err)
+	}
+	delete(state.Steps, migrationBalanceHistory)
+	stateBytes, _ := json.Marshal(state)
+	private[schemaStateKey] = stateBytes
+
+	check := func(stage string) {
+		t.Helper()
+		statement, err := generateStatement(env, bankAUser, bankAUser.id, december2023+7200, january2024+3615)
+		if err != nil {
+			t.Fatalf("%s: GenerateStatement failed: %v", stage, err)
+		}
+		if opening, closing := statement.balances(); opening != "4.00" || closing != "4.50" || len(statement.Stmt.Ntry) != 1 {
+			t.Fatalf("%s: got opening %s closing %s with %d entries", stage, opening, closing, len(statement.Stmt.Ntry))
+		}
+	}
+
+	check("before migration")
+	result, err := runMigration(env, migrationBalanceHistory, 10)
+	if err != nil {
+		t.Fatalf("RunMigration failed: %v", err)
+	}
+	if result["done"] != true || result["migrated"] != float64(2) {
+		t.Fatalf("unexpected migration result %v", result)
+	}
+	check("after migration")
+}
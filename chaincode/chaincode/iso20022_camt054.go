@@ -0,0 +1,125 @@
+/*
+ISO 20022 camt.054 借贷记通知
+
+- 每笔记账交易生成借方与贷方通知，商户手续费单独通知手续费账户
+- 通知随记账事件的加密明细发给账户所属银行与央行；未启用事件隐私时只在开启 plaintextEventDetails 后随明文明细发出
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// emitTransferEvent 发出记账事件，各接收机构的加密明细附带本机构账户的 camt.054 通知
+func (s *SmartContract) emitTransferEvent(ctx contractapi.TransactionContextInterface, record *TransactionRecord) error {
+	eventType, ok := bookingEventTypes[record.TransactionType]
+	if !ok {
+		eventType = eventTypeTransfer
+	}
+
+	parties := map[string]string{"from": record.From, "to": record.To}
+	details := map[string]interface{}{
+		"value":           record.Amount,
+		"transactionType": record.TransactionType,
+	}
+	if record.Spender != "" && record.Spender != record.From {
+		parties["spender"] = record.Spender
+	}
+	if record.FeeAccount != "" {
+		parties["feeAccount"] = record.FeeAccount
+		details["fee"] = record.Fee
+		details["netAmount"] = record.NetAmount
+	}
+	if record.EndToEndID != "" {
+		details["endToEndId"] = record.EndToEndID
+	}
+	if record.Memo != "" {
+		details["memo"] = record.Memo
+	}
+	if record.OriginalTxID != "" {
+		details["originalTxId"] = record.OriginalTxID
+		details["returnReason"] = record.ReturnReason
+	}
+
+	return s.emitEvent(ctx, eventSpec{
+		Type:    eventType,
+		Parties: parties,
+		Details: details,
+		PerRecipient: func(org string) (map[string]interface{}, error) {
+			notification, err := s.buildCamt054(ctx, record, org)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"camt054": notification}, nil
+		},
+	})
+}
+
+// buildCamt054 构建单笔记账交易的 camt.054 借贷记通知
+// 付款方收到借记通知，收款方收到贷记通知，手续费账户收到手续费贷记通知；零地址（铸币/销毁）不产生通知
+// org 不为空时只包含该机构账户的通知，央行包含全部通知
+func (s *SmartContract) buildCamt054(ctx contractapi.TransactionContextInterface, record *TransactionRecord, org string) (map[string]interface{}, error) {
+	stub := ctx.GetStub()
+	channelID := stub.GetChannelID()
+
+	tokenSymbol, tokenDecimals, err := s.getTokenMeta(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get token meta failed: %v", err)
+	}
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	amountJSON := func(amount int) map[string]interface{} {
+		return map[string]interface{}{"Ccy": tokenSymbol, "_text": s.formatAmount(amount, tokenDecimals)}
+	}
+
+	notifications := []map[string]interface{}{}
+	addNotifications := func(account string, cdtDbtInd string) {
+		if account == "0x0" {
+			return
+		}
+		if org != "" && org != config.CentralBankDomain {
+			if accountOrg, _ := s.extractDomainFromClientID(account); accountOrg != org {
+				return
+			}
+		}
+		for _, entry := range recordEntries(record, account) {
+			if entry.CdtDbtInd != cdtDbtInd {
+				continue
+			}
+			suffix := cdtDbtInd
+			if entry.Charge {
+				suffix = "CHRG"
+			}
+			notifications = append(notifications, map[string]interface{}{
+				"Id":        fmt.Sprintf("%s@%s/%s", record.TxID, channelID, suffix),
+				"CreDtTm":   fmt.Sprintf("%d", record.Timestamp),
+				"Acct":      s.buildISOAccount(account, tokenSymbol),
+				"TxsSummry": s.buildISOTransactionsSummary([]accountEntry{entry}, tokenDecimals),
+				"Ntry":      []map[string]interface{}{s.buildISOEntry(entry, amountJSON)},
+			})
+		}
+	}
+	addNotifications(record.From, "DBIT")
+	addNotifications(record.To, "CRDT")
+	if record.FeeAccount != "" && record.Fee > 0 && record.FeeAccount != record.To {
+		// 手续费账户收到单独的贷记通知
+		addNotifications(record.FeeAccount, "CRDT")
+	}
+
+	return map[string]interface{}{
+		"_std": "ISO20022",
+		"_msg": "camt.054",
+		"GrpHdr": map[string]interface{}{
+			"MsgId":   fmt.Sprintf("%s@%s", record.TxID, channelID),
+			"CreDtTm": fmt.Sprintf("%d", record.Timestamp),
+		},
+		"Ntfctn": notifications,
+	}, nil
+}
@@ -0,0 +1,412 @@
+/*
+ISO 20022 pacs.002 支付状态报告
+
+- 将支付结果归类为 ISO 20022 交易状态代码与原因代码
+- ValidatePayment 在不提交的情况下返回 pacs.002 状态报告
+- 成功的转账存储 ACSC 状态记录，可按交易ID或 UETR 查询
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// 交易状态代码（ExternalPaymentTransactionStatus1Code）
+const (
+	paymentStatusAccepted  = "ACCP" // 校验通过（未提交）
+	paymentStatusSettled   = "ACSC" // 结算完成
+	paymentStatusRejected  = "RJCT" // 拒绝
+	paymentStatusKeyPrefix = "pmtsts_"
+)
+
+// 状态原因代码（ExternalStatusReason1Code）
+const (
+	reasonIncorrectAccount     = "AC01"
+	reasonInvalidCreditor      = "AC03"
+	reasonClosedAccount        = "AC04"
+	reasonTransactionForbidden = "AG01"
+	reasonNotAllowedAmount     = "AM02"
+	reasonNotAllowedCurrency   = "AM03"
+	reasonInsufficientFunds    = "AM04"
+	reasonDuplication          = "AM05"
+	reasonInvalidControlSum    = "AM10"
+	reasonInvalidAmount        = "AM12"
+	reasonAmountExceedsLimit   = "AM14"
+	reasonInvalidNumberOfTxs   = "AM18"
+	reasonInvalidFileFormat    = "FF01"
+	reasonNoMandate            = "MD01"
+	reasonAgentGenerated       = "MS03"
+	reasonBankIdentifier       = "RC01"
+)
+
+// paymentRejection 被拒绝的支付及其 ISO 20022 原因代码
+type paymentRejection struct {
+	ReasonCode string
+	Message    string
+}
+
+func (r *paymentRejection) Error() string {
+	return fmt.Sprintf("payment rejected (%s/%s): %s", paymentStatusRejected, r.ReasonCode, r.Message)
+}
+
+// rejectPayment 创建带原因代码的拒绝错误
+func rejectPayment(reasonCode string, format string, args ...interface{}) error {
+	return &paymentRejection{ReasonCode: reasonCode, Message: fmt.Sprintf(format, args...)}
+}
+
+// PaymentStatusRecord 支付状态记录
+type PaymentStatusRecord struct {
+	TxID       string `json:"txId"`
+	InstrID    string `json:"instrId"`
+	EndToEndID string `json:"endToEndId"`
+	UETR       string `json:"uetr"`
+	Status     string `json:"status"`
+	ReasonCode string `json:"reasonCode,omitempty"`
+	Debtor     string `json:"debtor"`
+	Creditor   string `json:"creditor"`
+	Amount     int    `json:"amount"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+// ValidatePayment 校验一笔支付能否成功执行，返回 pacs.002 状态报告，不修改账本
+// from 为空时表示调用者本人付款；否则按 TransferFrom 语义校验调用者的授权额度
+func (s *SmartContract) ValidatePayment(ctx contractapi.TransactionContextInterface, from string, recipient string, amount int) (string, error) {
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+	if from == "" {
+		from = callerID
+	}
+
+	pmtID, err := defaultPaymentIdentification(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	record := &PaymentStatusRecord{
+		TxID:       ctx.GetStub().GetTxID(),
+		InstrID:    pmtID.InstrID,
+		EndToEndID: pmtID.EndToEndID,
+		UETR:       pmtID.UETR,
+		Status:     paymentStatusAccepted,
+		Debtor:     from,
+		Creditor:   recipient,
+		Amount:     amount,
+	}
+
+	// 报告保留调用方提供的账户引用，别名无法解析时按无效账户拒绝
+	var validationErr error
+	debtorID, err := s.resolveAccount(ctx, from)
+	if err != nil {
+		validationErr = rejectPayment(reasonIncorrectAccount, "%v", err)
+	}
+	creditorID, err := s.resolveAccount(ctx, recipient)
+	if err != nil && validationErr == nil {
+		validationErr = rejectPayment(reasonInvalidCreditor, "%v", err)
+	}
+	if validationErr == nil {
+		validationErr = s.validatePayment(ctx, callerID, debtorID, creditorID, amount)
+	}
+	var rejection *paymentRejection
+	if errors.As(validationErr, &rejection) {
+		record.Status = paymentStatusRejected
+		record.ReasonCode = rejection.ReasonCode
+	} else if validationErr != nil {
+		return "", validationErr
+	}
+
+	report, err := s.buildPacs002(ctx, record, rejection)
+	if err != nil {
+		return "", err
+	}
+
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal pacs.002: %v", err)
+	}
+
+	return string(reportJSON), nil
+}
+
+// validatePayment 检查合约状态后校验支付
+func (s *SmartContract) validatePayment(ctx contractapi.TransactionContextInterface, spender string, from string, to string, amount int) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return rejectPayment(reasonAgentGenerated, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	return s.assessTransfer(ctx, spender, from, to, amount)
+}
+
+// assessTransfer 在执行转账之前校验支付，失败时返回 *paymentRejection
+// spender 与 from 不同时按 TransferFrom 语义校验授权额度
+func (s *SmartContract) assessTransfer(ctx contractapi.TransactionContextInterface, spender string, from string, to string, amount int) error {
+	if amount <= 0 {
+		return rejectPayment(reasonInvalidAmount, "transfer amount must be positive")
+	}
+	if from == "" {
+		return rejectPayment(reasonIncorrectAccount, "debtor account is empty")
+	}
+	if to == "" || to == "0x0" {
+		return rejectPayment(reasonInvalidCreditor, "creditor account %q is invalid", to)
+	}
+
+	// 代扣场景校验授权额度
+	if spender != from {
+		allowance, err := s.getAllowanceRecord(ctx, from, spender)
+		if err != nil {
+			return err
+		}
+		timestamp, err := ctx.GetStub().GetTxTimestamp()
+		if err != nil {
+			return fmt.Errorf("failed to get transaction timestamp: %v", err)
+		}
+		if allowance.expired(timestamp.Seconds) {
+			return rejectPayment(reasonTransactionForbidden, "allowance for spender %s expired at %d", spender, allowance.ExpiresAt)
+		}
+		if allowance.Value < amount {
+			return rejectPayment(reasonTransactionForbidden, "spender does not have enough allowance for transfer")
+		}
+	}
+
+	fromAccount, err := s.getUserAccountInfo(ctx, from)
+	if err != nil {
+		return fmt.Errorf("failed to read sender account %s from private collection: %v", from, err)
+	}
+	switch fromAccount.Status {
+	case accountStatusActive:
+	case accountStatusClosed:
+		return rejectPayment(reasonClosedAccount, "sender account %s is closed", from)
+	default:
+		return rejectPayment(reasonIncorrectAccount, "sender account %s has not been opened", from)
+	}
+	if fromAccount.Balance < amount {
+		return rejectPayment(reasonInsufficientFunds, "sender account %s has insufficient funds", from)
+	}
+
+	toAccount, err := s.getUserAccountInfo(ctx, to)
+	if err != nil {
+		return fmt.Errorf("failed to read recipient account %s from private collection: %v", to, err)
+	}
+	switch toAccount.Status {
+	case accountStatusActive:
+	case accountStatusClosed:
+		return rejectPayment(reasonClosedAccount, "recipient account %s is closed", to)
+	default:
+		return rejectPayment(reasonInvalidCreditor, "recipient account %s has not been opened", to)
+	}
+	toBalance := toAccount.Balance
+	if _, err := add(toBalance, amount); err != nil {
+		return rejectPayment(reasonNotAllowedAmount, "%v", err)
+	}
+
+	return nil
+}
+
+// recordPaymentSettled 存储 ACSC 状态记录以及 UETR 到交易ID的映射
+func (s *SmartContract) recordPaymentSettled(ctx contractapi.TransactionContextInterface, pmtID *paymentIdentification, debtor string, creditor string, amount int) error {
+	stub := ctx.GetStub()
+	txID := stub.GetTxID()
+	timestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	record := PaymentStatusRecord{
+		TxID:       txID,
+		InstrID:    pmtID.InstrID,
+		EndToEndID: pmtID.EndToEndID,
+		UETR:       pmtID.UETR,
+		Status:     paymentStatusSettled,
+		Debtor:     debtor,
+		Creditor:   creditor,
+		Amount:     amount,
+		Timestamp:  timestamp.Seconds,
+	}
+
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payment status: %v", err)
+	}
+
+	err = stub.PutPrivateData(privateCollection(ctx), paymentStatusKeyPrefix+txID, recordBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store payment status: %v", err)
+	}
+
+	// 记录 UETR 到交易ID的映射，用于状态查询与重复报文检测
+	err = stub.PutPrivateData(privateCollection(ctx), uetrPrefix+pmtID.UETR, []byte(txID))
+	if err != nil {
+		return fmt.Errorf("failed to store UETR index: %v", err)
+	}
+
+	return nil
+}
+
+// GetPaymentStatus 按交易ID或 UETR 查询已结算支付的 pacs.002 状态报告
+// 只有付款方、收款方及有权查看其交易的银行管理员和央行可以查询
+func (s *SmartContract) GetPaymentStatus(ctx contractapi.TransactionContextInterface, reference string) (string, error) {
+	// 检查合约初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+
+	record, err := s.getPaymentStatusRecord(ctx, reference)
+	if err != nil {
+		return "", err
+	}
+
+	// 检查权限
+	hasPermission, err := s.checkTransactionQueryPermission(ctx, callerID, record.Debtor)
+	if err != nil {
+		return "", fmt.Errorf("failed to check permission: %v", err)
+	}
+	if !hasPermission {
+		hasPermission, err = s.checkTransactionQueryPermission(ctx, callerID, record.Creditor)
+		if err != nil {
+			return "", fmt.Errorf("failed to check permission: %v", err)
+		}
+	}
+	if !hasPermission {
+		return "", fmt.Errorf("caller does not have permission to view payment %s", reference)
+	}
+
+	report, err := s.buildPacs002(ctx, record, nil)
+	if err != nil {
+		return "", err
+	}
+
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal pacs.002: %v", err)
+	}
+
+	return string(reportJSON), nil
+}
+
+// getPaymentStatusRecord 按交易ID或 UETR 读取支付状态记录
+func (s *SmartContract) getPaymentStatusRecord(ctx contractapi.TransactionContextInterface, reference string) (*PaymentStatusRecord, error) {
+	stub := ctx.GetStub()
+
+	txID := reference
+	recordBytes, err := stub.GetPrivateData(privateCollection(ctx), paymentStatusKeyPrefix+txID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payment status: %v", err)
+	}
+
+	// 按 UETR 查找交易ID
+	if recordBytes == nil {
+		txIDBytes, err := stub.GetPrivateData(privateCollection(ctx), uetrPrefix+reference)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read UETR index: %v", err)
+		}
+		if txIDBytes != nil {
+			txID = string(txIDBytes)
+			recordBytes, err = stub.GetPrivateData(privateCollection(ctx), paymentStatusKeyPrefix+txID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read payment status: %v", err)
+			}
+		}
+	}
+
+	if recordBytes == nil {
+		return nil, fmt.Errorf("payment %s not found", reference)
+	}
+
+	var record PaymentStatusRecord
+	if err := json.Unmarshal(recordBytes, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payment status: %v", err)
+	}
+
+	return &record, nil
+}
+
+// buildPacs002 构建 pacs.002 状态报告
+func (s *SmartContract) buildPacs002(ctx contractapi.TransactionContextInterface, record *PaymentStatusRecord, rejection *paymentRejection) (map[string]interface{}, error) {
+	stub := ctx.GetStub()
+	txID := stub.GetTxID()
+	channelID := stub.GetChannelID()
+	ts, err := stub.GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	// 合约未初始化时没有代币元数据，ValidatePayment 的拒绝报告按最小单位输出金额、不带币种
+	tokenSymbol, tokenDecimals := "", 0
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if initialized {
+		tokenSymbol, tokenDecimals, err = s.getTokenMeta(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get token meta failed: %v", err)
+		}
+	}
+
+	txInfAndSts := map[string]interface{}{
+		"StsId":           txID,
+		"OrgnlInstrId":    record.InstrID,
+		"OrgnlEndToEndId": record.EndToEndID,
+		"OrgnlTxId":       record.TxID,
+		"OrgnlUETR":       record.UETR,
+		"TxSts":           record.Status,
+		"OrgnlTxRef": map[string]interface{}{
+			"IntrBkSttlmAmt": map[string]interface{}{
+				"Ccy": tokenSymbol, "_text": s.formatAmount(record.Amount, tokenDecimals),
+			},
+			"DbtrAcct": map[string]interface{}{
+				"Id": map[string]interface{}{"Othr": map[string]interface{}{"Id": record.Debtor}},
+			},
+			"CdtrAcct": map[string]interface{}{
+				"Id": map[string]interface{}{"Othr": map[string]interface{}{"Id": record.Creditor}},
+			},
+		},
+	}
+	if record.Status == paymentStatusSettled {
+		txInfAndSts["AccptncDtTm"] = fmt.Sprintf("%d", record.Timestamp)
+	}
+	if record.ReasonCode != "" {
+		stsRsnInf := map[string]interface{}{
+			"Rsn": map[string]interface{}{"Cd": record.ReasonCode},
+		}
+		if rejection != nil {
+			stsRsnInf["AddtlInf"] = rejection.Message
+		}
+		txInfAndSts["StsRsnInf"] = stsRsnInf
+	}
+
+	return map[string]interface{}{
+		"_std": "ISO20022",
+		"_msg": "pacs.002",
+		"GrpHdr": map[string]interface{}{
+			"MsgId":   fmt.Sprintf("%s@%s", txID, channelID),
+			"CreDtTm": fmt.Sprintf("%d", ts.Seconds),
+		},
+		"OrgnlGrpInfAndSts": map[string]interface{}{
+			"OrgnlMsgId":   fmt.Sprintf("%s@%s", record.TxID, channelID),
+			"OrgnlMsgNmId": "pacs.008",
+		},
+		"TxInfAndSts": txInfAndSts,
+	}, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// pacs002Report 状态报告中测试关心的字段
+type pacs002Report struct {
+	TxInfAndSts struct {
+		TxSts     string
+		StsRsnInf struct {
+			Rsn struct {
+				Cd string
+			}
+		}
+		OrgnlTxRef struct {
+			IntrBkSttlmAmt struct {
+				Ccy  string
+				Text string `json:"_text"`
+			}
+		}
+	}
+}
+
+func validatePayment(env *testEnv, caller testUser, from string, recipient string, amount int) (*pacs002Report, error) {
+	report := &pacs002Report{}
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		result, err := env.contract.ValidatePayment(ctx, from, recipient, amount)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(result), report)
+	})
+	return report, err
+}
+
+func TestValidatePaymentBeforeInitialize(t *testing.T) {
+	env := newTestEnv(t)
+
+	report, err := validatePayment(env, bankAUser, "", centralBankAdmin.id, 125)
+	if err != nil {
+		t.Fatalf("ValidatePayment must return a report before Initialize: %v", err)
+	}
+	status := report.TxInfAndSts
+	if status.TxSts != paymentStatusRejected || status.StsRsnInf.Rsn.Cd != reasonAgentGenerated {
+		t.Fatalf("got %s/%s, want %s/%s", status.TxSts, status.StsRsnInf.Rsn.Cd, paymentStatusRejected, reasonAgentGenerated)
+	}
+	if status.OrgnlTxRef.IntrBkSttlmAmt.Ccy != "" || status.OrgnlTxRef.IntrBkSttlmAmt.Text != "125" {
+		t.Fatalf("unexpected amount %+v", status.OrgnlTxRef.IntrBkSttlmAmt)
+	}
+}
+
+func TestValidatePayment(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 100)
+	bankBUser := newTestUser("User1", "b.example.com", "BMSP")
+
+	tests := []struct {
+		name       string
+		caller     testUser
+		from       string
+		recipient  string
+		amount     int
+		wantStatus string
+		wantReason string
+	}{
+		{name: "accepted", caller: bankAUser, recipient: centralBankAdmin.id, amount: 100, wantStatus: paymentStatusAccepted},
+		{name: "insufficient funds", caller: bankAUser, recipient: centralBankAdmin.id, amount: 101, wantStatus: paymentStatusRejected, wantReason: reasonInsufficientFunds},
+		{name: "non-positive amount", caller: bankAUser, recipient: centralBankAdmin.id, amount: 0, wantStatus: paymentStatusRejected, wantReason: reasonInvalidAmount},
+		{name: "creditor not opened", caller: bankAUser, recipient: bankBUser.id, amount: 10, wantStatus: paymentStatusRejected, wantReason: reasonInvalidCreditor},
+		{name: "unknown alias", caller: bankAUser, recipient: "@nobody", amount: 10, wantStatus: paymentStatusRejected, wantReason: reasonInvalidCreditor},
+		{name: "spender without allowance", caller: bankBUser, from: bankAUser.id, recipient: centralBankAdmin.id, amount: 10, wantStatus: paymentStatusRejected, wantReason: reasonTransactionForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := validatePayment(env, tt.caller, tt.from, tt.recipient, tt.amount)
+			if err != nil {
+				t.Fatalf("ValidatePayment failed: %v", err)
+			}
+			status := report.TxInfAndSts
+			if status.TxSts != tt.wantStatus || status.StsRsnInf.Rsn.Cd != tt.wantReason {
+				t.Fatalf("got %s/%s, want %s/%s", status.TxSts, status.StsRsnInf.Rsn.Cd, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestGetPaymentStatus(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 0)
+	settled := transfer(t, env, centralBankAdmin, bankAUser, 40)
+
+	// 校验不写账本
+	stub, err := env.invoke(bankAUser, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.ValidatePayment(ctx, "", centralBankAdmin.id, 10)
+		return err
+	})
+	if err != nil || len(stub.writes)+len(stub.pwrites) != 0 {
+		t.Fatalf("ValidatePayment must not write the ledger: %v %v %v", err, stub.writes, stub.pwrites)
+	}
+
+	paymentStatus := func(caller testUser, reference string) (*pacs002Report, error) {
+		report := &pacs002Report{}
+		_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+			result, err := env.contract.GetPaymentStatus(ctx, reference)
+			if err != nil {
+				return err
+			}
+			return json.Unmarshal([]byte(result), report)
+		})
+		return report, err
+	}
+	report, err := paymentStatus(bankAUser, settled.txID)
+	if err != nil {
+		t.Fatalf("GetPaymentStatus failed: %v", err)
+	}
+	if report.TxInfAndSts.TxSts != paymentStatusSettled || report.TxInfAndSts.OrgnlTxRef.IntrBkSttlmAmt.Text != "0.40" {
+		t.Fatalf("unexpected status %+v", report.TxInfAndSts)
+	}
+
+	outsider := newTestUser("User1", "b.example.com", "BMSP")
+	if _, err := paymentStatus(outsider, settled.txID); err == nil || !strings.Contains(err.Error(), "does not have permission to view payment") {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+	if _, err := paymentStatus(bankAUser, "missing"); err == nil || !strings.Contains(err.Error(), "payment missing not found") {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+/*
+ISO 20022 pacs.004 支付退汇
+
+- 央行可对已完成的转账全额或部分退汇
+- 资金从原收款方退回原付款方，收款方余额不足时拒绝退汇
+- 退汇记录与原交易记录相互关联
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// returnReasonCodes 支持的 ISO 20022 退汇原因代码（ExternalReturnReason1Code）
+var returnReasonCodes = map[string]string{
+	"AC01": "IncorrectAccountNumber",
+	"AC03": "InvalidCreditorAccountNumber",
+	"AC04": "ClosedAccountNumber",
+	"AC06": "BlockedAccount",
+	"AG01": "TransactionForbidden",
+	"AM05": "Duplication",
+	"AM09": "WrongAmount",
+	"BE04": "MissingCreditorAddress",
+	"CUST": "RequestedByCustomer",
+	"DUPL": "DuplicatePayment",
+	"FOCR": "FollowingCancellationRequest",
+	"FR01": "Fraud",
+	"MD06": "RefundRequestByEndCustomer",
+	"MS02": "NotSpecifiedReasonCustomerGenerated",
+	"MS03": "NotSpecifiedReasonAgentGenerated",
+	"RR04": "RegulatoryReason",
+}
+
+// ReturnPayment 央行对已完成的转账执行退汇，返回 pacs.004 报文
+// amount <= 0 表示退回原交易剩余的全部金额
+func (s *SmartContract) ReturnPayment(ctx contractapi.TransactionContextInterface, originalTxID string, reasonCode string, amount int) (string, error) {
+	// 检查合约初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", errors.New("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	// 仅央行可以退汇
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	isCentralBank, err := isCentralBankMSP(ctx, clientMSPID)
+	if err != nil {
+		return "", err
+	}
+	if !isCentralBank {
+		return "", errors.New("client is not authorized to return payments")
+	}
+
+	reasonName, ok := returnReasonCodes[reasonCode]
+	if !ok {
+		return "", fmt.Errorf("unsupported return reason code %s", reasonCode)
+	}
+
+	// 读取原交易
+	original, err := s.getTransactionRecord(ctx, originalTxID)
+	if err != nil {
+		return "", err
+	}
+	if original == nil {
+		return "", fmt.Errorf("original transaction %s does not exist", originalTxID)
+	}
+	if original.TransactionType != "transfer" && original.TransactionType != "transferFrom" && original.TransactionType != "directDebit" {
+		return "", fmt.Errorf("transaction %s of type %s cannot be returned", originalTxID, original.TransactionType)
+	}
+
+	// 计算可退汇金额
+	remaining, err := sub(original.Amount, original.ReturnedAmount)
+	if err != nil {
+		return "", err
+	}
+	if remaining <= 0 {
+		return "", fmt.Errorf("transaction %s has already been fully returned", originalTxID)
+	}
+	if amount <= 0 {
+		amount = remaining
+	}
+	if amount > remaining {
+		return "", fmt.Errorf("return amount %d exceeds returnable amount %d of transaction %s", amount, remaining, originalTxID)
+	}
+
+	// 原收款方余额不足时拒绝退汇
+	beneficiary := original.To
+	payer := original.From
+	beneficiaryBalance, err := s.getBalanceFromPrivateCollection(ctx, beneficiary)
+	if err != nil {
+		return "", fmt.Errorf("failed to read beneficiary account %s from private collection: %v", beneficiary, err)
+	}
+	if beneficiaryBalance < amount {
+		return "", fmt.Errorf("beneficiary account %s has insufficient funds for return: balance %d, return amount %d", beneficiary, beneficiaryBalance, amount)
+	}
+
+	// 资金退回原付款方
+	err = s.transferHelperPrivate(ctx, beneficiary, payer, amount)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute return: %v", err)
+	}
+
+	txID := ctx.GetStub().GetTxID()
+
+	// 更新原交易的退汇信息
+	original.ReturnedAmount, err = add(original.ReturnedAmount, amount)
+	if err != nil {
+		return "", err
+	}
+	original.ReturnTxIDs = append(original.ReturnTxIDs, txID)
+
+	err = s.putTransactionRecord(ctx, original)
+	if err != nil {
+		return "", fmt.Errorf("failed to update original transaction: %v", err)
+	}
+
+	// 创建退汇交易记录
+	record, err := s.newTransactionRecord(ctx, "return", beneficiary, payer, amount)
+	if err != nil {
+		return "", err
+	}
+	if original.ToMSP != "" {
+		record.FromMSP = original.ToMSP
+	}
+	if original.FromMSP != "" {
+		record.ToMSP = original.FromMSP
+	}
+	record.OriginalTxID = originalTxID
+	record.ReturnReason = reasonCode
+
+	// 央行存储交易记录
+	err = s.bookTransactionRecord(ctx, record)
+	if err != nil {
+		return "", err
+	}
+
+	// 发出 Transfer 事件（附带 camt.054 借贷记通知）
+	err = s.emitTransferEvent(ctx, record)
+	if err != nil {
+		return "", err
+	}
+
+	// 构建 pacs.004 报文
+	pacs004, err := s.buildPacs004(ctx, original, amount, reasonCode, reasonName)
+	if err != nil {
+		return "", err
+	}
+
+	pacs004JSON, err := json.Marshal(pacs004)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal pacs.004: %v", err)
+	}
+
+	log.Printf("payment %s returned: %s -> %s, amount: %d, reason: %s, txID: %s", originalTxID, beneficiary, payer, amount, reasonCode, txID)
+	log.Printf("ISO20022 PACS004: %s", string(pacs004JSON))
+
+	return string(pacs004JSON), nil
+}
+
+// buildPacs004 构建 pacs.004 退汇报文
+func (s *SmartContract) buildPacs004(ctx contractapi.TransactionContextInterface, original *TransactionRecord, amount int, reasonCode string, reasonName string) (map[string]interface{}, error) {
+	stub := ctx.GetStub()
+	txID := stub.GetTxID()
+	channelID := stub.GetChannelID()
+	ts, err := stub.GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	tokenSymbol, tokenDecimals, err := s.getTokenMeta(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get token meta failed: %v", err)
+	}
+	amountStr := s.formatAmount(amount, tokenDecimals)
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fromOrg, _ := s.extractDomainFromClientID(original.From)
+	toOrg, _ := s.extractDomainFromClientID(original.To)
+
+	// 原交易标识：早期交易未记录 EndToEndId 时使用原交易ID
+	originalEndToEndID := original.EndToEndID
+	if originalEndToEndID == "" {
+		originalEndToEndID = original.TxID
+	}
+
+	txInf := map[string]interface{}{
+		"RtrId": txID,
+		"OrgnlGrpInf": map[string]interface{}{
+			"OrgnlMsgId":   fmt.Sprintf("%s@%s", original.TxID, channelID),
+			"OrgnlMsgNmId": "pacs.008",
+		},
+		"OrgnlInstrId":    original.TxID,
+		"OrgnlEndToEndId": originalEndToEndID,
+		"OrgnlTxId":       original.TxID,
+		"OrgnlIntrBkSttlmAmt": map[string]interface{}{
+			"Ccy": tokenSymbol, "_text": s.formatAmount(original.Amount, tokenDecimals),
+		},
+		"RtrdIntrBkSttlmAmt": map[string]interface{}{"Ccy": tokenSymbol, "_text": amountStr},
+		"RtrRsnInf": map[string]interface{}{
+			"Rsn":      map[string]interface{}{"Cd": reasonCode},
+			"AddtlInf": reasonName,
+		},
+		"OrgnlTxRef": map[string]interface{}{
+			"Dbtr": map[string]interface{}{"Nm": original.From},
+			"DbtrAcct": map[string]interface{}{
+				"Id": map[string]interface{}{"Othr": map[string]interface{}{"Id": original.From}},
+			},
+			"DbtrAgt": map[string]interface{}{
+				"FinInstnId": map[string]interface{}{"Othr": map[string]interface{}{"Id": fromOrg}},
+			},
+			"CdtrAgt": map[string]interface{}{
+				"FinInstnId": map[string]interface{}{"Othr": map[string]interface{}{"Id": toOrg}},
+			},
+			"Cdtr": map[string]interface{}{"Nm": original.To},
+			"CdtrAcct": map[string]interface{}{
+				"Id": map[string]interface{}{"Othr": map[string]interface{}{"Id": original.To}},
+			},
+		},
+	}
+	if original.UETR != "" {
+		txInf["OrgnlUETR"] = original.UETR
+	}
+
+	return map[string]interface{}{
+		"_std": "ISO20022",
+		"_msg": "pacs.004",
+		"GrpHdr": map[string]interface{}{
+			"MsgId":   fmt.Sprintf("%s@%s", txID, channelID),
+			"CreDtTm": fmt.Sprintf("%d", ts.Seconds),
+			"NbOfTxs": 1,
+			"InstgAgt": map[string]interface{}{
+				"FinInstnId": map[string]interface{}{
+					"Othr": map[string]interface{}{"Id": config.CentralBankMSP},
+				},
+			},
+		},
+		"TxInf": txInf,
+	}, nil
+}
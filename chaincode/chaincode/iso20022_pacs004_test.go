@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func returnPayment(env *testEnv, caller testUser, originalTxID string, reasonCode string, amount int) (map[string]interface{}, error) {
+	var pacs004 map[string]interface{}
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		result, err := env.contract.ReturnPayment(ctx, originalTxID, reasonCode, amount)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(result), &pacs004)
+	})
+	return pacs004, err
+}
+
+func getTransactionRecord(t *testing.T, env *testEnv, txID string) TransactionRecord {
+	t.Helper()
+	var record TransactionRecord
+	_, err := env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		result, err := env.contract.GetTransactionRecord(ctx, txID)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(result), &record)
+	})
+	if err != nil {
+		t.Fatalf("GetTransactionRecord failed: %v", err)
+	}
+	return record
+}
+
+func TestReturnPayment(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 0)
+	original := transfer(t, env, centralBankAdmin, bankAUser, 100).txID
+
+	// 部分退汇：从原收款方扣款，需其所属银行授权
+	authorizeDebit(t, env, bankAUser)
+	pacs004, err := returnPayment(env, centralBankAdmin, original, "AC04", 30)
+	if err != nil {
+		t.Fatalf("ReturnPayment failed: %v", err)
+	}
+	txInf := pacs004["TxInf"].(map[string]interface{})
+	returned := txInf["RtrdIntrBkSttlmAmt"].(map[string]interface{})
+	reason := txInf["RtrRsnInf"].(map[string]interface{})
+	if pacs004["_msg"] != "pacs.004" || txInf["OrgnlTxId"] != original || returned["_text"] != "0.30" || reason["AddtlInf"] != "ClosedAccountNumber" {
+		t.Fatalf("unexpected pacs.004 %v", pacs004)
+	}
+	if got := balanceOf(t, env, bankAUser); got != 70 {
+		t.Fatalf("beneficiary balance = %d, want 70", got)
+	}
+	record := getTransactionRecord(t, env, original)
+	if record.ReturnedAmount != 30 || len(record.ReturnTxIDs) != 1 {
+		t.Fatalf("original record not linked to the return: %+v", record)
+	}
+	returnRecord := getTransactionRecord(t, env, record.ReturnTxIDs[0])
+	if returnRecord.TransactionType != "return" || returnRecord.OriginalTxID != original || returnRecord.ReturnReason != "AC04" {
+		t.Fatalf("unexpected return record %+v", returnRecord)
+	}
+
+	// 剩余金额全部退回后不能再退
+	authorizeDebit(t, env, bankAUser)
+	if _, err := returnPayment(env, centralBankAdmin, original, "CUST", 0); err != nil {
+		t.Fatalf("ReturnPayment of the remainder failed: %v", err)
+	}
+	if _, err := returnPayment(env, centralBankAdmin, original, "CUST", 0); err == nil || !strings.Contains(err.Error(), "already been fully returned") {
+		t.Fatalf("expected a fully returned error, got %v", err)
+	}
+}
+
+func TestReturnPaymentRejects(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 0)
+	original := transfer(t, env, centralBankAdmin, bankAUser, 100).txID
+
+	tests := []struct {
+		name    string
+		caller  testUser
+		txID    string
+		reason  string
+		amount  int
+		wantErr string
+	}{
+		{name: "not the central bank", caller: bankAAdmin, txID: original, reason: "AC04", wantErr: "not authorized to return payments"},
+		{name: "unknown reason code", caller: centralBankAdmin, txID: original, reason: "XX99", wantErr: "unsupported return reason code XX99"},
+		{name: "unknown transaction", caller: centralBankAdmin, txID: "missing", reason: "AC04", wantErr: "does not exist"},
+		{name: "amount above the original", caller: centralBankAdmin, txID: original, reason: "AC04", amount: 101, wantErr: "exceeds returnable amount 100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := returnPayment(env, tt.caller, tt.txID, tt.reason, tt.amount); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,389 @@
+/*
+ISO 20022 pacs.008 入站贷记转账
+
+- 支持 XML 报文（FIToFICstmrCdtTrf）与日志中使用的 JSON 映射
+- 校验债务人、债权人、金额与币种后，沿用 Transfer 的执行路径
+- 保留指示方提供的 EndToEndId 与 UETR
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// uetrPattern UETR 必须是小写的 UUID v4
+var uetrPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// maxISOTextLength ISO 20022 Max35Text 长度限制
+const maxISOTextLength = 35
+
+// maxMemoLength 非结构化附言（Ustrd）Max140Text 长度限制
+const maxMemoLength = 140
+
+// isoAmount 带币种的金额，XML 中为 <Amt Ccy="...">1.00</Amt>，JSON 中为 {"Ccy":"...","_text":"1.00"}
+type isoAmount struct {
+	Ccy   string `xml:"Ccy,attr" json:"Ccy"`
+	Value string `xml:",chardata" json:"_text"`
+}
+
+// isoOtherID 通用标识 <Othr><Id>...</Id></Othr>
+type isoOtherID struct {
+	Othr struct {
+		Id string `xml:"Id" json:"Id"`
+	} `xml:"Othr" json:"Othr"`
+}
+
+// isoAccount 账户标识 <Id><Othr><Id>...</Id></Othr></Id>
+type isoAccount struct {
+	Id isoOtherID `xml:"Id" json:"Id"`
+}
+
+// isoAgent 金融机构标识 <FinInstnId><Othr><Id>...</Id></Othr></FinInstnId>
+type isoAgent struct {
+	FinInstnId isoOtherID `xml:"FinInstnId" json:"FinInstnId"`
+}
+
+// pacs008GroupHeader pacs.008 组头
+type pacs008GroupHeader struct {
+	MsgId    string      `xml:"MsgId" json:"MsgId"`
+	CreDtTm  string      `xml:"CreDtTm" json:"CreDtTm"`
+	NbOfTxs  json.Number `xml:"NbOfTxs" json:"NbOfTxs"`
+	CtrlSum  string      `xml:"CtrlSum" json:"CtrlSum"`
+	InstgAgt *isoAgent   `xml:"InstgAgt" json:"InstgAgt"`
+}
+
+// pacs008Transaction pacs.008 单笔贷记转账信息（CdtTrfTxInf）
+type pacs008Transaction struct {
+	PmtId struct {
+		InstrId    string `xml:"InstrId" json:"InstrId"`
+		EndToEndId string `xml:"EndToEndId" json:"EndToEndId"`
+		UETR       string `xml:"UETR" json:"UETR"`
+	} `xml:"PmtId" json:"PmtId"`
+	IntrBkSttlmAmt *isoAmount `xml:"IntrBkSttlmAmt" json:"IntrBkSttlmAmt"`
+	Amt            struct {
+		InstdAmt *isoAmount `xml:"InstdAmt" json:"InstdAmt"`
+	} `xml:"Amt" json:"Amt"`
+	InstdAmt *isoAmount `xml:"InstdAmt" json:"InstdAmt"`
+	Dbtr     struct {
+		Nm string `xml:"Nm" json:"Nm"`
+	} `xml:"Dbtr" json:"Dbtr"`
+	DbtrAcct isoAccount `xml:"DbtrAcct" json:"DbtrAcct"`
+	DbtrAgt  *isoAgent  `xml:"DbtrAgt" json:"DbtrAgt"`
+	CdtrAgt  *isoAgent  `xml:"CdtrAgt" json:"CdtrAgt"`
+	Cdtr     struct {
+		Nm string `xml:"Nm" json:"Nm"`
+	} `xml:"Cdtr" json:"Cdtr"`
+	CdtrAcct isoAccount `xml:"CdtrAcct" json:"CdtrAcct"`
+	RmtInf   struct {
+		Ustrd string `xml:"Ustrd" json:"Ustrd"`
+	} `xml:"RmtInf" json:"RmtInf"`
+}
+
+// pacs008XMLDocument XML 报文根节点
+type pacs008XMLDocument struct {
+	XMLName           xml.Name `xml:"Document"`
+	FIToFICstmrCdtTrf struct {
+		GrpHdr      pacs008GroupHeader   `xml:"GrpHdr"`
+		CdtTrfTxInf []pacs008Transaction `xml:"CdtTrfTxInf"`
+	} `xml:"FIToFICstmrCdtTrf"`
+}
+
+// pacs008JSONDocument 日志中使用的 JSON 映射
+type pacs008JSONDocument struct {
+	Std         string              `json:"_std"`
+	Msg         string              `json:"_msg"`
+	GrpHdr      pacs008GroupHeader  `json:"GrpHdr"`
+	CdtTrfTxInf *pacs008Transaction `json:"CdtTrfTxInf"`
+}
+
+// pacs008Instruction 解析后的支付指令
+type pacs008Instruction struct {
+	MsgID       string
+	NbOfTxs     string
+	CtrlSum     string
+	Instructing string
+	Tx          pacs008Transaction
+}
+
+// SubmitPacs008 接收 pacs.008 贷记转账报文并执行转账
+// document 可以是 XML 报文，也可以是 ISO 20022 日志中使用的 JSON 映射
+// 债务人账户必须是调用者本人，币种必须与代币符号一致
+func (s *SmartContract) SubmitPacs008(ctx contractapi.TransactionContextInterface, document string) (string, error) {
+	// 检查合约初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	// 获取发送方信息
+	sender, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get sender ID: %v", err)
+	}
+
+	senderMSP, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get sender MSP: %v", err)
+	}
+
+	instruction, err := parsePacs008(document)
+	if err != nil {
+		return "", rejectPayment(reasonInvalidFileFormat, "invalid pacs.008 document: %v", err)
+	}
+
+	// 校验失败时返回带 ISO 20022 原因代码的错误
+	recipient, amount, pmtID, err := s.validatePacs008(ctx, instruction, sender, senderMSP)
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("pacs.008 %s accepted: EndToEndId=%s, UETR=%s, amount=%d", instruction.MsgID, pmtID.EndToEndID, pmtID.UETR, amount)
+
+	pmtID, err = s.transfer(ctx, sender, recipient, amount, pmtID)
+	if err != nil {
+		return "", err
+	}
+
+	result := map[string]interface{}{
+		"txId":       ctx.GetStub().GetTxID(),
+		"msgId":      instruction.MsgID,
+		"instrId":    pmtID.InstrID,
+		"endToEndId": pmtID.EndToEndID,
+		"uetr":       pmtID.UETR,
+		"from":       sender,
+		"to":         recipient,
+		"amount":     amount,
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %v", err)
+	}
+
+	return string(resultJSON), nil
+}
+
+// parsePacs008 解析 XML 或 JSON 形式的 pacs.008 报文
+func parsePacs008(document string) (*pacs008Instruction, error) {
+	document = strings.TrimSpace(document)
+	if document == "" {
+		return nil, errors.New("document is empty")
+	}
+
+	// XML 报文
+	if strings.HasPrefix(document, "<") {
+		var doc pacs008XMLDocument
+		if err := xml.Unmarshal([]byte(document), &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse XML: %v", err)
+		}
+		if len(doc.FIToFICstmrCdtTrf.CdtTrfTxInf) != 1 {
+			return nil, fmt.Errorf("exactly one CdtTrfTxInf is supported, got %d", len(doc.FIToFICstmrCdtTrf.CdtTrfTxInf))
+		}
+		return newPacs008Instruction(doc.FIToFICstmrCdtTrf.GrpHdr, doc.FIToFICstmrCdtTrf.CdtTrfTxInf[0]), nil
+	}
+
+	// JSON 映射
+	var doc pacs008JSONDocument
+	if err := json.Unmarshal([]byte(document), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %v", err)
+	}
+	if doc.Msg != "" && doc.Msg != "pacs.008" {
+		return nil, fmt.Errorf("unexpected message type %s", doc.Msg)
+	}
+	if doc.CdtTrfTxInf == nil {
+		return nil, errors.New("CdtTrfTxInf is missing")
+	}
+	return newPacs008Instruction(doc.GrpHdr, *doc.CdtTrfTxInf), nil
+}
+
+// newPacs008Instruction 由组头与交易信息构建支付指令
+func newPacs008Instruction(hdr pacs008GroupHeader, tx pacs008Transaction) *pacs008Instruction {
+	instruction := &pacs008Instruction{
+		MsgID:   strings.TrimSpace(hdr.MsgId),
+		NbOfTxs: strings.TrimSpace(hdr.NbOfTxs.String()),
+		CtrlSum: strings.TrimSpace(hdr.CtrlSum),
+		Tx:      tx,
+	}
+	if hdr.InstgAgt != nil {
+		instruction.Instructing = strings.TrimSpace(hdr.InstgAgt.FinInstnId.Othr.Id)
+	}
+	return instruction
+}
+
+// settlementAmount 返回报文中的结算金额（优先 IntrBkSttlmAmt，其次 InstdAmt）
+func (tx *pacs008Transaction) settlementAmount() *isoAmount {
+	if tx.IntrBkSttlmAmt != nil {
+		return tx.IntrBkSttlmAmt
+	}
+	if tx.Amt.InstdAmt != nil {
+		return tx.Amt.InstdAmt
+	}
+	return tx.InstdAmt
+}
+
+// validatePacs008 校验支付指令，返回接收方、金额（最小单位）与支付标识
+func (s *SmartContract) validatePacs008(ctx contractapi.TransactionContextInterface, instruction *pacs008Instruction, sender string, senderMSP string) (string, int, *paymentIdentification, error) {
+	tx := instruction.Tx
+
+	if instruction.NbOfTxs != "" && instruction.NbOfTxs != "1" {
+		return "", 0, nil, rejectPayment(reasonInvalidNumberOfTxs, "NbOfTxs must be 1, got %s", instruction.NbOfTxs)
+	}
+
+	// 指示机构必须是调用者所属机构
+	if instruction.Instructing != "" && instruction.Instructing != senderMSP {
+		return "", 0, nil, rejectPayment(reasonBankIdentifier, "instructing agent %s does not match caller MSP %s", instruction.Instructing, senderMSP)
+	}
+
+	// 债务人账户必须是调用者本人
+	debtor := strings.TrimSpace(tx.DbtrAcct.Id.Othr.Id)
+	if debtor == "" {
+		return "", 0, nil, rejectPayment(reasonIncorrectAccount, "DbtrAcct is missing")
+	}
+	if debtor != sender {
+		return "", 0, nil, rejectPayment(reasonTransactionForbidden, "debtor account does not match the caller")
+	}
+	if tx.DbtrAgt != nil && tx.DbtrAgt.FinInstnId.Othr.Id != "" {
+		senderOrg, _ := s.extractDomainFromClientID(sender)
+		if tx.DbtrAgt.FinInstnId.Othr.Id != senderOrg {
+			return "", 0, nil, rejectPayment(reasonBankIdentifier, "debtor agent %s does not match debtor organization %s", tx.DbtrAgt.FinInstnId.Othr.Id, senderOrg)
+		}
+	}
+
+	// 债权人账户
+	creditor := strings.TrimSpace(tx.CdtrAcct.Id.Othr.Id)
+	if creditor == "" {
+		return "", 0, nil, rejectPayment(reasonInvalidCreditor, "CdtrAcct is missing")
+	}
+	creditor, err := s.resolveAccount(ctx, creditor)
+	if err != nil {
+		return "", 0, nil, rejectPayment(reasonInvalidCreditor, "%v", err)
+	}
+	if tx.CdtrAgt != nil && tx.CdtrAgt.FinInstnId.Othr.Id != "" {
+		creditorOrg, _ := s.extractDomainFromClientID(creditor)
+		if tx.CdtrAgt.FinInstnId.Othr.Id != creditorOrg {
+			return "", 0, nil, rejectPayment(reasonBankIdentifier, "creditor agent %s does not match creditor organization %s", tx.CdtrAgt.FinInstnId.Othr.Id, creditorOrg)
+		}
+	}
+
+	// 金额与币种
+	tokenSymbol, tokenDecimals, err := s.getTokenMeta(ctx)
+	if err != nil {
+		return "", 0, nil, fmt.Errorf("get token meta failed: %v", err)
+	}
+	amt := tx.settlementAmount()
+	if amt == nil {
+		return "", 0, nil, rejectPayment(reasonInvalidAmount, "settlement amount is missing")
+	}
+	if amt.Ccy != tokenSymbol {
+		return "", 0, nil, rejectPayment(reasonNotAllowedCurrency, "currency %s does not match token symbol %s", amt.Ccy, tokenSymbol)
+	}
+	amount, err := parseAmount(amt.Value, tokenDecimals)
+	if err != nil {
+		return "", 0, nil, rejectPayment(reasonInvalidAmount, "%v", err)
+	}
+	if amount <= 0 {
+		return "", 0, nil, rejectPayment(reasonInvalidAmount, "amount must be positive")
+	}
+	if instruction.CtrlSum != "" {
+		ctrlSum, err := parseAmount(instruction.CtrlSum, tokenDecimals)
+		if err != nil {
+			return "", 0, nil, rejectPayment(reasonInvalidControlSum, "invalid CtrlSum: %v", err)
+		}
+		if ctrlSum != amount {
+			return "", 0, nil, rejectPayment(reasonInvalidControlSum, "CtrlSum %s does not match settlement amount %s", instruction.CtrlSum, amt.Value)
+		}
+	}
+
+	// 支付标识
+	pmtID, err := s.inboundPaymentIdentification(ctx, tx)
+	if err != nil {
+		return "", 0, nil, err
+	}
+
+	return creditor, amount, pmtID, nil
+}
+
+// inboundPaymentIdentification 校验并返回指示方提供的支付标识
+func (s *SmartContract) inboundPaymentIdentification(ctx contractapi.TransactionContextInterface, tx pacs008Transaction) (*paymentIdentification, error) {
+	endToEndID := strings.TrimSpace(tx.PmtId.EndToEndId)
+	if endToEndID == "" {
+		return nil, rejectPayment(reasonInvalidFileFormat, "EndToEndId is missing")
+	}
+	if len(endToEndID) > maxISOTextLength {
+		return nil, rejectPayment(reasonInvalidFileFormat, "EndToEndId exceeds %d characters", maxISOTextLength)
+	}
+
+	uetr := strings.ToLower(strings.TrimSpace(tx.PmtId.UETR))
+	if !uetrPattern.MatchString(uetr) {
+		return nil, rejectPayment(reasonInvalidFileFormat, "UETR %s is not a valid UUID v4", tx.PmtId.UETR)
+	}
+
+	// 同一 UETR 只能结算一次
+	existing, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), uetrPrefix+uetr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read UETR index: %v", err)
+	}
+	if existing != nil {
+		return nil, rejectPayment(reasonDuplication, "UETR %s was already settled in transaction %s", uetr, string(existing))
+	}
+
+	instrID := strings.TrimSpace(tx.PmtId.InstrId)
+	if instrID == "" {
+		instrID = ctx.GetStub().GetTxID()
+	}
+
+	memo := strings.TrimSpace(tx.RmtInf.Ustrd)
+	if len(memo) > maxMemoLength {
+		return nil, rejectPayment(reasonInvalidFileFormat, "Ustrd exceeds %d characters", maxMemoLength)
+	}
+
+	return &paymentIdentification{
+		InstrID:    instrID,
+		EndToEndID: endToEndID,
+		UETR:       uetr,
+		Memo:       memo,
+	}, nil
+}
+
+// parseAmount 将十进制字符串金额按 decimals 转换为最小单位整数（formatAmount 的逆运算）
+func parseAmount(value string, decimals int) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, errors.New("amount is empty")
+	}
+
+	intPart, fracPart, hasFrac := strings.Cut(value, ".")
+	if intPart == "" || strings.Trim(intPart, "0123456789") != "" || strings.Trim(fracPart, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid amount %s", value)
+	}
+	if hasFrac && fracPart == "" {
+		return 0, fmt.Errorf("invalid amount %s", value)
+	}
+	if len(fracPart) > decimals {
+		return 0, fmt.Errorf("amount %s has more than %d fractional digits", value, decimals)
+	}
+
+	// 右侧补零到 decimals 位后按整数解析
+	digits := intPart + fracPart + strings.Repeat("0", decimals-len(fracPart))
+	amount, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %s: %v", value, err)
+	}
+
+	return amount, nil
+}
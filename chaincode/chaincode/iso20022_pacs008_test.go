@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// pacs008Message 测试报文中可变的字段
+type pacs008Message struct {
+	NbOfTxs     string
+	CtrlSum     string
+	Instructing string
+	EndToEndID  string
+	UETR        string
+	Ccy         string
+	Amount      string
+	Debtor      string
+	DebtorAgt   string
+	Creditor    string
+	CreditorAgt string
+	Memo        string
+}
+
+var pacs008Template = template.Must(template.New("pacs008").Parse(`<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pacs.008.001.08">
+  <FIToFICstmrCdtTrf>
+    <GrpHdr>
+      <MsgId>MSG-1</MsgId>
+      <CreDtTm>2025-01-01T00:00:00Z</CreDtTm>
+      {{if .NbOfTxs}}<NbOfTxs>{{.NbOfTxs}}</NbOfTxs>{{end}}
+      {{if .CtrlSum}}<CtrlSum>{{.CtrlSum}}</CtrlSum>{{end}}
+      {{if .Instructing}}<InstgAgt><FinInstnId><Othr><Id>{{.Instructing}}</Id></Othr></FinInstnId></InstgAgt>{{end}}
+    </GrpHdr>
+    <CdtTrfTxInf>
+      <PmtId><InstrId>INSTR-1</InstrId><EndToEndId>{{.EndToEndID}}</EndToEndId><UETR>{{.UETR}}</UETR></PmtId>
+      {{if .Amount}}<IntrBkSttlmAmt Ccy="{{.Ccy}}">{{.Amount}}</IntrBkSttlmAmt>{{end}}
+      <DbtrAcct><Id><Othr><Id>{{.Debtor}}</Id></Othr></Id></DbtrAcct>
+      {{if .DebtorAgt}}<DbtrAgt><FinInstnId><Othr><Id>{{.DebtorAgt}}</Id></Othr></FinInstnId></DbtrAgt>{{end}}
+      {{if .CreditorAgt}}<CdtrAgt><FinInstnId><Othr><Id>{{.CreditorAgt}}</Id></Othr></FinInstnId></CdtrAgt>{{end}}
+      <CdtrAcct><Id><Othr><Id>{{.Creditor}}</Id></Othr></Id></CdtrAcct>
+      {{if .Memo}}<RmtInf><Ustrd>{{.Memo}}</Ustrd></RmtInf>{{end}}
+    </CdtTrfTxInf>
+  </FIToFICstmrCdtTrf>
+</Document>`))
+
+// validPacs008 bankAUser 向央行支付 1.25 DCEP 的报文
+func validPacs008() pacs008Message {
+	return pacs008Message{
+		NbOfTxs:     "1",
+		CtrlSum:     "1.25",
+		Instructing: "AMSP",
+		EndToEndID:  "E2E-1",
+		UETR:        "2f3c6a52-0f5b-4b3e-9c55-1d0a7f6c9e21",
+		Ccy:         "DCEP",
+		Amount:      "1.25",
+		Debtor:      bankAUser.id,
+		DebtorAgt:   "a.example.com",
+		Creditor:    centralBankAdmin.id,
+		CreditorAgt: CENTRAL_BANK_DOMAIN,
+		Memo:        "invoice 42",
+	}
+}
+
+func (m pacs008Message) xml(t *testing.T) string {
+	t.Helper()
+	var b strings.Builder
+	if err := pacs008Template.Execute(&b, m); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestParsePacs008(t *testing.T) {
+	tests := []struct {
+		name         string
+		document     string
+		wantErr      string
+		wantMsgID    string
+		wantEndToEnd string
+	}{
+		{name: "xml", document: validPacs008().xml(t), wantMsgID: "MSG-1", wantEndToEnd: "E2E-1"},
+		{
+			name:         "json mapping",
+			document:     `{"_std":"ISO20022","_msg":"pacs.008","GrpHdr":{"MsgId":"MSG-2","NbOfTxs":1},"CdtTrfTxInf":{"PmtId":{"EndToEndId":"E2E-2"},"IntrBkSttlmAmt":{"Ccy":"DCEP","_text":"1.00"}}}`,
+			wantMsgID:    "MSG-2",
+			wantEndToEnd: "E2E-2",
+		},
+		{name: "empty", document: "  ", wantErr: "document is empty"},
+		{name: "malformed xml", document: "<Document><FIToFICstmrCdtTrf>", wantErr: "failed to parse XML"},
+		{
+			name:     "two transactions",
+			document: "<Document><FIToFICstmrCdtTrf><CdtTrfTxInf/><CdtTrfTxInf/></FIToFICstmrCdtTrf></Document>",
+			wantErr:  "exactly one CdtTrfTxInf is supported, got 2",
+		},
+		{name: "no transaction", document: "<Document><FIToFICstmrCdtTrf/></Document>", wantErr: "got 0"},
+		{name: "malformed json", document: `{"_msg":`, wantErr: "failed to parse JSON"},
+		{name: "other message type", document: `{"_msg":"pacs.009","CdtTrfTxInf":{}}`, wantErr: "unexpected message type pacs.009"},
+		{name: "json without transaction", document: `{"_msg":"pacs.008","GrpHdr":{"MsgId":"MSG-3"}}`, wantErr: "CdtTrfTxInf is missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instruction, err := parsePacs008(tt.document)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if instruction.MsgID != tt.wantMsgID || instruction.Tx.PmtId.EndToEndId != tt.wantEndToEnd {
+				t.Fatalf("parsed MsgId=%s EndToEndId=%s, want %s %s", instruction.MsgID, instruction.Tx.PmtId.EndToEndId, tt.wantMsgID, tt.wantEndToEnd)
+			}
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr string
+	}{
+		{value: "1", want: 100},
+		{value: "1.5", want: 150},
+		{value: " 12.34 ", want: 1234},
+		{value: "0.01", want: 1},
+		{value: "", wantErr: "amount is empty"},
+		{value: "1.234", wantErr: "more than 2 fractional digits"},
+		{value: "-1.00", wantErr: "invalid amount"},
+		{value: "1.", wantErr: "invalid amount"},
+		{value: ".5", wantErr: "invalid amount"},
+		{value: "1e3", wantErr: "invalid amount"},
+		{value: "99999999999999999999", wantErr: "invalid amount"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseAmount(tt.value, 2)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %d, %v", tt.wantErr, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("parseAmount(%q) = %d, %v; want %d", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubmitPacs008(t *testing.T) {
+	env := newTestEnv(t)
+	env.initialize()
+	_, err := env.invoke(bankAAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.OpenAccount(ctx, bankAUser.id, accountTypeIndividual)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+	_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.Mint(ctx, 1000)
+	})
+	if err != nil {
+		t.Fatalf("Mint failed: %v", err)
+	}
+	_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.Transfer(ctx, bankAUser.id, 500)
+	})
+	if err != nil {
+		t.Fatalf("Transfer failed: %v", err)
+	}
+
+	submit := func(caller testUser, document string) (string, error) {
+		var result string
+		_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			result, err = env.contract.SubmitPacs008(ctx, document)
+			return err
+		})
+		return result, err
+	}
+
+	authorizeDebit(t, env, bankAUser)
+	result, err := submit(bankAUser, validPacs008().xml(t))
+	if err != nil {
+		t.Fatalf("SubmitPacs008 failed: %v", err)
+	}
+	var accepted map[string]interface{}
+	if err := json.Unmarshal([]byte(result), &accepted); err != nil {
+		t.Fatal(err)
+	}
+	if accepted["amount"] != float64(125) || accepted["to"] != centralBankAdmin.id || accepted["endToEndId"] != "E2E-1" {
+		t.Fatalf("unexpected result: %s", result)
+	}
+	// 附言（Ustrd）随交易记录保存
+	if record := getTransactionRecord(t, env, accepted["txId"].(string)); record.Memo != "invoice 42" || record.EndToEndID != "E2E-1" {
+		t.Fatalf("unexpected transaction record: %+v", record)
+	}
+
+	// 债权人账户可以使用已验证的别名
+	registerAlias(t, env, bankAUser, "@alice", "")
+	if _, err := verifyAlias(env, bankAAdmin, "@alice"); err != nil {
+		t.Fatalf("VerifyAlias failed: %v", err)
+	}
+	toAlias := validPacs008()
+	toAlias.Instructing, toAlias.Debtor, toAlias.DebtorAgt = CENTRAL_MSP_ID, centralBankAdmin.id, CENTRAL_BANK_DOMAIN
+	toAlias.Creditor, toAlias.CreditorAgt = "@alice", "a.example.com"
+	toAlias.EndToEndID, toAlias.UETR = "E2E-ALIAS", "7d2b1b0e-3c1a-4f5e-8a6b-2c9d0e1f2a3b"
+	result, err = submit(centralBankAdmin, toAlias.xml(t))
+	if err != nil {
+		t.Fatalf("SubmitPacs008 to an alias failed: %v", err)
+	}
+	if err := json.Unmarshal([]byte(result), &accepted); err != nil {
+		t.Fatal(err)
+	}
+	if accepted["to"] != bankAUser.id || balanceOf(t, env, bankAUser) != 500 {
+		t.Fatalf("payment to @alice was not credited to the account: %s", result)
+	}
+
+	tests := []struct {
+		name     string
+		caller   testUser
+		mutate   func(m *pacs008Message)
+		wantErr  string
+		wantCode string
+	}{
+		{name: "malformed document", mutate: func(m *pacs008Message) { m.Debtor = "<" }, wantErr: "invalid pacs.008 document", wantCode: reasonInvalidFileFormat},
+		{name: "several transactions", mutate: func(m *pacs008Message) { m.NbOfTxs = "2" }, wantErr: "NbOfTxs must be 1", wantCode: reasonInvalidNumberOfTxs},
+		{name: "instructing agent of another bank", mutate: func(m *pacs008Message) { m.Instructing = "BMSP" }, wantErr: "instructing agent BMSP does not match", wantCode: reasonBankIdentifier},
+		{name: "missing debtor", mutate: func(m *pacs008Message) { m.Debtor = "" }, wantErr: "DbtrAcct is missing", wantCode: reasonIncorrectAccount},
+		{name: "debtor is not the caller", caller: bankAAdmin, mutate: func(m *pacs008Message) { m.DebtorAgt = "" }, wantErr: "debtor account does not match the caller", wantCode: reasonTransactionForbidden},
+		{name: "debtor agent mismatch", mutate: func(m *pacs008Message) { m.DebtorAgt = "b.example.com" }, wantErr: "debtor agent b.example.com does not match", wantCode: reasonBankIdentifier},
+		{name: "missing creditor", mutate: func(m *pacs008Message) { m.Creditor = "" }, wantErr: "CdtrAcct is missing", wantCode: reasonInvalidCreditor},
+		{name: "creditor agent mismatch", mutate: func(m *pacs008Message) { m.CreditorAgt = "a.example.com" }, wantErr: "creditor agent a.example.com does not match", wantCode: reasonBankIdentifier},
+		{name: "missing amount", mutate: func(m *pacs008Message) { m.Amount = "" }, wantErr: "settlement amount is missing", wantCode: reasonInvalidAmount},
+		{name: "other currency", mutate: func(m *pacs008Message) { m.Ccy = "EUR" }, wantErr: "currency EUR does not match", wantCode: reasonNotAllowedCurrency},
+		{name: "too many decimals", mutate: func(m *pacs008Message) { m.Amount, m.CtrlSum = "1.255", "" }, wantErr: "more than 2 fractional digits", wantCode: reasonInvalidAmount},
+		{name: "zero amount", mutate: func(m *pacs008Message) { m.Amount, m.CtrlSum = "0.00", "" }, wantErr: "amount must be positive", wantCode: reasonInvalidAmount},
+		{name: "control sum mismatch", mutate: func(m *pacs008Message) { m.CtrlSum = "2.00" }, wantErr: "CtrlSum 2.00 does not match", wantCode: reasonInvalidControlSum},
+		{name: "missing end-to-end id", mutate: func(m *pacs008Message) { m.EndToEndID = "" }, wantErr: "EndToEndId is missing", wantCode: reasonInvalidFileFormat},
+		{name: "end-to-end id too long", mutate: func(m *pacs008Message) { m.EndToEndID = strings.Repeat("E", 36) }, wantErr: "EndToEndId exceeds 35 characters", wantCode: reasonInvalidFileFormat},
+		{name: "invalid uetr", mutate: func(m *pacs008Message) { m.UETR = "not-a-uuid" }, wantErr: "is not a valid UUID v4", wantCode: reasonInvalidFileFormat},
+		{name: "memo too long", mutate: func(m *pacs008Message) {
+			m.Memo, m.UETR = strings.Repeat("m", maxMemoLength+1), "0b9e6f1a-5d4c-4a3b-9f2e-1c0d9e8f7a6b"
+		}, wantErr: "Ustrd exceeds 140 characters", wantCode: reasonInvalidFileFormat},
+		{name: "unknown creditor alias", mutate: func(m *pacs008Message) { m.Creditor, m.CreditorAgt = "@nobody", "" }, wantErr: "@nobody", wantCode: reasonInvalidCreditor},
+		{name: "replayed uetr", mutate: func(m *pacs008Message) { m.EndToEndID = "E2E-2" }, wantErr: "was already settled", wantCode: reasonDuplication},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := validPacs008()
+			tt.mutate(&message)
+			caller := tt.caller
+			if caller == (testUser{}) {
+				caller = bankAUser
+			}
+			_, err := submit(caller, message.xml(t))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			var rejection *paymentRejection
+			if !errors.As(err, &rejection) || rejection.ReasonCode != tt.wantCode {
+				t.Fatalf("expected reason code %s, got %v", tt.wantCode, err)
+			}
+		})
+	}
+}
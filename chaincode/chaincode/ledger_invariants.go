@@ -0,0 +1,374 @@
+/*
+账本不变量校验
+
+- VerifyLedgerInvariants 分页遍历余额记录、交易记录与旧格式查询副本，生成机器可读的差异报告
+- 余额阶段累计余额合计与各银行余额合计，阶段结束时与总供应量（扣除保密余额合计）及银行汇总账核对
+- 书签携带阶段、最后处理的键与累计值，调用方按书签继续直到 done 为 true
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// 校验分页参数；最大页大小的实际值取自合约配置
+const defaultVerifyPageSize = 100
+const maxVerifyPageSize = 500
+
+// 差异类型
+const (
+	issueUnparseableBalance     = "unparseable_balance"
+	issueLegacyBalanceFormat    = "legacy_balance_format"
+	issueUserIDMismatch         = "user_id_mismatch"
+	issueMissingOrg             = "missing_org"
+	issueUnrecognizedAccount    = "unrecognized_account"
+	issueNegativeBalance        = "negative_balance"
+	issueClosedAccountBalance   = "closed_account_balance"
+	issueSupplyMismatch         = "supply_mismatch"
+	issueBankAggregateMismatch  = "bank_aggregate_mismatch"
+	issueUnparseableTransaction = "unparseable_transaction"
+	issueOutdatedTransaction    = "outdated_transaction_schema"
+	issueMissingIndexEntry      = "missing_index_entry"
+	issueLegacyQueryRecord      = "legacy_query_record"
+	issueOrphanQueryRecord      = "orphan_query_record"
+	issueConfidentialCommitment = "confidential_commitment"
+)
+
+// ledgerIssue 一条差异
+type ledgerIssue struct {
+	Type     string      `json:"type"`
+	Key      string      `json:"key,omitempty"`
+	Detail   string      `json:"detail"`
+	Expected interface{} `json:"expected,omitempty"`
+	Actual   interface{} `json:"actual,omitempty"`
+}
+
+// invariantBookmark 校验书签：当前阶段、最后处理的键与跨页累计值
+type invariantBookmark struct {
+	Phase        string         `json:"p"`
+	LastKey      string         `json:"k,omitempty"`
+	Accounts     int            `json:"a"`
+	BalanceSum   int            `json:"s"`
+	BankBalances map[string]int `json:"b,omitempty"`
+	Transactions int            `json:"t"`
+	Issues       int            `json:"i"`
+}
+
+// invariantPhases 校验阶段顺序
+var invariantPhases = []string{balancePrefix, transactionPrefix, legacyQueryPrefix}
+
+// VerifyLedgerInvariants 央行分页校验账本不变量
+// 检查余额合计等于总供应量、余额非负、余额与交易记录均为当前格式、交易记录已建立索引且没有遗留的 query_ 副本，
+// 以及各银行汇总账与其客户余额合计一致
+// bookmark 为上一页返回的书签，为空时从头开始；两页之间有新的记账时，合计核对结果只反映各页读取时的状态
+func (s *SmartContract) VerifyLedgerInvariants(ctx contractapi.TransactionContextInterface, bookmark string, pageSize int) (string, error) {
+	if err := s.checkAnalyticsAccess(ctx); err != nil {
+		return "", err
+	}
+
+	if pageSize <= 0 {
+		pageSize = defaultVerifyPageSize
+	}
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if pageSize > config.Limits.MaxVerifyPageSize {
+		pageSize = config.Limits.MaxVerifyPageSize
+	}
+
+	state, err := decodeInvariantBookmark(bookmark)
+	if err != nil {
+		return "", err
+	}
+	phase := state.Phase
+
+	issues, exhausted, scanned, err := s.verifyInvariantPage(ctx, state, pageSize)
+	if err != nil {
+		return "", err
+	}
+
+	// 阶段结束：余额阶段核对合计，然后进入下一阶段
+	if exhausted {
+		if phase == balancePrefix {
+			supplyIssues, err := s.verifyBalanceTotals(ctx, state)
+			if err != nil {
+				return "", err
+			}
+			issues = append(issues, supplyIssues...)
+		}
+		state.Phase = nextInvariantPhase(phase)
+		state.LastKey = ""
+	}
+	state.Issues += len(issues)
+
+	done := state.Phase == ""
+	nextBookmark := ""
+	if !done {
+		nextBookmark, err = encodeInvariantBookmark(state)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	response := map[string]interface{}{
+		"phase":         phase,
+		"scanned":       scanned,
+		"discrepancies": issues,
+		"bookmark":      nextBookmark,
+		"done":          done,
+	}
+	if done {
+		response["summary"] = map[string]interface{}{
+			"accounts":      state.Accounts,
+			"balanceSum":    state.BalanceSum,
+			"transactions":  state.Transactions,
+			"discrepancies": state.Issues,
+			"consistent":    state.Issues == 0,
+		}
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal response: %v", err)
+	}
+	return string(responseJSON), nil
+}
+
+// verifyInvariantPage 校验当前阶段的一页记录，返回差异、阶段是否结束与扫描条数
+func (s *SmartContract) verifyInvariantPage(ctx contractapi.TransactionContextInterface, state *invariantBookmark, pageSize int) ([]ledgerIssue, bool, int, error) {
+	prefix := state.Phase
+	startKey := prefix
+	if state.LastKey != "" {
+		startKey = state.LastKey + "\x00"
+	}
+	// prefix 以 "_" 结尾，"`" 是其后的第一个字符
+	endKey := prefix[:len(prefix)-1] + "`"
+
+	iterator, err := ctx.GetStub().GetPrivateDataByRange(privateCollection(ctx), startKey, endKey)
+	if err != nil {
+		return nil, false, 0, fmt.Errorf("failed to scan %s records: %v", prefix, err)
+	}
+	defer iterator.Close()
+
+	issues := []ledgerIssue{}
+	scanned := 0
+	for scanned < pageSize && iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, false, 0, fmt.Errorf("failed to get next %s record: %v", prefix, err)
+		}
+		scanned++
+		state.LastKey = kv.Key
+
+		var recordIssues []ledgerIssue
+		switch prefix {
+		case balancePrefix:
+			recordIssues = s.verifyBalanceRecord(state, kv.Key, kv.Value)
+		case transactionPrefix:
+			recordIssues, err = s.verifyTransactionRecord(ctx, state, kv.Key, kv.Value)
+		case legacyQueryPrefix:
+			recordIssues, err = s.verifyLegacyQueryRecord(ctx, kv.Key)
+		}
+		if err != nil {
+			return nil, false, 0, err
+		}
+		issues = append(issues, recordIssues...)
+	}
+
+	return issues, !iterator.HasNext(), scanned, nil
+}
+
+// verifyBalanceRecord 校验单条余额记录并累计合计
+func (s *SmartContract) verifyBalanceRecord(state *invariantBookmark, key string, value []byte) []ledgerIssue {
+	userID := key[len(balancePrefix):]
+	issues := []ledgerIssue{}
+
+	var account UserBalance
+	if err := json.Unmarshal(value, &account); err != nil {
+		balance, err := strconv.Atoi(string(value))
+		if err != nil {
+			return append(issues, ledgerIssue{Type: issueUnparseableBalance, Key: key, Detail: err.Error()})
+		}
+		issues = append(issues, ledgerIssue{Type: issueLegacyBalanceFormat, Key: key, Detail: "balance is stored as a plain number, run RunMigration balances"})
+		account = UserBalance{UserID: userID, Balance: balance}
+		if orgMSP, err := s.extractOrgMSPFromClientID(userID); err == nil {
+			account.OrgMSP = orgMSP
+		}
+	} else {
+		if account.UserID != userID {
+			issues = append(issues, ledgerIssue{Type: issueUserIDMismatch, Key: key, Detail: "userId does not match the record key", Expected: userID, Actual: account.UserID})
+		}
+		if account.OrgMSP == "" {
+			issues = append(issues, ledgerIssue{Type: issueMissingOrg, Key: key, Detail: "orgMsp is empty"})
+		}
+	}
+
+	if _, err := s.extractOrgMSPFromClientID(userID); err != nil {
+		issues = append(issues, ledgerIssue{Type: issueUnrecognizedAccount, Key: key, Detail: "account id is not a client identity"})
+	}
+	if account.Balance < 0 {
+		issues = append(issues, ledgerIssue{Type: issueNegativeBalance, Key: key, Detail: "balance is negative", Actual: account.Balance})
+	}
+	if account.Status == accountStatusClosed && account.Balance != 0 {
+		issues = append(issues, ledgerIssue{Type: issueClosedAccountBalance, Key: key, Detail: "closed account still holds a balance", Actual: account.Balance})
+	}
+
+	state.Accounts++
+	state.BalanceSum += account.Balance
+	if state.BankBalances == nil {
+		state.BankBalances = map[string]int{}
+	}
+	state.BankBalances[bankAggregateOrg(account.OrgMSP)] += account.Balance
+
+	return issues
+}
+
+// verifyBalanceTotals 核对余额合计与总供应量、各银行余额合计与银行汇总账
+func (s *SmartContract) verifyBalanceTotals(ctx contractapi.TransactionContextInterface, state *invariantBookmark) ([]ledgerIssue, error) {
+	issues := []ledgerIssue{}
+
+	totalSupply, err := s.getTotalSupplyFromPrivateCollection(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve total token supply: %v", err)
+	}
+	// 存入保密余额的部分不在明文余额中，按保密余额合计补足
+	confidentialSupply, err := s.getConfidentialSupply(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if totalSupply != state.BalanceSum+confidentialSupply {
+		issues = append(issues, ledgerIssue{Type: issueSupplyMismatch, Key: totalSupplyKey, Detail: "sum of balances and confidential supply does not equal total supply", Expected: totalSupply, Actual: state.BalanceSum + confidentialSupply})
+	}
+
+	aggregates, err := s.listBankAggregates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	aggregated := map[string]int{}
+	for _, aggregate := range aggregates {
+		aggregated[aggregate.OrgMSP] = aggregate.Balance
+	}
+
+	orgs := map[string]bool{}
+	for org := range aggregated {
+		orgs[org] = true
+	}
+	for org := range state.BankBalances {
+		orgs[org] = true
+	}
+	for _, org := range sortedKeys(orgs) {
+		if aggregated[org] != state.BankBalances[org] {
+			issues = append(issues, ledgerIssue{Type: issueBankAggregateMismatch, Key: bankAggregateIndex + ":" + org, Detail: "bank aggregate does not equal the sum of its customer balances", Expected: state.BankBalances[org], Actual: aggregated[org]})
+		}
+	}
+
+	return issues, nil
+}
+
+// verifyTransactionRecord 校验交易记录为当前版本、已建立索引且没有遗留的 query_ 副本
+func (s *SmartContract) verifyTransactionRecord(ctx contractapi.TransactionContextInterface, state *invariantBookmark, key string, value []byte) ([]ledgerIssue, error) {
+	txID := key[len(transactionPrefix):]
+	issues := []ledgerIssue{}
+	state.Transactions++
+
+	var record TransactionRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return append(issues, ledgerIssue{Type: issueUnparseableTransaction, Key: key, Detail: err.Error()}), nil
+	}
+	if record.SchemaVersion != transactionRecordSchemaVersion || record.DocType != transactionDocType || record.TxID != txID {
+		issues = append(issues, ledgerIssue{Type: issueOutdatedTransaction, Key: key, Detail: "record is not in the current schema, run RunMigration transactionRecords", Expected: transactionRecordSchemaVersion, Actual: record.SchemaVersion})
+	} else {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(timeTxIndex, []string{formatIndexTimestamp(record.Timestamp), txID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", timeTxIndex, err)
+		}
+		entry, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), indexKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read index entry for %s: %v", txID, err)
+		}
+		if entry == nil {
+			issues = append(issues, ledgerIssue{Type: issueMissingIndexEntry, Key: key, Detail: "transaction is missing from the time index"})
+		}
+	}
+
+	twin, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), legacyQueryPrefix+txID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read query record %s: %v", txID, err)
+	}
+	if twin != nil {
+		issues = append(issues, ledgerIssue{Type: issueLegacyQueryRecord, Key: legacyQueryPrefix + txID, Detail: "legacy query copy was not merged, run RunMigration transactionRecords"})
+	}
+
+	return issues, nil
+}
+
+// verifyLegacyQueryRecord 报告没有 tx_ 记录的 query_ 副本（有 tx_ 记录的已在交易阶段报告）
+func (s *SmartContract) verifyLegacyQueryRecord(ctx contractapi.TransactionContextInterface, key string) ([]ledgerIssue, error) {
+	txID := key[len(legacyQueryPrefix):]
+	recordBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), transactionPrefix+txID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transaction %s: %v", txID, err)
+	}
+	if recordBytes != nil {
+		return nil, nil
+	}
+	return []ledgerIssue{{Type: issueOrphanQueryRecord, Key: key, Detail: "query copy has no transaction record, run RunMigration transactionRecords"}}, nil
+}
+
+// nextInvariantPhase 返回下一个校验阶段，全部完成时返回空
+func nextInvariantPhase(phase string) string {
+	for i, p := range invariantPhases {
+		if p == phase && i+1 < len(invariantPhases) {
+			return invariantPhases[i+1]
+		}
+	}
+	return ""
+}
+
+// encodeInvariantBookmark 编码校验书签
+func encodeInvariantBookmark(state *invariantBookmark) (string, error) {
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal bookmark: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(stateJSON), nil
+}
+
+// decodeInvariantBookmark 解析校验书签，为空时从余额阶段开始
+func decodeInvariantBookmark(bookmark string) (*invariantBookmark, error) {
+	if bookmark == "" {
+		return &invariantBookmark{Phase: balancePrefix}, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bookmark: %v", err)
+	}
+	var state invariantBookmark
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, fmt.Errorf("invalid bookmark: %v", err)
+	}
+	if !isInvariantPhase(state.Phase) {
+		return nil, fmt.Errorf("invalid bookmark phase %s", state.Phase)
+	}
+	return &state, nil
+}
+
+// isInvariantPhase 判断是否为有效的校验阶段
+func isInvariantPhase(phase string) bool {
+	for _, p := range invariantPhases {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+type invariantReport struct {
+	Discrepancies []ledgerIssue `json:"discrepancies"`
+	Bookmark      string        `json:"bookmark"`
+	Done          bool          `json:"done"`
+	Summary       struct {
+		Accounts      int  `json:"accounts"`
+		BalanceSum    int  `json:"balanceSum"`
+		Transactions  int  `json:"transactions"`
+		Discrepancies int  `json:"discrepancies"`
+		Consistent    bool `json:"consistent"`
+	} `json:"summary"`
+}
+
+func verifyInvariantsPage(env *testEnv, caller testUser, bookmark string, pageSize int) (*invariantReport, error) {
+	var report invariantReport
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		response, err := env.contract.VerifyLedgerInvariants(ctx, bookmark, pageSize)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &report)
+	})
+	return &report, err
+}
+
+// verifyInvariants 按书签翻页直到校验完成，返回最后一页与全部差异类型
+func verifyInvariants(t *testing.T, env *testEnv) (*invariantReport, []string) {
+	t.Helper()
+	types := []string{}
+	bookmark := ""
+	for pages := 0; pages < 50; pages++ {
+		report, err := verifyInvariantsPage(env, centralBankAdmin, bookmark, 1)
+		if err != nil {
+			t.Fatalf("VerifyLedgerInvariants failed: %v", err)
+		}
+		for _, issue := range report.Discrepancies {
+			types = append(types, issue.Type)
+		}
+		if report.Done {
+			sort.Strings(types)
+			return report, types
+		}
+		bookmark = report.Bookmark
+	}
+	t.Fatalf("invariant verification did not complete")
+	return nil, nil
+}
+
+func TestVerifyLedgerInvariants(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 100)
+	transfer(t, env, bankAUser, centralBankAdmin, 30)
+
+	report, types := verifyInvariants(t, env)
+	if !report.Summary.Consistent || len(types) != 0 {
+		t.Fatalf("expected a consistent ledger, got %v", types)
+	}
+	if report.Summary.Accounts != 2 || report.Summary.BalanceSum != 1000 || report.Summary.Transactions != 3 {
+		t.Fatalf("unexpected summary: %+v", report.Summary)
+	}
+
+	// 篡改余额并留下孤立的查询副本
+	private := env.ledger.private[defaultPrivateCollection]
+	var account UserBalance
+	if err := json.Unmarshal(private[balancePrefix+bankAUser.id], &account); err != nil {
+		t.Fatal(err)
+	}
+	account.Balance += 5
+	private[balancePrefix+bankAUser.id], _ = json.Marshal(account)
+	private[legacyQueryPrefix+"orphan"] = []byte(`{"txId":"orphan","amount":5}`)
+
+	report, types = verifyInvariants(t, env)
+	if report.Summary.Consistent || strings.Join(types, ",") != strings.Join([]string{issueBankAggregateMismatch, issueOrphanQueryRecord, issueSupplyMismatch}, ",") {
+		t.Fatalf("discrepancies = %v", types)
+	}
+	if report.Summary.Discrepancies != len(types) {
+		t.Fatalf("summary counts %d discrepancies, pages reported %d", report.Summary.Discrepancies, len(types))
+	}
+}
+
+func TestVerifyLedgerInvariantsRejects(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 100)
+
+	if _, err := verifyInvariantsPage(env, bankAAdmin, "", 0); err == nil || !strings.Contains(err.Error(), "not authorized") {
+		t.Fatalf("expected an authorization error, got %v", err)
+	}
+	if _, err := verifyInvariantsPage(env, centralBankAdmin, "bogus!", 0); err == nil || !strings.Contains(err.Error(), "invalid bookmark") {
+		t.Fatalf("expected an invalid bookmark error, got %v", err)
+	}
+}
@@ -7,11 +7,29 @@ import (
 )
 
 func main() {
-	tokenChaincode, err := contractapi.NewChaincode(&SmartContract{})
+	// 自定义交易上下文，同一交易内的链码事件累积在一个信封中
+	contract := &SmartContract{}
+	contract.TransactionContextHandler = new(eventContext)
+	// 每个交易开始前读取生效的合约配置
+	contract.BeforeTransaction = loadActiveConfig
+
+	tokenChaincode, err := contractapi.NewChaincode(contract)
 	if err != nil {
 		log.Panicf("Error creating token chaincode: %v", err)
 	}
 
+	// 设置了 CHAINCODE_SERVER_ADDRESS 时以链码即服务模式运行，否则由peer启动
+	ccaasConfig, err := loadServerConfig()
+	if err != nil {
+		log.Panicf("Error reading chaincode server configuration: %v", err)
+	}
+	if ccaasConfig != nil {
+		if err := runChaincodeServer(tokenChaincode, ccaasConfig); err != nil {
+			log.Panicf("Error running token chaincode server: %v", err)
+		}
+		return
+	}
+
 	if err := tokenChaincode.Start(); err != nil {
 		log.Panicf("Error starting token chaincode: %v", err)
 	}
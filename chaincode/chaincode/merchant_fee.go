@@ -0,0 +1,482 @@
+/*
+商户手续费引擎
+
+- 央行维护手续费方案：默认规则与按商户类别码（MCC）覆盖的规则
+- 规则支持固定金额、百分比（基点）与分档三种方式，可设置最低收费与封顶
+- 向商户账户付款时在同一交易内计算手续费：付款方支付全额，商户按净额入账，手续费划入手续费账户
+- 交易记录分别记录净额与手续费，ISO 20022 报文以 ChrgsInf 表示
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// feeScheduleKey 手续费方案的存储键
+const feeScheduleKey = "feeschedule"
+
+// 手续费规则类型
+const (
+	feeTypeFlat       = "flat"       // 每笔固定金额
+	feeTypePercentage = "percentage" // 按金额的基点收费
+	feeTypeTiered     = "tiered"     // 按金额所在档位的固定金额与基点收费
+)
+
+// basisPointsDenominator 基点分母（1 基点 = 0.01%）
+const basisPointsDenominator = 10000
+
+// merchantCategoryPattern 商户类别码（ISO 18245，4 位数字）
+var merchantCategoryPattern = regexp.MustCompile(`^[0-9]{4}$`)
+
+// FeeTier 分档规则中的一档；UpTo 为该档的金额上限（含），0 表示不设上限
+type FeeTier struct {
+	UpTo        int `json:"upTo"`
+	Flat        int `json:"flat,omitempty"`
+	BasisPoints int `json:"basisPoints,omitempty"`
+}
+
+// FeeRule 手续费规则，金额均以最小单位计
+type FeeRule struct {
+	Type        string    `json:"type"`                  // flat | percentage | tiered
+	Flat        int       `json:"flat,omitempty"`        // flat：每笔固定金额
+	BasisPoints int       `json:"basisPoints,omitempty"` // percentage：基点
+	Tiers       []FeeTier `json:"tiers,omitempty"`       // tiered：按 UpTo 升序排列，最后一档 UpTo 为 0
+	Min         int       `json:"min,omitempty"`         // 最低收费
+	Max         int       `json:"max,omitempty"`         // 封顶，0 表示不封顶
+}
+
+// FeeSchedule 手续费方案
+type FeeSchedule struct {
+	Version          int                `json:"version"`
+	CollectorAccount string             `json:"collectorAccount"`     // 手续费入账账户
+	Default          *FeeRule           `json:"default,omitempty"`    // 没有类别覆盖时使用的规则，为空表示不收费
+	Categories       map[string]FeeRule `json:"categories,omitempty"` // 按商户类别码覆盖的规则
+	UpdatedAt        int64              `json:"updatedAt"`
+	UpdatedBy        string             `json:"updatedBy"`
+}
+
+// merchantCharge 一笔商户收款的手续费
+type merchantCharge struct {
+	Fee       int
+	Net       int
+	Collector string
+	Category  string // 使用的商户类别码，默认规则为空
+}
+
+// validate 校验手续费规则
+func (r *FeeRule) validate() error {
+	if r.Flat < 0 || r.Min < 0 || r.Max < 0 {
+		return fmt.Errorf("fee amounts cannot be negative")
+	}
+	if r.Max > 0 && r.Min > r.Max {
+		return fmt.Errorf("min fee %d exceeds max fee %d", r.Min, r.Max)
+	}
+	checkBasisPoints := func(basisPoints int) error {
+		if basisPoints < 0 || basisPoints > basisPointsDenominator {
+			return fmt.Errorf("basis points must be between 0 and %d", basisPointsDenominator)
+		}
+		return nil
+	}
+
+	switch r.Type {
+	case feeTypeFlat:
+		return nil
+	case feeTypePercentage:
+		return checkBasisPoints(r.BasisPoints)
+	case feeTypeTiered:
+		if len(r.Tiers) == 0 {
+			return fmt.Errorf("tiered fee rule requires at least one tier")
+		}
+		for i, tier := range r.Tiers {
+			if tier.Flat < 0 {
+				return fmt.Errorf("fee amounts cannot be negative")
+			}
+			if err := checkBasisPoints(tier.BasisPoints); err != nil {
+				return err
+			}
+			last := i == len(r.Tiers)-1
+			if last && tier.UpTo != 0 {
+				return fmt.Errorf("the last tier must have upTo 0 (no upper bound)")
+			}
+			if !last && (tier.UpTo <= 0 || (i > 0 && tier.UpTo <= r.Tiers[i-1].UpTo)) {
+				return fmt.Errorf("tier upper bounds must be positive and ascending")
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid fee type %s: expected flat, percentage or tiered", r.Type)
+}
+
+// compute 计算金额对应的手续费，结果不超过金额本身
+func (r *FeeRule) compute(amount int) int {
+	flat := r.Flat
+	basisPoints := r.BasisPoints
+	if r.Type == feeTypeTiered {
+		for _, tier := range r.Tiers {
+			if tier.UpTo == 0 || amount <= tier.UpTo {
+				flat = tier.Flat
+				basisPoints = tier.BasisPoints
+				break
+			}
+		}
+	}
+
+	fee := flat
+	if r.Type != feeTypeFlat {
+		// 分两步计算避免大额乘法溢出，按四舍五入取整
+		fee += amount/basisPointsDenominator*basisPoints + (amount%basisPointsDenominator*basisPoints+basisPointsDenominator/2)/basisPointsDenominator
+	}
+
+	if fee < r.Min {
+		fee = r.Min
+	}
+	if r.Max > 0 && fee > r.Max {
+		fee = r.Max
+	}
+	if fee > amount {
+		fee = amount
+	}
+	return fee
+}
+
+// ruleFor 返回商户类别适用的规则，没有规则时返回 nil
+func (f *FeeSchedule) ruleFor(category string) (*FeeRule, string) {
+	if rule, ok := f.Categories[category]; ok && category != "" {
+		return &rule, category
+	}
+	return f.Default, ""
+}
+
+// getFeeSchedule 读取手续费方案，尚未设置时返回 nil
+func (s *SmartContract) getFeeSchedule(ctx contractapi.TransactionContextInterface) (*FeeSchedule, error) {
+	scheduleBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), feeScheduleKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fee schedule: %v", err)
+	}
+	if scheduleBytes == nil {
+		return nil, nil
+	}
+	var schedule FeeSchedule
+	if err := json.Unmarshal(scheduleBytes, &schedule); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal fee schedule: %v", err)
+	}
+	return &schedule, nil
+}
+
+// merchantCharge 计算向 to 付款时的商户手续费，不收费时返回 nil
+// 收款方不是商户、付款方与收款方相同或收款方就是手续费账户时不收费
+func (s *SmartContract) merchantCharge(ctx contractapi.TransactionContextInterface, from string, to string, amount int) (*merchantCharge, error) {
+	if from == to {
+		return nil, nil
+	}
+	merchant, err := s.getUserAccountInfo(ctx, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipient account %s from private collection: %v", to, err)
+	}
+	if merchant.AccountType != accountTypeMerchant {
+		return nil, nil
+	}
+
+	schedule, err := s.getFeeSchedule(ctx)
+	if err != nil || schedule == nil {
+		return nil, err
+	}
+	if schedule.CollectorAccount == to {
+		return nil, nil
+	}
+	rule, category := schedule.ruleFor(merchant.MerchantCategory)
+	if rule == nil {
+		return nil, nil
+	}
+
+	fee := rule.compute(amount)
+	if fee == 0 {
+		return nil, nil
+	}
+	return &merchantCharge{
+		Fee:       fee,
+		Net:       amount - fee,
+		Collector: schedule.CollectorAccount,
+		Category:  category,
+	}, nil
+}
+
+// paymentLegs 返回一笔支付的划转：付款方向收款方支付全额，收款方为商户时再由商户向手续费账户支付手续费
+func (s *SmartContract) paymentLegs(ctx contractapi.TransactionContextInterface, from string, to string, amount int) ([]transferLeg, *merchantCharge, error) {
+	legs := []transferLeg{{From: from, To: to, Value: amount}}
+	charge, err := s.merchantCharge(ctx, from, to, amount)
+	if err != nil {
+		return nil, nil, err
+	}
+	if charge != nil {
+		legs = append(legs, transferLeg{From: to, To: charge.Collector, Value: charge.Fee})
+	}
+	return legs, charge, nil
+}
+
+// applyTo 在交易记录中记录手续费与净额
+func (c *merchantCharge) applyTo(record *TransactionRecord) {
+	if c == nil {
+		return
+	}
+	record.Fee = c.Fee
+	record.NetAmount = c.Net
+	record.FeeAccount = c.Collector
+}
+
+// SetFeeSchedule 央行设置手续费方案，scheduleJSON 为 FeeSchedule 的 JSON（版本与修改信息由合约填写）
+// 示例：{"collectorAccount":"<clientID>","default":{"type":"percentage","basisPoints":50,"max":500},"categories":{"5411":{"type":"tiered","tiers":[{"upTo":10000,"flat":5},{"upTo":0,"basisPoints":30}]}}}
+func (s *SmartContract) SetFeeSchedule(ctx contractapi.TransactionContextInterface, scheduleJSON string) (string, error) {
+	// 检查合约初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	// 仅央行可以设置手续费方案
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	isCentralBank, err := isCentralBankMSP(ctx, clientMSPID)
+	if err != nil {
+		return "", err
+	}
+	if !isCentralBank {
+		return "", fmt.Errorf("client is not authorized to set the fee schedule")
+	}
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+
+	var schedule FeeSchedule
+	if err := json.Unmarshal([]byte(scheduleJSON), &schedule); err != nil {
+		return "", fmt.Errorf("invalid fee schedule: %v", err)
+	}
+	if schedule.Default != nil {
+		if err := schedule.Default.validate(); err != nil {
+			return "", fmt.Errorf("invalid default fee rule: %v", err)
+		}
+	}
+	for _, category := range sortedCategories(schedule.Categories) {
+		rule := schedule.Categories[category]
+		if !merchantCategoryPattern.MatchString(category) {
+			return "", fmt.Errorf("invalid merchant category %s: expected a 4-digit code", category)
+		}
+		if err := rule.validate(); err != nil {
+			return "", fmt.Errorf("invalid fee rule for category %s: %v", category, err)
+		}
+	}
+
+	// 手续费账户必须是已开立的账户
+	schedule.CollectorAccount, err = s.resolveAccount(ctx, schedule.CollectorAccount)
+	if err != nil {
+		return "", err
+	}
+	if schedule.CollectorAccount == "" {
+		return "", fmt.Errorf("collectorAccount is required")
+	}
+	collector, err := s.getUserAccountInfo(ctx, schedule.CollectorAccount)
+	if err != nil {
+		return "", fmt.Errorf("failed to read collector account: %v", err)
+	}
+	if err := checkAccountActive(collector, "collector"); err != nil {
+		return "", err
+	}
+	if collector.AccountType == accountTypeMerchant {
+		return "", fmt.Errorf("collector account cannot be a merchant account")
+	}
+
+	current, err := s.getFeeSchedule(ctx)
+	if err != nil {
+		return "", err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	schedule.Version = 1
+	if current != nil {
+		schedule.Version = current.Version + 1
+	}
+	schedule.UpdatedAt = timestamp.Seconds
+	schedule.UpdatedBy = callerID
+
+	scheduleBytes, err := json.Marshal(schedule)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal fee schedule: %v", err)
+	}
+	err = ctx.GetStub().PutPrivateData(privateCollection(ctx), feeScheduleKey, scheduleBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to store fee schedule: %v", err)
+	}
+
+	log.Printf("Fee schedule updated to version %d", schedule.Version)
+
+	return string(scheduleBytes), nil
+}
+
+// GetFeeSchedule 返回当前手续费方案，尚未设置时返回空对象
+func (s *SmartContract) GetFeeSchedule(ctx contractapi.TransactionContextInterface) (string, error) {
+	// 检查合约初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	schedule, err := s.getFeeSchedule(ctx)
+	if err != nil {
+		return "", err
+	}
+	if schedule == nil {
+		return "{}", nil
+	}
+
+	scheduleJSON, err := json.Marshal(schedule)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal fee schedule: %v", err)
+	}
+	return string(scheduleJSON), nil
+}
+
+// QuoteMerchantFee 试算调用者向 merchant 付款 amount 时的手续费与商户净收金额，不修改账本
+func (s *SmartContract) QuoteMerchantFee(ctx contractapi.TransactionContextInterface, merchant string, amount int) (string, error) {
+	// 检查合约初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	if amount <= 0 {
+		return "", fmt.Errorf("amount must be positive")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+	merchantID, err := s.resolveAccount(ctx, merchant)
+	if err != nil {
+		return "", err
+	}
+
+	charge, err := s.merchantCharge(ctx, callerID, merchantID, amount)
+	if err != nil {
+		return "", err
+	}
+	quote := map[string]interface{}{
+		"amount":    amount,
+		"fee":       0,
+		"netAmount": amount,
+	}
+	if charge != nil {
+		quote["fee"] = charge.Fee
+		quote["netAmount"] = charge.Net
+		quote["merchantCategory"] = charge.Category
+	}
+
+	quoteJSON, err := json.Marshal(quote)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal fee quote: %v", err)
+	}
+	return string(quoteJSON), nil
+}
+
+// SetMerchantCategory 商户所属银行的admin或央行admin设置商户类别码，用于匹配手续费规则
+// category 为空时清除类别，使用默认规则
+func (s *SmartContract) SetMerchantCategory(ctx contractapi.TransactionContextInterface, account string, category string) (string, error) {
+	// 检查合约初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+	if category != "" && !merchantCategoryPattern.MatchString(category) {
+		return "", fmt.Errorf("invalid merchant category %s: expected a 4-digit code", category)
+	}
+
+	accountID, err := s.resolveAccount(ctx, account)
+	if err != nil {
+		return "", err
+	}
+	merchant, err := s.getUserAccountInfo(ctx, accountID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read account %s: %v", account, err)
+	}
+	if err := checkAccountActive(merchant, "merchant"); err != nil {
+		return "", err
+	}
+	if merchant.AccountType != accountTypeMerchant {
+		return "", fmt.Errorf("account %s is not a merchant account", account)
+	}
+
+	// 检查权限
+	allowed, err := s.canManageAccount(ctx, callerID, merchant.OrgMSP)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "", fmt.Errorf("client is not authorized to manage account %s", account)
+	}
+
+	merchant.MerchantCategory = category
+	err = s.updateUserAccountInPrivateCollection(ctx, merchant)
+	if err != nil {
+		return "", err
+	}
+
+	merchantJSON, err := json.Marshal(merchant)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal account: %v", err)
+	}
+	return string(merchantJSON), nil
+}
+
+// buildISOCharges 构建 ISO 20022 ChrgsInf 元素（收款方承担，从收款金额中扣除）
+func (s *SmartContract) buildISOCharges(record *TransactionRecord, amountJSON func(int) map[string]interface{}) []map[string]interface{} {
+	collectorOrg, _ := s.extractDomainFromClientID(record.FeeAccount)
+	return []map[string]interface{}{
+		{
+			"Amt": amountJSON(record.Fee),
+			"Agt": map[string]interface{}{
+				"FinInstnId": map[string]interface{}{"Othr": map[string]interface{}{"Id": collectorOrg}},
+			},
+			"Tp": map[string]interface{}{"Cd": "COMM"},
+		},
+	}
+}
+
+// sortedCategories 返回排序后的商户类别码
+func sortedCategories(categories map[string]FeeRule) []string {
+	keys := make([]string, 0, len(categories))
+	for key := range categories {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
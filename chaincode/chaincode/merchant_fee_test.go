@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func setFeeSchedule(env *testEnv, caller testUser, scheduleJSON string) (*FeeSchedule, error) {
+	var schedule FeeSchedule
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		response, err := env.contract.SetFeeSchedule(ctx, scheduleJSON)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &schedule)
+	})
+	return &schedule, err
+}
+
+func setMerchantCategory(env *testEnv, caller testUser, account string, category string) error {
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.SetMerchantCategory(ctx, account, category)
+		return err
+	})
+	return err
+}
+
+func quoteMerchantFee(env *testEnv, caller testUser, merchant string, amount int) (map[string]interface{}, error) {
+	var quote map[string]interface{}
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		response, err := env.contract.QuoteMerchantFee(ctx, merchant, amount)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &quote)
+	})
+	return quote, err
+}
+
+// TestMerchantFees 按商户类别选择规则，付款时商户按净额入账，手续费划入手续费账户
+func TestMerchantFees(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 100)
+	merchant := newTestUser("Shop1", "a.example.com", "AMSP")
+	if err := openAccountAs(env, bankAAdmin, merchant.id, accountTypeMerchant); err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+	if err := setMerchantCategory(env, bankAAdmin, merchant.id, "5411"); err != nil {
+		t.Fatalf("SetMerchantCategory failed: %v", err)
+	}
+
+	schedule, err := setFeeSchedule(env, centralBankAdmin, `{"collectorAccount":"`+centralBankAdmin.id+`",`+
+		`"default":{"type":"percentage","basisPoints":100,"min":2},`+
+		`"categories":{"5411":{"type":"tiered","tiers":[{"upTo":50,"flat":1},{"upTo":0,"basisPoints":200}]}}}`)
+	if err != nil {
+		t.Fatalf("SetFeeSchedule failed: %v", err)
+	}
+	if schedule.Version != 1 || schedule.UpdatedBy != centralBankAdmin.id {
+		t.Fatalf("unexpected schedule: %+v", schedule)
+	}
+
+	for _, tc := range []struct {
+		category string
+		amount   int
+		fee      float64
+	}{
+		{"5411", 40, 1},   // 第一档固定金额
+		{"5411", 500, 10}, // 第二档 200 基点
+		{"", 40, 2},       // 默认规则，低于最低收费
+		{"", 500, 5},
+	} {
+		if err := setMerchantCategory(env, bankAAdmin, merchant.id, tc.category); err != nil {
+			t.Fatalf("SetMerchantCategory failed: %v", err)
+		}
+		quote, err := quoteMerchantFee(env, bankAUser, merchant.id, tc.amount)
+		if err != nil {
+			t.Fatalf("QuoteMerchantFee failed: %v", err)
+		}
+		if quote["fee"] != tc.fee || quote["netAmount"] != float64(tc.amount)-tc.fee {
+			t.Fatalf("quote for %d in category %q = %v, want fee %v", tc.amount, tc.category, quote, tc.fee)
+		}
+	}
+
+	// 付款方支付全额，商户按净额入账
+	stub := transfer(t, env, bankAUser, merchant, 60)
+	if got := balanceOf(t, env, merchant); got != 58 {
+		t.Fatalf("merchant balance = %d, want 58", got)
+	}
+	if got := balanceOf(t, env, centralBankAdmin); got != 902 {
+		t.Fatalf("collector balance = %d, want 902", got)
+	}
+	record := getTransactionRecord(t, env, stub.txID)
+	if record.Amount != 60 || record.Fee != 2 || record.NetAmount != 58 || record.FeeAccount != centralBankAdmin.id {
+		t.Fatalf("unexpected payment record: %+v", record)
+	}
+
+	// 非商户收款不收费
+	if quote, err := quoteMerchantFee(env, merchant, bankAUser.id, 60); err != nil || quote["fee"] != float64(0) {
+		t.Fatalf("quote for a non-merchant = %v, %v", quote, err)
+	}
+}
+
+func TestMerchantFeesReject(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 0)
+	merchant := newTestUser("Shop1", "a.example.com", "AMSP")
+	if err := openAccountAs(env, bankAAdmin, merchant.id, accountTypeMerchant); err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+	collector := `"collectorAccount":"` + centralBankAdmin.id + `"`
+
+	for _, tc := range []struct {
+		caller   testUser
+		schedule string
+		want     string
+	}{
+		{bankAAdmin, `{` + collector + `}`, "not authorized to set the fee schedule"},
+		{centralBankAdmin, `{` + collector + `,"default":{"type":"percentage","basisPoints":20000}}`, "basis points must be between"},
+		{centralBankAdmin, `{` + collector + `,"default":{"type":"tiered","tiers":[{"upTo":50,"flat":1}]}}`, "last tier must have upTo 0"},
+		{centralBankAdmin, `{` + collector + `,"default":{"type":"flat","flat":5,"min":10,"max":8}}`, "exceeds max fee"},
+		{centralBankAdmin, `{` + collector + `,"categories":{"54":{"type":"flat","flat":1}}}`, "invalid merchant category"},
+		{centralBankAdmin, `{"collectorAccount":"` + merchant.id + `"}`, "cannot be a merchant account"},
+		{centralBankAdmin, `{}`, "collectorAccount is required"},
+		{centralBankAdmin, `not json`, "invalid fee schedule"},
+	} {
+		if _, err := setFeeSchedule(env, tc.caller, tc.schedule); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("SetFeeSchedule(%s) error = %v, want %q", tc.schedule, err, tc.want)
+		}
+	}
+
+	if err := setMerchantCategory(env, newTestUser("Admin", "b.example.com", "BMSP"), merchant.id, "5411"); err == nil || !strings.Contains(err.Error(), "not authorized to manage") {
+		t.Fatalf("expected an authorization error, got %v", err)
+	}
+	if err := setMerchantCategory(env, bankAAdmin, merchant.id, "54a1"); err == nil || !strings.Contains(err.Error(), "invalid merchant category") {
+		t.Fatalf("expected an invalid category error, got %v", err)
+	}
+	if err := setMerchantCategory(env, bankAAdmin, bankAUser.id, "5411"); err == nil || !strings.Contains(err.Error(), "not a merchant account") {
+		t.Fatalf("expected a non-merchant error, got %v", err)
+	}
+	if _, err := quoteMerchantFee(env, bankAUser, merchant.id, 0); err == nil || !strings.Contains(err.Error(), "amount must be positive") {
+		t.Fatalf("expected an invalid amount error, got %v", err)
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// testLedger 测试用账本：公共状态、私有数据与键级背书策略，交易成功后才提交写入
+type testLedger struct {
+	state   map[string][]byte
+	private map[string]map[string][]byte
+	ep      map[string][]byte
+	txn     int
+	now     int64
+}
+
+func newTestLedger() *testLedger {
+	return &testLedger{state: map[string][]byte{}, private: map[string]map[string][]byte{}, ep: map[string][]byte{}, now: 1700000000}
+}
+
+// mockStub 只实现合约用到的 stub 方法；读取看不到本交易的写入，与 Fabric 一致
+type mockStub struct {
+	shim.ChaincodeStubInterface
+	ledger    *testLedger
+	txID      string
+	timestamp int64
+	writes    map[string][]byte
+	deletes   map[string]bool
+	pwrites   map[string]map[string][]byte
+	pdeletes  map[string]map[string]bool
+	preads    map[string]bool // 点读过的私有数据键，用于检查热点键是否进入读集
+	epWrites  map[string][]byte
+	events    map[string][]byte
+	transient map[string][]byte
+}
+
+func (l *testLedger) newStub() *mockStub {
+	l.txn++
+	l.now += 10
+	return &mockStub{
+		ledger:    l,
+		txID:      fmt.Sprintf("tx%04d", l.txn),
+		timestamp: l.now,
+		writes:    map[string][]byte{},
+		deletes:   map[string]bool{},
+		pwrites:   map[string]map[string][]byte{},
+		pdeletes:  map[string]map[string]bool{},
+		preads:    map[string]bool{},
+		epWrites:  map[string][]byte{},
+		events:    map[string][]byte{},
+		transient: map[string][]byte{},
+	}
+}
+
+func (s *mockStub) commit() {
+	for k, v := range s.writes {
+		s.ledger.state[k] = v
+	}
+	for k := range s.deletes {
+		delete(s.ledger.state, k)
+	}
+	for c, writes := range s.pwrites {
+		if s.ledger.private[c] == nil {
+			s.ledger.private[c] = map[string][]byte{}
+		}
+		for k, v := range writes {
+			s.ledger.private[c][k] = v
+		}
+	}
+	for c, deletes := range s.pdeletes {
+		for k := range deletes {
+			delete(s.ledger.private[c], k)
+		}
+	}
+	for k, v := range s.epWrites {
+		s.ledger.ep[k] = v
+	}
+}
+
+func (s *mockStub) GetTxID() string      { return s.txID }
+func (s *mockStub) GetChannelID() string { return "cbdc-channel" }
+func (s *mockStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return &timestamppb.Timestamp{Seconds: s.timestamp}, nil
+}
+func (s *mockStub) GetTransient() (map[string][]byte, error) { return s.transient, nil }
+func (s *mockStub) SetEvent(name string, payload []byte) error {
+	s.events = map[string][]byte{name: payload}
+	return nil
+}
+
+func (s *mockStub) GetState(key string) ([]byte, error) { return s.ledger.state[key], nil }
+func (s *mockStub) PutState(key string, value []byte) error {
+	if key == "" {
+		return fmt.Errorf("key must not be empty")
+	}
+	s.writes[key] = value
+	return nil
+}
+func (s *mockStub) DelState(key string) error {
+	s.deletes[key] = true
+	return nil
+}
+
+func (s *mockStub) GetPrivateData(collection string, key string) ([]byte, error) {
+	s.preads[key] = true
+	return s.ledger.private[collection][key], nil
+}
+func (s *mockStub) GetPrivateDataHash(collection string, key string) ([]byte, error) {
+	value := s.ledger.private[collection][key]
+	if value == nil {
+		return nil, nil
+	}
+	digest := sha256.Sum256(value)
+	return digest[:], nil
+}
+func (s *mockStub) PutPrivateData(collection string, key string, value []byte) error {
+	if key == "" {
+		return fmt.Errorf("key must not be empty")
+	}
+	if s.pwrites[collection] == nil {
+		s.pwrites[collection] = map[string][]byte{}
+	}
+	s.pwrites[collection][key] = value
+	return nil
+}
+func (s *mockStub) DelPrivateData(collection string, key string) error {
+	if s.pdeletes[collection] == nil {
+		s.pdeletes[collection] = map[string]bool{}
+	}
+	s.pdeletes[collection][key] = true
+	return nil
+}
+
+func (s *mockStub) SetStateValidationParameter(key string, ep []byte) error {
+	s.epWrites[key] = ep
+	return nil
+}
+func (s *mockStub) GetStateValidationParameter(key string) ([]byte, error) {
+	return s.ledger.ep[key], nil
+}
+func (s *mockStub) SetPrivateDataValidationParameter(collection string, key string, ep []byte) error {
+	s.epWrites[collection+":"+key] = ep
+	return nil
+}
+func (s *mockStub) GetPrivateDataValidationParameter(collection string, key string) ([]byte, error) {
+	return s.ledger.ep[collection+":"+key], nil
+}
+
+func (s *mockStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+func (s *mockStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	parts := strings.Split(compositeKey[1:len(compositeKey)-1], "\x00")
+	return parts[0], parts[1:], nil
+}
+
+func (s *mockStub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	return sortedIterator(s.ledger.state, inRange(startKey, endKey)), nil
+}
+func (s *mockStub) GetPrivateDataByRange(collection string, startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	return sortedIterator(s.ledger.private[collection], inRange(startKey, endKey)), nil
+}
+func (s *mockStub) GetStateByPartialCompositeKey(objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := shim.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return sortedIterator(s.ledger.state, func(k string) bool { return strings.HasPrefix(k, prefix) }), nil
+}
+func (s *mockStub) GetPrivateDataByPartialCompositeKey(collection string, objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := shim.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return sortedIterator(s.ledger.private[collection], func(k string) bool { return strings.HasPrefix(k, prefix) }), nil
+}
+
+// inRange 范围查询不返回组合键，与 Fabric 一致
+func inRange(startKey string, endKey string) func(string) bool {
+	return func(k string) bool {
+		if strings.HasPrefix(k, "\x00") {
+			return false
+		}
+		return k >= startKey && (endKey == "" || k < endKey)
+	}
+}
+
+type kvIterator struct {
+	kvs []*queryresult.KV
+	pos int
+}
+
+func (it *kvIterator) HasNext() bool { return it.pos < len(it.kvs) }
+func (it *kvIterator) Next() (*queryresult.KV, error) {
+	kv := it.kvs[it.pos]
+	it.pos++
+	return kv, nil
+}
+func (it *kvIterator) Close() error { return nil }
+
+func sortedIterator(values map[string][]byte, match func(string) bool) *kvIterator {
+	keys := []string{}
+	for k := range values {
+		if match(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	it := &kvIterator{}
+	for _, k := range keys {
+		it.kvs = append(it.kvs, &queryresult.KV{Key: k, Value: values[k]})
+	}
+	return it
+}
+
+// mockIdentity 测试用客户端身份
+type mockIdentity struct {
+	cid.ClientIdentity
+	id  string
+	msp string
+}
+
+func (m *mockIdentity) GetID() (string, error)    { return m.id, nil }
+func (m *mockIdentity) GetMSPID() (string, error) { return m.msp, nil }
+func (m *mockIdentity) GetAttributeValue(string) (string, bool, error) {
+	return "", false, nil
+}
+func (m *mockIdentity) GetX509Certificate() (*x509.Certificate, error) { return nil, nil }
+
+// testUser 测试用户：clientID 与 Fabric 的 x509 身份格式一致
+type testUser struct {
+	id  string
+	msp string
+}
+
+func newTestUser(name string, domain string, msp string) testUser {
+	subject := fmt.Sprintf("x509::CN=%s@%s,OU=client,O=%s,L=San Francisco,ST=California,C=US::CN=ca.%s,O=%s,L=San Francisco,ST=California,C=US", name, domain, domain, domain, domain)
+	return testUser{id: base64.StdEncoding.EncodeToString([]byte(subject)), msp: msp}
+}
+
+var (
+	centralBankAdmin = newTestUser("Admin", CENTRAL_BANK_DOMAIN, CENTRAL_MSP_ID)
+	bankAAdmin       = newTestUser("Admin", "a.example.com", "AMSP")
+	bankAUser        = newTestUser("User1", "a.example.com", "AMSP")
+)
+
+// testEnv 在同一账本上依次执行交易
+type testEnv struct {
+	t        *testing.T
+	ledger   *testLedger
+	contract *SmartContract
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	return &testEnv{t: t, ledger: newTestLedger(), contract: &SmartContract{}}
+}
+
+// invoke 以 caller 身份执行 fn，成功时提交写入
+func (e *testEnv) invoke(caller testUser, transient map[string][]byte, fn func(ctx contractapi.TransactionContextInterface) error) (*mockStub, error) {
+	stub := e.ledger.newStub()
+	for k, v := range transient {
+		stub.transient[k] = v
+	}
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(&mockIdentity{id: caller.id, msp: caller.msp})
+	err := fn(ctx)
+	if err == nil {
+		stub.commit()
+	}
+	return stub, err
+}
+
+// initialize 央行以默认配置初始化合约
+func (e *testEnv) initialize() {
+	e.t.Helper()
+	_, err := e.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := e.contract.Initialize(ctx, "CBDC", "DCEP", "2")
+		return err
+	})
+	if err != nil {
+		e.t.Fatalf("Initialize failed: %v", err)
+	}
+}
+
+// seedAccount 初始化合约，银行admin为 bankAUser 开户，央行铸币 1000 后向其转入 amount
+func seedAccount(t *testing.T, env *testEnv, amount int) {
+	t.Helper()
+	env.initialize()
+	_, err := env.invoke(bankAAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.OpenAccount(ctx, bankAUser.id, accountTypeIndividual)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+	_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.Mint(ctx, 1000)
+	})
+	if err != nil {
+		t.Fatalf("Mint failed: %v", err)
+	}
+	if amount > 0 {
+		transfer(t, env, centralBankAdmin, bankAUser, amount)
+	}
+}
+
+// balanceOf 以央行身份查询账户余额
+func balanceOf(t *testing.T, env *testEnv, user testUser) int {
+	t.Helper()
+	var balance int
+	_, err := env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		balance, err = env.contract.BalanceOf(ctx, user.id)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("BalanceOf failed: %v", err)
+	}
+	return balance
+}
@@ -0,0 +1,274 @@
+/*
+Pedersen 承诺与范围证明
+
+- 在 P-256 上计算承诺 C = v·G + r·H，H 由固定标签哈希到曲线得到，无人知道其相对 G 的离散对数
+- 承诺具有加法同态性：输入承诺之和等于输出承诺之和即证明金额守恒，无需知道金额
+- 范围证明把金额按位分解，每一位给出一个 0/1 的 OR 证明（Fiat-Shamir 非交互），证明 0 ≤ v < 2^64
+- 点以压缩格式、标量以 32 字节大端序的 base64 编码
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// rangeProofBits 范围证明覆盖的位数
+const rangeProofBits = 64
+
+// pedersenHTag 生成元 H 的派生标签
+const pedersenHTag = "CBDC Pedersen generator H v1"
+
+// rangeProofTag 范围证明 Fiat-Shamir 挑战的域分隔标签
+const rangeProofTag = "CBDC range proof v1"
+
+var (
+	pedersenCurve = elliptic.P256()
+	curveOrder    = pedersenCurve.Params().N
+	pedersenG     = ecPoint{X: pedersenCurve.Params().Gx, Y: pedersenCurve.Params().Gy}
+	pedersenH     = derivePedersenH()
+)
+
+// ecPoint 曲线上的点，(0,0) 表示无穷远点
+type ecPoint struct {
+	X, Y *big.Int
+}
+
+// derivePedersenH 对递增计数器哈希，取第一个落在曲线上的 x 坐标作为 H
+func derivePedersenH() ecPoint {
+	for counter := uint32(0); ; counter++ {
+		var buf [4]byte
+		binary.BigEndian.PutUint32(buf[:], counter)
+		digest := sha256.Sum256(append([]byte(pedersenHTag), buf[:]...))
+		compressed := append([]byte{0x02}, digest[:]...)
+		if x, y := elliptic.UnmarshalCompressed(pedersenCurve, compressed); x != nil {
+			return ecPoint{X: x, Y: y}
+		}
+	}
+}
+
+func (p ecPoint) isIdentity() bool {
+	return p.X.Sign() == 0 && p.Y.Sign() == 0
+}
+
+func (p ecPoint) equal(q ecPoint) bool {
+	return p.X.Cmp(q.X) == 0 && p.Y.Cmp(q.Y) == 0
+}
+
+func (p ecPoint) add(q ecPoint) ecPoint {
+	x, y := pedersenCurve.Add(p.X, p.Y, q.X, q.Y)
+	return ecPoint{X: x, Y: y}
+}
+
+func (p ecPoint) neg() ecPoint {
+	if p.isIdentity() {
+		return p
+	}
+	return ecPoint{X: new(big.Int).Set(p.X), Y: new(big.Int).Sub(pedersenCurve.Params().P, p.Y)}
+}
+
+func (p ecPoint) sub(q ecPoint) ecPoint {
+	return p.add(q.neg())
+}
+
+// mul 计算 k·p，k 先按群阶取模
+func (p ecPoint) mul(k *big.Int) ecPoint {
+	x, y := pedersenCurve.ScalarMult(p.X, p.Y, scalarBytes(k))
+	return ecPoint{X: x, Y: y}
+}
+
+// scalarBytes 标量按群阶取模后的 32 字节大端序表示
+func scalarBytes(k *big.Int) []byte {
+	return new(big.Int).Mod(k, curveOrder).FillBytes(make([]byte, 32))
+}
+
+// pedersenCommit 计算 value·G + blinding·H
+func pedersenCommit(value *big.Int, blinding *big.Int) ecPoint {
+	return pedersenG.mul(value).add(pedersenH.mul(blinding))
+}
+
+// encodePoint 压缩格式的 base64 编码
+func encodePoint(p ecPoint) string {
+	return base64.StdEncoding.EncodeToString(elliptic.MarshalCompressed(pedersenCurve, p.X, p.Y))
+}
+
+// decodePoint 解码并校验点在曲线上，拒绝无穷远点
+func decodePoint(encoded string) (ecPoint, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return ecPoint{}, fmt.Errorf("invalid point encoding: %v", err)
+	}
+	x, y := elliptic.UnmarshalCompressed(pedersenCurve, raw)
+	if x == nil {
+		return ecPoint{}, fmt.Errorf("invalid point: not a compressed P-256 point")
+	}
+	return ecPoint{X: x, Y: y}, nil
+}
+
+// encodeScalar 32 字节大端序的 base64 编码
+func encodeScalar(k *big.Int) string {
+	return base64.StdEncoding.EncodeToString(scalarBytes(k))
+}
+
+// decodeScalar 解码标量，要求小于群阶
+func decodeScalar(encoded string) (*big.Int, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scalar encoding: %v", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("invalid scalar: expected 32 bytes, got %d", len(raw))
+	}
+	k := new(big.Int).SetBytes(raw)
+	if k.Cmp(curveOrder) >= 0 {
+		return nil, fmt.Errorf("invalid scalar: not reduced modulo the group order")
+	}
+	return k, nil
+}
+
+// randomScalar 从 rand 读取 [1, n) 内的随机标量
+func randomScalar(rand io.Reader) (*big.Int, error) {
+	for {
+		buf := make([]byte, 32)
+		if _, err := io.ReadFull(rand, buf); err != nil {
+			return nil, fmt.Errorf("failed to read randomness: %v", err)
+		}
+		k := new(big.Int).SetBytes(buf)
+		if k.Sign() > 0 && k.Cmp(curveOrder) < 0 {
+			return k, nil
+		}
+	}
+}
+
+// RangeProof 证明承诺中的金额在 [0, 2^64) 内
+type RangeProof struct {
+	Bits []BitProof `json:"bits"`
+}
+
+// BitProof 一位的承诺与 0/1 OR 证明
+// 分支 j 的陈述为 P_j = C_i - j·G = x·H，两个分支组成环签名：e1 = hash(A0)、e0 = hash(A1)，
+// 其中 A_j = s_j·H - e_j·P_j；只需提交 e0、s0、s1，验证时依次重算 A0、e1、A1 并核对 e0
+type BitProof struct {
+	Commitment string `json:"c"`
+	E0         string `json:"e0"`
+	S0         string `json:"s0"`
+	S1         string `json:"s1"`
+}
+
+// bitChallenge 第 index 位环上的 Fiat-Shamir 挑战，绑定整体承诺、位承诺与前一分支的承诺
+func bitChallenge(commitment ecPoint, index int, bit ecPoint, link ecPoint) *big.Int {
+	h := sha256.New()
+	h.Write([]byte(rangeProofTag))
+	for _, p := range []ecPoint{commitment, bit, link} {
+		h.Write(elliptic.Marshal(pedersenCurve, p.X, p.Y))
+	}
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(index))
+	h.Write(buf[:])
+	return new(big.Int).Mod(new(big.Int).SetBytes(h.Sum(nil)), curveOrder)
+}
+
+// ringCommitment 计算 s·H - e·P
+func ringCommitment(s *big.Int, e *big.Int, statement ecPoint) ecPoint {
+	return pedersenH.mul(s).sub(statement.mul(e))
+}
+
+// proveRange 为 commit(value, blinding) 生成范围证明
+// 各位盲化因子按 2 的幂加权求和等于 blinding，最高位的盲化因子由其余位倒推
+func proveRange(value uint64, blinding *big.Int, rand io.Reader) (*RangeProof, error) {
+	commitment := pedersenCommit(new(big.Int).SetUint64(value), blinding)
+
+	blindings := make([]*big.Int, rangeProofBits)
+	weighted := new(big.Int)
+	for i := 0; i < rangeProofBits-1; i++ {
+		r, err := randomScalar(rand)
+		if err != nil {
+			return nil, err
+		}
+		blindings[i] = r
+		weighted.Add(weighted, new(big.Int).Lsh(r, uint(i)))
+	}
+	top := new(big.Int).Lsh(big.NewInt(1), rangeProofBits-1)
+	last := new(big.Int).Sub(blinding, weighted)
+	last.Mul(last, new(big.Int).ModInverse(top, curveOrder))
+	blindings[rangeProofBits-1] = last.Mod(last, curveOrder)
+
+	proof := &RangeProof{Bits: make([]BitProof, rangeProofBits)}
+	for i := 0; i < rangeProofBits; i++ {
+		bit := int((value >> uint(i)) & 1)
+		x := blindings[i]
+		bitCommitment := pedersenCommit(big.NewInt(int64(bit)), x)
+		statements := [2]ecPoint{bitCommitment, bitCommitment.sub(pedersenG)}
+
+		// 真实分支从随机承诺 k·H 出发，沿环模拟另一分支，最后用 x 闭合真实分支的响应
+		k, err := randomScalar(rand)
+		if err != nil {
+			return nil, err
+		}
+		fakeS, err := randomScalar(rand)
+		if err != nil {
+			return nil, err
+		}
+		var es, ss [2]*big.Int
+		es[1-bit] = bitChallenge(commitment, i, bitCommitment, pedersenH.mul(k))
+		ss[1-bit] = fakeS
+		es[bit] = bitChallenge(commitment, i, bitCommitment, ringCommitment(fakeS, es[1-bit], statements[1-bit]))
+		realS := new(big.Int).Mul(es[bit], x)
+		realS.Add(realS, k)
+		ss[bit] = realS.Mod(realS, curveOrder)
+
+		proof.Bits[i] = BitProof{
+			Commitment: encodePoint(bitCommitment),
+			E0:         encodeScalar(es[0]),
+			S0:         encodeScalar(ss[0]),
+			S1:         encodeScalar(ss[1]),
+		}
+	}
+	return proof, nil
+}
+
+// verifyRange 校验 proof 证明 commitment 中的金额在 [0, 2^64) 内
+func verifyRange(commitment ecPoint, proof *RangeProof) error {
+	if proof == nil || len(proof.Bits) != rangeProofBits {
+		return fmt.Errorf("range proof must contain %d bit proofs", rangeProofBits)
+	}
+
+	sum := ecPoint{X: new(big.Int), Y: new(big.Int)}
+	for i, bitProof := range proof.Bits {
+		bitCommitment, err := decodePoint(bitProof.Commitment)
+		if err != nil {
+			return fmt.Errorf("bit %d: %v", i, err)
+		}
+		e0, err := decodeScalar(bitProof.E0)
+		if err != nil {
+			return fmt.Errorf("bit %d: %v", i, err)
+		}
+		s0, err := decodeScalar(bitProof.S0)
+		if err != nil {
+			return fmt.Errorf("bit %d: %v", i, err)
+		}
+		s1, err := decodeScalar(bitProof.S1)
+		if err != nil {
+			return fmt.Errorf("bit %d: %v", i, err)
+		}
+
+		e1 := bitChallenge(commitment, i, bitCommitment, ringCommitment(s0, e0, bitCommitment))
+		closing := bitChallenge(commitment, i, bitCommitment, ringCommitment(s1, e1, bitCommitment.sub(pedersenG)))
+		if closing.Cmp(e0) != 0 {
+			return fmt.Errorf("bit %d: proof does not verify", i)
+		}
+		sum = sum.add(bitCommitment.mul(new(big.Int).Lsh(big.NewInt(1), uint(i))))
+	}
+	if !sum.equal(commitment) {
+		return fmt.Errorf("bit commitments do not sum to the commitment")
+	}
+	return nil
+}
@@ -0,0 +1,461 @@
+/*
+账本数据版本与迁移
+
+- 账本数据版本保存在央行私有集合的 schemastate 中；版本号为从第一个步骤起连续完成的最后一个步骤的版本
+- 注册的迁移步骤：balances 将纯数字余额改写为账户记录并补全用户ID、组织与账户状态，
+  allowances 将纯数字授权额度改写为授权记录，transactionRecords 将旧格式交易记录改写为当前版本，
+  balanceHistory 为对账单补写按月分桶的账户索引与月末余额
+- 央行admin调用 RunMigration 分批执行，进度（书签与累计条数）随每批保存，书签为空时从保存的进度继续；
+  balanceHistory 依赖 transactionRecords 建立的账户索引，其余步骤互不依赖，已完成的步骤再次执行直接返回完成
+- 迁移期间读取路径照常兼容该步骤处理的旧格式；步骤完成后只接受当前格式，遇到旧格式报错并提示对应的迁移步骤
+- 新初始化的合约没有旧格式数据，直接记为最新版本
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// schemaStateKey 账本数据版本与迁移进度的存储键
+const schemaStateKey = "schemastate"
+
+// defaultMigrationBatchSize 迁移默认批大小
+const defaultMigrationBatchSize = 100
+
+// maxMigrationBatchSize 迁移最大批大小的默认值，实际取自合约配置
+const maxMigrationBatchSize = 500
+
+// 迁移步骤名称
+const (
+	migrationBalances           = "balances"
+	migrationAllowances         = "allowances"
+	migrationTransactionRecords = "transactionRecords"
+	migrationBalanceHistory     = "balanceHistory"
+)
+
+// migrationBatch 一批迁移的结果
+type migrationBatch struct {
+	Scanned  int
+	Migrated int
+	Bookmark string // 下一批的书签
+	Done     bool
+}
+
+// migrationStep 注册的迁移步骤，完成后账本数据达到 Version
+type migrationStep struct {
+	Name        string
+	Version     int
+	Description string
+	run         func(s *SmartContract, ctx contractapi.TransactionContextInterface, bookmark string, batchSize int) (*migrationBatch, error)
+}
+
+// migrationSteps 迁移步骤注册表，按执行顺序排列，版本号依次递增
+var migrationSteps = []migrationStep{
+	{Name: migrationBalances, Version: 1, Description: "rewrite plain-number balances as account records and fill userId, orgMsp and account status", run: (*SmartContract).migrateBalanceBatch},
+	{Name: migrationAllowances, Version: 2, Description: "rewrite plain-number allowances as allowance records", run: (*SmartContract).migrateAllowanceBatch},
+	{Name: migrationTransactionRecords, Version: 3, Description: "merge legacy tx_/query_ records into the current transaction record schema", run: (*SmartContract).migrateTransactionBatch},
+	{Name: migrationBalanceHistory, Version: 4, Description: "backfill per-month account indexes and month-end balances for statements", run: (*SmartContract).migrateBalanceHistoryBatch},
+}
+
+// ledgerSchemaVersion 当前代码要求的账本数据版本
+var ledgerSchemaVersion = migrationSteps[len(migrationSteps)-1].Version
+
+// MigrationProgress 单个迁移步骤的进度
+type MigrationProgress struct {
+	Bookmark    string `json:"bookmark,omitempty"`
+	Scanned     int    `json:"scanned"`
+	Migrated    int    `json:"migrated"`
+	Batches     int    `json:"batches"`
+	Done        bool   `json:"done"`
+	StartedAt   int64  `json:"startedAt,omitempty"`
+	CompletedAt int64  `json:"completedAt,omitempty"`
+	LastTxID    string `json:"lastTxId,omitempty"`
+}
+
+// SchemaState 账本数据版本与各步骤进度
+type SchemaState struct {
+	Version int                           `json:"version"`
+	Steps   map[string]*MigrationProgress `json:"steps"`
+}
+
+// getSchemaState 读取账本数据版本；早于本功能初始化的合约返回版本 0
+func getSchemaState(ctx contractapi.TransactionContextInterface) (*SchemaState, error) {
+	stateBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), schemaStateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema state: %v", err)
+	}
+	state := &SchemaState{Steps: map[string]*MigrationProgress{}}
+	if stateBytes != nil {
+		if err := json.Unmarshal(stateBytes, state); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal schema state: %v", err)
+		}
+		if state.Steps == nil {
+			state.Steps = map[string]*MigrationProgress{}
+		}
+	}
+	return state, nil
+}
+
+func putSchemaState(ctx contractapi.TransactionContextInterface, state *SchemaState) error {
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema state: %v", err)
+	}
+	if err := ctx.GetStub().PutPrivateData(privateCollection(ctx), schemaStateKey, stateBytes); err != nil {
+		return fmt.Errorf("failed to store schema state: %v", err)
+	}
+	return nil
+}
+
+// initSchemaState Initialize 时将账本记为最新版本，所有迁移步骤视为已完成
+func initSchemaState(ctx contractapi.TransactionContextInterface) error {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	state := &SchemaState{Version: ledgerSchemaVersion, Steps: map[string]*MigrationProgress{}}
+	for _, step := range migrationSteps {
+		state.Steps[step.Name] = &MigrationProgress{Done: true, CompletedAt: timestamp.Seconds, LastTxID: ctx.GetStub().GetTxID()}
+	}
+	return putSchemaState(ctx, state)
+}
+
+// legacyFormatError 遇到未迁移的旧格式记录时的错误
+func legacyFormatError(kind string, key string, step string) error {
+	return fmt.Errorf("%s %s is stored in a legacy format, run RunMigration %s", kind, key, step)
+}
+
+// RunMigration 央行admin分批执行迁移步骤
+// bookmark 为上一批返回的书签，为空时从保存的进度继续；batchSize 不大于配置的上限
+func (s *SmartContract) RunMigration(ctx contractapi.TransactionContextInterface, step string, bookmark string, batchSize int) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", errors.New("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	// 仅央行管理员可以执行迁移
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+	isCentralBank, err := callerIsCentralBank(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !isCentralBank || !s.isAdminUser(ctx, callerID) {
+		return "", errors.New("client is not authorized to run migrations")
+	}
+
+	if batchSize <= 0 {
+		batchSize = defaultMigrationBatchSize
+	}
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if batchSize > config.Limits.MaxMigrationBatchSize {
+		batchSize = config.Limits.MaxMigrationBatchSize
+	}
+
+	state, err := getSchemaState(ctx)
+	if err != nil {
+		return "", err
+	}
+	position := -1
+	names := make([]string, 0, len(migrationSteps))
+	for i, registered := range migrationSteps {
+		names = append(names, registered.Name)
+		if registered.Name == step {
+			position = i
+		}
+	}
+	if position < 0 {
+		return "", fmt.Errorf("unknown migration step %s, expected one of %s", step, strings.Join(names, ", "))
+	}
+	current := migrationSteps[position]
+
+	progress := state.Steps[step]
+	if progress == nil {
+		progress = &MigrationProgress{}
+		state.Steps[step] = progress
+	}
+	if progress.Done {
+		return migrationResponse(step, &migrationBatch{Done: true}, state)
+	}
+	if bookmark == "" {
+		bookmark = progress.Bookmark
+	}
+
+	batch, err := current.run(s, ctx, bookmark, batchSize)
+	if err != nil {
+		return "", err
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	if progress.StartedAt == 0 {
+		progress.StartedAt = timestamp.Seconds
+	}
+	progress.Bookmark = batch.Bookmark
+	progress.Scanned += batch.Scanned
+	progress.Migrated += batch.Migrated
+	progress.Batches++
+	progress.LastTxID = ctx.GetStub().GetTxID()
+	if batch.Done {
+		progress.Done = true
+		progress.CompletedAt = timestamp.Seconds
+		state.Version = completedSchemaVersion(state)
+	}
+	if err := putSchemaState(ctx, state); err != nil {
+		return "", err
+	}
+
+	log.Printf("migration %s batch: scanned %d, migrated %d, done %t", step, batch.Scanned, batch.Migrated, batch.Done)
+
+	return migrationResponse(step, batch, state)
+}
+
+// migrationResponse RunMigration 的返回结果
+func migrationResponse(step string, batch *migrationBatch, state *SchemaState) (string, error) {
+	responseJSON, err := json.Marshal(map[string]interface{}{
+		"step":                step,
+		"scanned":             batch.Scanned,
+		"migrated":            batch.Migrated,
+		"bookmark":            batch.Bookmark,
+		"done":                batch.Done,
+		"schemaVersion":       state.Version,
+		"targetSchemaVersion": ledgerSchemaVersion,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal response: %v", err)
+	}
+	return string(responseJSON), nil
+}
+
+// completedSchemaVersion 从第一个步骤起连续完成的最后一个步骤的版本
+func completedSchemaVersion(state *SchemaState) int {
+	version := 0
+	for _, step := range migrationSteps {
+		if progress := state.Steps[step.Name]; progress == nil || !progress.Done {
+			break
+		}
+		version = step.Version
+	}
+	return version
+}
+
+// migrationDone 迁移步骤是否已完成；完成前读取路径兼容该步骤处理的旧格式
+func migrationDone(ctx contractapi.TransactionContextInterface, step string) (bool, error) {
+	state, err := getSchemaState(ctx)
+	if err != nil {
+		return false, err
+	}
+	progress := state.Steps[step]
+	return progress != nil && progress.Done, nil
+}
+
+// GetMigrationStatus 央行查询账本数据版本与各迁移步骤的进度
+func (s *SmartContract) GetMigrationStatus(ctx contractapi.TransactionContextInterface) (string, error) {
+	if err := s.checkAnalyticsAccess(ctx); err != nil {
+		return "", err
+	}
+
+	state, err := getSchemaState(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	type stepStatus struct {
+		Name        string `json:"name"`
+		Version     int    `json:"version"`
+		Description string `json:"description"`
+		MigrationProgress
+	}
+	steps := []stepStatus{}
+	pending := []string{}
+	for _, step := range migrationSteps {
+		status := stepStatus{Name: step.Name, Version: step.Version, Description: step.Description}
+		if progress := state.Steps[step.Name]; progress != nil {
+			status.MigrationProgress = *progress
+		}
+		if !status.Done {
+			pending = append(pending, step.Name)
+		}
+		steps = append(steps, status)
+	}
+
+	responseJSON, err := json.Marshal(map[string]interface{}{
+		"schemaVersion":       state.Version,
+		"targetSchemaVersion": ledgerSchemaVersion,
+		"upToDate":            len(pending) == 0,
+		"pending":             pending,
+		"steps":               steps,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal response: %v", err)
+	}
+	return string(responseJSON), nil
+}
+
+// migrateBalanceBatch 迁移步骤 balances：按键顺序改写 balance_ 记录，书签为最后处理的键
+func (s *SmartContract) migrateBalanceBatch(ctx contractapi.TransactionContextInterface, bookmark string, batchSize int) (*migrationBatch, error) {
+	startKey := balancePrefix
+	if bookmark != "" {
+		if !strings.HasPrefix(bookmark, balancePrefix) {
+			return nil, fmt.Errorf("invalid bookmark %s", bookmark)
+		}
+		startKey = bookmark + "\x00"
+	}
+	iterator, err := ctx.GetStub().GetPrivateDataByRange(privateCollection(ctx), startKey, balancePrefix[:len(balancePrefix)-1]+"`")
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan balances: %v", err)
+	}
+	defer iterator.Close()
+
+	batch := &migrationBatch{}
+	for batch.Scanned < batchSize && iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next balance: %v", err)
+		}
+		batch.Scanned++
+		batch.Bookmark = kv.Key
+
+		account, legacy, err := s.upgradeLegacyBalance(kv.Key[len(balancePrefix):], kv.Value)
+		if err != nil {
+			return nil, err
+		}
+		if !legacy {
+			continue
+		}
+		if err := s.updateUserAccountInPrivateCollection(ctx, account); err != nil {
+			return nil, err
+		}
+		batch.Migrated++
+	}
+	if !iterator.HasNext() {
+		batch.Bookmark = ""
+		batch.Done = true
+	}
+	return batch, nil
+}
+
+// upgradeLegacyBalance 将一条余额记录转换为当前格式，返回是否为旧格式
+// 纯数字余额与账户生命周期之前的记录视为已开立的个人账户，缺失的组织按客户端ID补全
+func (s *SmartContract) upgradeLegacyBalance(userID string, value []byte) (*UserBalance, bool, error) {
+	account := &UserBalance{}
+	legacy := false
+	if err := json.Unmarshal(value, account); err != nil {
+		balance, convErr := strconv.Atoi(string(value))
+		if convErr != nil {
+			return nil, false, fmt.Errorf("failed to parse balance of %s: %v", userID, err)
+		}
+		account = &UserBalance{Balance: balance}
+		legacy = true
+	}
+	if account.UserID == "" {
+		account.UserID = userID
+		legacy = true
+	}
+	if account.Status == "" {
+		account.AccountType = accountTypeIndividual
+		account.Status = accountStatusActive
+		legacy = true
+	}
+	if account.OrgMSP == "" {
+		if orgMSP, err := s.extractOrgMSPFromClientID(userID); err == nil {
+			account.OrgMSP = orgMSP
+			legacy = true
+		}
+	}
+	return account, legacy, nil
+}
+
+// decodeBalanceRecord 解析余额记录，不回写存储；迁移步骤 balances 完成后旧格式报错
+func (s *SmartContract) decodeBalanceRecord(ctx contractapi.TransactionContextInterface, userID string, value []byte) (*UserBalance, error) {
+	account, legacy, err := s.upgradeLegacyBalance(userID, value)
+	if err != nil {
+		return nil, err
+	}
+	if legacy {
+		done, err := migrationDone(ctx, migrationBalances)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return nil, legacyFormatError("balance of", userID, migrationBalances)
+		}
+	}
+	return account, nil
+}
+
+// migrateAllowanceBatch 迁移步骤 allowances：按组合键顺序改写授权记录
+// 组合键含 \x00，书签为最后处理的键的 base64url 编码
+func (s *SmartContract) migrateAllowanceBatch(ctx contractapi.TransactionContextInterface, bookmark string, batchSize int) (*migrationBatch, error) {
+	lastKey := ""
+	if bookmark != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(bookmark)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bookmark: %v", err)
+		}
+		lastKey = string(raw)
+	}
+
+	stub := ctx.GetStub()
+	iterator, err := stub.GetPrivateDataByPartialCompositeKey(privateCollection(ctx), allowancePrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan allowances: %v", err)
+	}
+	defer iterator.Close()
+
+	batch := &migrationBatch{}
+	for batch.Scanned < batchSize && iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate allowances: %v", err)
+		}
+		// 私有数据的组合键查询不支持从指定键开始，跳过已处理的键
+		if kv.Key <= lastKey {
+			continue
+		}
+		batch.Scanned++
+		batch.Bookmark = base64.RawURLEncoding.EncodeToString([]byte(kv.Key))
+
+		_, keyParts, err := stub.SplitCompositeKey(kv.Key)
+		if err != nil || len(keyParts) != 2 {
+			return nil, fmt.Errorf("invalid allowance key %s", kv.Key)
+		}
+		record, legacy, err := upgradeLegacyAllowance(keyParts[0], keyParts[1], kv.Value)
+		if err != nil {
+			return nil, err
+		}
+		if !legacy {
+			continue
+		}
+		if err := s.putAllowanceRecord(ctx, record); err != nil {
+			return nil, err
+		}
+		batch.Migrated++
+	}
+	if !iterator.HasNext() {
+		batch.Bookmark = ""
+		batch.Done = true
+	}
+	return batch, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// seedLegacyLedger 模拟早于数据版本的账本：删除 schemastate 并写入旧格式的余额与授权
+func seedLegacyLedger(t *testing.T, env *testEnv) {
+	t.Helper()
+	private := env.ledger.private[defaultPrivateCollection]
+	delete(private, schemaStateKey)
+	private[balancePrefix+bankAUser.id] = []byte("500")
+	private[balancePrefix+bankAAdmin.id] = []byte(`{"userId":"","balance":30}`)
+	allowanceKey, err := env.ledger.newStub().CreateCompositeKey(allowancePrefix, []string{bankAUser.id, bankAAdmin.id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	private[allowanceKey] = []byte("25")
+}
+
+func runMigration(env *testEnv, step string, batchSize int) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	_, err := env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		response, err := env.contract.RunMigration(ctx, step, "", batchSize)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &result)
+	})
+	return result, err
+}
+
+func readBalanceAndAllowance(env *testEnv) (int, int, error) {
+	var balance, allowance int
+	_, err := env.invoke(bankAUser, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		if balance, err = env.contract.ClientAccountBalance(ctx); err != nil {
+			return err
+		}
+		allowance, err = env.contract.Allowance(ctx, bankAUser.id, bankAAdmin.id)
+		return err
+	})
+	return balance, allowance, err
+}
+
+func TestLegacyRecordsReadableDuringMigration(t *testing.T) {
+	env := newTestEnv(t)
+	env.initialize()
+	seedLegacyLedger(t, env)
+
+	balance, allowance, err := readBalanceAndAllowance(env)
+	if err != nil {
+		t.Fatalf("legacy records must stay readable before migration: %v", err)
+	}
+	if balance != 500 || allowance != 25 {
+		t.Fatalf("got balance %d allowance %d, want 500 and 25", balance, allowance)
+	}
+
+	// 第一批只处理一条余额，迁移中途仍可读取
+	result, err := runMigration(env, migrationBalances, 1)
+	if err != nil {
+		t.Fatalf("RunMigration failed: %v", err)
+	}
+	if result["done"] != false || result["bookmark"] == "" {
+		t.Fatalf("expected a partial batch, got %v", result)
+	}
+	if _, _, err := readBalanceAndAllowance(env); err != nil {
+		t.Fatalf("records must stay readable mid-migration: %v", err)
+	}
+
+	for i, step := range []string{migrationBalances, migrationAllowances} {
+		result, err := runMigration(env, step, 10)
+		if err != nil {
+			t.Fatalf("RunMigration %s failed: %v", step, err)
+		}
+		if result["done"] != true || result["schemaVersion"] != float64(migrationSteps[i].Version) {
+			t.Fatalf("RunMigration %s did not complete: %v", step, result)
+		}
+	}
+
+	balance, allowance, err = readBalanceAndAllowance(env)
+	if err != nil || balance != 500 || allowance != 25 {
+		t.Fatalf("after migration got balance %d allowance %d err %v", balance, allowance, err)
+	}
+	var account UserBalance
+	if err := json.Unmarshal(env.ledger.private[defaultPrivateCollection][balancePrefix+bankAUser.id], &account); err != nil {
+		t.Fatalf("balance was not rewritten: %v", err)
+	}
+	if account.Status != accountStatusActive || account.OrgMSP != "a.example.com" {
+		t.Fatalf("unexpected migrated account %+v", account)
+	}
+
+	// 完成后再出现的旧格式记录报错
+	env.ledger.private[defaultPrivateCollection][balancePrefix+bankAUser.id] = []byte("500")
+	if _, _, err := readBalanceAndAllowance(env); err == nil || !strings.Contains(err.Error(), "RunMigration balances") {
+		t.Fatalf("expected legacy format error after migration, got %v", err)
+	}
+}
+
+func TestRunMigrationSteps(t *testing.T) {
+	env := newTestEnv(t)
+	env.initialize()
+
+	// 新初始化的合约已是最新版本，再次执行直接返回完成
+	result, err := runMigration(env, migrationBalances, 10)
+	if err != nil || result["done"] != true || result["schemaVersion"] != float64(ledgerSchemaVersion) {
+		t.Fatalf("expected completed step on a new ledger, got %v, %v", result, err)
+	}
+
+	if _, err := runMigration(env, "bogus", 10); err == nil || !strings.Contains(err.Error(), "unknown migration step") {
+		t.Fatalf("expected unknown step error, got %v", err)
+	}
+
+	_, err = env.invoke(bankAAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.RunMigration(ctx, migrationBalances, "", 10)
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "not authorized") {
+		t.Fatalf("expected authorization error, got %v", err)
+	}
+
+	// 各步骤互不依赖，旧入口直接执行交易记录迁移
+	seedLegacyLedger(t, env)
+	bookmark := ""
+	for batches := 0; ; batches++ {
+		if batches > 5 {
+			t.Fatalf("transaction record migration did not complete")
+		}
+		var result map[string]interface{}
+		_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+			response, err := env.contract.MigrateTransactionRecords(ctx, 10, bookmark)
+			if err != nil {
+				return err
+			}
+			return json.Unmarshal([]byte(response), &result)
+		})
+		if err != nil {
+			t.Fatalf("MigrateTransactionRecords failed: %v", err)
+		}
+		if result["done"] == true {
+			break
+		}
+		bookmark = result["bookmark"].(string)
+	}
+
+	var status struct {
+		SchemaVersion int      `json:"schemaVersion"`
+		Pending       []string `json:"pending"`
+	}
+	_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		response, err := env.contract.GetMigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &status)
+	})
+	if err != nil {
+		t.Fatalf("GetMigrationStatus failed: %v", err)
+	}
+	if status.SchemaVersion != 0 || strings.Join(status.Pending, ",") != "balances,allowances,balanceHistory" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
@@ -34,47 +34,66 @@ const allowancePrefix = "allowance"
 const balancePrefix = "balance_"
 
 // 隐私功能相关常量
-const centralBankCollection = "central_bank_full_data"
+// defaultPrivateCollection 默认的央行私有集合，实际使用的集合取自合约配置
+const defaultPrivateCollection = "central_bank_full_data"
 const transactionPrefix = "tx_"
+const uetrPrefix = "uetr_"
 
 // SmartContract 提供在账户间转移代币的功能
 type SmartContract struct {
 	contractapi.Contract
 }
 
-// event 提供一个用于发出事件的有组织结构
-type event struct {
-	From  string `json:"from"`
-	To    string `json:"to"`
-	Value int    `json:"value"`
-}
-
-// PrivateTransactionData 完整的私有交易数据结构
-type PrivateTransactionData struct {
-	TxID            string `json:"txId"`
-	From            string `json:"from"`
-	To              string `json:"to"`
-	FromMSP         string `json:"fromMsp"`
-	ToMSP           string `json:"toMsp"`
-	Amount          int    `json:"amount"`
-	TransactionType string `json:"transactionType"` // 新增：交易类型 (transfer, approve, transferFrom, mint, burn)
-	Spender         string `json:"spender"`         // 新增：授权转账中的spender
-	BlockNumber     uint64 `json:"blockNumber"`
-	TxIndex         uint32 `json:"txIndex"`
+// TransactionRecord 交易记录，以 tx_<txID> 存储在央行私有集合中
+// 同一文档既用于按交易ID读取，也用于 CouchDB 富查询（docType = "transaction"）
+type TransactionRecord struct {
+	DocType         string   `json:"docType"`
+	SchemaVersion   int      `json:"schemaVersion"`
+	TxID            string   `json:"txId"`
+	From            string   `json:"from"`
+	To              string   `json:"to"`
+	FromMSP         string   `json:"fromMsp"`
+	ToMSP           string   `json:"toMsp"`
+	Amount          int      `json:"amount"`
+	TransactionType string   `json:"transactionType"` // 交易类型 (transfer, approve, transferFrom, directDebit, mint, burn, return)
+	Spender         string   `json:"spender"`         // 授权转账中的spender
+	EndToEndID      string   `json:"endToEndId,omitempty"`
+	UETR            string   `json:"uetr,omitempty"`
+	OriginalTxID    string   `json:"originalTxId,omitempty"`   // 退汇记录对应的原交易
+	ReturnReason    string   `json:"returnReason,omitempty"`   // 退汇原因代码
+	ReturnedAmount  int      `json:"returnedAmount,omitempty"` // 原交易已退汇金额
+	ReturnTxIDs     []string `json:"returnTxIds,omitempty"`    // 原交易关联的退汇交易
+	Memo            string   `json:"memo,omitempty"`           // 附言（pacs.008 RmtInf/Ustrd）
+	Fee             int      `json:"fee,omitempty"`            // 商户手续费（从收款金额中扣除）
+	NetAmount       int      `json:"netAmount,omitempty"`      // 商户实收净额
+	FeeAccount      string   `json:"feeAccount,omitempty"`     // 手续费入账账户
+	Timestamp       int64    `json:"timestamp"`
 }
 
 // UserBalance 用户余额记录
 type UserBalance struct {
-	UserID  string `json:"userId"`
-	Balance int    `json:"balance"`
-	OrgMSP  string `json:"orgMsp"` // 新增：用户所属的组织MSP
+	UserID           string `json:"userId"`
+	Balance          int    `json:"balance"`
+	OrgMSP           string `json:"orgMsp"`                     // 新增：用户所属的组织MSP
+	AccountType      string `json:"accountType,omitempty"`      // 账户类型（individual, merchant, settlement, system）
+	Status           string `json:"status,omitempty"`           // 账户状态（active, closed），未开立时为空
+	OpenedAt         int64  `json:"openedAt,omitempty"`         // 开立时间
+	OpenedBy         string `json:"openedBy,omitempty"`         // 开立人客户端ID
+	ClosedAt         int64  `json:"closedAt,omitempty"`         // 销户时间
+	ClosedBy         string `json:"closedBy,omitempty"`         // 销户人客户端ID
+	SweepAccount     string `json:"sweepAccount,omitempty"`     // 销户时余额划入的账户
+	MerchantCategory string `json:"merchantCategory,omitempty"` // 商户类别码（MCC），用于匹配手续费规则
+	BankMSP          string `json:"bankMsp,omitempty"`          // 账户所属银行的 MSP ID，用于扣款的背书策略
+	FirstBookedAt    int64  `json:"firstBookedAt,omitempty"`    // 首次余额变动时间，月末余额从该月开始记录
 }
 
 // AllowanceRecord 授权记录
 type AllowanceRecord struct {
-	Owner   string `json:"owner"`
-	Spender string `json:"spender"`
-	Value   int    `json:"value"`
+	Owner     string `json:"owner"`
+	Spender   string `json:"spender"`
+	Value     int    `json:"value"`
+	ExpiresAt int64  `json:"expiresAt,omitempty"` // 到期时间（Unix 秒），0 表示不过期
+	UpdatedAt int64  `json:"updatedAt,omitempty"`
 }
 
 // Mint 创建新代币并将其添加到铸币者的账户余额中
@@ -95,7 +114,11 @@ func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount
 	if err != nil {
 		return fmt.Errorf("failed to get MSPID: %v", err)
 	}
-	if clientMSPID != CENTRAL_MSP_ID {
+	isCentralBank, err := isCentralBankMSP(ctx, clientMSPID)
+	if err != nil {
+		return err
+	}
+	if !isCentralBank {
 		return errors.New("client is not authorized to mint new tokens")
 	}
 
@@ -110,10 +133,27 @@ func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount
 	}
 
 	// 从私有集合获取当前余额
-	currentBalance, err := s.getBalanceFromPrivateCollection(ctx, minter)
+	minterAccount, err := s.getUserAccountInfo(ctx, minter)
 	if err != nil {
 		return fmt.Errorf("failed to read minter account %s from private collection: %v", minter, err)
 	}
+	currentBalance := minterAccount.Balance
+
+	// 央行首次铸币时为自身开立国库账户，扣款只需央行背书
+	if minterAccount.Status == "" {
+		timestamp, err := ctx.GetStub().GetTxTimestamp()
+		if err != nil {
+			return fmt.Errorf("failed to get transaction timestamp: %v", err)
+		}
+		openAccount(minterAccount, accountTypeSystem, minter, timestamp.Seconds)
+		minterAccount.BankMSP = clientMSPID
+		if _, err := setAccountEndorsement(ctx, minter, clientMSPID); err != nil {
+			return err
+		}
+	}
+	if err := checkAccountActive(minterAccount, "minter"); err != nil {
+		return err
+	}
 
 	updatedBalance, err := add(currentBalance, amount)
 	if err != nil {
@@ -121,7 +161,8 @@ func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount
 	}
 
 	// 更新私有集合中的余额
-	err = s.updateBalanceInPrivateCollection(ctx, minter, updatedBalance)
+	minterAccount.Balance = updatedBalance
+	err = s.updateUserAccountInPrivateCollection(ctx, minterAccount)
 	if err != nil {
 		return fmt.Errorf("failed to update balance in private collection: %v", err)
 	}
@@ -138,87 +179,39 @@ func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount
 		return err
 	}
 
-	err = s.updateTotalSupplyInPrivateCollection(ctx, totalSupply)
+	err = s.updateTotalSupplyInPrivateCollection(ctx, totalSupply, amount)
 	if err != nil {
 		return fmt.Errorf("failed to update total supply in private collection: %v", err)
 	}
 
-	// 创建完整的私有交易数据
-	txID := ctx.GetStub().GetTxID()
-	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	// 更新铸币者所属银行的汇总账
+	err = s.recordBankIssuance(ctx, minterAccount.OrgMSP, amount)
 	if err != nil {
-		return fmt.Errorf("failed to get transaction timestamp: %v", err)
-	}
-
-	privateData := PrivateTransactionData{
-		TxID:            txID,
-		From:            "0x0", // 铸币从零地址
-		To:              minter,
-		FromMSP:         "", // 简化实现
-		ToMSP:           "", // 简化实现
-		Amount:          amount,
-		TransactionType: "mint",
-		Spender:         "",
-		BlockNumber:     0, // 简化实现
-		TxIndex:         0, // 简化实现
-	}
-
-	// 创建用于查询的扩展交易数据
-	queryData := map[string]interface{}{
-		"docType":         "transaction",
-		"txId":            txID,
-		"from":            "0x0",
-		"to":              minter,
-		"fromMsp":         "",
-		"amount":          amount,
-		"transactionType": "mint",
-		"spender":         "",
-		"timestamp":       timestamp.Seconds,
-		"blockNumber":     0, // 简化实现
-		"txIndex":         0, // 简化实现
-	}
-
-	// 序列化私有数据
-	privateDataBytes, err := json.Marshal(privateData)
-	if err != nil {
-		return fmt.Errorf("failed to marshal private data: %v", err)
-	}
-
-	// 序列化查询数据
-	queryDataBytes, err := json.Marshal(queryData)
-	if err != nil {
-		return fmt.Errorf("failed to marshal query data: %v", err)
+		return err
 	}
 
-	// 央行存储完整数据
-	privateDataKey := transactionPrefix + txID
-	err = ctx.GetStub().PutPrivateData(centralBankCollection, privateDataKey, privateDataBytes)
+	// 创建交易记录
+	record, err := s.newTransactionRecord(ctx, "mint", "0x0", minter, amount)
 	if err != nil {
-		return fmt.Errorf("failed to store private data: %v", err)
+		return err
 	}
 
-	// 存储用于查询的数据
-	queryDataKey := "query_" + txID
-	err = ctx.GetStub().PutPrivateData(centralBankCollection, queryDataKey, queryDataBytes)
+	// 央行存储交易记录
+	err = s.bookTransactionRecord(ctx, record)
 	if err != nil {
-		return fmt.Errorf("failed to store query data: %v", err)
+		return err
 	}
 
-	// 发出 Transfer 事件
-	transferEvent := event{"0x0", minter, amount}
-	transferEventJSON, err := json.Marshal(transferEvent)
+	// 发出 Transfer 事件（附带 camt.054 借贷记通知）
+	err = s.emitTransferEvent(ctx, record)
 	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
-	}
-	err = ctx.GetStub().SetEvent("Transfer", transferEventJSON)
-	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return err
 	}
 
 	log.Printf("minter account %s balance updated from %d to %d", minter, currentBalance, updatedBalance)
 
 	// ISO 20022 结构化日志（pacs.008 + camt.053）
-	if err := s.logISO20022Pacs008AndCamt053(ctx, "mint", "0x0", minter, amount, ""); err != nil {
+	if err := s.logISO20022Pacs008AndCamt053(ctx, "mint", "0x0", minter, amount, "", nil, nil); err != nil {
 		log.Printf("ISO20022 logging (mint) failed: %v", err)
 	}
 
@@ -242,7 +235,11 @@ func (s *SmartContract) Burn(ctx contractapi.TransactionContextInterface, amount
 	if err != nil {
 		return fmt.Errorf("failed to get MSPID: %v", err)
 	}
-	if clientMSPID != CENTRAL_MSP_ID {
+	isCentralBank, err := isCentralBankMSP(ctx, clientMSPID)
+	if err != nil {
+		return err
+	}
+	if !isCentralBank {
 		return errors.New("client is not authorized to burn tokens")
 	}
 
@@ -257,10 +254,14 @@ func (s *SmartContract) Burn(ctx contractapi.TransactionContextInterface, amount
 	}
 
 	// 从私有集合获取当前余额
-	currentBalance, err := s.getBalanceFromPrivateCollection(ctx, minter)
+	minterAccount, err := s.getUserAccountInfo(ctx, minter)
 	if err != nil {
 		return fmt.Errorf("failed to read minter account %s from private collection: %v", minter, err)
 	}
+	if err := checkAccountActive(minterAccount, "minter"); err != nil {
+		return err
+	}
+	currentBalance := minterAccount.Balance
 
 	if currentBalance < amount {
 		return fmt.Errorf("insufficient balance to burn %d tokens", amount)
@@ -289,87 +290,39 @@ func (s *SmartContract) Burn(ctx contractapi.TransactionContextInterface, amount
 		return err
 	}
 
-	err = s.updateTotalSupplyInPrivateCollection(ctx, totalSupply)
+	err = s.updateTotalSupplyInPrivateCollection(ctx, totalSupply, -amount)
 	if err != nil {
 		return fmt.Errorf("failed to update total supply in private collection: %v", err)
 	}
 
-	// 创建完整的私有交易数据
-	txID := ctx.GetStub().GetTxID()
-	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	// 更新铸币者所属银行的汇总账
+	err = s.recordBankIssuance(ctx, minterAccount.OrgMSP, -amount)
 	if err != nil {
-		return fmt.Errorf("failed to get transaction timestamp: %v", err)
-	}
-
-	privateData := PrivateTransactionData{
-		TxID:            txID,
-		From:            minter,
-		To:              "0x0", // 销毁到零地址
-		FromMSP:         "",    // 简化实现
-		ToMSP:           "",    // 简化实现
-		Amount:          amount,
-		TransactionType: "burn",
-		Spender:         "",
-		BlockNumber:     0, // 简化实现
-		TxIndex:         0, // 简化实现
-	}
-
-	// 创建用于查询的扩展交易数据
-	queryData := map[string]interface{}{
-		"docType":         "transaction",
-		"txId":            txID,
-		"from":            minter,
-		"to":              "0x0",
-		"fromMsp":         "",
-		"amount":          amount,
-		"transactionType": "burn",
-		"spender":         "",
-		"timestamp":       timestamp.Seconds,
-		"blockNumber":     0, // 简化实现
-		"txIndex":         0, // 简化实现
-	}
-
-	// 序列化私有数据
-	privateDataBytes, err := json.Marshal(privateData)
-	if err != nil {
-		return fmt.Errorf("failed to marshal private data: %v", err)
-	}
-
-	// 序列化查询数据
-	queryDataBytes, err := json.Marshal(queryData)
-	if err != nil {
-		return fmt.Errorf("failed to marshal query data: %v", err)
+		return err
 	}
 
-	// 央行存储完整数据
-	privateDataKey := transactionPrefix + txID
-	err = ctx.GetStub().PutPrivateData(centralBankCollection, privateDataKey, privateDataBytes)
+	// 创建交易记录
+	record, err := s.newTransactionRecord(ctx, "burn", minter, "0x0", amount)
 	if err != nil {
-		return fmt.Errorf("failed to store private data: %v", err)
+		return err
 	}
 
-	// 存储用于查询的数据
-	queryDataKey := "query_" + txID
-	err = ctx.GetStub().PutPrivateData(centralBankCollection, queryDataKey, queryDataBytes)
+	// 央行存储交易记录
+	err = s.bookTransactionRecord(ctx, record)
 	if err != nil {
-		return fmt.Errorf("failed to store query data: %v", err)
+		return err
 	}
 
-	// 发出 Transfer 事件
-	transferEvent := event{minter, "0x0", amount}
-	transferEventJSON, err := json.Marshal(transferEvent)
-	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
-	}
-	err = ctx.GetStub().SetEvent("Transfer", transferEventJSON)
+	// 发出 Transfer 事件（附带 camt.054 借贷记通知）
+	err = s.emitTransferEvent(ctx, record)
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return err
 	}
 
 	log.Printf("minter account %s balance updated from %d to %d", minter, currentBalance, updatedBalance)
 
 	// ISO 20022 结构化日志（pacs.008 + camt.053）
-	if err := s.logISO20022Pacs008AndCamt053(ctx, "burn", minter, "0x0", amount, ""); err != nil {
+	if err := s.logISO20022Pacs008AndCamt053(ctx, "burn", minter, "0x0", amount, "", nil, nil); err != nil {
 		log.Printf("ISO20022 logging (burn) failed: %v", err)
 	}
 
@@ -379,26 +332,6 @@ func (s *SmartContract) Burn(ctx contractapi.TransactionContextInterface, amount
 // Transfer 将代币从客户端账户转移到接收者账户（隐私版本）
 // 此函数触发 Transfer 事件，但所有数据都通过隐私机制处理
 func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, recipient string, amount int) error {
-	// 🔍 添加链码地址跟踪日志
-	log.Printf("🔍 CHAINCODE TRANSFER 地址跟踪开始:")
-	log.Printf("  📥 链码接收到的 recipient: %s", recipient)
-	log.Printf("  📥 recipient 类型: %T", recipient)
-	log.Printf("  📥 recipient 长度: %d", len(recipient))
-	log.Printf("  📥 recipient 是否为空: %t", recipient == "")
-	log.Printf("  📥 recipient 是否只包含空格: %t", strings.TrimSpace(recipient) == "")
-	log.Printf("  📥 recipient 前10个字符: %s", func() string {
-		if len(recipient) > 10 {
-			return recipient[:10]
-		}
-		return recipient
-	}())
-	log.Printf("  📥 recipient 后10个字符: %s", func() string {
-		if len(recipient) > 10 {
-			return recipient[len(recipient)-10:]
-		}
-		return recipient
-	}())
-
 	// 检查合约初始化
 	initialized, err := checkInitialized(ctx)
 	if err != nil {
@@ -414,104 +347,81 @@ func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, re
 		return fmt.Errorf("failed to get sender ID: %v", err)
 	}
 
-	senderMSP, err := ctx.GetClientIdentity().GetMSPID()
+	// 解析收款人别名
+	recipient, err = s.resolveAccount(ctx, recipient)
 	if err != nil {
-		return fmt.Errorf("failed to get sender MSP: %v", err)
-	}
-
-	// 验证转账金额
-	if amount <= 0 {
-		return fmt.Errorf("transfer amount must be positive")
+		return err
 	}
 
-	log.Printf("🔍 准备执行转账:")
-	log.Printf("  📤 发送方: %s", sender)
-	log.Printf("  📤 接收方: %s", recipient)
-	log.Printf("  📤 金额: %d", amount)
+	log.Printf("transfer of %d requested in transaction %s", amount, ctx.GetStub().GetTxID())
 
-	// 执行隐私余额转账
-	err = s.transferHelperPrivate(ctx, sender, recipient, amount)
-	if err != nil {
-		return fmt.Errorf("failed to execute transfer: %v", err)
-	}
-
-	// 创建完整的私有交易数据
-	txID := ctx.GetStub().GetTxID()
-	timestamp, err := ctx.GetStub().GetTxTimestamp()
-	if err != nil {
-		return fmt.Errorf("failed to get transaction timestamp: %v", err)
-	}
+	_, err = s.transfer(ctx, sender, recipient, amount, nil)
+	return err
+}
 
-	privateData := PrivateTransactionData{
-		TxID:            txID,
-		From:            sender,
-		To:              recipient,
-		FromMSP:         senderMSP,
-		Amount:          amount,
-		TransactionType: "transfer",
-		Spender:         "",
-		BlockNumber:     0, // 简化实现
-		TxIndex:         0, // 简化实现
+// transfer 执行客户端发起的转账并记录交易数据
+// pmtID 为空时使用基于交易ID生成的支付标识
+func (s *SmartContract) transfer(ctx contractapi.TransactionContextInterface, sender string, recipient string, amount int, pmtID *paymentIdentification) (*paymentIdentification, error) {
+	// 校验支付，失败时返回带 ISO 20022 原因代码的错误
+	if err := s.assessTransfer(ctx, sender, sender, recipient, amount); err != nil {
+		return nil, err
 	}
 
-	// 创建用于查询的扩展交易数据
-	queryData := map[string]interface{}{
-		"docType":         "transaction",
-		"txId":            txID,
-		"from":            sender,
-		"to":              recipient,
-		"fromMsp":         senderMSP,
-		"amount":          amount,
-		"transactionType": "transfer",
-		"timestamp":       timestamp.Seconds,
-		"blockNumber":     0, // 简化实现
-		"txIndex":         0, // 简化实现
+	if pmtID == nil {
+		var err error
+		pmtID, err = defaultPaymentIdentification(ctx)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// 序列化私有数据
-	privateDataBytes, err := json.Marshal(privateData)
+	// 收款方为商户时计算手续费
+	legs, charge, err := s.paymentLegs(ctx, sender, recipient, amount)
 	if err != nil {
-		return fmt.Errorf("failed to marshal private data: %v", err)
+		return nil, err
 	}
 
-	// 序列化查询数据
-	queryDataBytes, err := json.Marshal(queryData)
+	// 执行隐私余额转账
+	err = s.postTransferLegs(ctx, legs)
 	if err != nil {
-		return fmt.Errorf("failed to marshal query data: %v", err)
+		return nil, fmt.Errorf("failed to execute transfer: %v", err)
 	}
 
-	// 央行存储完整数据
-	privateDataKey := transactionPrefix + txID
-	err = ctx.GetStub().PutPrivateData(centralBankCollection, privateDataKey, privateDataBytes)
+	// 创建交易记录
+	record, err := s.newTransactionRecord(ctx, "transfer", sender, recipient, amount)
 	if err != nil {
-		return fmt.Errorf("failed to store private data: %v", err)
+		return nil, err
 	}
+	record.EndToEndID = pmtID.EndToEndID
+	record.UETR = pmtID.UETR
+	record.Memo = pmtID.Memo
+	charge.applyTo(record)
 
-	// 存储用于查询的数据
-	queryDataKey := "query_" + txID
-	err = ctx.GetStub().PutPrivateData(centralBankCollection, queryDataKey, queryDataBytes)
+	// 央行存储交易记录
+	err = s.bookTransactionRecord(ctx, record)
 	if err != nil {
-		return fmt.Errorf("failed to store query data: %v", err)
+		return nil, err
 	}
 
-	// 发出Transfer事件（保持ERC20兼容性）
-	transferEvent := event{sender, recipient, amount}
-	transferEventJSON, err := json.Marshal(transferEvent)
+	// 存储 ACSC 支付状态
+	err = s.recordPaymentSettled(ctx, pmtID, sender, recipient, amount)
 	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+		return nil, err
 	}
-	err = ctx.GetStub().SetEvent("Transfer", transferEventJSON)
+
+	// 发出 Transfer 事件（附带 camt.054 借贷记通知）
+	err = s.emitTransferEvent(ctx, record)
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return nil, err
 	}
 
-	log.Printf("Private transfer completed: %s -> %s, amount: %d, txID: %s", sender, recipient, amount, txID)
+	log.Printf("Private transfer completed: %s -> %s, amount: %d, txID: %s", sender, recipient, amount, record.TxID)
 
 	// ISO 20022 结构化日志（pacs.008 + camt.053）
-	if err := s.logISO20022Pacs008AndCamt053(ctx, "transfer", sender, recipient, amount, ""); err != nil {
+	if err := s.logISO20022Pacs008AndCamt053(ctx, "transfer", sender, recipient, amount, "", pmtID, charge); err != nil {
 		log.Printf("ISO20022 logging (transfer) failed: %v", err)
 	}
-	return nil
+	return pmtID, nil
 }
 
 // BalanceOf 返回给定账户的余额（带权限控制）
@@ -532,8 +442,14 @@ func (s *SmartContract) BalanceOf(ctx contractapi.TransactionContextInterface, a
 		return 0, fmt.Errorf("failed to get caller id: %v", err)
 	}
 
+	// 解析账户别名
+	accountID, err := s.resolveAccount(ctx, account)
+	if err != nil {
+		return 0, err
+	}
+
 	// 检查权限
-	hasPermission, err := s.checkBalancePermission(ctx, callerID, account)
+	hasPermission, err := s.checkBalancePermission(ctx, callerID, accountID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to check permission: %v", err)
 	}
@@ -542,7 +458,7 @@ func (s *SmartContract) BalanceOf(ctx contractapi.TransactionContextInterface, a
 	}
 
 	// 从私有集合获取余额
-	balance, err := s.getBalanceFromPrivateCollection(ctx, account)
+	balance, err := s.getBalanceFromPrivateCollection(ctx, accountID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to read client account %s from private collection: %v", account, err)
 	}
@@ -702,8 +618,14 @@ func (s *SmartContract) GetUserAccountInfo(ctx contractapi.TransactionContextInt
 		return "", fmt.Errorf("failed to get caller id: %v", err)
 	}
 
+	// 解析账户别名
+	accountID, err := s.resolveAccount(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
 	// 检查权限
-	hasPermission, err := s.checkAccountInfoPermission(ctx, callerID, userID)
+	hasPermission, err := s.checkAccountInfoPermission(ctx, callerID, accountID)
 	if err != nil {
 		return "", fmt.Errorf("failed to check permission: %v", err)
 	}
@@ -711,7 +633,7 @@ func (s *SmartContract) GetUserAccountInfo(ctx contractapi.TransactionContextInt
 		return "", fmt.Errorf("caller does not have permission to view account info of user %s", userID)
 	}
 
-	return s.getAccountInfoAsJSON(ctx, userID, "user")
+	return s.getAccountInfoAsJSON(ctx, accountID, "user")
 }
 
 // GetClientAccountInfo 返回当前调用客户端的完整账户信息
@@ -749,183 +671,64 @@ func (s *SmartContract) TotalSupply(ctx contractapi.TransactionContextInterface)
 }
 
 // Approve 允许 spender 从调用客户端账户中提取代币，最多到 value 金额
+// 覆盖原有额度且不过期；value 为 0 时撤销授权
 // 此函数触发 Approval 事件
 func (s *SmartContract) Approve(ctx contractapi.TransactionContextInterface, spender string, value int) error {
+	return s.approve(ctx, spender, value, 0)
+}
+
+// Allowance 返回 owner 仍允许 spender 提取的代币数量，已到期的授权返回 0
+// 调用者需有权查看 owner 或 spender 的余额
+func (s *SmartContract) Allowance(ctx contractapi.TransactionContextInterface, owner string, spender string) (int, error) {
 
 	// 首先检查合约是否已初始化
 	initialized, err := checkInitialized(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
 	}
 	if !initialized {
-		return fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
-	}
-
-	// 获取提交客户端身份的ID
-	owner, err := ctx.GetClientIdentity().GetID()
-	if err != nil {
-		return fmt.Errorf("failed to get client id: %v", err)
-	}
-
-	// 创建 allowanceKey
-	allowanceKey, err := ctx.GetStub().CreateCompositeKey(allowancePrefix, []string{owner, spender})
-	if err != nil {
-		return fmt.Errorf("failed to create the composite key for prefix %s: %v", allowancePrefix, err)
-	}
-
-	// 创建授权记录
-	allowanceRecord := AllowanceRecord{
-		Owner:   owner,
-		Spender: spender,
-		Value:   value,
-	}
-
-	// 序列化授权记录
-	allowanceRecordBytes, err := json.Marshal(allowanceRecord)
-	if err != nil {
-		return fmt.Errorf("failed to marshal allowance record: %v", err)
-	}
-
-	// 将 approval 金额更新到私有集合
-	err = ctx.GetStub().PutPrivateData(centralBankCollection, allowanceKey, allowanceRecordBytes)
-	if err != nil {
-		return fmt.Errorf("failed to update state of smart contract for key %s: %v", allowanceKey, err)
-	}
-
-	// 创建完整的私有交易数据
-	txID := ctx.GetStub().GetTxID()
-	timestamp, err := ctx.GetStub().GetTxTimestamp()
-	if err != nil {
-		return fmt.Errorf("failed to get transaction timestamp: %v", err)
-	}
-
-	privateData := PrivateTransactionData{
-		TxID:            txID,
-		From:            owner,
-		To:              spender,
-		FromMSP:         "", // 简化实现
-		ToMSP:           "", // 简化实现
-		Amount:          value,
-		TransactionType: "approve",
-		Spender:         spender,
-		BlockNumber:     0, // 简化实现
-		TxIndex:         0, // 简化实现
-	}
-
-	// 创建用于查询的扩展交易数据
-	queryData := map[string]interface{}{
-		"docType":         "transaction",
-		"txId":            txID,
-		"from":            owner,
-		"to":              spender,
-		"fromMsp":         "",
-		"amount":          value,
-		"transactionType": "approve",
-		"spender":         spender,
-		"timestamp":       timestamp.Seconds,
-		"blockNumber":     0, // 简化实现
-		"txIndex":         0, // 简化实现
-	}
-
-	// 序列化私有数据
-	privateDataBytes, err := json.Marshal(privateData)
-	if err != nil {
-		return fmt.Errorf("failed to marshal private data: %v", err)
-	}
-
-	// 序列化查询数据
-	queryDataBytes, err := json.Marshal(queryData)
-	if err != nil {
-		return fmt.Errorf("failed to marshal query data: %v", err)
-	}
-
-	// 央行存储完整数据
-	privateDataKey := transactionPrefix + txID
-	err = ctx.GetStub().PutPrivateData(centralBankCollection, privateDataKey, privateDataBytes)
-	if err != nil {
-		return fmt.Errorf("failed to store private data: %v", err)
+		return 0, fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
 	}
 
-	// 存储用于查询的数据
-	queryDataKey := "query_" + txID
-	err = ctx.GetStub().PutPrivateData(centralBankCollection, queryDataKey, queryDataBytes)
+	// 获取当前调用者的信息
+	callerID, err := ctx.GetClientIdentity().GetID()
 	if err != nil {
-		return fmt.Errorf("failed to store query data: %v", err)
-	}
-
-	// 发出 Approval 事件
-	approvalEvent := struct {
-		Owner   string `json:"owner"`
-		Spender string `json:"spender"`
-		Value   int    `json:"value"`
-	}{
-		Owner:   owner,
-		Spender: spender,
-		Value:   value,
+		return 0, fmt.Errorf("failed to get caller id: %v", err)
 	}
 
-	approvalEventJSON, err := json.Marshal(approvalEvent)
+	// 解析账户别名
+	ownerID, err := s.resolveAccount(ctx, owner)
 	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+		return 0, err
 	}
-	err = ctx.GetStub().SetEvent("Approval", approvalEventJSON)
+	spenderID, err := s.resolveAccount(ctx, spender)
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
-	}
-
-	log.Printf("client %s approved a withdrawal of %d tokens for spender %s", owner, value, spender)
-
-	// ISO 20022 结构化日志（pacs.008 + camt.053）——非清算授权事件
-	if err := s.logISO20022Pacs008AndCamt053(ctx, "approve", owner, spender, value, spender); err != nil {
-		log.Printf("ISO20022 logging (approve) failed: %v", err)
+		return 0, err
 	}
 
-	return nil
-}
-
-// Allowance 返回 owner 仍允许 spender 提取的代币数量
-func (s *SmartContract) Allowance(ctx contractapi.TransactionContextInterface, owner string, spender string) (int, error) {
-
-	// 首先检查合约是否已初始化
-	initialized, err := checkInitialized(ctx)
+	// 检查权限
+	hasPermission, err := s.checkBalancePermission(ctx, callerID, ownerID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return 0, fmt.Errorf("failed to check permission: %v", err)
 	}
-	if !initialized {
-		return 0, fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	if !hasPermission {
+		hasPermission, err = s.checkBalancePermission(ctx, callerID, spenderID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to check permission: %v", err)
+		}
 	}
-
-	// 创建 allowanceKey
-	allowanceKey, err := ctx.GetStub().CreateCompositeKey(allowancePrefix, []string{owner, spender})
-	if err != nil {
-		return 0, fmt.Errorf("failed to create the composite key for prefix %s: %v", allowancePrefix, err)
+	if !hasPermission {
+		return 0, fmt.Errorf("caller does not have permission to view the allowance of %s for %s", owner, spender)
 	}
 
-	// 从私有集合中读取 allowance 金额
-	allowanceBytes, err := ctx.GetStub().GetPrivateData(centralBankCollection, allowanceKey)
+	record, _, err := s.currentAllowance(ctx, ownerID, spenderID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read allowance for %s from private collection: %v", allowanceKey, err)
-	}
-
-	var allowance int
-
-	// 如果没有当前的 allowance，将其设置为0
-	if allowanceBytes == nil {
-		allowance = 0
-	} else {
-		// 尝试解析为新的格式
-		var allowanceRecord AllowanceRecord
-		if err := json.Unmarshal(allowanceBytes, &allowanceRecord); err == nil {
-			allowance = allowanceRecord.Value
-		} else {
-			// 兼容旧格式
-			allowance, _ = strconv.Atoi(string(allowanceBytes))
-		}
+		return 0, err
 	}
 
-	log.Printf("The allowance left for spender %s to withdraw from owner %s: %d", spender, owner, allowance)
+	log.Printf("The allowance left for spender %s to withdraw from owner %s: %d", spenderID, ownerID, record.Value)
 
-	return allowance, nil
+	return record.Value, nil
 }
 
 // TransferFrom 使用 allowance 机制将代币从一个账户转移到另一个账户
@@ -947,39 +750,36 @@ func (s *SmartContract) TransferFrom(ctx contractapi.TransactionContextInterface
 		return fmt.Errorf("failed to get client id: %v", err)
 	}
 
-	// 检索 allowance key
-	allowanceKey, err := ctx.GetStub().CreateCompositeKey(allowancePrefix, []string{from, spender})
+	// 解析付款人与收款人别名
+	from, err = s.resolveAccount(ctx, from)
+	if err != nil {
+		return err
+	}
+	to, err = s.resolveAccount(ctx, to)
 	if err != nil {
-		return fmt.Errorf("failed to create the composite key for prefix %s: %v", allowancePrefix, err)
+		return err
 	}
 
-	// 从私有集合中检索 allowance
-	currentAllowanceBytes, err := ctx.GetStub().GetPrivateData(centralBankCollection, allowanceKey)
-	if err != nil {
-		return fmt.Errorf("failed to retrieve the allowance for %s from private collection: %v", allowanceKey, err)
+	// 校验授权额度与余额，失败时返回带 ISO 20022 原因代码的错误
+	if err := s.assessTransfer(ctx, spender, from, to, value); err != nil {
+		return err
 	}
 
-	var currentAllowance int
-	if currentAllowanceBytes == nil {
-		currentAllowance = 0
-	} else {
-		// 尝试解析为新的格式
-		var allowanceRecord AllowanceRecord
-		if err := json.Unmarshal(currentAllowanceBytes, &allowanceRecord); err == nil {
-			currentAllowance = allowanceRecord.Value
-		} else {
-			// 兼容旧格式
-			currentAllowance, _ = strconv.Atoi(string(currentAllowanceBytes))
-		}
+	// 读取授权记录（到期与额度已在 assessTransfer 中校验）
+	allowance, err := s.getAllowanceRecord(ctx, from, spender)
+	if err != nil {
+		return err
 	}
+	currentAllowance := allowance.Value
 
-	// 检查转账金额是否小于等于 allowance
-	if currentAllowance < value {
-		return fmt.Errorf("spender does not have enough allowance for transfer")
+	// 收款方为商户时计算手续费
+	legs, charge, err := s.paymentLegs(ctx, from, to, value)
+	if err != nil {
+		return err
 	}
 
 	// 启动隐私转账
-	err = s.transferHelperPrivate(ctx, from, to, value)
+	err = s.postTransferLegs(ctx, legs)
 	if err != nil {
 		return fmt.Errorf("failed to transfer: %v", err)
 	}
@@ -990,99 +790,50 @@ func (s *SmartContract) TransferFrom(ctx contractapi.TransactionContextInterface
 		return err
 	}
 
-	// 更新授权记录
-	allowanceRecord := AllowanceRecord{
-		Owner:   from,
-		Spender: spender,
-		Value:   updatedAllowance,
-	}
-
-	allowanceRecordBytes, err := json.Marshal(allowanceRecord)
-	if err != nil {
-		return fmt.Errorf("failed to marshal allowance record: %v", err)
-	}
-
-	err = ctx.GetStub().PutPrivateData(centralBankCollection, allowanceKey, allowanceRecordBytes)
+	// 更新授权记录，保留到期时间
+	allowance.Value = updatedAllowance
+	err = s.putAllowanceRecord(ctx, allowance)
 	if err != nil {
 		return err
 	}
 
-	// 创建完整的私有交易数据
-	txID := ctx.GetStub().GetTxID()
-	timestamp, err := ctx.GetStub().GetTxTimestamp()
-	if err != nil {
-		return fmt.Errorf("failed to get transaction timestamp: %v", err)
-	}
-
-	privateData := PrivateTransactionData{
-		TxID:            txID,
-		From:            from,
-		To:              to,
-		FromMSP:         "", // 简化实现
-		ToMSP:           "", // 简化实现
-		Amount:          value,
-		TransactionType: "transferFrom",
-		Spender:         spender,
-		BlockNumber:     0, // 简化实现
-		TxIndex:         0, // 简化实现
-	}
-
-	// 创建用于查询的扩展交易数据
-	queryData := map[string]interface{}{
-		"docType":         "transaction",
-		"txId":            txID,
-		"from":            from,
-		"to":              to,
-		"fromMsp":         "",
-		"amount":          value,
-		"transactionType": "transferFrom",
-		"spender":         spender,
-		"timestamp":       timestamp.Seconds,
-		"blockNumber":     0, // 简化实现
-		"txIndex":         0, // 简化实现
-	}
-
-	// 序列化私有数据
-	privateDataBytes, err := json.Marshal(privateData)
+	pmtID, err := defaultPaymentIdentification(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to marshal private data: %v", err)
+		return err
 	}
 
-	// 序列化查询数据
-	queryDataBytes, err := json.Marshal(queryData)
+	// 创建交易记录
+	record, err := s.newTransactionRecord(ctx, "transferFrom", from, to, value)
 	if err != nil {
-		return fmt.Errorf("failed to marshal query data: %v", err)
+		return err
 	}
+	record.Spender = spender
+	record.EndToEndID = pmtID.EndToEndID
+	record.UETR = pmtID.UETR
+	charge.applyTo(record)
 
-	// 央行存储完整数据
-	privateDataKey := transactionPrefix + txID
-	err = ctx.GetStub().PutPrivateData(centralBankCollection, privateDataKey, privateDataBytes)
+	// 央行存储交易记录
+	err = s.bookTransactionRecord(ctx, record)
 	if err != nil {
-		return fmt.Errorf("failed to store private data: %v", err)
+		return err
 	}
 
-	// 存储用于查询的数据
-	queryDataKey := "query_" + txID
-	err = ctx.GetStub().PutPrivateData(centralBankCollection, queryDataKey, queryDataBytes)
+	// 存储 ACSC 支付状态
+	err = s.recordPaymentSettled(ctx, pmtID, from, to, value)
 	if err != nil {
-		return fmt.Errorf("failed to store query data: %v", err)
+		return err
 	}
 
-	// 发出 Transfer 事件
-	transferEvent := event{from, to, value}
-	transferEventJSON, err := json.Marshal(transferEvent)
-	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
-	}
-	err = ctx.GetStub().SetEvent("Transfer", transferEventJSON)
+	// 发出 Transfer 事件（附带 camt.054 借贷记通知）
+	err = s.emitTransferEvent(ctx, record)
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return err
 	}
 
 	log.Printf("spender %s allowance updated from %d to %d", spender, currentAllowance, updatedAllowance)
 
 	// ISO 20022 结构化日志（pacs.008 + camt.053）
-	if err := s.logISO20022Pacs008AndCamt053(ctx, "transferFrom", from, to, value, spender); err != nil {
+	if err := s.logISO20022Pacs008AndCamt053(ctx, "transferFrom", from, to, value, spender, pmtID, charge); err != nil {
 		log.Printf("ISO20022 logging (transferFrom) failed: %v", err)
 	}
 
@@ -1092,10 +843,12 @@ func (s *SmartContract) TransferFrom(ctx contractapi.TransactionContextInterface
 // ========== ISO 20022 结构化日志辅助函数 ==========
 
 // logISO20022Pacs008AndCamt053 构建并打印符合 ISO 20022 语义的结构化日志
-// - txType: transfer | transferFrom | mint | burn | approve
+// - txType: transfer | transferFrom | directDebit | mint | burn | approve
 // - from, to: 客户链上账户标识（Fabric 客户端ID）或特殊地址"0x0"
 // - amount: 以最小单位计数的整数金额
 // - spender: 授权/代扣场景下的代扣方（可为空）
+// - pmtID: 支付标识（InstrId/EndToEndId/UETR），为空时基于交易ID生成
+// - charge: 商户手续费（可为空）
 func (s *SmartContract) logISO20022Pacs008AndCamt053(
 	ctx contractapi.TransactionContextInterface,
 	txType string,
@@ -1103,6 +856,8 @@ func (s *SmartContract) logISO20022Pacs008AndCamt053(
 	to string,
 	amount int,
 	spender string,
+	pmtID *paymentIdentification,
+	charge *merchantCharge,
 ) error {
 	// 基础上下文
 	stub := ctx.GetStub()
@@ -1131,8 +886,14 @@ func (s *SmartContract) logISO20022Pacs008AndCamt053(
 	fromOrg, _ := s.extractDomainFromClientID(from)
 	toOrg, _ := s.extractDomainFromClientID(to)
 
-	// UETR（端到端追踪）——基于链上确定性数据
-	uetr := generateDeterministicUETR(txID, channelID, eventSeconds, eventNanos)
+	// UETR（端到端追踪）——基于链上确定性数据，或沿用指示方提供的标识
+	if pmtID == nil {
+		pmtID = &paymentIdentification{
+			InstrID:    txID,
+			EndToEndID: txID,
+			UETR:       generateDeterministicUETR(txID, channelID, eventSeconds, eventNanos),
+		}
+	}
 
 	// 是否为非清算（授权等）
 	nonSettlement := (txType == "approve")
@@ -1154,9 +915,9 @@ func (s *SmartContract) logISO20022Pacs008AndCamt053(
 		},
 		"CdtTrfTxInf": map[string]interface{}{
 			"PmtId": map[string]interface{}{
-				"InstrId":    txID,
-				"EndToEndId": txID,
-				"UETR":       uetr,
+				"InstrId":    pmtID.InstrID,
+				"EndToEndId": pmtID.EndToEndID,
+				"UETR":       pmtID.UETR,
 			},
 			"Amt": map[string]interface{}{
 				"InstdAmt": map[string]interface{}{"Ccy": tokenSymbol, "_text": amountStr},
@@ -1227,6 +988,36 @@ func (s *SmartContract) logISO20022Pacs008AndCamt053(
 		},
 	}
 
+	// 商户手续费：收款方承担，贷方按净额入账，手续费单独记入手续费账户
+	if charge != nil {
+		amountJSON := func(value int) map[string]interface{} {
+			return map[string]interface{}{"Ccy": tokenSymbol, "_text": s.formatAmount(value, tokenDecimals)}
+		}
+		collectorOrg, _ := s.extractDomainFromClientID(charge.Collector)
+
+		txInf := pacs["CdtTrfTxInf"].(map[string]interface{})
+		txInf["ChrgBr"] = "CRED"
+		txInf["IntrBkSttlmAmt"] = amountJSON(charge.Net)
+		txInf["ChrgsInf"] = s.buildISOCharges(&TransactionRecord{Fee: charge.Fee, FeeAccount: charge.Collector}, amountJSON)
+		blockchainInfo := txInf["SplmtryData"].(map[string]interface{})["Envlp"].(map[string]interface{})["BlockchainInfo"].(map[string]interface{})
+		blockchainInfo["Fee"] = charge.Fee
+		blockchainInfo["NetAmount"] = charge.Net
+		blockchainInfo["FeeAccount"] = charge.Collector
+		blockchainInfo["MerchantCategory"] = charge.Category
+
+		stmt := camt["Stmt"].(map[string]interface{})
+		entries := stmt["Ntry"].([]map[string]interface{})
+		entries[1]["Amt"] = amountJSON(charge.Net)
+		entries = append(entries, map[string]interface{}{
+			"Amt":          amountJSON(charge.Fee),
+			"CdtDbtInd":    "CRDT",
+			"AcctSvcrRef":  txID,
+			"RltdPties":    map[string]interface{}{"Dbtr": to, "Cdtr": charge.Collector},
+			"AddtlNtryInf": fmt.Sprintf("TxType=charge;FromOrg=%s;ToOrg=%s", toOrg, collectorOrg),
+		})
+		stmt["Ntry"] = entries
+	}
+
 	// 打印结构化 JSON
 	pacsJSON, _ := json.Marshal(pacs)
 	log.Printf("ISO20022 PACS008: %s", string(pacsJSON))
@@ -1247,12 +1038,9 @@ func (s *SmartContract) getTokenMeta(ctx contractapi.TransactionContextInterface
 		return "", 0, fmt.Errorf("failed to get token decimals: %w", err)
 	}
 	symbol := string(symbolBytes)
-	decStr := string(decBytes)
-	decimals := 0
-	if decStr != "" {
-		if d, err := strconv.Atoi(decStr); err == nil {
-			decimals = d
-		}
+	decimals, err := strconv.Atoi(string(decBytes))
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid token decimals %q: %w", string(decBytes), err)
 	}
 	return symbol, decimals, nil
 }
@@ -1287,6 +1075,29 @@ func (s *SmartContract) formatAmount(amount int, decimals int) string {
 	return res
 }
 
+// paymentIdentification ISO 20022 支付标识（PmtId）
+type paymentIdentification struct {
+	InstrID    string `json:"instrId"`
+	EndToEndID string `json:"endToEndId"`
+	UETR       string `json:"uetr"`
+	Memo       string `json:"-"` // 附言（RmtInf/Ustrd），随支付标识一并传递
+}
+
+// defaultPaymentIdentification 基于当前交易生成支付标识
+func defaultPaymentIdentification(ctx contractapi.TransactionContextInterface) (*paymentIdentification, error) {
+	stub := ctx.GetStub()
+	txID := stub.GetTxID()
+	ts, err := stub.GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	return &paymentIdentification{
+		InstrID:    txID,
+		EndToEndID: txID,
+		UETR:       generateDeterministicUETR(txID, stub.GetChannelID(), ts.Seconds, ts.Nanos),
+	}, nil
+}
+
 // generateDeterministicUETR 基于链上确定性数据生成 UUID 形式的 UETR
 func generateDeterministicUETR(txID string, channelID string, seconds int64, nanos int32) string {
 	seed := fmt.Sprintf("%s|%s|%d|%d", channelID, txID, seconds, nanos)
@@ -1338,36 +1149,16 @@ func (s *SmartContract) Symbol(ctx contractapi.TransactionContextInterface) (str
 	return string(bytes), nil
 }
 
-// Set information for a token and intialize contract.
-// ===================================================
-// This function has only one input, a JSON string with four properties: name, symbol, decimals, and totalSupply
+// Initialize 设置代币名称、符号与小数位数并初始化合约，仅央行 MSP 可调用一次
+// 需要发行机构、国家或法币代码时使用 InitializeWithMetadata
 func (s *SmartContract) Initialize(ctx contractapi.TransactionContextInterface, name string, symbol string, decimals string) (bool, error) {
-
-	// 检查合约是否已经初始化 - 在第一次使用时调用Initialize()
-	initialized, err := checkInitialized(ctx)
+	decimalsValue, err := strconv.Atoi(decimals)
 	if err != nil {
-		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return false, fmt.Errorf("invalid decimals %q: must be an integer", decimals)
 	}
-	if initialized {
-		return false, fmt.Errorf("contract is already initialized")
-	}
-
-	// 在初始化函数中设置合约选项
-	err = ctx.GetStub().PutState(nameKey, []byte(name))
-	if err != nil {
-		return false, fmt.Errorf("failed to set token name: %v", err)
+	if _, err := s.initializeToken(ctx, TokenMetadata{Name: name, Symbol: symbol, Decimals: decimalsValue}); err != nil {
+		return false, err
 	}
-
-	err = ctx.GetStub().PutState(symbolKey, []byte(symbol))
-	if err != nil {
-		return false, fmt.Errorf("failed to set token symbol: %v", err)
-	}
-
-	err = ctx.GetStub().PutState(decimalsKey, []byte(decimals))
-	if err != nil {
-		return false, fmt.Errorf("failed to set token decimals: %v", err)
-	}
-
 	return true, nil
 }
 
@@ -1493,7 +1284,7 @@ func (s *SmartContract) extractOrgMSPFromClientID(clientID string) (string, erro
 // getUserAccountInfo 获取用户账户信息，包括余额和组织MSP
 func (s *SmartContract) getUserAccountInfo(ctx contractapi.TransactionContextInterface, userID string) (*UserBalance, error) {
 	balanceKey := balancePrefix + userID
-	balanceBytes, err := ctx.GetStub().GetPrivateData(centralBankCollection, balanceKey)
+	balanceBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), balanceKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read balance from private collection: %v", err)
 	}
@@ -1505,40 +1296,10 @@ func (s *SmartContract) getUserAccountInfo(ctx contractapi.TransactionContextInt
 	}
 
 	if balanceBytes != nil {
-		// 尝试解析为新的格式
-		if err := json.Unmarshal(balanceBytes, userBalance); err == nil {
-			// 如果成功解析，检查是否需要更新orgMSP
-			if userBalance.OrgMSP == "" {
-				// 提取并更新orgMSP
-				orgMSP, err := s.extractOrgMSPFromClientID(userID)
-				if err == nil {
-					userBalance.OrgMSP = orgMSP
-					// 更新存储的账户信息
-					err = s.updateUserAccountInPrivateCollection(ctx, userBalance)
-					if err != nil {
-						log.Printf("Warning: failed to update user account with orgMSP: %v", err)
-					}
-				}
-			}
-			return userBalance, nil
-		}
-
-		// 兼容旧格式 - 只有余额信息
-		balance, err := strconv.Atoi(string(balanceBytes))
+		// 迁移步骤 balances 完成前兼容纯数字余额与缺少账户状态、组织的旧记录
+		userBalance, err = s.decodeBalanceRecord(ctx, userID, balanceBytes)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse balance: %v", err)
-		}
-		userBalance.Balance = balance
-
-		// 为旧格式数据提取orgMSP
-		orgMSP, err := s.extractOrgMSPFromClientID(userID)
-		if err == nil {
-			userBalance.OrgMSP = orgMSP
-			// 更新存储的账户信息
-			err = s.updateUserAccountInPrivateCollection(ctx, userBalance)
-			if err != nil {
-				log.Printf("Warning: failed to update user account with orgMSP: %v", err)
-			}
+			return nil, err
 		}
 	} else {
 		// 账户不存在，尝试提取orgMSP用于新账户创建
@@ -1552,9 +1313,40 @@ func (s *SmartContract) getUserAccountInfo(ctx contractapi.TransactionContextInt
 }
 
 // updateUserAccountInPrivateCollection 更新私有集合中的用户账户信息
+// 余额减少时核对账户所属银行对本交易的扣款授权
 func (s *SmartContract) updateUserAccountInPrivateCollection(ctx contractapi.TransactionContextInterface, userBalance *UserBalance) error {
 	balanceKey := balancePrefix + userBalance.UserID
 
+	storedBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), balanceKey)
+	if err != nil {
+		return fmt.Errorf("failed to read balance from private collection: %v", err)
+	}
+	storedBalance := 0
+	if storedBytes != nil {
+		stored, _, err := s.upgradeLegacyBalance(userBalance.UserID, storedBytes)
+		if err != nil {
+			return err
+		}
+		storedBalance = stored.Balance
+		if userBalance.Balance < stored.Balance {
+			if err := checkDebitAuthorization(ctx, stored); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 余额变动时写入当月月末余额，供对账单计算期初余额
+	if userBalance.Balance != storedBalance {
+		if err := recordMonthBalance(ctx, userBalance); err != nil {
+			return err
+		}
+	}
+
+	return s.putUserAccount(ctx, userBalance)
+}
+
+// putUserAccount 将账户记录写入私有集合
+func (s *SmartContract) putUserAccount(ctx contractapi.TransactionContextInterface, userBalance *UserBalance) error {
 	// 序列化用户账户信息
 	balanceBytes, err := json.Marshal(userBalance)
 	if err != nil {
@@ -1562,7 +1354,7 @@ func (s *SmartContract) updateUserAccountInPrivateCollection(ctx contractapi.Tra
 	}
 
 	// 存储到私有集合
-	err = ctx.GetStub().PutPrivateData(centralBankCollection, balanceKey, balanceBytes)
+	err = ctx.GetStub().PutPrivateData(privateCollection(ctx), balancePrefix+userBalance.UserID, balanceBytes)
 	if err != nil {
 		return fmt.Errorf("failed to store balance in private collection: %v", err)
 	}
@@ -1586,17 +1378,7 @@ func (s *SmartContract) updateBalanceInPrivateCollection(ctx contractapi.Transac
 	// 获取现有账户信息
 	userBalance, err := s.getUserAccountInfo(ctx, userID)
 	if err != nil {
-		// 如果获取失败，创建新的账户信息
-		userBalance = &UserBalance{
-			UserID:  userID,
-			Balance: 0,
-			OrgMSP:  "",
-		}
-		// 尝试提取orgMSP
-		orgMSP, extractErr := s.extractOrgMSPFromClientID(userID)
-		if extractErr == nil {
-			userBalance.OrgMSP = orgMSP
-		}
+		return err
 	}
 
 	// 更新余额
@@ -1608,7 +1390,7 @@ func (s *SmartContract) updateBalanceInPrivateCollection(ctx contractapi.Transac
 
 // getTotalSupplyFromPrivateCollection 从私有集合获取总供应量
 func (s *SmartContract) getTotalSupplyFromPrivateCollection(ctx contractapi.TransactionContextInterface) (int, error) {
-	totalSupplyBytes, err := ctx.GetStub().GetPrivateData(centralBankCollection, totalSupplyKey)
+	totalSupplyBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), totalSupplyKey)
 	if err != nil {
 		return 0, fmt.Errorf("failed to read total supply from private collection: %v", err)
 	}
@@ -1625,65 +1407,106 @@ func (s *SmartContract) getTotalSupplyFromPrivateCollection(ctx contractapi.Tran
 	return totalSupply, nil
 }
 
-// updateTotalSupplyInPrivateCollection 更新私有集合中的总供应量
-func (s *SmartContract) updateTotalSupplyInPrivateCollection(ctx contractapi.TransactionContextInterface, totalSupply int) error {
+// updateTotalSupplyInPrivateCollection 更新私有集合中的总供应量，delta 为本次变动
+func (s *SmartContract) updateTotalSupplyInPrivateCollection(ctx contractapi.TransactionContextInterface, totalSupply int, delta int) error {
 	totalSupplyBytes := []byte(strconv.Itoa(totalSupply))
-	err := ctx.GetStub().PutPrivateData(centralBankCollection, totalSupplyKey, totalSupplyBytes)
+	err := ctx.GetStub().PutPrivateData(privateCollection(ctx), totalSupplyKey, totalSupplyBytes)
 	if err != nil {
 		return fmt.Errorf("failed to store total supply in private collection: %v", err)
 	}
 
-	return nil
+	// 记录供应量变动，供发行历史与流通速度统计累加收盘供应量
+	return s.recordSupplyChange(ctx, delta)
+}
+
+// transferLeg 一笔账户间划转
+type transferLeg struct {
+	From  string
+	To    string
+	Value int
 }
 
 // transferHelperPrivate 隐私版本的转账辅助函数
 func (s *SmartContract) transferHelperPrivate(ctx contractapi.TransactionContextInterface, from string, to string, value int) error {
-	if value < 0 {
-		return fmt.Errorf("transfer amount cannot be negative")
-	}
+	return s.postTransferLegs(ctx, []transferLeg{{From: from, To: to, Value: value}})
+}
 
-	// 从私有集合获取发送方余额
-	fromCurrentBalance, err := s.getBalanceFromPrivateCollection(ctx, from)
-	if err != nil {
-		return fmt.Errorf("failed to read sender account %s from private collection: %v", from, err)
+// postTransferLegs 在同一交易内依次执行多笔划转
+// 同一交易内读不到自身写入，每个账户只读取一次，余额变动在内存中累计后统一写入；
+// 自转账余额不变，不产生写入
+func (s *SmartContract) postTransferLegs(ctx contractapi.TransactionContextInterface, legs []transferLeg) error {
+	accounts := map[string]*UserBalance{}
+	originalBalances := map[string]int{}
+	var accountOrder []string
+	loadAccount := func(userID string, role string) (*UserBalance, error) {
+		if account, ok := accounts[userID]; ok {
+			return account, nil
+		}
+		account, err := s.getUserAccountInfo(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s account %s from private collection: %v", role, userID, err)
+		}
+		if err := checkAccountActive(account, role); err != nil {
+			return nil, err
+		}
+		accounts[userID] = account
+		originalBalances[userID] = account.Balance
+		accountOrder = append(accountOrder, userID)
+		return account, nil
 	}
 
-	if fromCurrentBalance < value {
-		return fmt.Errorf("sender account %s has insufficient funds", from)
-	}
+	var bankTransfers []bankTransfer
+	for _, leg := range legs {
+		if leg.Value < 0 {
+			return fmt.Errorf("transfer amount cannot be negative")
+		}
 
-	// 从私有集合获取接收方余额
-	toCurrentBalance, err := s.getBalanceFromPrivateCollection(ctx, to)
-	if err != nil {
-		return fmt.Errorf("failed to read recipient account %s from private collection: %v", to, err)
-	}
+		// 从私有集合获取发送方账户
+		fromAccount, err := loadAccount(leg.From, "sender")
+		if err != nil {
+			return err
+		}
+		if fromAccount.Balance < leg.Value {
+			return fmt.Errorf("sender account %s has insufficient funds", leg.From)
+		}
 
-	// 计算新余额
-	fromUpdatedBalance, err := sub(fromCurrentBalance, value)
-	if err != nil {
-		return err
-	}
+		if leg.From == leg.To {
+			continue
+		}
 
-	toUpdatedBalance, err := add(toCurrentBalance, value)
-	if err != nil {
-		return err
-	}
+		// 从私有集合获取接收方账户
+		toAccount, err := loadAccount(leg.To, "recipient")
+		if err != nil {
+			return err
+		}
 
-	// 更新私有集合中的余额
-	err = s.updateBalanceInPrivateCollection(ctx, from, fromUpdatedBalance)
-	if err != nil {
-		return err
-	}
+		// 计算新余额
+		fromAccount.Balance, err = sub(fromAccount.Balance, leg.Value)
+		if err != nil {
+			return err
+		}
+		toAccount.Balance, err = add(toAccount.Balance, leg.Value)
+		if err != nil {
+			return err
+		}
 
-	err = s.updateBalanceInPrivateCollection(ctx, to, toUpdatedBalance)
-	if err != nil {
-		return err
+		bankTransfers = append(bankTransfers, bankTransfer{FromOrgMSP: fromAccount.OrgMSP, ToOrgMSP: toAccount.OrgMSP, Value: leg.Value})
 	}
 
-	log.Printf("sender %s balance updated from %d to %d", from, fromCurrentBalance, fromUpdatedBalance)
-	log.Printf("recipient %s balance updated from %d to %d", to, toCurrentBalance, toUpdatedBalance)
+	// 更新私有集合中的余额
+	for _, userID := range accountOrder {
+		account := accounts[userID]
+		if account.Balance == originalBalances[userID] {
+			continue
+		}
+		if err := s.updateUserAccountInPrivateCollection(ctx, account); err != nil {
+			return err
+		}
+		log.Printf("account %s balance updated from %d to %d", userID, originalBalances[userID], account.Balance)
+	}
 
-	return nil
+	// 跨行转账同步更新银行汇总账
+	return s.recordBankTransfers(ctx, bankTransfers)
 }
 
 // ========== 权限控制辅助函数 ==========
@@ -1714,12 +1537,16 @@ func (s *SmartContract) checkAccountInfoPermission(ctx contractapi.TransactionCo
 	}
 
 	// 检查是否是央行用户（可以查看所有账户信息）
-	if callerDomain == CENTRAL_BANK_DOMAIN {
+	isCentralBank, err := isCentralBankDomain(ctx, callerDomain)
+	if err != nil {
+		return false, err
+	}
+	if isCentralBank {
 		return true, nil
 	}
 
 	// 检查是否是银行admin用户
-	if s.isAdminUserByDomain(callerID) {
+	if s.isAdminUserByDomain(ctx, callerID) {
 		// admin用户可以查看同一银行的所有账户信息
 		if callerDomain == targetInfo.OrgMSP {
 			return true, nil
@@ -1756,12 +1583,16 @@ func (s *SmartContract) checkBalancePermission(ctx contractapi.TransactionContex
 	}
 
 	// 检查是否是央行用户（可以查看所有账户）
-	if callerDomain == CENTRAL_BANK_DOMAIN {
+	isCentralBank, err := isCentralBankDomain(ctx, callerDomain)
+	if err != nil {
+		return false, err
+	}
+	if isCentralBank {
 		return true, nil
 	}
 
 	// 检查是否是银行admin用户
-	if s.isAdminUserByDomain(callerID) {
+	if s.isAdminUserByDomain(ctx, callerID) {
 		// admin用户可以查看同一银行的所有账户
 		if callerDomain == targetInfo.OrgMSP {
 			return true, nil
@@ -1772,8 +1603,14 @@ func (s *SmartContract) checkBalancePermission(ctx contractapi.TransactionContex
 	return false, nil
 }
 
-// isAdminUser 检查用户是否是admin用户
-func (s *SmartContract) isAdminUser(clientID string) bool {
+// isAdminUser 检查用户是否是admin用户，管理员关键字取自合约配置；配置读取失败时视为非admin
+func (s *SmartContract) isAdminUser(ctx contractapi.TransactionContextInterface, clientID string) bool {
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return false
+	}
+	keyword := strings.ToLower(config.AdminRoleKeyword)
+
 	// 尝试解码 base64 编码的 clientID
 	decodedBytes, err := base64.StdEncoding.DecodeString(clientID)
 	if err != nil {
@@ -1791,8 +1628,8 @@ func (s *SmartContract) isAdminUser(clientID string) bool {
 			// 解析 CN (Common Name)
 			if cnMatch := strings.Split(subjectPart, "CN="); len(cnMatch) > 1 {
 				cnPart := strings.Split(cnMatch[1], ",")[0]
-				// 检查是否包含Admin（不区分大小写）
-				if strings.Contains(strings.ToLower(cnPart), "admin") {
+				// 检查是否包含管理员关键字（不区分大小写）
+				if strings.Contains(strings.ToLower(cnPart), keyword) {
 					return true
 				}
 			}
@@ -1803,15 +1640,20 @@ func (s *SmartContract) isAdminUser(clientID string) bool {
 }
 
 // isAdminUserByDomain 通过domain检查用户是否是admin用户（更准确的方法）
-func (s *SmartContract) isAdminUserByDomain(clientID string) bool {
+func (s *SmartContract) isAdminUserByDomain(ctx contractapi.TransactionContextInterface, clientID string) bool {
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return false
+	}
+
 	// 提取domain
 	domain, err := s.extractDomainFromClientID(clientID)
 	if err != nil {
 		return false
 	}
 
-	// 检查domain是否包含admin（不区分大小写）
-	return strings.Contains(strings.ToLower(domain), "admin")
+	// 检查domain是否包含管理员关键字（不区分大小写）
+	return strings.Contains(strings.ToLower(domain), strings.ToLower(config.AdminRoleKeyword))
 }
 
 // extractDomainFromClientID 从clientID中提取组织域名信息
@@ -1885,12 +1727,16 @@ func (s *SmartContract) checkTransactionQueryPermission(ctx contractapi.Transact
 	}
 
 	// 检查是否是央行用户（可以查看所有交易记录）
-	if callerDomain == CENTRAL_BANK_DOMAIN {
+	isCentralBank, err := isCentralBankDomain(ctx, callerDomain)
+	if err != nil {
+		return false, err
+	}
+	if isCentralBank {
 		return true, nil
 	}
 
 	// 检查是否是银行admin用户
-	if s.isAdminUserByDomain(callerID) {
+	if s.isAdminUserByDomain(ctx, callerID) {
 		// admin用户可以查看同一银行的所有交易记录
 		targetInfo, err := s.getUserAccountInfo(ctx, targetUserID)
 		if err != nil {
@@ -1908,7 +1754,8 @@ func (s *SmartContract) checkTransactionQueryPermission(ctx contractapi.Transact
 // ========== 统一的交易查询方法 ==========
 
 // QueryUserTransactions 统一的交易查询方法，支持多种筛选条件和分页
-func (s *SmartContract) QueryUserTransactions(ctx contractapi.TransactionContextInterface, userID string, minAmount int, maxAmount int, transactionType string, counterparty string, pageSize int, offset int) (string, error) {
+// 结果按时间顺序返回；cursor 为上一页返回的 nextCursor，空字符串或 "0" 表示第一页
+func (s *SmartContract) QueryUserTransactions(ctx contractapi.TransactionContextInterface, userID string, minAmount int, maxAmount int, transactionType string, counterparty string, pageSize int, cursor string) (string, error) {
 	// 检查合约初始化
 	initialized, err := checkInitialized(ctx)
 	if err != nil {
@@ -1924,6 +1771,16 @@ func (s *SmartContract) QueryUserTransactions(ctx contractapi.TransactionContext
 		return "", fmt.Errorf("failed to get caller id: %v", err)
 	}
 
+	// 解析账户与交易对手方别名
+	userID, err = s.resolveAccount(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	counterparty, err = s.resolveAccount(ctx, counterparty)
+	if err != nil {
+		return "", err
+	}
+
 	// 检查权限 - 用户只能查询自己的交易，央行可以查询所有交易
 	hasPermission, err := s.checkTransactionQueryPermission(ctx, callerID, userID)
 	if err != nil {
@@ -1934,108 +1791,22 @@ func (s *SmartContract) QueryUserTransactions(ctx contractapi.TransactionContext
 	}
 
 	// 验证和设置页面大小
-	if pageSize <= 0 {
-		pageSize = 20 // 默认页面大小
-	}
-	if pageSize > 100 {
-		pageSize = 100 // 最大页面大小限制
-	}
-
-	// 验证偏移量
-	if offset < 0 {
-		offset = 0
-	}
-
-	// 构建查询条件
-	querySelector := map[string]interface{}{
-		"selector": map[string]interface{}{
-			"docType": "transaction",
-		},
-		"limit": pageSize + offset, // 获取更多数据以支持偏移量
-	}
-
-	// 添加用户筛选条件
-	if userID != "" {
-		querySelector["selector"].(map[string]interface{})["$or"] = []map[string]interface{}{
-			{"from": userID},
-			{"to": userID},
-		}
-	}
-
-	// 添加金额范围筛选
-	if minAmount > 0 || maxAmount > 0 {
-		amountCondition := map[string]interface{}{}
-		if minAmount > 0 {
-			amountCondition["$gte"] = minAmount
-		}
-		if maxAmount > 0 {
-			amountCondition["$lte"] = maxAmount
-		}
-		querySelector["selector"].(map[string]interface{})["amount"] = amountCondition
-	}
-
-	// 添加交易类型筛选
-	if transactionType != "" {
-		querySelector["selector"].(map[string]interface{})["transactionType"] = transactionType
-	}
-
-	// 添加参与方筛选
-	if counterparty != "" {
-		querySelector["selector"].(map[string]interface{})["$or"] = []map[string]interface{}{
-			{"from": counterparty},
-			{"to": counterparty},
-		}
-	}
-
-	// 序列化查询条件
-	queryJSON, err := json.Marshal(querySelector)
+	pageSize, err = normalizePageSize(ctx, pageSize)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal query selector: %v", err)
+		return "", err
 	}
 
-	// 执行查询
-	queryResults, err := ctx.GetStub().GetPrivateDataQueryResult(centralBankCollection, string(queryJSON))
+	// 解析游标
+	position, err := parseQueryCursor(cursor)
 	if err != nil {
-		return "", fmt.Errorf("failed to query private data: %v", err)
-	}
-	defer queryResults.Close()
-
-	// 处理查询结果
-	var allTransactions []map[string]interface{}
-	for queryResults.HasNext() {
-		queryResult, err := queryResults.Next()
-		if err != nil {
-			return "", fmt.Errorf("failed to get next query result: %v", err)
-		}
-
-		var transaction map[string]interface{}
-		err = json.Unmarshal(queryResult.Value, &transaction)
-		if err != nil {
-			return "", fmt.Errorf("failed to unmarshal transaction: %v", err)
-		}
-
-		// 添加查询结果信息
-		transaction["key"] = queryResult.Key
-		allTransactions = append(allTransactions, transaction)
-	}
-
-	// 应用偏移量和页面大小
-	var transactions []map[string]interface{}
-	totalCount := len(allTransactions)
-
-	if offset < totalCount {
-		endIndex := offset + pageSize
-		if endIndex > totalCount {
-			endIndex = totalCount
-		}
-		transactions = allTransactions[offset:endIndex]
+		return "", err
 	}
 
-	// 计算分页信息
-	hasMore := (offset + pageSize) < totalCount
-	nextOffset := offset + pageSize
-	if nextOffset >= totalCount {
-		nextOffset = -1 // 表示没有下一页
+	// 按账户索引扫描，其余条件逐条筛选
+	page, err := s.scanTransactionIndex(ctx, accountTxIndex, []string{userID}, position, pageSize,
+		transactionFilter(minAmount, maxAmount, transactionType, counterparty))
+	if err != nil {
+		return "", err
 	}
 
 	// 构建响应
@@ -2048,14 +1819,13 @@ func (s *SmartContract) QueryUserTransactions(ctx contractapi.TransactionContext
 			"counterparty":    counterparty,
 		},
 		"pagination": map[string]interface{}{
-			"pageSize":      pageSize,
-			"currentOffset": offset,
-			"nextOffset":    nextOffset,
-			"hasMore":       hasMore,
-			"totalCount":    totalCount,
+			"pageSize":   pageSize,
+			"cursor":     cursor,
+			"nextCursor": page.NextCursor,
+			"hasMore":    page.HasMore,
 		},
-		"currentPageCount": len(transactions),
-		"transactions":     transactions,
+		"currentPageCount": len(page.Records),
+		"transactions":     page.Records,
 	}
 
 	// 序列化响应
@@ -2067,19 +1837,76 @@ func (s *SmartContract) QueryUserTransactions(ctx contractapi.TransactionContext
 	return string(responseJSON), nil
 }
 
+// transactionFilter 构建金额范围、交易类型与交易对手方筛选条件
+// counterparty 匹配交易的任意一方
+func transactionFilter(minAmount int, maxAmount int, transactionType string, counterparty string) func(record *TransactionRecord) bool {
+	return func(record *TransactionRecord) bool {
+		if minAmount > 0 && record.Amount < minAmount {
+			return false
+		}
+		if maxAmount > 0 && record.Amount > maxAmount {
+			return false
+		}
+		if transactionType != "" && record.TransactionType != transactionType {
+			return false
+		}
+		if counterparty != "" && record.From != counterparty && record.To != counterparty {
+			return false
+		}
+		return true
+	}
+}
+
 // 为了向后兼容，保留一些简化的查询方法
 // QueryUserTransactionsSimple 简化版查询，用于基本查询需求
 func (s *SmartContract) QueryUserTransactionsSimple(ctx contractapi.TransactionContextInterface, userID string) (string, error) {
-	return s.QueryUserTransactions(ctx, userID, 0, 0, "", "", 100, 0)
+	return s.QueryUserTransactions(ctx, userID, 0, 0, "", "", 100, "")
 }
 
 // GetUserTransactionHistory 获取用户交易历史（向后兼容）
 func (s *SmartContract) GetUserTransactionHistory(ctx contractapi.TransactionContextInterface, userID string) (string, error) {
-	return s.QueryUserTransactions(ctx, userID, 0, 0, "", "", 50, 0)
+	return s.QueryUserTransactions(ctx, userID, 0, 0, "", "", 50, "")
+}
+
+// GetTransactionRecord 央行读取单笔交易的完整私有记录（供链下区块索引服务关联区块数据）
+func (s *SmartContract) GetTransactionRecord(ctx contractapi.TransactionContextInterface, txID string) (string, error) {
+	// 检查合约初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	// 仅央行可以读取完整私有记录
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	isCentralBank, err := isCentralBankMSP(ctx, clientMSPID)
+	if err != nil {
+		return "", err
+	}
+	if !isCentralBank {
+		return "", fmt.Errorf("client is not authorized to read transaction records")
+	}
+
+	recordBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), transactionPrefix+txID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read transaction %s: %v", txID, err)
+	}
+	if recordBytes == nil {
+		return "", fmt.Errorf("transaction %s does not exist", txID)
+	}
+
+	return string(recordBytes), nil
 }
 
 // QueryAllTransactions 查询所有交易记录，根据用户角色实现权限控制
-func (s *SmartContract) QueryAllTransactions(ctx contractapi.TransactionContextInterface, minAmount int, maxAmount int, transactionType string, counterparty string, pageSize int, offset int) (string, error) {
+// 央行查询全部交易，银行admin查询本行客户参与的交易，普通用户查询自己的交易
+// 结果按时间顺序返回；cursor 为上一页返回的 nextCursor，空字符串或 "0" 表示第一页
+func (s *SmartContract) QueryAllTransactions(ctx contractapi.TransactionContextInterface, minAmount int, maxAmount int, transactionType string, counterparty string, pageSize int, cursor string) (string, error) {
 	// 检查合约初始化
 	initialized, err := checkInitialized(ctx)
 	if err != nil {
@@ -2101,148 +1928,50 @@ func (s *SmartContract) QueryAllTransactions(ctx contractapi.TransactionContextI
 		return "", fmt.Errorf("failed to extract caller domain: %v", err)
 	}
 
-	// 验证和设置页面大小
-	if pageSize <= 0 {
-		pageSize = 20 // 默认页面大小
-	}
-	if pageSize > 100 {
-		pageSize = 100 // 最大页面大小限制
+	// 解析交易对手方别名
+	counterparty, err = s.resolveAccount(ctx, counterparty)
+	if err != nil {
+		return "", err
 	}
 
-	// 验证偏移量
-	if offset < 0 {
-		offset = 0
+	// 验证和设置页面大小
+	pageSize, err = normalizePageSize(ctx, pageSize)
+	if err != nil {
+		return "", err
 	}
 
-	// 构建查询条件
-	querySelector := map[string]interface{}{
-		"selector": map[string]interface{}{
-			"docType": "transaction",
-		},
-		"limit": pageSize + offset, // 获取更多数据以支持偏移量
+	// 解析游标
+	position, err := parseQueryCursor(cursor)
+	if err != nil {
+		return "", err
 	}
 
-	// 根据用户角色添加不同的筛选条件
-	if callerDomain == CENTRAL_BANK_DOMAIN {
-		// 央行用户（admin和user）：可以查询所有交易，不需要额外筛选
+	// 根据用户角色选择索引
+	isCentralBank, err := isCentralBankDomain(ctx, callerDomain)
+	if err != nil {
+		return "", err
+	}
+	indexType := accountTxIndex
+	indexAttributes := []string{callerID}
+	if isCentralBank {
+		// 央行用户（admin和user）：可以查询所有交易
 		log.Printf("央行用户查询所有交易记录")
-	} else if s.isAdminUserByDomain(callerID) {
+		indexType = timeTxIndex
+		indexAttributes = []string{}
+	} else if s.isAdminUserByDomain(ctx, callerID) {
 		// 银行admin用户：只能查询同一银行的所有交易
 		log.Printf("银行admin用户查询本行所有交易记录，银行MSP: %s", callerDomain)
-		// 这里需要通过MSP字段筛选，但由于当前交易数据结构中没有存储toMsp字段
-		// 我们需要通过其他方式来实现银行级别的筛选
-		// 暂时先查询所有交易，后续可以通过增强交易数据结构来完善
+		indexType = orgTxIndex
+		indexAttributes = []string{callerDomain}
 	} else {
 		// 普通用户：只能查询自己的交易
 		log.Printf("普通用户查询自己的交易记录，用户ID: %s", callerID)
-		querySelector["selector"].(map[string]interface{})["$or"] = []map[string]interface{}{
-			{"from": callerID},
-			{"to": callerID},
-		}
-	}
-
-	// 添加金额范围筛选
-	if minAmount > 0 || maxAmount > 0 {
-		amountCondition := map[string]interface{}{}
-		if minAmount > 0 {
-			amountCondition["$gte"] = minAmount
-		}
-		if maxAmount > 0 {
-			amountCondition["$lte"] = maxAmount
-		}
-		querySelector["selector"].(map[string]interface{})["amount"] = amountCondition
-	}
-
-	// 添加交易类型筛选
-	if transactionType != "" {
-		querySelector["selector"].(map[string]interface{})["transactionType"] = transactionType
-	}
-
-	// 添加参与方筛选
-	if counterparty != "" {
-		// 如果已经有$or条件，需要合并
-		if _, exists := querySelector["selector"].(map[string]interface{})["$or"]; exists {
-			// 合并现有的$or条件和新的参与方条件
-			// 这里需要更复杂的逻辑来合并条件，暂时简化处理
-			log.Printf("Warning: 参与方筛选与现有条件冲突，暂时忽略参与方筛选")
-		} else {
-			querySelector["selector"].(map[string]interface{})["$or"] = []map[string]interface{}{
-				{"from": counterparty},
-				{"to": counterparty},
-			}
-		}
 	}
 
-	// 序列化查询条件
-	queryJSON, err := json.Marshal(querySelector)
+	page, err := s.scanTransactionIndex(ctx, indexType, indexAttributes, position, pageSize,
+		transactionFilter(minAmount, maxAmount, transactionType, counterparty))
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal query selector: %v", err)
-	}
-
-	// 执行查询
-	queryResults, err := ctx.GetStub().GetPrivateDataQueryResult(centralBankCollection, string(queryJSON))
-	if err != nil {
-		return "", fmt.Errorf("failed to query private data: %v", err)
-	}
-	defer queryResults.Close()
-
-	// 处理查询结果
-	var allTransactions []map[string]interface{}
-	for queryResults.HasNext() {
-		queryResult, err := queryResults.Next()
-		if err != nil {
-			return "", fmt.Errorf("failed to get next query result: %v", err)
-		}
-
-		var transaction map[string]interface{}
-		err = json.Unmarshal(queryResult.Value, &transaction)
-		if err != nil {
-			return "", fmt.Errorf("failed to unmarshal transaction: %v", err)
-		}
-
-		// 添加查询结果信息
-		transaction["key"] = queryResult.Key
-		allTransactions = append(allTransactions, transaction)
-	}
-
-	// 对于银行admin用户，需要在应用层进行MSP筛选
-	if callerDomain != CENTRAL_BANK_DOMAIN && s.isAdminUserByDomain(callerID) && !s.isCentralBankUser(callerID) {
-		// 银行admin用户：筛选同一银行的交易
-		var filteredTransactions []map[string]interface{}
-		for _, tx := range allTransactions {
-			// 检查交易的from和to是否属于同一银行
-			fromUser := tx["from"].(string)
-			toUser := tx["to"].(string)
-
-			// 获取from和to用户的MSP信息
-			fromMSP, _ := s.extractDomainFromClientID(fromUser)
-			toMSP, _ := s.extractDomainFromClientID(toUser)
-
-			// 如果from或to属于同一银行，则包含此交易
-			if fromMSP == callerDomain || toMSP == callerDomain {
-				filteredTransactions = append(filteredTransactions, tx)
-			}
-		}
-		allTransactions = filteredTransactions
-	}
-
-	// 应用偏移量和页面大小
-	var transactions []map[string]interface{}
-	totalCount := len(allTransactions)
-
-	if offset < totalCount {
-		endIndex := offset + pageSize
-		if endIndex > totalCount {
-			endIndex = totalCount
-		}
-		transactions = allTransactions[offset:endIndex]
-	}
-
-	// 计算分页信息
-	hasMore := (offset + pageSize) < totalCount
-	nextOffset := offset + pageSize
-	if nextOffset >= totalCount {
-		nextOffset = -1 // 表示没有下一页
+		return "", err
 	}
 
 	// 构建响应
@@ -2254,19 +1983,18 @@ func (s *SmartContract) QueryAllTransactions(ctx contractapi.TransactionContextI
 			"counterparty":    counterparty,
 		},
 		"pagination": map[string]interface{}{
-			"pageSize":      pageSize,
-			"currentOffset": offset,
-			"nextOffset":    nextOffset,
-			"hasMore":       hasMore,
-			"totalCount":    totalCount,
+			"pageSize":   pageSize,
+			"cursor":     cursor,
+			"nextCursor": page.NextCursor,
+			"hasMore":    page.HasMore,
 		},
-		"currentPageCount": len(transactions),
-		"transactions":     transactions,
+		"currentPageCount": len(page.Records),
+		"transactions":     page.Records,
 		"userRole": map[string]interface{}{
 			"callerID":      callerID,
 			"callerDomain":  callerDomain,
-			"isAdmin":       s.isAdminUserByDomain(callerID),
-			"isCentralBank": callerDomain == CENTRAL_BANK_DOMAIN,
+			"isAdmin":       s.isAdminUserByDomain(ctx, callerID),
+			"isCentralBank": isCentralBank,
 		},
 	}
 
@@ -2280,10 +2008,11 @@ func (s *SmartContract) QueryAllTransactions(ctx contractapi.TransactionContextI
 }
 
 // isCentralBankUser 检查用户是否是央行用户
-func (s *SmartContract) isCentralBankUser(clientID string) bool {
+func (s *SmartContract) isCentralBankUser(ctx contractapi.TransactionContextInterface, clientID string) bool {
 	domain, err := s.extractDomainFromClientID(clientID)
 	if err != nil {
 		return false
 	}
-	return domain == CENTRAL_BANK_DOMAIN
+	isCentralBank, err := isCentralBankDomain(ctx, domain)
+	return err == nil && isCentralBank
 }
@@ -0,0 +1,260 @@
+/*
+代币元数据与初始化
+
+- 只有发行机构（内置的央行 MSP，不受 transient 字段 "config" 影响）可以初始化合约，且只能初始化一次
+- 名称、符号、小数位数必填：符号为 3-6 位大写字母（类 ISO 4217），小数位数 0-18
+- 发行机构名称、国家（ISO 3166-1 alpha-2）与法币代码（ISO 4217）可选
+- 名称、符号、小数位数仍分别保存在 name、symbol、decimals 键中；完整元数据保存在 tokeninfo 键中
+- GetTokenInfo 一次返回元数据、配置版本与合约代码版本
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// contractVersion 合约代码版本，随链码发布递增
+const contractVersion = "2.0.0"
+
+// tokenInfoKey 完整代币元数据的存储键（公共状态）
+const tokenInfoKey = "tokeninfo"
+
+// 元数据限制
+const (
+	maxTokenNameLength   = 64
+	maxTokenDecimals     = 18
+	maxIssuerNameLength  = maxMemoLength
+	tokenSymbolMinLength = 3
+	tokenSymbolMaxLength = 6
+)
+
+var (
+	tokenSymbolPattern  = regexp.MustCompile(fmt.Sprintf(`^[A-Z]{%d,%d}$`, tokenSymbolMinLength, tokenSymbolMaxLength))
+	countryCodePattern  = regexp.MustCompile(`^[A-Z]{2}$`)
+	currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// TokenMetadata 代币元数据
+type TokenMetadata struct {
+	Name          string `json:"name"`
+	Symbol        string `json:"symbol"`
+	Decimals      int    `json:"decimals"`
+	Issuer        string `json:"issuer,omitempty"`   // 发行机构名称
+	Country       string `json:"country,omitempty"`  // ISO 3166-1 alpha-2 国家代码
+	Currency      string `json:"currency,omitempty"` // 对应法币的 ISO 4217 代码
+	InitializedAt int64  `json:"initializedAt,omitempty"`
+	InitializedBy string `json:"initializedBy,omitempty"`
+	TxID          string `json:"txId,omitempty"`
+}
+
+// validate 校验调用者提供的元数据
+func (m *TokenMetadata) validate() error {
+	m.Name = strings.TrimSpace(m.Name)
+	if m.Name == "" {
+		return errors.New("name must not be empty")
+	}
+	if utf8.RuneCountInString(m.Name) > maxTokenNameLength {
+		return fmt.Errorf("name must not exceed %d characters", maxTokenNameLength)
+	}
+	if strings.IndexFunc(m.Name, unicode.IsControl) >= 0 {
+		return errors.New("name must not contain control characters")
+	}
+	if !tokenSymbolPattern.MatchString(m.Symbol) {
+		return fmt.Errorf("invalid symbol %q: expected %d-%d uppercase letters", m.Symbol, tokenSymbolMinLength, tokenSymbolMaxLength)
+	}
+	if m.Decimals < 0 || m.Decimals > maxTokenDecimals {
+		return fmt.Errorf("decimals must be between 0 and %d", maxTokenDecimals)
+	}
+
+	m.Issuer = strings.TrimSpace(m.Issuer)
+	if utf8.RuneCountInString(m.Issuer) > maxIssuerNameLength {
+		return fmt.Errorf("issuer must not exceed %d characters", maxIssuerNameLength)
+	}
+	if strings.IndexFunc(m.Issuer, unicode.IsControl) >= 0 {
+		return errors.New("issuer must not contain control characters")
+	}
+	if m.Country != "" && !countryCodePattern.MatchString(m.Country) {
+		return fmt.Errorf("invalid country %q: expected an ISO 3166-1 alpha-2 code", m.Country)
+	}
+	if m.Currency != "" && !currencyCodePattern.MatchString(m.Currency) {
+		return fmt.Errorf("invalid currency %q: expected an ISO 4217 code", m.Currency)
+	}
+	return nil
+}
+
+// initializeToken 校验调用者与元数据，写入代币元数据与第 1 版合约配置
+func (s *SmartContract) initializeToken(ctx contractapi.TransactionContextInterface, metadata TokenMetadata) (*TokenMetadata, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if initialized {
+		return nil, errors.New("contract is already initialized")
+	}
+
+	// 仅发行机构可以初始化；先校验调用者，再读取调用者提供的配置
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != CENTRAL_MSP_ID {
+		return nil, fmt.Errorf("only the issuing MSP %s can initialize the contract", CENTRAL_MSP_ID)
+	}
+	settings, err := initialConfigSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller id: %v", err)
+	}
+
+	if err := metadata.validate(); err != nil {
+		return nil, fmt.Errorf("invalid token metadata: %v", err)
+	}
+
+	stub := ctx.GetStub()
+	timestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	metadata.InitializedAt = timestamp.Seconds
+	metadata.InitializedBy = callerID
+	metadata.TxID = stub.GetTxID()
+
+	if err := stub.PutState(nameKey, []byte(metadata.Name)); err != nil {
+		return nil, fmt.Errorf("failed to set token name: %v", err)
+	}
+	if err := stub.PutState(symbolKey, []byte(metadata.Symbol)); err != nil {
+		return nil, fmt.Errorf("failed to set token symbol: %v", err)
+	}
+	if err := stub.PutState(decimalsKey, []byte(strconv.Itoa(metadata.Decimals))); err != nil {
+		return nil, fmt.Errorf("failed to set token decimals: %v", err)
+	}
+	metadataBytes, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal token metadata: %v", err)
+	}
+	if err := stub.PutState(tokenInfoKey, metadataBytes); err != nil {
+		return nil, fmt.Errorf("failed to store token metadata: %v", err)
+	}
+
+	if _, err := initContractConfig(ctx, settings, callerID); err != nil {
+		return nil, err
+	}
+	if err := initSchemaState(ctx); err != nil {
+		return nil, err
+	}
+
+	log.Printf("contract initialized: %s (%s), %d decimals", metadata.Name, metadata.Symbol, metadata.Decimals)
+
+	return &metadata, nil
+}
+
+// InitializeWithMetadata 以完整元数据初始化合约，仅央行 MSP 可调用一次
+// metadataJSON 示例：{"name":"Digital Yuan","symbol":"DCEP","decimals":2,"issuer":"People's Bank","country":"CN","currency":"CNY"}
+func (s *SmartContract) InitializeWithMetadata(ctx contractapi.TransactionContextInterface, metadataJSON string) (string, error) {
+	var request struct {
+		Name     string `json:"name"`
+		Symbol   string `json:"symbol"`
+		Decimals *int   `json:"decimals"`
+		Issuer   string `json:"issuer"`
+		Country  string `json:"country"`
+		Currency string `json:"currency"`
+	}
+	decoder := json.NewDecoder(strings.NewReader(metadataJSON))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		return "", fmt.Errorf("invalid token metadata: %v", err)
+	}
+	if request.Decimals == nil {
+		return "", errors.New("invalid token metadata: decimals is required")
+	}
+
+	metadata, err := s.initializeToken(ctx, TokenMetadata{
+		Name:     request.Name,
+		Symbol:   request.Symbol,
+		Decimals: *request.Decimals,
+		Issuer:   request.Issuer,
+		Country:  request.Country,
+		Currency: request.Currency,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	metadataBytes, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal token metadata: %v", err)
+	}
+	return string(metadataBytes), nil
+}
+
+// getTokenMetadata 读取代币元数据；早于 tokeninfo 键初始化的合约由 name、symbol、decimals 键组装
+func (s *SmartContract) getTokenMetadata(ctx contractapi.TransactionContextInterface) (*TokenMetadata, error) {
+	stub := ctx.GetStub()
+	metadataBytes, err := stub.GetState(tokenInfoKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token metadata: %v", err)
+	}
+	if metadataBytes != nil {
+		metadata := &TokenMetadata{}
+		if err := json.Unmarshal(metadataBytes, metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal token metadata: %v", err)
+		}
+		return metadata, nil
+	}
+
+	nameBytes, err := stub.GetState(nameKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token name: %v", err)
+	}
+	symbol, decimals, err := s.getTokenMeta(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenMetadata{Name: string(nameBytes), Symbol: symbol, Decimals: decimals}, nil
+}
+
+// GetTokenInfo 返回代币元数据、当前配置版本与合约代码版本
+func (s *SmartContract) GetTokenInfo(ctx contractapi.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", errors.New("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	metadata, err := s.getTokenMetadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	infoBytes, err := json.Marshal(struct {
+		*TokenMetadata
+		ConfigVersion   int    `json:"configVersion"`
+		ContractVersion string `json:"contractVersion"`
+	}{metadata, config.Version, contractVersion})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal token info: %v", err)
+	}
+	return string(infoBytes), nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func TestInitializeRequiresIssuingMSP(t *testing.T) {
+	tests := []struct {
+		name      string
+		caller    testUser
+		transient map[string][]byte
+		wantErr   string
+	}{
+		{
+			name:    "other MSP",
+			caller:  bankAAdmin,
+			wantErr: "only the issuing MSP",
+		},
+		{
+			name:      "other MSP claims to be the central bank",
+			caller:    bankAAdmin,
+			transient: map[string][]byte{configTransientField: []byte(`{"centralBankMsp":"AMSP","centralBankDomain":"a.example.com"}`)},
+			wantErr:   "only the issuing MSP",
+		},
+		{
+			name:      "issuer hands over in the initial config",
+			caller:    centralBankAdmin,
+			transient: map[string][]byte{configTransientField: []byte(`{"centralBankMsp":"AMSP"}`)},
+			wantErr:   "centralBankMsp cannot be set at initialization",
+		},
+		{
+			name:      "issuer overrides other settings",
+			caller:    centralBankAdmin,
+			transient: map[string][]byte{configTransientField: []byte(`{"limits":{"maxQueryPageSize":50}}`)},
+		},
+		{
+			name:   "issuer with defaults",
+			caller: centralBankAdmin,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			_, err := env.invoke(tt.caller, tt.transient, func(ctx contractapi.TransactionContextInterface) error {
+				_, err := env.contract.Initialize(ctx, "CBDC", "DCEP", "2")
+				return err
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if env.ledger.state[nameKey] != nil || env.ledger.state[contractConfigKey] != nil {
+					t.Fatalf("rejected Initialize must not write state")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+				config, err := activeConfig(ctx)
+				if err != nil {
+					return err
+				}
+				if config.CentralBankMSP != CENTRAL_MSP_ID {
+					t.Errorf("centralBankMsp = %s, want %s", config.CentralBankMSP, CENTRAL_MSP_ID)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("failed to read config: %v", err)
+			}
+		})
+	}
+}
+
+func TestInitializeOnlyOnce(t *testing.T) {
+	env := newTestEnv(t)
+	env.initialize()
+
+	_, err := env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.Initialize(ctx, "CBDC", "DCEP", "2")
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "already initialized") {
+		t.Fatalf("expected already initialized error, got %v", err)
+	}
+}
+
+func TestTokenMetadataValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata TokenMetadata
+		wantErr  string
+	}{
+		{name: "valid", metadata: TokenMetadata{Name: "Digital Yuan", Symbol: "DCEP", Decimals: 2, Country: "CN", Currency: "CNY"}},
+		{name: "empty name", metadata: TokenMetadata{Name: "  ", Symbol: "DCEP", Decimals: 2}, wantErr: "name must not be empty"},
+		{name: "lowercase symbol", metadata: TokenMetadata{Name: "CBDC", Symbol: "dcep", Decimals: 2}, wantErr: "invalid symbol"},
+		{name: "decimals too large", metadata: TokenMetadata{Name: "CBDC", Symbol: "DCEP", Decimals: 19}, wantErr: "decimals must be between"},
+		{name: "negative decimals", metadata: TokenMetadata{Name: "CBDC", Symbol: "DCEP", Decimals: -1}, wantErr: "decimals must be between"},
+		{name: "bad country", metadata: TokenMetadata{Name: "CBDC", Symbol: "DCEP", Country: "CHN"}, wantErr: "invalid country"},
+		{name: "bad currency", metadata: TokenMetadata{Name: "CBDC", Symbol: "DCEP", Currency: "yuan"}, wantErr: "invalid currency"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.metadata.validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+/*
+交易时间序索引
+
+- 每笔记账写入组合键索引：账户、组织域名与全局时间序；账户索引另按月分桶，见 balance_history.go
+- 键中的时间戳补零到固定宽度，LevelDB 与 CouchDB 上按键扫描即按时间排序
+- 分页使用不透明游标（最后返回交易的时间戳与交易ID），不再依赖偏移量
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// 索引的组合键对象类型
+const accountTxIndex = "acct~ts~tx" // 账户 -> 时间戳 -> 交易ID
+const orgTxIndex = "org~ts~tx"      // 组织域名 -> 时间戳 -> 交易ID
+const timeTxIndex = "ts~tx"         // 时间戳 -> 交易ID
+
+// 分页参数；最大页大小的实际值取自合约配置
+const defaultQueryPageSize = 20
+const maxQueryPageSize = 100
+
+// maxIndexScan 单次查询最多扫描的索引条目数，超过后返回游标由调用方继续
+const maxIndexScan = 1000
+
+// indexValue 索引条目的值（组合键本身已包含全部信息）
+var indexValue = []byte{0x00}
+
+// transactionPage 一页交易查询结果
+type transactionPage struct {
+	Records    []*TransactionRecord
+	NextCursor string
+	HasMore    bool
+}
+
+// formatIndexTimestamp 将时间戳补零到固定宽度，使字典序与时间顺序一致
+func formatIndexTimestamp(timestamp int64) string {
+	return fmt.Sprintf("%020d", timestamp)
+}
+
+// normalizePageSize 校验并按配置的上限限制页面大小
+func normalizePageSize(ctx contractapi.TransactionContextInterface, pageSize int) (int, error) {
+	if pageSize <= 0 {
+		return defaultQueryPageSize, nil
+	}
+	config, err := activeConfig(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if pageSize > config.Limits.MaxQueryPageSize {
+		return config.Limits.MaxQueryPageSize, nil
+	}
+	return pageSize, nil
+}
+
+// parseQueryCursor 解析查询游标；空字符串或 "0" 表示从头开始
+func parseQueryCursor(cursor string) (entryCursor, error) {
+	if cursor == "" || cursor == "0" {
+		return entryCursor{}, nil
+	}
+	return decodeEntryCursor(cursor)
+}
+
+// putIndexEntry 写入一条索引
+func putIndexEntry(ctx contractapi.TransactionContextInterface, objectType string, attributes ...string) error {
+	stub := ctx.GetStub()
+	key, err := stub.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", objectType, err)
+	}
+	err = stub.PutPrivateData(privateCollection(ctx), key, indexValue)
+	if err != nil {
+		return fmt.Errorf("failed to store index %s: %v", objectType, err)
+	}
+	return nil
+}
+
+// indexTransaction 为交易记录写入账户（含按月分桶）、组织与全局时间序索引
+func (s *SmartContract) indexTransaction(ctx contractapi.TransactionContextInterface, record *TransactionRecord) error {
+	ts := formatIndexTimestamp(record.Timestamp)
+	month := formatIndexMonth(record.Timestamp)
+	putIndex := func(objectType string, attributes ...string) error {
+		return putIndexEntry(ctx, objectType, attributes...)
+	}
+
+	if err := putIndex(timeTxIndex, ts, record.TxID); err != nil {
+		return err
+	}
+
+	// 自转账只写一条账户索引；零地址不建立索引；手续费账户也有入账分录
+	accounts := map[string]bool{}
+	domains := map[string]bool{}
+	for _, account := range []string{record.From, record.To, record.FeeAccount} {
+		if account == "" || account == "0x0" {
+			continue
+		}
+		accounts[account] = true
+		if domain, err := s.extractDomainFromClientID(account); err == nil {
+			domains[domain] = true
+		}
+	}
+	for _, account := range sortedKeys(accounts) {
+		if err := putIndex(accountTxIndex, account, ts, record.TxID); err != nil {
+			return err
+		}
+		if err := putIndex(accountMonthTxIndex, account, month, ts, record.TxID); err != nil {
+			return err
+		}
+	}
+	for _, domain := range sortedKeys(domains) {
+		if err := putIndex(orgTxIndex, domain, ts, record.TxID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// forEachIndexedTransaction 按时间顺序遍历索引中位于 cursor 之后的交易位置
+// fn 返回 false 时停止遍历
+func (s *SmartContract) forEachIndexedTransaction(ctx contractapi.TransactionContextInterface, objectType string, attributes []string, cursor entryCursor, fn func(position entryCursor) (bool, error)) error {
+	stub := ctx.GetStub()
+	iterator, err := stub.GetPrivateDataByPartialCompositeKey(privateCollection(ctx), objectType, attributes)
+	if err != nil {
+		return fmt.Errorf("failed to scan index %s: %v", objectType, err)
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return fmt.Errorf("failed to get next index entry: %v", err)
+		}
+
+		_, parts, err := stub.SplitCompositeKey(kv.Key)
+		if err != nil {
+			return fmt.Errorf("failed to split index key: %v", err)
+		}
+		if len(parts) < 2 {
+			return fmt.Errorf("malformed index key for %s", objectType)
+		}
+		timestamp, err := strconv.ParseInt(parts[len(parts)-2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("malformed index timestamp: %v", err)
+		}
+		position := entryCursor{Timestamp: timestamp, TxID: parts[len(parts)-1]}
+
+		if !cursor.afterPosition(position.Timestamp, position.TxID) {
+			continue
+		}
+
+		more, err := fn(position)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// scanTransactionIndex 从索引读取一页满足 match 的交易记录
+// match 为空表示不筛选；扫描条目数超过 maxIndexScan 时提前返回游标
+func (s *SmartContract) scanTransactionIndex(ctx contractapi.TransactionContextInterface, objectType string, attributes []string, cursor entryCursor, pageSize int, match func(record *TransactionRecord) bool) (*transactionPage, error) {
+	page := &transactionPage{Records: []*TransactionRecord{}}
+	last := cursor
+	scanned := 0
+
+	err := s.forEachIndexedTransaction(ctx, objectType, attributes, cursor, func(position entryCursor) (bool, error) {
+		if len(page.Records) >= pageSize || scanned >= maxIndexScan {
+			page.HasMore = true
+			return false, nil
+		}
+		scanned++
+
+		record, err := s.getTransactionRecord(ctx, position.TxID)
+		if err != nil {
+			return false, err
+		}
+		last = position
+		if record == nil || (match != nil && !match(record)) {
+			return true, nil
+		}
+
+		page.Records = append(page.Records, record)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if page.HasMore {
+		page.NextCursor = last.encode()
+	}
+	return page, nil
+}
+
+// sortedKeys 返回集合中按字典序排列的键，保证写集顺序确定
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
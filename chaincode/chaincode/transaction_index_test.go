@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+type userTransactionsPage struct {
+	Pagination struct {
+		NextCursor string `json:"nextCursor"`
+		HasMore    bool   `json:"hasMore"`
+	} `json:"pagination"`
+	Transactions []TransactionRecord `json:"transactions"`
+}
+
+func queryUserTransactions(env *testEnv, caller testUser, userID string, minAmount int, counterparty string, pageSize int, cursor string) (*userTransactionsPage, error) {
+	var page userTransactionsPage
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		response, err := env.contract.QueryUserTransactions(ctx, userID, minAmount, 0, "", counterparty, pageSize, cursor)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &page)
+	})
+	return &page, err
+}
+
+// TestQueryUserTransactionsCursor 账户索引按时间顺序分页，游标续读不重复也不遗漏
+func TestQueryUserTransactionsCursor(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 0)
+	wantTxIDs := []string{}
+	for _, amount := range []int{10, 20, 30, 40, 50} {
+		stub := transfer(t, env, centralBankAdmin, bankAUser, amount)
+		wantTxIDs = append(wantTxIDs, stub.txID)
+	}
+
+	gotTxIDs := []string{}
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatalf("pagination did not terminate")
+		}
+		page, err := queryUserTransactions(env, bankAUser, bankAUser.id, 0, "", 2, cursor)
+		if err != nil {
+			t.Fatalf("QueryUserTransactions failed: %v", err)
+		}
+		if len(page.Transactions) > 2 {
+			t.Fatalf("page exceeds the page size: %d records", len(page.Transactions))
+		}
+		for _, record := range page.Transactions {
+			gotTxIDs = append(gotTxIDs, record.TxID)
+		}
+		if !page.Pagination.HasMore {
+			break
+		}
+		cursor = page.Pagination.NextCursor
+	}
+	if strings.Join(gotTxIDs, ",") != strings.Join(wantTxIDs, ",") {
+		t.Fatalf("paged transactions = %v, want %v", gotTxIDs, wantTxIDs)
+	}
+
+	// 筛选条件在索引扫描后逐条应用
+	page, err := queryUserTransactions(env, bankAUser, bankAUser.id, 25, centralBankAdmin.id, 0, "")
+	if err != nil {
+		t.Fatalf("filtered QueryUserTransactions failed: %v", err)
+	}
+	if len(page.Transactions) != 3 || page.Transactions[0].Amount != 30 {
+		t.Fatalf("filtered transactions = %+v, want the three largest transfers", page.Transactions)
+	}
+
+	// 央行可以查询任意账户，其他银行的客户不行
+	if _, err := queryUserTransactions(env, centralBankAdmin, bankAUser.id, 0, "", 0, ""); err != nil {
+		t.Fatalf("central bank query failed: %v", err)
+	}
+	outsider := newTestUser("User1", "b.example.com", "BMSP")
+	if _, err := queryUserTransactions(env, outsider, bankAUser.id, 0, "", 0, ""); err == nil || !strings.Contains(err.Error(), "does not have permission") {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+
+	if _, err := queryUserTransactions(env, bankAUser, bankAUser.id, 0, "", 0, "not-a-cursor!"); err == nil || !strings.Contains(err.Error(), "invalid cursor") {
+		t.Fatalf("expected an invalid cursor error, got %v", err)
+	}
+}
@@ -0,0 +1,386 @@
+/*
+交易记录存储
+
+- 每笔交易只写一条带版本号的 TransactionRecord（tx_<txID>），同时用于读取与 CouchDB 查询
+- 交易双方的 MSP 取自调用者身份，以及此前记录的组织域名到 MSP 的映射
+- 迁移步骤 transactionRecords 将旧格式的 tx_/query_ 记录对分批改写为当前版本，补建索引并计入统计聚合；
+  MigrateTransactionRecords 保留为该步骤的入口
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// transactionDocType 交易记录的 CouchDB 文档类型
+const transactionDocType = "transaction"
+
+// transactionRecordSchemaVersion 当前交易记录版本
+// 版本 1（无 schemaVersion 字段）：tx_ 完整数据 + query_ 查询副本
+// 版本 2：单一 tx_ 记录
+// 版本 3：单一 tx_ 记录 + 时间序组合键索引
+// 版本 4：计入统计聚合（按日期与类型的交易统计、活跃账户）
+const transactionRecordSchemaVersion = 4
+
+// indexedSchemaVersion 起始写入时间序索引的记录版本
+const indexedSchemaVersion = 3
+
+// aggregatedSchemaVersion 起始计入统计聚合的记录版本
+const aggregatedSchemaVersion = 4
+
+// legacyQueryPrefix 版本 1 查询副本的键前缀
+const legacyQueryPrefix = "query_"
+
+// orgMSPPrefix 组织域名到 MSP ID 映射的键前缀
+const orgMSPPrefix = "orgmsp_"
+
+// newTransactionRecord 为当前交易创建交易记录，填充交易ID、时间戳与双方 MSP
+func (s *SmartContract) newTransactionRecord(ctx contractapi.TransactionContextInterface, transactionType string, from string, to string, amount int) (*TransactionRecord, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	fromMSP, err := s.partyMSP(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	toMSP, err := s.partyMSP(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransactionRecord{
+		DocType:         transactionDocType,
+		SchemaVersion:   transactionRecordSchemaVersion,
+		TxID:            ctx.GetStub().GetTxID(),
+		From:            from,
+		To:              to,
+		FromMSP:         fromMSP,
+		ToMSP:           toMSP,
+		Amount:          amount,
+		TransactionType: transactionType,
+		Timestamp:       timestamp.Seconds,
+	}, nil
+}
+
+// putTransactionRecord 将交易记录写入央行私有集合，当前版本的记录同时写入时间序索引
+func (s *SmartContract) putTransactionRecord(ctx contractapi.TransactionContextInterface, record *TransactionRecord) error {
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transaction record: %v", err)
+	}
+
+	err = ctx.GetStub().PutPrivateData(privateCollection(ctx), transactionPrefix+record.TxID, recordBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store transaction record: %v", err)
+	}
+
+	// 旧版本记录的时间戳可能缺失，迁移后再建立索引
+	if record.SchemaVersion >= indexedSchemaVersion {
+		return s.indexTransaction(ctx, record)
+	}
+
+	return nil
+}
+
+// bookTransactionRecord 写入新记账的交易记录并更新统计聚合
+// 改写已有记录（如退汇更新原交易）应使用 putTransactionRecord，避免重复计入统计
+func (s *SmartContract) bookTransactionRecord(ctx contractapi.TransactionContextInterface, record *TransactionRecord) error {
+	if err := s.putTransactionRecord(ctx, record); err != nil {
+		return err
+	}
+
+	aggregates := newTransactionAggregates()
+	aggregates.add(record)
+	return s.flushTransactionAggregates(ctx, aggregates)
+}
+
+// getTransactionRecord 读取交易记录，不存在时返回 nil
+func (s *SmartContract) getTransactionRecord(ctx contractapi.TransactionContextInterface, txID string) (*TransactionRecord, error) {
+	recordBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), transactionPrefix+txID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transaction %s: %v", txID, err)
+	}
+	if recordBytes == nil {
+		return nil, nil
+	}
+
+	var record TransactionRecord
+	if err := json.Unmarshal(recordBytes, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transaction %s: %v", txID, err)
+	}
+
+	return &record, nil
+}
+
+// partyMSP 返回交易一方所属的 MSP ID
+// 调用者直接取自身份（并记录其组织域名映射），其他账户按组织域名查找已记录的映射
+func (s *SmartContract) partyMSP(ctx contractapi.TransactionContextInterface, account string) (string, error) {
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+	if account != callerID {
+		return s.knownAccountMSP(ctx, account)
+	}
+
+	callerMSP, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	knownMSP, err := s.knownAccountMSP(ctx, account)
+	if err != nil {
+		return "", err
+	}
+
+	// 只在映射缺失或变化时写入，避免每笔交易都修改同一个键
+	if knownMSP != callerMSP {
+		domain, err := s.extractDomainFromClientID(account)
+		if err == nil {
+			err = ctx.GetStub().PutPrivateData(privateCollection(ctx), orgMSPPrefix+domain, []byte(callerMSP))
+			if err != nil {
+				return "", fmt.Errorf("failed to store org msp for %s: %v", domain, err)
+			}
+		}
+	}
+
+	return callerMSP, nil
+}
+
+// MigrateTransactionRecords 央行管理员将旧格式交易记录分批改写为当前版本，等同于 RunMigration transactionRecords
+// bookmark 为上一批返回的书签；返回结果中 done 为 true 表示迁移完成
+func (s *SmartContract) MigrateTransactionRecords(ctx contractapi.TransactionContextInterface, batchSize int, bookmark string) (string, error) {
+	return s.RunMigration(ctx, migrationTransactionRecords, bookmark, batchSize)
+}
+
+// migrateTransactionBatch 迁移步骤 transactionRecords：将旧格式交易记录分批改写为当前版本
+// 第一阶段改写 tx_ 记录并合并、删除其 query_ 副本；第二阶段将没有 tx_ 记录的孤立 query_ 副本转为 tx_ 记录
+// bookmark 为上一批最后处理的键，其前缀决定所处阶段；为空时从头开始
+func (s *SmartContract) migrateTransactionBatch(ctx contractapi.TransactionContextInterface, bookmark string, batchSize int) (*migrationBatch, error) {
+	prefix := transactionPrefix
+	startKey := transactionPrefix
+	if bookmark != "" {
+		switch {
+		case strings.HasPrefix(bookmark, transactionPrefix):
+		case strings.HasPrefix(bookmark, legacyQueryPrefix):
+			prefix = legacyQueryPrefix
+		default:
+			return nil, fmt.Errorf("invalid bookmark %s", bookmark)
+		}
+		startKey = bookmark + "\x00"
+	}
+
+	aggregates := newTransactionAggregates()
+	scanned, migrated, lastKey, err := s.migrateTransactionRange(ctx, prefix, startKey, batchSize, aggregates)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.flushTransactionAggregates(ctx, aggregates); err != nil {
+		return nil, err
+	}
+
+	nextBookmark := lastKey
+	if scanned < batchSize {
+		nextBookmark = ""
+		if prefix == transactionPrefix {
+			// tx_ 阶段结束，下一批从 query_ 阶段开始
+			nextBookmark = legacyQueryPrefix
+		}
+	}
+
+	return &migrationBatch{
+		Scanned:  scanned,
+		Migrated: migrated,
+		Bookmark: nextBookmark,
+		Done:     nextBookmark == "",
+	}, nil
+}
+
+// migrateTransactionRange 从 startKey 开始扫描 prefix 下最多 batchSize 条记录并迁移
+// 尚未计入统计的记录累积到 aggregates，由调用方在批次结束时写入
+func (s *SmartContract) migrateTransactionRange(ctx contractapi.TransactionContextInterface, prefix string, startKey string, batchSize int, aggregates *transactionAggregates) (int, int, string, error) {
+	// prefix 以 "_" 结尾，"`" 是其后的第一个字符
+	endKey := prefix[:len(prefix)-1] + "`"
+
+	iterator, err := ctx.GetStub().GetPrivateDataByRange(privateCollection(ctx), startKey, endKey)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("failed to scan %s records: %v", prefix, err)
+	}
+	defer iterator.Close()
+
+	scanned, migrated := 0, 0
+	lastKey := ""
+	for scanned < batchSize && iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return 0, 0, "", fmt.Errorf("failed to get next %s record: %v", prefix, err)
+		}
+		scanned++
+		lastKey = kv.Key
+
+		var changed bool
+		if prefix == transactionPrefix {
+			changed, err = s.migrateTransactionRecord(ctx, kv.Key[len(transactionPrefix):], kv.Value, aggregates)
+		} else {
+			changed, err = s.migrateOrphanQueryRecord(ctx, kv.Key[len(legacyQueryPrefix):], kv.Value, aggregates)
+		}
+		if err != nil {
+			return 0, 0, "", err
+		}
+		if changed {
+			migrated++
+		}
+	}
+
+	return scanned, migrated, lastKey, nil
+}
+
+// migrateTransactionRecord 将一条旧格式 tx_ 记录与其 query_ 副本合并为当前版本
+func (s *SmartContract) migrateTransactionRecord(ctx contractapi.TransactionContextInterface, txID string, value []byte, aggregates *transactionAggregates) (bool, error) {
+	var record TransactionRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return false, fmt.Errorf("failed to unmarshal transaction %s: %v", txID, err)
+	}
+	if record.SchemaVersion >= transactionRecordSchemaVersion {
+		return false, nil
+	}
+
+	// 查询副本中有时间戳，以及部分写入函数只写在副本中的字段
+	queryBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), legacyQueryPrefix+txID)
+	if err != nil {
+		return false, fmt.Errorf("failed to read query record %s: %v", txID, err)
+	}
+	if queryBytes != nil {
+		var queryRecord TransactionRecord
+		if err := json.Unmarshal(queryBytes, &queryRecord); err != nil {
+			return false, fmt.Errorf("failed to unmarshal query record %s: %v", txID, err)
+		}
+		mergeLegacyTransactionFields(&record, &queryRecord)
+
+		err = ctx.GetStub().DelPrivateData(privateCollection(ctx), legacyQueryPrefix+txID)
+		if err != nil {
+			return false, fmt.Errorf("failed to delete query record %s: %v", txID, err)
+		}
+	}
+
+	if err := s.upgradeTransactionRecord(ctx, txID, &record, aggregates); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// migrateOrphanQueryRecord 将没有 tx_ 记录的 query_ 副本转为当前版本的 tx_ 记录
+func (s *SmartContract) migrateOrphanQueryRecord(ctx contractapi.TransactionContextInterface, txID string, value []byte, aggregates *transactionAggregates) (bool, error) {
+	existing, err := s.getTransactionRecord(ctx, txID)
+	if err != nil {
+		return false, err
+	}
+
+	var record TransactionRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return false, fmt.Errorf("failed to unmarshal query record %s: %v", txID, err)
+	}
+	if existing != nil {
+		// 第一阶段之后新写入的旧格式数据：合并到已有记录
+		mergeLegacyTransactionFields(existing, &record)
+		record = *existing
+	}
+
+	err = ctx.GetStub().DelPrivateData(privateCollection(ctx), legacyQueryPrefix+txID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete query record %s: %v", txID, err)
+	}
+
+	if err := s.upgradeTransactionRecord(ctx, txID, &record, aggregates); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// upgradeTransactionRecord 补全当前版本需要的字段并写回，版本 4 之前的记录计入统计增量
+func (s *SmartContract) upgradeTransactionRecord(ctx contractapi.TransactionContextInterface, txID string, record *TransactionRecord, aggregates *transactionAggregates) error {
+	counted := record.SchemaVersion >= aggregatedSchemaVersion
+	record.DocType = transactionDocType
+	record.SchemaVersion = transactionRecordSchemaVersion
+	if record.TxID == "" {
+		record.TxID = txID
+	}
+
+	// 旧记录的 MSP 多为空，按已记录的组织映射补全
+	if record.FromMSP == "" {
+		fromMSP, err := s.knownAccountMSP(ctx, record.From)
+		if err != nil {
+			return err
+		}
+		record.FromMSP = fromMSP
+	}
+	if record.ToMSP == "" {
+		toMSP, err := s.knownAccountMSP(ctx, record.To)
+		if err != nil {
+			return err
+		}
+		record.ToMSP = toMSP
+	}
+
+	if err := s.putTransactionRecord(ctx, record); err != nil {
+		return err
+	}
+	if !counted {
+		aggregates.add(record)
+	}
+	return nil
+}
+
+// knownAccountMSP 按组织域名查找已记录的 MSP ID，未知时返回空
+func (s *SmartContract) knownAccountMSP(ctx contractapi.TransactionContextInterface, account string) (string, error) {
+	if account == "" || account == "0x0" {
+		return "", nil
+	}
+	domain, err := s.extractDomainFromClientID(account)
+	if err != nil {
+		return "", nil
+	}
+
+	mspBytes, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), orgMSPPrefix+domain)
+	if err != nil {
+		return "", fmt.Errorf("failed to read org msp for %s: %v", domain, err)
+	}
+	return string(mspBytes), nil
+}
+
+// mergeLegacyTransactionFields 用查询副本补全记录中缺失的字段
+func mergeLegacyTransactionFields(record *TransactionRecord, queryRecord *TransactionRecord) {
+	if record.Timestamp == 0 {
+		record.Timestamp = queryRecord.Timestamp
+	}
+	if record.FromMSP == "" {
+		record.FromMSP = queryRecord.FromMSP
+	}
+	if record.ToMSP == "" {
+		record.ToMSP = queryRecord.ToMSP
+	}
+	if record.Spender == "" {
+		record.Spender = queryRecord.Spender
+	}
+	if record.EndToEndID == "" {
+		record.EndToEndID = queryRecord.EndToEndID
+	}
+	if record.UETR == "" {
+		record.UETR = queryRecord.UETR
+	}
+	if record.OriginalTxID == "" {
+		record.OriginalTxID = queryRecord.OriginalTxID
+	}
+	if record.ReturnReason == "" {
+		record.ReturnReason = queryRecord.ReturnReason
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func migrateTransactionRecords(env *testEnv, caller testUser, batchSize int, bookmark string) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	_, err := env.invoke(caller, nil, func(ctx contractapi.TransactionContextInterface) error {
+		response, err := env.contract.MigrateTransactionRecords(ctx, batchSize, bookmark)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(response), &result)
+	})
+	return result, err
+}
+
+// TestMigrateTransactionRecords 版本 1 的 tx_/query_ 记录对与孤立的 query_ 副本改写为当前版本
+func TestMigrateTransactionRecords(t *testing.T) {
+	env := newTestEnv(t)
+	seedAccount(t, env, 0)
+
+	private := env.ledger.private[defaultPrivateCollection]
+	private[transactionPrefix+"legacy1"] = []byte(`{"from":"` + centralBankAdmin.id + `","to":"` + bankAUser.id + `","amount":40,"transactionType":"transfer"}`)
+	private[legacyQueryPrefix+"legacy1"] = []byte(`{"txId":"legacy1","from":"` + centralBankAdmin.id + `","to":"` + bankAUser.id + `","amount":40,"transactionType":"transfer","endToEndId":"E2E-LEGACY-1","timestamp":1700000005}`)
+	private[legacyQueryPrefix+"legacy2"] = []byte(`{"txId":"legacy2","from":"` + bankAUser.id + `","to":"` + centralBankAdmin.id + `","amount":15,"transactionType":"transfer","timestamp":1700000006}`)
+	var state SchemaState
+	if err := json.Unmarshal(private[schemaStateKey], &state); err != nil {
+		t.Fatal(err)
+	}
+	delete(state.Steps, migrationTransactionRecords)
+	stateBytes, _ := json.Marshal(state)
+	private[schemaStateKey] = stateBytes
+
+	if _, err := migrateTransactionRecords(env, bankAAdmin, 10, ""); err == nil || !strings.Contains(err.Error(), "not authorized") {
+		t.Fatalf("expected an authorization error, got %v", err)
+	}
+	if _, err := migrateTransactionRecords(env, centralBankAdmin, 10, "bogus_1"); err == nil || !strings.Contains(err.Error(), "invalid bookmark") {
+		t.Fatalf("expected an invalid bookmark error, got %v", err)
+	}
+
+	bookmark := ""
+	for batches := 0; ; batches++ {
+		if batches > 10 {
+			t.Fatalf("transaction record migration did not complete")
+		}
+		result, err := migrateTransactionRecords(env, centralBankAdmin, 1, bookmark)
+		if err != nil {
+			t.Fatalf("MigrateTransactionRecords failed: %v", err)
+		}
+		if result["done"] == true {
+			break
+		}
+		bookmark = result["bookmark"].(string)
+	}
+
+	for txID, want := range map[string]struct {
+		amount     int
+		timestamp  int64
+		endToEndID string
+	}{
+		"legacy1": {amount: 40, timestamp: 1700000005, endToEndID: "E2E-LEGACY-1"},
+		"legacy2": {amount: 15, timestamp: 1700000006},
+	} {
+		if private[legacyQueryPrefix+txID] != nil {
+			t.Fatalf("query copy of %s was not removed", txID)
+		}
+		record := getTransactionRecord(t, env, txID)
+		if record.SchemaVersion != transactionRecordSchemaVersion || record.DocType != transactionDocType || record.TxID != txID {
+			t.Fatalf("record %s was not upgraded: %+v", txID, record)
+		}
+		if record.Amount != want.amount || record.Timestamp != want.timestamp || record.EndToEndID != want.endToEndID || record.ToMSP == "" || record.FromMSP == "" {
+			t.Fatalf("record %s lost fields: %+v", txID, record)
+		}
+		key, _ := env.ledger.newStub().CreateCompositeKey(accountTxIndex, []string{bankAUser.id, formatIndexTimestamp(want.timestamp), txID})
+		if private[key] == nil {
+			t.Fatalf("record %s was not indexed for the account", txID)
+		}
+	}
+}
@@ -0,0 +1,567 @@
+/*
+交易检索
+
+- SearchTransactions 接收 JSON 查询：筛选表达式（and/or 组合参与方、交易对手方、类型、金额、时间、MSP、附言）、排序与分页
+- 查询先解析校验为 CouchDB 选择器，再叠加调用者的可见范围，调用者无法通过筛选条件扩大可见范围
+- 排序组合与 META-INF 中打包的 CouchDB 索引一一对应，分页使用基于排序值的游标
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// 筛选表达式限制
+const maxSearchDepth = 4
+const maxSearchConditions = 20
+const maxSearchTextLength = 256
+
+// searchSortIndexes 支持的排序组合及对应的 CouchDB 索引（交易ID作为最后的排序键保证顺序稳定）
+var searchSortIndexes = map[string]string{
+	"timestamp":        "indexTransactionSortTimestamp",
+	"amount":           "indexTransactionSortAmount",
+	"amount,timestamp": "indexTransactionSortAmountTimestamp",
+}
+
+// searchSortFields 排序字段到记录字段的映射
+var searchSortFields = map[string]string{
+	"timestamp": "timestamp",
+	"amount":    "amount",
+}
+
+// transactionSearchRequest SearchTransactions 的查询参数
+type transactionSearchRequest struct {
+	Account  string          `json:"account"`  // 可选：只查询该账户参与的交易，交易对手方条件相对该账户
+	Filter   json.RawMessage `json:"filter"`   // 筛选表达式
+	Sort     []searchSortKey `json:"sort"`     // 排序，默认按时间升序
+	PageSize int             `json:"pageSize"` // 页面大小
+	Cursor   string          `json:"cursor"`   // 上一页返回的 nextCursor
+}
+
+// searchSortKey 排序键
+type searchSortKey struct {
+	Field string `json:"field"`
+	Order string `json:"order"` // asc 或 desc，所有排序键方向必须一致
+}
+
+// searchRange 金额或时间范围
+type searchRange struct {
+	Gt  *int64 `json:"gt"`
+	Gte *int64 `json:"gte"`
+	Lt  *int64 `json:"lt"`
+	Lte *int64 `json:"lte"`
+}
+
+// searchCursor 分页游标：上一页最后一条记录的排序值与交易ID
+type searchCursor struct {
+	Values []int64 `json:"v"`
+	TxID   string  `json:"tx"`
+	Query  string  `json:"q"` // 查询摘要，游标只能用于生成它的查询
+}
+
+// searchParser 将筛选表达式解析为 CouchDB 选择器
+type searchParser struct {
+	account    string
+	conditions int
+}
+
+// SearchTransactions 按筛选表达式检索交易记录
+//
+// 查询示例：
+//
+//	{
+//	  "account": "<clientID>",
+//	  "filter": {"and": [
+//	    {"or": [{"type": "transfer"}, {"type": "transferFrom"}]},
+//	    {"amount": {"gte": 100, "lt": 10000}},
+//	    {"timestamp": {"gte": 1700000000}},
+//	    {"counterparty": "<clientID>"},
+//	    {"memo": "invoice"}
+//	  ]},
+//	  "sort": [{"field": "amount", "order": "desc"}],
+//	  "pageSize": 20,
+//	  "cursor": ""
+//	}
+//
+// 条件：party（交易任一方）、counterparty（与 account 之间的交易）、type、amount、timestamp、msp（任一方所属 MSP）、memo（附言包含）
+// 央行可检索全部交易，银行admin只能检索本行参与的交易，普通用户只能检索自己的交易
+func (s *SmartContract) SearchTransactions(ctx contractapi.TransactionContextInterface, queryJSON string) (string, error) {
+	// 检查合约初始化
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	// 解析查询
+	var request transactionSearchRequest
+	decoder := json.NewDecoder(strings.NewReader(queryJSON))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		return "", fmt.Errorf("invalid search query: %v", err)
+	}
+	request.PageSize, err = normalizePageSize(ctx, request.PageSize)
+	if err != nil {
+		return "", err
+	}
+
+	// 获取当前调用者的信息
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller id: %v", err)
+	}
+	callerDomain, err := s.extractDomainFromClientID(callerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract caller domain: %v", err)
+	}
+
+	// 普通用户默认查询自己的账户
+	isCentralBank, err := isCentralBankDomain(ctx, callerDomain)
+	if err != nil {
+		return "", err
+	}
+	isAdmin := s.isAdminUserByDomain(ctx, callerID)
+	if request.Account == "" && !isCentralBank && !isAdmin {
+		request.Account = callerID
+	}
+	if request.Account != "" {
+		request.Account, err = s.resolveAccount(ctx, request.Account)
+		if err != nil {
+			return "", err
+		}
+		hasPermission, err := s.checkTransactionQueryPermission(ctx, callerID, request.Account)
+		if err != nil {
+			return "", fmt.Errorf("failed to check permission: %v", err)
+		}
+		if !hasPermission {
+			return "", fmt.Errorf("caller does not have permission to query transactions for user %s", request.Account)
+		}
+	}
+
+	// 解析筛选表达式
+	parser := &searchParser{account: request.Account}
+	filter, err := parser.parse(request.Filter)
+	if err != nil {
+		return "", fmt.Errorf("invalid search filter: %v", err)
+	}
+
+	// 解析排序
+	sortFields, descending, indexName, err := parseSearchSort(request.Sort)
+	if err != nil {
+		return "", fmt.Errorf("invalid search sort: %v", err)
+	}
+
+	// 解析之后再叠加可见范围
+	conditions := []interface{}{}
+	scope := "all"
+	if request.Account != "" {
+		scope = "account"
+		conditions = append(conditions, partyCondition(request.Account))
+	}
+	if !isCentralBank && request.Account == "" {
+		// 银行admin：只能检索本行参与的交易
+		mspID, err := ctx.GetClientIdentity().GetMSPID()
+		if err != nil {
+			return "", fmt.Errorf("failed to get MSP ID: %v", err)
+		}
+		scope = "organization"
+		conditions = append(conditions, mspCondition(mspID))
+	}
+	if filter != nil {
+		conditions = append(conditions, filter)
+	}
+
+	// 游标只能用于生成它的查询
+	digest, err := searchDigest(conditions, sortFields, descending)
+	if err != nil {
+		return "", err
+	}
+	if request.Cursor != "" {
+		cursor, err := decodeSearchCursor(request.Cursor, digest, len(sortFields))
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, keysetCondition(sortFields, descending, cursor))
+	}
+
+	selector := map[string]interface{}{"docType": transactionDocType}
+	if len(conditions) > 0 {
+		selector["$and"] = conditions
+	}
+
+	order := "asc"
+	if descending {
+		order = "desc"
+	}
+	sortSpec := []map[string]string{{"docType": order}}
+	for _, field := range sortFields {
+		sortSpec = append(sortSpec, map[string]string{field: order})
+	}
+	sortSpec = append(sortSpec, map[string]string{"txId": order})
+
+	query := map[string]interface{}{
+		"selector":  selector,
+		"sort":      sortSpec,
+		"limit":     request.PageSize + 1,
+		"use_index": []string{"_design/" + indexName + "Doc", indexName},
+	}
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal query selector: %v", err)
+	}
+
+	queryResults, err := ctx.GetStub().GetPrivateDataQueryResult(privateCollection(ctx), string(queryBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to query private data: %v", err)
+	}
+	defer queryResults.Close()
+
+	records := []*TransactionRecord{}
+	hasMore := false
+	for queryResults.HasNext() {
+		queryResult, err := queryResults.Next()
+		if err != nil {
+			return "", fmt.Errorf("failed to get next query result: %v", err)
+		}
+		if len(records) >= request.PageSize {
+			hasMore = true
+			break
+		}
+
+		var record TransactionRecord
+		if err := json.Unmarshal(queryResult.Value, &record); err != nil {
+			return "", fmt.Errorf("failed to unmarshal transaction %s: %v", queryResult.Key, err)
+		}
+		records = append(records, &record)
+	}
+
+	nextCursor := ""
+	if hasMore {
+		nextCursor, err = encodeSearchCursor(records[len(records)-1], sortFields, digest)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// 构建响应
+	response := map[string]interface{}{
+		"scope": scope,
+		"sort": map[string]interface{}{
+			"fields": sortFields,
+			"order":  order,
+		},
+		"pagination": map[string]interface{}{
+			"pageSize":   request.PageSize,
+			"cursor":     request.Cursor,
+			"nextCursor": nextCursor,
+			"hasMore":    hasMore,
+		},
+		"currentPageCount": len(records),
+		"transactions":     records,
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal response: %v", err)
+	}
+
+	return string(responseJSON), nil
+}
+
+// parse 解析筛选表达式，空表达式返回 nil
+func (p *searchParser) parse(raw json.RawMessage) (interface{}, error) {
+	if len(bytes.TrimSpace(raw)) == 0 || string(bytes.TrimSpace(raw)) == "null" {
+		return nil, nil
+	}
+	return p.parseExpression(raw, 1)
+}
+
+// parseExpression 解析 and/or 组合或单个条件
+func (p *searchParser) parseExpression(raw json.RawMessage, depth int) (interface{}, error) {
+	if depth > maxSearchDepth {
+		return nil, fmt.Errorf("filter nesting exceeds %d levels", maxSearchDepth)
+	}
+
+	var node map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &node); err != nil {
+		return nil, fmt.Errorf("filter expression must be an object: %v", err)
+	}
+	if len(node) != 1 {
+		return nil, fmt.Errorf("filter expression must have exactly one key, got %d", len(node))
+	}
+
+	for key, value := range node {
+		switch key {
+		case "and", "or":
+			var children []json.RawMessage
+			if err := json.Unmarshal(value, &children); err != nil {
+				return nil, fmt.Errorf("%s expects an array: %v", key, err)
+			}
+			if len(children) == 0 {
+				return nil, fmt.Errorf("%s expects at least one expression", key)
+			}
+			parsed := make([]interface{}, 0, len(children))
+			for _, child := range children {
+				expression, err := p.parseExpression(child, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				parsed = append(parsed, expression)
+			}
+			return map[string]interface{}{"$" + key: parsed}, nil
+		default:
+			p.conditions++
+			if p.conditions > maxSearchConditions {
+				return nil, fmt.Errorf("filter exceeds %d conditions", maxSearchConditions)
+			}
+			return p.parseCondition(key, value)
+		}
+	}
+	return nil, nil
+}
+
+// parseCondition 解析单个筛选条件
+func (p *searchParser) parseCondition(key string, value json.RawMessage) (interface{}, error) {
+	switch key {
+	case "party":
+		party, err := parseSearchText(key, value)
+		if err != nil {
+			return nil, err
+		}
+		return partyCondition(party), nil
+	case "counterparty":
+		counterparty, err := parseSearchText(key, value)
+		if err != nil {
+			return nil, err
+		}
+		if p.account == "" {
+			return nil, fmt.Errorf("counterparty requires account")
+		}
+		return map[string]interface{}{"$or": []interface{}{
+			map[string]interface{}{"from": p.account, "to": counterparty},
+			map[string]interface{}{"from": counterparty, "to": p.account},
+		}}, nil
+	case "type":
+		transactionType, err := parseSearchText(key, value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"transactionType": transactionType}, nil
+	case "amount", "timestamp":
+		condition, err := parseSearchRange(key, value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{key: condition}, nil
+	case "msp":
+		mspID, err := parseSearchText(key, value)
+		if err != nil {
+			return nil, err
+		}
+		return mspCondition(mspID), nil
+	case "memo":
+		memo, err := parseSearchText(key, value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"memo": map[string]interface{}{"$regex": regexp.QuoteMeta(memo)}}, nil
+	default:
+		return nil, fmt.Errorf("unknown filter condition %s", key)
+	}
+}
+
+// parseSearchText 解析非空字符串条件
+func parseSearchText(key string, value json.RawMessage) (string, error) {
+	var text string
+	if err := json.Unmarshal(value, &text); err != nil {
+		return "", fmt.Errorf("%s expects a string: %v", key, err)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("%s must not be empty", key)
+	}
+	if len(text) > maxSearchTextLength {
+		return "", fmt.Errorf("%s exceeds %d characters", key, maxSearchTextLength)
+	}
+	return text, nil
+}
+
+// parseSearchRange 解析金额或时间范围条件
+func parseSearchRange(key string, value json.RawMessage) (map[string]interface{}, error) {
+	var bounds searchRange
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&bounds); err != nil {
+		return nil, fmt.Errorf("%s expects a range object with gt/gte/lt/lte: %v", key, err)
+	}
+	if bounds.Gt != nil && bounds.Gte != nil {
+		return nil, fmt.Errorf("%s cannot have both gt and gte", key)
+	}
+	if bounds.Lt != nil && bounds.Lte != nil {
+		return nil, fmt.Errorf("%s cannot have both lt and lte", key)
+	}
+
+	condition := map[string]interface{}{}
+	for op, bound := range map[string]*int64{"$gt": bounds.Gt, "$gte": bounds.Gte, "$lt": bounds.Lt, "$lte": bounds.Lte} {
+		if bound == nil {
+			continue
+		}
+		if *bound < 0 {
+			return nil, fmt.Errorf("%s bounds must not be negative", key)
+		}
+		condition[op] = *bound
+	}
+	if len(condition) == 0 {
+		return nil, fmt.Errorf("%s range must have at least one bound", key)
+	}
+
+	lower, upper := bounds.Gte, bounds.Lte
+	if bounds.Gt != nil {
+		lower = bounds.Gt
+	}
+	if bounds.Lt != nil {
+		upper = bounds.Lt
+	}
+	if lower != nil && upper != nil && *lower > *upper {
+		return nil, fmt.Errorf("%s range is empty", key)
+	}
+	return condition, nil
+}
+
+// parseSearchSort 校验排序键，返回排序字段、方向与对应的索引名
+func parseSearchSort(keys []searchSortKey) ([]string, bool, string, error) {
+	if len(keys) == 0 {
+		keys = []searchSortKey{{Field: "timestamp", Order: "asc"}}
+	}
+
+	fields := make([]string, 0, len(keys))
+	descending := false
+	for i, key := range keys {
+		field, ok := searchSortFields[key.Field]
+		if !ok {
+			return nil, false, "", fmt.Errorf("unsupported sort field %s", key.Field)
+		}
+		order := strings.ToLower(key.Order)
+		if order == "" {
+			order = "asc"
+		}
+		if order != "asc" && order != "desc" {
+			return nil, false, "", fmt.Errorf("sort order must be asc or desc, got %s", key.Order)
+		}
+		if i > 0 && (order == "desc") != descending {
+			return nil, false, "", fmt.Errorf("all sort keys must use the same order")
+		}
+		descending = order == "desc"
+		fields = append(fields, field)
+	}
+
+	indexName, ok := searchSortIndexes[strings.Join(fields, ",")]
+	if !ok {
+		return nil, false, "", fmt.Errorf("unsupported sort combination %s", strings.Join(fields, ","))
+	}
+	return fields, descending, indexName, nil
+}
+
+// partyCondition 交易任一方为 account
+func partyCondition(account string) map[string]interface{} {
+	return map[string]interface{}{"$or": []interface{}{
+		map[string]interface{}{"from": account},
+		map[string]interface{}{"to": account},
+	}}
+}
+
+// mspCondition 交易任一方属于 mspID
+func mspCondition(mspID string) map[string]interface{} {
+	return map[string]interface{}{"$or": []interface{}{
+		map[string]interface{}{"fromMsp": mspID},
+		map[string]interface{}{"toMsp": mspID},
+	}}
+}
+
+// keysetCondition 构建位于游标之后的条件：(f1, ..., fn, txId) 按字典序大于（或小于）游标值
+func keysetCondition(fields []string, descending bool, cursor searchCursor) map[string]interface{} {
+	op := "$gt"
+	if descending {
+		op = "$lt"
+	}
+
+	branches := []interface{}{}
+	for i := 0; i <= len(fields); i++ {
+		branch := map[string]interface{}{}
+		for j := 0; j < i; j++ {
+			branch[fields[j]] = cursor.Values[j]
+		}
+		if i < len(fields) {
+			branch[fields[i]] = map[string]interface{}{op: cursor.Values[i]}
+		} else {
+			branch["txId"] = map[string]interface{}{op: cursor.TxID}
+		}
+		branches = append(branches, branch)
+	}
+	return map[string]interface{}{"$or": branches}
+}
+
+// searchDigest 计算查询摘要，用于校验游标
+func searchDigest(conditions []interface{}, fields []string, descending bool) (string, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"conditions": conditions,
+		"fields":     fields,
+		"descending": descending,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal search digest: %v", err)
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// encodeSearchCursor 将记录的排序值编码为游标
+func encodeSearchCursor(record *TransactionRecord, fields []string, digest string) (string, error) {
+	cursor := searchCursor{TxID: record.TxID, Query: digest}
+	for _, field := range fields {
+		switch field {
+		case "timestamp":
+			cursor.Values = append(cursor.Values, record.Timestamp)
+		case "amount":
+			cursor.Values = append(cursor.Values, int64(record.Amount))
+		}
+	}
+	cursorJSON, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal cursor: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(cursorJSON), nil
+}
+
+// decodeSearchCursor 解析游标并校验其属于当前查询
+func decodeSearchCursor(encoded string, digest string, fieldCount int) (searchCursor, error) {
+	var cursor searchCursor
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, fmt.Errorf("invalid cursor: %v", err)
+	}
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return cursor, fmt.Errorf("invalid cursor: %v", err)
+	}
+	if cursor.Query != digest {
+		return cursor, fmt.Errorf("cursor does not belong to this query")
+	}
+	if len(cursor.Values) != fieldCount || cursor.TxID == "" {
+		return cursor, fmt.Errorf("invalid cursor format")
+	}
+	return cursor, nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSearchFilterParser(t *testing.T) {
+	account := bankAUser.id
+	nested := `{"party":"x"}`
+	for i := 0; i < maxSearchDepth; i++ {
+		nested = `{"and":[` + nested + `]}`
+	}
+	many := make([]string, maxSearchConditions+1)
+	for i := range many {
+		many[i] = `{"type":"transfer"}`
+	}
+
+	tests := []struct {
+		name    string
+		account string
+		filter  string
+		want    string
+		wantErr string
+	}{
+		{name: "empty", filter: "", want: "null"},
+		{name: "null", filter: "null", want: "null"},
+		{name: "party", filter: `{"party":" alice "}`, want: `{"$or":[{"from":"alice"},{"to":"alice"}]}`},
+		{
+			name:    "counterparty",
+			account: account,
+			filter:  `{"counterparty":"bob"}`,
+			want:    fmt.Sprintf(`{"$or":[{"from":%q,"to":"bob"},{"from":"bob","to":%q}]}`, account, account),
+		},
+		{name: "type", filter: `{"type":"transferFrom"}`, want: `{"transactionType":"transferFrom"}`},
+		{name: "msp", filter: `{"msp":"AMSP"}`, want: `{"$or":[{"fromMsp":"AMSP"},{"toMsp":"AMSP"}]}`},
+		{name: "memo is matched literally", filter: `{"memo":"inv.42*"}`, want: `{"memo":{"$regex":"inv\\.42\\*"}}`},
+		{name: "amount range", filter: `{"amount":{"gte":100,"lt":10000}}`, want: `{"amount":{"$gte":100,"$lt":10000}}`},
+		{name: "timestamp lower bound", filter: `{"timestamp":{"gt":1700000000}}`, want: `{"timestamp":{"$gt":1700000000}}`},
+		{name: "single point range", filter: `{"amount":{"gte":5,"lte":5}}`, want: `{"amount":{"$gte":5,"$lte":5}}`},
+		{
+			name:   "combined",
+			filter: `{"and":[{"or":[{"type":"transfer"},{"type":"transferFrom"}]},{"amount":{"lte":50}}]}`,
+			want:   `{"$and":[{"$or":[{"transactionType":"transfer"},{"transactionType":"transferFrom"}]},{"amount":{"$lte":50}}]}`,
+		},
+		{name: "unknown field", filter: `{"balance":{"gt":1}}`, wantErr: "unknown filter condition balance"},
+		{name: "selector operator", filter: `{"$or":[{"type":"transfer"}]}`, wantErr: "unknown filter condition $or"},
+		{name: "two keys", filter: `{"type":"transfer","msp":"AMSP"}`, wantErr: "exactly one key, got 2"},
+		{name: "not an object", filter: `["type"]`, wantErr: "must be an object"},
+		{name: "and without array", filter: `{"and":{"type":"transfer"}}`, wantErr: "and expects an array"},
+		{name: "empty or", filter: `{"or":[]}`, wantErr: "or expects at least one expression"},
+		{name: "too deep", filter: nested, wantErr: fmt.Sprintf("nesting exceeds %d levels", maxSearchDepth)},
+		{name: "too many conditions", filter: `{"or":[` + strings.Join(many, ",") + `]}`, wantErr: fmt.Sprintf("exceeds %d conditions", maxSearchConditions)},
+		{name: "counterparty without account", filter: `{"counterparty":"bob"}`, wantErr: "counterparty requires account"},
+		{name: "empty text", filter: `{"type":"  "}`, wantErr: "type must not be empty"},
+		{name: "text not a string", filter: `{"party":42}`, wantErr: "party expects a string"},
+		{name: "text too long", filter: fmt.Sprintf(`{"memo":%q}`, strings.Repeat("m", maxSearchTextLength+1)), wantErr: "memo exceeds"},
+		{name: "unknown range operator", filter: `{"amount":{"eq":5}}`, wantErr: "expects a range object"},
+		{name: "gt and gte", filter: `{"amount":{"gt":1,"gte":2}}`, wantErr: "both gt and gte"},
+		{name: "lt and lte", filter: `{"timestamp":{"lt":1,"lte":2}}`, wantErr: "both lt and lte"},
+		{name: "negative bound", filter: `{"amount":{"gte":-1}}`, wantErr: "must not be negative"},
+		{name: "no bound", filter: `{"amount":{}}`, wantErr: "at least one bound"},
+		{name: "inverted range", filter: `{"amount":{"gt":10,"lt":5}}`, wantErr: "amount range is empty"},
+		{name: "fractional bound", filter: `{"amount":{"gte":1.5}}`, wantErr: "expects a range object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := &searchParser{account: tt.account}
+			selector, err := parser.parse(json.RawMessage(tt.filter))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := json.Marshal(selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("selector = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSearchSort(t *testing.T) {
+	tests := []struct {
+		name           string
+		keys           []searchSortKey
+		wantFields     string
+		wantDescending bool
+		wantIndex      string
+		wantErr        string
+	}{
+		{name: "default", wantFields: "timestamp", wantIndex: "indexTransactionSortTimestamp"},
+		{name: "amount desc", keys: []searchSortKey{{Field: "amount", Order: "DESC"}}, wantFields: "amount", wantDescending: true, wantIndex: "indexTransactionSortAmount"},
+		{
+			name:       "amount then timestamp",
+			keys:       []searchSortKey{{Field: "amount"}, {Field: "timestamp", Order: "asc"}},
+			wantFields: "amount,timestamp",
+			wantIndex:  "indexTransactionSortAmountTimestamp",
+		},
+		{name: "unknown field", keys: []searchSortKey{{Field: "memo"}}, wantErr: "unsupported sort field memo"},
+		{name: "unknown order", keys: []searchSortKey{{Field: "amount", Order: "up"}}, wantErr: "sort order must be asc or desc"},
+		{name: "mixed orders", keys: []searchSortKey{{Field: "amount", Order: "desc"}, {Field: "timestamp", Order: "asc"}}, wantErr: "same order"},
+		{name: "no index", keys: []searchSortKey{{Field: "timestamp"}, {Field: "amount"}}, wantErr: "unsupported sort combination timestamp,amount"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, descending, index, err := parseSearchSort(tt.keys)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(fields, ",") != tt.wantFields || descending != tt.wantDescending || index != tt.wantIndex {
+				t.Fatalf("got %v %v %s, want %s %v %s", fields, descending, index, tt.wantFields, tt.wantDescending, tt.wantIndex)
+			}
+		})
+	}
+}
+
+func TestSearchCursor(t *testing.T) {
+	fields := []string{"amount", "timestamp"}
+	conditions := []interface{}{partyCondition(bankAUser.id)}
+	digest, err := searchDigest(conditions, fields, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor, err := encodeSearchCursor(&TransactionRecord{TxID: "tx42", Amount: 300, Timestamp: 1700000000}, fields, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 筛选、排序方向或排序字段不同的查询不能使用该游标
+	otherFilter, _ := searchDigest([]interface{}{partyCondition(bankAAdmin.id)}, fields, true)
+	otherOrder, _ := searchDigest(conditions, fields, false)
+	otherFields, _ := searchDigest(conditions, []string{"amount"}, true)
+
+	tests := []struct {
+		name       string
+		cursor     string
+		digest     string
+		fieldCount int
+		wantErr    string
+	}{
+		{name: "same query", cursor: cursor, digest: digest, fieldCount: 2},
+		{name: "other filter", cursor: cursor, digest: otherFilter, fieldCount: 2, wantErr: "does not belong to this query"},
+		{name: "other order", cursor: cursor, digest: otherOrder, fieldCount: 2, wantErr: "does not belong to this query"},
+		{name: "other fields", cursor: cursor, digest: otherFields, fieldCount: 1, wantErr: "does not belong to this query"},
+		{name: "not base64", cursor: "!!!", digest: digest, fieldCount: 2, wantErr: "invalid cursor"},
+		{name: "not json", cursor: "bm90IGpzb24", digest: digest, fieldCount: 2, wantErr: "invalid cursor"},
+		{name: "missing values", cursor: encodeCursorJSON(t, searchCursor{TxID: "tx42", Query: digest}), digest: digest, fieldCount: 2, wantErr: "invalid cursor format"},
+		{name: "missing tx id", cursor: encodeCursorJSON(t, searchCursor{Values: []int64{1, 2}, Query: digest}), digest: digest, fieldCount: 2, wantErr: "invalid cursor format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := decodeSearchCursor(tt.cursor, tt.digest, tt.fieldCount)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if decoded.TxID != "tx42" || len(decoded.Values) != 2 || decoded.Values[0] != 300 || decoded.Values[1] != 1700000000 {
+				t.Fatalf("unexpected cursor: %+v", decoded)
+			}
+
+			// 降序时下一页为 (amount, timestamp, txId) 字典序小于游标的记录
+			got, _ := json.Marshal(keysetCondition(fields, true, decoded))
+			want := `{"$or":[{"amount":{"$lt":300}},{"amount":300,"timestamp":{"$lt":1700000000}},{"amount":300,"timestamp":1700000000,"txId":{"$lt":"tx42"}}]}`
+			if string(got) != want {
+				t.Fatalf("keyset condition = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+// encodeCursorJSON 按游标格式直接编码，用于构造不完整的游标
+func encodeCursorJSON(t *testing.T, cursor searchCursor) string {
+	t.Helper()
+	cursorJSON, err := json.Marshal(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(cursorJSON)
+}
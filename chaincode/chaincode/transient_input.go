@@ -0,0 +1,513 @@
+/*
+Transient 参数输入
+
+- 每个改变状态的函数都有 <函数名>Transient 版本，业务参数以 JSON 对象放在 transient 字段 request 中
+- 公开参数只有调用方生成的不透明请求ID，收款方、金额等不会写入区块
+- 链码事件同样写入区块：经由 transient 版本的交易即使开启了 plaintextEventDetails 也不发出明文明细，
+  未配置事件盐值时账户与明细均不发出（withheld），配置后只发出加盐哈希与按机构加密的明细
+- 解析后直接调用原函数，校验规则与明文版本完全一致
+- 返回值同样写入区块，因此带返回值的函数只返回请求回执，明细通过查询函数获取
+- 请求ID按提交者去重，记录保存在央行私有集合，可用 GetTransientRequest 对账
+- Initialize、InitializeWithMetadata、RegisterEventKey、RunMigration、MigrateTransactionRecords 的参数本身公开或不涉及交易内容，不提供 transient 版本
+- UpdateConfig 修改的配置文档保存在公共状态中，参数藏在 transient 字段里也会随写集公开，不提供 transient 版本
+- ConfidentialWithdraw 的公开参数只有承诺与范围证明，金额已在 transient 字段 confidential 中；
+  ConfidentialDeposit、ConfigureEventPrivacy、RebuildBankAggregates 没有公开参数，均不提供 transient 版本
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// transientRequestField 存放业务参数的 transient 字段
+const transientRequestField = "request"
+
+// transientRequestPrefix 请求记录的键前缀：txreq_<sha256(提交者|请求ID)>
+const transientRequestPrefix = "txreq_"
+
+// requestIDPattern 请求ID只允许不透明的短标识（如 UUID）
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// TransientRequest 已处理的 transient 请求
+type TransientRequest struct {
+	RequestID string `json:"requestId"`
+	Function  string `json:"function"`
+	TxID      string `json:"txId"`
+	Submitter string `json:"submitter"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// transientReceipt 带返回值的 transient 函数返回的回执
+type transientReceipt struct {
+	RequestID string `json:"requestId"`
+	TxID      string `json:"txId"`
+}
+
+// createTransientRequestKey 请求ID按提交者隔离，键中不出现请求ID原文
+func createTransientRequestKey(submitter string, requestID string) string {
+	digest := sha256.Sum256([]byte(submitter + "|" + requestID))
+	return transientRequestPrefix + hex.EncodeToString(digest[:])
+}
+
+// readTransientRequest 校验请求ID、登记请求并把 transient 字段 request 解析到 args
+// 未知字段视为错误，缺失字段取零值后由原函数校验
+func readTransientRequest(ctx contractapi.TransactionContextInterface, function string, requestID string, args interface{}) (*TransientRequest, error) {
+	if !requestIDPattern.MatchString(requestID) {
+		return nil, fmt.Errorf("request id must be 1-64 characters of letters, digits, '.', '_', ':' or '-'")
+	}
+
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transient data: %v", err)
+	}
+	payload, ok := transient[transientRequestField]
+	if !ok || len(payload) == 0 {
+		return nil, fmt.Errorf("transient field %s is required", transientRequestField)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(args); err != nil {
+		return nil, fmt.Errorf("invalid %s request: %v", function, err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("invalid %s request: unexpected data after JSON object", function)
+	}
+
+	submitter, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+	requestKey := createTransientRequestKey(submitter, requestID)
+	existingJSON, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), requestKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request %s: %v", requestID, err)
+	}
+	if existingJSON != nil {
+		var existing TransientRequest
+		if err := json.Unmarshal(existingJSON, &existing); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal request %s: %v", requestID, err)
+		}
+		return nil, fmt.Errorf("request %s has already been processed in transaction %s", requestID, existing.TxID)
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	request := &TransientRequest{
+		RequestID: requestID,
+		Function:  function,
+		TxID:      ctx.GetStub().GetTxID(),
+		Submitter: submitter,
+		Timestamp: timestamp.Seconds,
+	}
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request %s: %v", requestID, err)
+	}
+	if err := ctx.GetStub().PutPrivateData(privateCollection(ctx), requestKey, requestJSON); err != nil {
+		return nil, fmt.Errorf("failed to record request %s: %v", requestID, err)
+	}
+	return request, nil
+}
+
+// isTransientRequest 本交易是否经由 transient 版本提交（带有 transient 字段 request）
+func isTransientRequest(ctx contractapi.TransactionContextInterface) (bool, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return false, fmt.Errorf("failed to get transient data: %v", err)
+	}
+	return len(transient[transientRequestField]) > 0, nil
+}
+
+// receipt 原函数的返回值不写入区块，只返回请求ID与交易ID
+func (r *TransientRequest) receipt() (string, error) {
+	receiptJSON, err := json.Marshal(transientReceipt{RequestID: r.RequestID, TxID: r.TxID})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal receipt: %v", err)
+	}
+	return string(receiptJSON), nil
+}
+
+// GetTransientRequest 查询调用者提交过的 transient 请求
+func (s *SmartContract) GetTransientRequest(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	if !requestIDPattern.MatchString(requestID) {
+		return "", fmt.Errorf("invalid request id")
+	}
+	submitter, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+	requestJSON, err := ctx.GetStub().GetPrivateData(privateCollection(ctx), createTransientRequestKey(submitter, requestID))
+	if err != nil {
+		return "", fmt.Errorf("failed to read request %s: %v", requestID, err)
+	}
+	if requestJSON == nil {
+		return "", fmt.Errorf("request %s not found", requestID)
+	}
+	return string(requestJSON), nil
+}
+
+// MintTransient Mint 的 transient 版本：{"amount":1000}
+func (s *SmartContract) MintTransient(ctx contractapi.TransactionContextInterface, requestID string) error {
+	var args struct {
+		Amount int `json:"amount"`
+	}
+	if _, err := readTransientRequest(ctx, "Mint", requestID, &args); err != nil {
+		return err
+	}
+	return s.Mint(ctx, args.Amount)
+}
+
+// BurnTransient Burn 的 transient 版本：{"amount":1000}
+func (s *SmartContract) BurnTransient(ctx contractapi.TransactionContextInterface, requestID string) error {
+	var args struct {
+		Amount int `json:"amount"`
+	}
+	if _, err := readTransientRequest(ctx, "Burn", requestID, &args); err != nil {
+		return err
+	}
+	return s.Burn(ctx, args.Amount)
+}
+
+// TransferTransient Transfer 的 transient 版本：{"recipient":"<clientID或别名>","amount":100}
+func (s *SmartContract) TransferTransient(ctx contractapi.TransactionContextInterface, requestID string) error {
+	var args struct {
+		Recipient string `json:"recipient"`
+		Amount    int    `json:"amount"`
+	}
+	if _, err := readTransientRequest(ctx, "Transfer", requestID, &args); err != nil {
+		return err
+	}
+	return s.Transfer(ctx, args.Recipient, args.Amount)
+}
+
+// TransferFromTransient TransferFrom 的 transient 版本：{"from":"...","to":"...","value":100}
+func (s *SmartContract) TransferFromTransient(ctx contractapi.TransactionContextInterface, requestID string) error {
+	var args struct {
+		From  string `json:"from"`
+		To    string `json:"to"`
+		Value int    `json:"value"`
+	}
+	if _, err := readTransientRequest(ctx, "TransferFrom", requestID, &args); err != nil {
+		return err
+	}
+	return s.TransferFrom(ctx, args.From, args.To, args.Value)
+}
+
+// ApproveTransient Approve 的 transient 版本：{"spender":"...","value":100}
+func (s *SmartContract) ApproveTransient(ctx contractapi.TransactionContextInterface, requestID string) error {
+	var args struct {
+		Spender string `json:"spender"`
+		Value   int    `json:"value"`
+	}
+	if _, err := readTransientRequest(ctx, "Approve", requestID, &args); err != nil {
+		return err
+	}
+	return s.Approve(ctx, args.Spender, args.Value)
+}
+
+// ApproveWithExpiryTransient ApproveWithExpiry 的 transient 版本：{"spender":"...","value":100,"expiresAt":1735689600}
+func (s *SmartContract) ApproveWithExpiryTransient(ctx contractapi.TransactionContextInterface, requestID string) error {
+	var args struct {
+		Spender   string `json:"spender"`
+		Value     int    `json:"value"`
+		ExpiresAt int64  `json:"expiresAt"`
+	}
+	if _, err := readTransientRequest(ctx, "ApproveWithExpiry", requestID, &args); err != nil {
+		return err
+	}
+	return s.ApproveWithExpiry(ctx, args.Spender, args.Value, args.ExpiresAt)
+}
+
+// IncreaseAllowanceTransient IncreaseAllowance 的 transient 版本：{"spender":"...","addedValue":100}
+func (s *SmartContract) IncreaseAllowanceTransient(ctx contractapi.TransactionContextInterface, requestID string) error {
+	var args struct {
+		Spender    string `json:"spender"`
+		AddedValue int    `json:"addedValue"`
+	}
+	if _, err := readTransientRequest(ctx, "IncreaseAllowance", requestID, &args); err != nil {
+		return err
+	}
+	return s.IncreaseAllowance(ctx, args.Spender, args.AddedValue)
+}
+
+// DecreaseAllowanceTransient DecreaseAllowance 的 transient 版本：{"spender":"...","subtractedValue":100}
+func (s *SmartContract) DecreaseAllowanceTransient(ctx contractapi.TransactionContextInterface, requestID string) error {
+	var args struct {
+		Spender         string `json:"spender"`
+		SubtractedValue int    `json:"subtractedValue"`
+	}
+	if _, err := readTransientRequest(ctx, "DecreaseAllowance", requestID, &args); err != nil {
+		return err
+	}
+	return s.DecreaseAllowance(ctx, args.Spender, args.SubtractedValue)
+}
+
+// RevokeAllowanceTransient RevokeAllowance 的 transient 版本：{"spender":"..."}
+func (s *SmartContract) RevokeAllowanceTransient(ctx contractapi.TransactionContextInterface, requestID string) error {
+	var args struct {
+		Spender string `json:"spender"`
+	}
+	if _, err := readTransientRequest(ctx, "RevokeAllowance", requestID, &args); err != nil {
+		return err
+	}
+	return s.RevokeAllowance(ctx, args.Spender)
+}
+
+// OpenAccountTransient OpenAccount 的 transient 版本：{"account":"...","accountType":"individual"}
+func (s *SmartContract) OpenAccountTransient(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	var args struct {
+		Account     string `json:"account"`
+		AccountType string `json:"accountType"`
+	}
+	request, err := readTransientRequest(ctx, "OpenAccount", requestID, &args)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.OpenAccount(ctx, args.Account, args.AccountType); err != nil {
+		return "", err
+	}
+	return request.receipt()
+}
+
+// CloseAccountTransient CloseAccount 的 transient 版本：{"account":"...","sweepTo":"..."}
+func (s *SmartContract) CloseAccountTransient(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	var args struct {
+		Account string `json:"account"`
+		SweepTo string `json:"sweepTo"`
+	}
+	request, err := readTransientRequest(ctx, "CloseAccount", requestID, &args)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.CloseAccount(ctx, args.Account, args.SweepTo); err != nil {
+		return "", err
+	}
+	return request.receipt()
+}
+
+// RegisterAliasTransient RegisterAlias 的 transient 版本：{"alias":"@alice","visibility":"bank"}
+func (s *SmartContract) RegisterAliasTransient(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	var args struct {
+		Alias      string `json:"alias"`
+		Visibility string `json:"visibility"`
+	}
+	request, err := readTransientRequest(ctx, "RegisterAlias", requestID, &args)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.RegisterAlias(ctx, args.Alias, args.Visibility); err != nil {
+		return "", err
+	}
+	return request.receipt()
+}
+
+// VerifyAliasTransient VerifyAlias 的 transient 版本：{"alias":"@alice"}
+func (s *SmartContract) VerifyAliasTransient(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	var args struct {
+		Alias string `json:"alias"`
+	}
+	request, err := readTransientRequest(ctx, "VerifyAlias", requestID, &args)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.VerifyAlias(ctx, args.Alias); err != nil {
+		return "", err
+	}
+	return request.receipt()
+}
+
+// UpdateAliasTransient UpdateAlias 的 transient 版本：{"alias":"@alice","newAlias":"@alice2","visibility":"bank"}
+func (s *SmartContract) UpdateAliasTransient(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	var args struct {
+		Alias      string `json:"alias"`
+		NewAlias   string `json:"newAlias"`
+		Visibility string `json:"visibility"`
+	}
+	request, err := readTransientRequest(ctx, "UpdateAlias", requestID, &args)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.UpdateAlias(ctx, args.Alias, args.NewAlias, args.Visibility); err != nil {
+		return "", err
+	}
+	return request.receipt()
+}
+
+// DeleteAliasTransient DeleteAlias 的 transient 版本：{"alias":"@alice"}
+func (s *SmartContract) DeleteAliasTransient(ctx contractapi.TransactionContextInterface, requestID string) error {
+	var args struct {
+		Alias string `json:"alias"`
+	}
+	if _, err := readTransientRequest(ctx, "DeleteAlias", requestID, &args); err != nil {
+		return err
+	}
+	return s.DeleteAlias(ctx, args.Alias)
+}
+
+// SetFeeScheduleTransient SetFeeSchedule 的 transient 版本：{"schedule":{...}}
+func (s *SmartContract) SetFeeScheduleTransient(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	var args struct {
+		Schedule json.RawMessage `json:"schedule"`
+	}
+	request, err := readTransientRequest(ctx, "SetFeeSchedule", requestID, &args)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.SetFeeSchedule(ctx, string(args.Schedule)); err != nil {
+		return "", err
+	}
+	return request.receipt()
+}
+
+// SetMerchantCategoryTransient SetMerchantCategory 的 transient 版本：{"account":"...","category":"5411"}
+func (s *SmartContract) SetMerchantCategoryTransient(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	var args struct {
+		Account  string `json:"account"`
+		Category string `json:"category"`
+	}
+	request, err := readTransientRequest(ctx, "SetMerchantCategory", requestID, &args)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.SetMerchantCategory(ctx, args.Account, args.Category); err != nil {
+		return "", err
+	}
+	return request.receipt()
+}
+
+// CreateDirectDebitMandateTransient CreateDirectDebitMandate 的 transient 版本：{"mandate":{...}}，授权ID即回执中的交易ID
+func (s *SmartContract) CreateDirectDebitMandateTransient(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	var args struct {
+		Mandate json.RawMessage `json:"mandate"`
+	}
+	request, err := readTransientRequest(ctx, "CreateDirectDebitMandate", requestID, &args)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.CreateDirectDebitMandate(ctx, string(args.Mandate)); err != nil {
+		return "", err
+	}
+	return request.receipt()
+}
+
+// CollectDirectDebitTransient CollectDirectDebit 的 transient 版本：{"mandateId":"...","amount":100,"reference":"..."}
+func (s *SmartContract) CollectDirectDebitTransient(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	var args struct {
+		MandateID string `json:"mandateId"`
+		Amount    int    `json:"amount"`
+		Reference string `json:"reference"`
+	}
+	request, err := readTransientRequest(ctx, "CollectDirectDebit", requestID, &args)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.CollectDirectDebit(ctx, args.MandateID, args.Amount, args.Reference); err != nil {
+		return "", err
+	}
+	return request.receipt()
+}
+
+// mandateStatusTransient 授权状态变更类函数的 transient 版本：{"mandateId":"..."}
+func (s *SmartContract) mandateStatusTransient(ctx contractapi.TransactionContextInterface, function string, requestID string, change func(contractapi.TransactionContextInterface, string) (string, error)) (string, error) {
+	var args struct {
+		MandateID string `json:"mandateId"`
+	}
+	request, err := readTransientRequest(ctx, function, requestID, &args)
+	if err != nil {
+		return "", err
+	}
+	if _, err := change(ctx, args.MandateID); err != nil {
+		return "", err
+	}
+	return request.receipt()
+}
+
+// SuspendDirectDebitMandateTransient SuspendDirectDebitMandate 的 transient 版本
+func (s *SmartContract) SuspendDirectDebitMandateTransient(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	return s.mandateStatusTransient(ctx, "SuspendDirectDebitMandate", requestID, s.SuspendDirectDebitMandate)
+}
+
+// ResumeDirectDebitMandateTransient ResumeDirectDebitMandate 的 transient 版本
+func (s *SmartContract) ResumeDirectDebitMandateTransient(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	return s.mandateStatusTransient(ctx, "ResumeDirectDebitMandate", requestID, s.ResumeDirectDebitMandate)
+}
+
+// CancelDirectDebitMandateTransient CancelDirectDebitMandate 的 transient 版本
+func (s *SmartContract) CancelDirectDebitMandateTransient(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	return s.mandateStatusTransient(ctx, "CancelDirectDebitMandate", requestID, s.CancelDirectDebitMandate)
+}
+
+// ReturnPaymentTransient ReturnPayment 的 transient 版本：{"originalTxId":"...","reasonCode":"AC04","amount":100}
+func (s *SmartContract) ReturnPaymentTransient(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	var args struct {
+		OriginalTxID string `json:"originalTxId"`
+		ReasonCode   string `json:"reasonCode"`
+		Amount       int    `json:"amount"`
+	}
+	request, err := readTransientRequest(ctx, "ReturnPayment", requestID, &args)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.ReturnPayment(ctx, args.OriginalTxID, args.ReasonCode, args.Amount); err != nil {
+		return "", err
+	}
+	return request.receipt()
+}
+
+// SubmitPacs008Transient SubmitPacs008 的 transient 版本：{"document":"<pacs.008 XML>"}
+func (s *SmartContract) SubmitPacs008Transient(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	var args struct {
+		Document string `json:"document"`
+	}
+	request, err := readTransientRequest(ctx, "SubmitPacs008", requestID, &args)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.SubmitPacs008(ctx, args.Document); err != nil {
+		return "", err
+	}
+	return request.receipt()
+}
+
+// ConfidentialTransferTransient ConfidentialTransfer 的 transient 版本：{"transfer":{...}}，收款方不写入区块
+func (s *SmartContract) ConfidentialTransferTransient(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	var args struct {
+		Transfer json.RawMessage `json:"transfer"`
+	}
+	request, err := readTransientRequest(ctx, "ConfidentialTransfer", requestID, &args)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.ConfidentialTransfer(ctx, string(args.Transfer)); err != nil {
+		return "", err
+	}
+	return request.receipt()
+}
+
+// SetAccountEndorsementPolicyTransient SetAccountEndorsementPolicy 的 transient 版本：{"account":"...","bankMsp":"BankAMSP"}
+func (s *SmartContract) SetAccountEndorsementPolicyTransient(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	var args struct {
+		Account string `json:"account"`
+		BankMSP string `json:"bankMsp"`
+	}
+	request, err := readTransientRequest(ctx, "SetAccountEndorsementPolicy", requestID, &args)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.SetAccountEndorsementPolicy(ctx, args.Account, args.BankMSP); err != nil {
+		return "", err
+	}
+	return request.receipt()
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func TestTransientVariants(t *testing.T) {
+	env := newTestEnv(t)
+	env.initialize()
+	_, err := env.invoke(bankAAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.OpenAccount(ctx, bankAUser.id, accountTypeIndividual)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		requestID string
+		request   string
+		call      func(ctx contractapi.TransactionContextInterface, requestID string) (string, error)
+		wantErr   string
+	}{
+		{
+			name:      "endorsement policy",
+			requestID: "ep-1",
+			request:   `{"account":"` + bankAUser.id + `","bankMsp":"AMSP"}`,
+			call:      env.contract.SetAccountEndorsementPolicyTransient,
+		},
+		{
+			name:      "replayed request id",
+			requestID: "ep-1",
+			request:   `{"account":"` + bankAUser.id + `","bankMsp":"AMSP"}`,
+			call:      env.contract.SetAccountEndorsementPolicyTransient,
+			wantErr:   "already been processed",
+		},
+		{
+			name:      "unknown field",
+			requestID: "ep-2",
+			request:   `{"account":"` + bankAUser.id + `","bank":"AMSP"}`,
+			call:      env.contract.SetAccountEndorsementPolicyTransient,
+			wantErr:   "unknown field",
+		},
+		{
+			name:      "confidential transfer",
+			requestID: "ct-1",
+			request:   `{"transfer":{"recipient":"` + bankAUser.id + `"}}`,
+			call:      env.contract.ConfidentialTransferTransient,
+			wantErr:   "has not been opened",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var receipt string
+			_, err := env.invoke(centralBankAdmin, map[string][]byte{transientRequestField: []byte(tt.request)}, func(ctx contractapi.TransactionContextInterface) error {
+				var err error
+				receipt, err = tt.call(ctx, tt.requestID)
+				return err
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(receipt, `"requestId":"`+tt.requestID+`"`) || strings.Contains(receipt, "AMSP") {
+				t.Fatalf("receipt must only carry the request and transaction ids: %s", receipt)
+			}
+		})
+	}
+}
+
+// TestTransientTransferWithholdsEventDetails transient 版本的转账即使开启明文明细也不在事件中发出收款方与金额
+func TestTransientTransferWithholdsEventDetails(t *testing.T) {
+	env := newTestEnv(t)
+	env.initialize()
+	_, err := env.invoke(bankAAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := env.contract.OpenAccount(ctx, bankAUser.id, accountTypeIndividual)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("OpenAccount failed: %v", err)
+	}
+	_, err = env.invoke(centralBankAdmin, nil, func(ctx contractapi.TransactionContextInterface) error {
+		if err := env.contract.Mint(ctx, 1000); err != nil {
+			return err
+		}
+		_, err := env.contract.UpdateConfig(ctx, `{"plaintextEventDetails":true}`, "test")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	request := map[string][]byte{transientRequestField: []byte(`{"recipient":"` + bankAUser.id + `","amount":321}`)}
+	stub, err := env.invoke(centralBankAdmin, request, func(ctx contractapi.TransactionContextInterface) error {
+		return env.contract.TransferTransient(ctx, "tr-1")
+	})
+	if err != nil {
+		t.Fatalf("TransferTransient failed: %v", err)
+	}
+	envelopeJSON := string(stub.events[eventEnvelopeName])
+	if strings.Contains(envelopeJSON, bankAUser.id) || strings.Contains(envelopeJSON, "321") || !strings.Contains(envelopeJSON, `"withheld":true`) {
+		t.Fatalf("transient transfer leaked its parameters in the event: %s", envelopeJSON)
+	}
+
+	// 明文版本照常发出明细
+	stub = transfer(t, env, centralBankAdmin, bankAUser, 123)
+	if !strings.Contains(string(stub.events[eventEnvelopeName]), bankAUser.id) {
+		t.Fatalf("plaintext transfer must carry its details: %s", stub.events[eventEnvelopeName])
+	}
+}
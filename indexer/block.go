@@ -0,0 +1,165 @@
+/*
+区块解析：交易位置、验证结果、创建者与链码事件
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/msp"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// BlockTransaction 从区块中解析出的一笔背书交易
+type BlockTransaction struct {
+	TxID           string
+	ChannelID      string
+	BlockNumber    uint64
+	TxIndex        uint32
+	ValidationCode string
+	Valid          bool
+	Timestamp      int64
+	CreatorMSP     string
+	CreatorID      string // 与链码 GetClientIdentity().GetID() 一致的客户端ID
+	ChaincodeName  string
+	Function       string
+	EventName      string
+	EventPayload   []byte
+}
+
+// ParseBlock 解析区块中的背书交易，跳过配置交易
+func ParseBlock(block *common.Block) ([]BlockTransaction, error) {
+	if block.GetHeader() == nil || block.GetData() == nil {
+		return nil, fmt.Errorf("block is missing header or data")
+	}
+	blockNumber := block.GetHeader().GetNumber()
+
+	var filter []byte
+	if metadata := block.GetMetadata().GetMetadata(); len(metadata) > int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		filter = metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+
+	var txs []BlockTransaction
+	for i, envelopeBytes := range block.GetData().GetData() {
+		tx, err := parseEnvelope(envelopeBytes)
+		if err != nil {
+			return nil, fmt.Errorf("block %d tx %d: %w", blockNumber, i, err)
+		}
+		if tx == nil {
+			continue
+		}
+
+		tx.BlockNumber = blockNumber
+		tx.TxIndex = uint32(i)
+		tx.ValidationCode = peer.TxValidationCode_NOT_VALIDATED.String()
+		if i < len(filter) {
+			code := peer.TxValidationCode(filter[i])
+			tx.ValidationCode = code.String()
+			tx.Valid = code == peer.TxValidationCode_VALID
+		}
+
+		txs = append(txs, *tx)
+	}
+
+	return txs, nil
+}
+
+// parseEnvelope 解析单个交易信封；非背书交易返回 nil
+func parseEnvelope(envelopeBytes []byte) (*BlockTransaction, error) {
+	envelope := &common.Envelope{}
+	if err := proto.Unmarshal(envelopeBytes, envelope); err != nil {
+		return nil, fmt.Errorf("unmarshal envelope: %w", err)
+	}
+
+	payload := &common.Payload{}
+	if err := proto.Unmarshal(envelope.GetPayload(), payload); err != nil {
+		return nil, fmt.Errorf("unmarshal payload: %w", err)
+	}
+
+	channelHeader := &common.ChannelHeader{}
+	if err := proto.Unmarshal(payload.GetHeader().GetChannelHeader(), channelHeader); err != nil {
+		return nil, fmt.Errorf("unmarshal channel header: %w", err)
+	}
+	if common.HeaderType(channelHeader.GetType()) != common.HeaderType_ENDORSER_TRANSACTION {
+		return nil, nil
+	}
+
+	tx := &BlockTransaction{
+		TxID:      channelHeader.GetTxId(),
+		ChannelID: channelHeader.GetChannelId(),
+		Timestamp: channelHeader.GetTimestamp().GetSeconds(),
+	}
+
+	// 交易创建者
+	signatureHeader := &common.SignatureHeader{}
+	if err := proto.Unmarshal(payload.GetHeader().GetSignatureHeader(), signatureHeader); err != nil {
+		return nil, fmt.Errorf("unmarshal signature header: %w", err)
+	}
+	creator := &msp.SerializedIdentity{}
+	if err := proto.Unmarshal(signatureHeader.GetCreator(), creator); err != nil {
+		return nil, fmt.Errorf("unmarshal creator: %w", err)
+	}
+	tx.CreatorMSP = creator.GetMspid()
+	if id, err := clientIDFromPEM(creator.GetIdBytes()); err == nil {
+		tx.CreatorID = id
+	}
+
+	// 链码调用与事件
+	transaction := &peer.Transaction{}
+	if err := proto.Unmarshal(payload.GetData(), transaction); err != nil {
+		return nil, fmt.Errorf("unmarshal transaction: %w", err)
+	}
+	for _, action := range transaction.GetActions() {
+		if err := parseChaincodeAction(action, tx); err != nil {
+			return nil, err
+		}
+	}
+
+	return tx, nil
+}
+
+// parseChaincodeAction 提取链码名称、调用函数与链码事件
+func parseChaincodeAction(action *peer.TransactionAction, tx *BlockTransaction) error {
+	actionPayload := &peer.ChaincodeActionPayload{}
+	if err := proto.Unmarshal(action.GetPayload(), actionPayload); err != nil {
+		return fmt.Errorf("unmarshal chaincode action payload: %w", err)
+	}
+
+	proposalPayload := &peer.ChaincodeProposalPayload{}
+	if err := proto.Unmarshal(actionPayload.GetChaincodeProposalPayload(), proposalPayload); err != nil {
+		return fmt.Errorf("unmarshal chaincode proposal payload: %w", err)
+	}
+	invocation := &peer.ChaincodeInvocationSpec{}
+	if err := proto.Unmarshal(proposalPayload.GetInput(), invocation); err != nil {
+		return fmt.Errorf("unmarshal chaincode invocation spec: %w", err)
+	}
+	if args := invocation.GetChaincodeSpec().GetInput().GetArgs(); len(args) > 0 {
+		tx.Function = string(args[0])
+	}
+
+	responsePayload := &peer.ProposalResponsePayload{}
+	if err := proto.Unmarshal(actionPayload.GetAction().GetProposalResponsePayload(), responsePayload); err != nil {
+		return fmt.Errorf("unmarshal proposal response payload: %w", err)
+	}
+	chaincodeAction := &peer.ChaincodeAction{}
+	if err := proto.Unmarshal(responsePayload.GetExtension(), chaincodeAction); err != nil {
+		return fmt.Errorf("unmarshal chaincode action: %w", err)
+	}
+	tx.ChaincodeName = chaincodeAction.GetChaincodeId().GetName()
+
+	if len(chaincodeAction.GetEvents()) > 0 {
+		chaincodeEvent := &peer.ChaincodeEvent{}
+		if err := proto.Unmarshal(chaincodeAction.GetEvents(), chaincodeEvent); err != nil {
+			return fmt.Errorf("unmarshal chaincode event: %w", err)
+		}
+		tx.EventName = chaincodeEvent.GetEventName()
+		tx.EventPayload = chaincodeEvent.GetPayload()
+	}
+
+	return nil
+}
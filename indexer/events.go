@@ -0,0 +1,201 @@
+/*
+链码事件解析
+
+- 新版链码以 CBDCEvents 信封发出事件，账户为加盐哈希，明细按接收机构加密
+- 配置了本机构登记的事件私钥时，解密信封中的记账事件明细；链码未启用事件隐私时明细默认不发出，
+  央行开启 plaintextEventDetails 后为明文
+- 兼容旧版链码的明文 Transfer 事件
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// eventEnvelopeName 链码事件信封名
+const eventEnvelopeName = "CBDCEvents"
+
+// bookingEventTypes 记账类事件，携带付款方、收款方与金额
+var bookingEventTypes = map[string]bool{
+	"Transfer":     true,
+	"TransferFrom": true,
+	"DirectDebit":  true,
+	"Mint":         true,
+	"Burn":         true,
+	"Return":       true,
+	"Sweep":        true,
+
+	"ConfidentialDeposit":  true,
+	"ConfidentialWithdraw": true,
+}
+
+// eventEnvelope 链码事件信封
+type eventEnvelope struct {
+	Version int              `json:"version"`
+	TxID    string           `json:"txId"`
+	Events  []chaincodeEvent `json:"events"`
+}
+
+// chaincodeEvent 信封中的逻辑事件
+type chaincodeEvent struct {
+	Seq      int             `json:"seq"`
+	Type     string          `json:"type"`
+	Payloads []sealedPayload `json:"payloads"`
+	Details  json.RawMessage `json:"details"` // 未启用事件隐私且开启明文明细时的明细
+}
+
+// sealedPayload 为一个接收机构加密的事件明细
+type sealedPayload struct {
+	Recipient    string `json:"recipient"`
+	KeyID        string `json:"keyId"`
+	EphemeralKey string `json:"epk"`
+	Nonce        string `json:"nonce"`
+	Ciphertext   string `json:"ciphertext"`
+}
+
+// bookingDetails 记账事件的明细（解密后或明文）
+type bookingDetails struct {
+	Parties struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	} `json:"parties"`
+	Value int `json:"value"`
+}
+
+// EventKey 本机构的事件解密私钥
+type EventKey struct {
+	key   *ecdh.PrivateKey
+	keyID string
+}
+
+// LoadEventKey 读取 PEM 编码的 P-256 私钥（PKCS#8 或 SEC 1），对应链码中 RegisterEventKey 登记的公钥
+func LoadEventKey(path string) (*EventKey, error) {
+	keyPEM, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read event key: %w", err)
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("event key %s is not PEM encoded", path)
+	}
+
+	var ecdsaKey *ecdsa.PrivateKey
+	if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		var ok bool
+		if ecdsaKey, ok = parsed.(*ecdsa.PrivateKey); !ok {
+			return nil, fmt.Errorf("event key must be an EC key")
+		}
+	} else if ecdsaKey, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+		return nil, fmt.Errorf("parse event key: %w", err)
+	}
+
+	key, err := ecdsaKey.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("event key must be on curve P-256: %w", err)
+	}
+	fingerprint := sha256.Sum256(key.PublicKey().Bytes())
+	return &EventKey{key: key, keyID: hex.EncodeToString(fingerprint[:8])}, nil
+}
+
+// open 解密发给本机构的明细，没有本机构的密文时返回 nil
+func (k *EventKey) open(txID string, evt chaincodeEvent) ([]byte, error) {
+	for _, payload := range evt.Payloads {
+		if payload.KeyID != k.keyID {
+			continue
+		}
+		epk, err := base64.StdEncoding.DecodeString(payload.EphemeralKey)
+		if err != nil {
+			return nil, fmt.Errorf("decode ephemeral key: %w", err)
+		}
+		ephemeral, err := ecdh.P256().NewPublicKey(epk)
+		if err != nil {
+			return nil, fmt.Errorf("parse ephemeral key: %w", err)
+		}
+		shared, err := k.key.ECDH(ephemeral)
+		if err != nil {
+			return nil, fmt.Errorf("derive shared secret: %w", err)
+		}
+		digest := sha256.Sum256(append(append(shared, epk...), []byte(payload.Recipient)...))
+
+		block, err := aes.NewCipher(digest[:])
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		nonce, err := base64.StdEncoding.DecodeString(payload.Nonce)
+		if err != nil {
+			return nil, fmt.Errorf("decode nonce: %w", err)
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(payload.Ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("decode ciphertext: %w", err)
+		}
+		aad := fmt.Sprintf("%s|%d|%s", txID, evt.Seq, evt.Type)
+		plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(aad))
+		if err != nil {
+			return nil, fmt.Errorf("decrypt %s event: %w", evt.Type, err)
+		}
+		return plaintext, nil
+	}
+	return nil, nil
+}
+
+// bookingFromEvent 从链码事件中取出记账双方与金额；无法取得时 ok 为 false
+func bookingFromEvent(name string, payload []byte, key *EventKey) (from string, to string, amount int, ok bool, err error) {
+	switch name {
+	case "Transfer":
+		// 旧版链码的明文事件
+		var ev transferEvent
+		if err := json.Unmarshal(payload, &ev); err != nil {
+			return "", "", 0, false, fmt.Errorf("unmarshal Transfer event: %w", err)
+		}
+		return ev.From, ev.To, ev.Value, true, nil
+
+	case eventEnvelopeName:
+		var envelope eventEnvelope
+		if err := json.Unmarshal(payload, &envelope); err != nil {
+			return "", "", 0, false, fmt.Errorf("unmarshal %s event: %w", eventEnvelopeName, err)
+		}
+		for _, evt := range envelope.Events {
+			if !bookingEventTypes[evt.Type] {
+				continue
+			}
+			plaintext := []byte(evt.Details)
+			if len(plaintext) == 0 {
+				if key == nil {
+					continue
+				}
+				plaintext, err = key.open(envelope.TxID, evt)
+				if err != nil {
+					return "", "", 0, false, err
+				}
+			}
+			if plaintext == nil {
+				continue
+			}
+			var details bookingDetails
+			if err := json.Unmarshal(plaintext, &details); err != nil {
+				return "", "", 0, false, fmt.Errorf("unmarshal %s event details: %w", evt.Type, err)
+			}
+			return details.Parties.From, details.Parties.To, details.Value, true, nil
+		}
+	}
+	return "", "", 0, false, nil
+}
@@ -0,0 +1,66 @@
+/*
+客户端ID与组织域名解析，规则与链码保持一致
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// clientIDFromPEM 按链码 cid.GetID() 的规则由 PEM 证书计算客户端ID
+func clientIDFromPEM(certPEM []byte) (string, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return "", fmt.Errorf("no PEM certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("parse certificate: %w", err)
+	}
+
+	id := fmt.Sprintf("x509::%s::%s", cert.Subject.String(), cert.Issuer.String())
+	return base64.StdEncoding.EncodeToString([]byte(id)), nil
+}
+
+// domainFromClientID 从客户端ID中提取组织域名，规则与链码 extractDomainFromClientID 一致
+func domainFromClientID(clientID string) string {
+	decoded, err := base64.StdEncoding.DecodeString(clientID)
+	if err != nil {
+		return ""
+	}
+
+	parts := strings.Split(string(decoded), "::")
+	if len(parts) < 2 || parts[0] != "x509" {
+		return ""
+	}
+
+	// 用户证书的 O 字段
+	subject := parts[1]
+	if orgMatch := strings.Split(subject, "O="); len(orgMatch) > 1 {
+		return strings.Split(orgMatch[1], ",")[0]
+	}
+
+	// CN 中 @ 之后的部分
+	if cnMatch := strings.Split(subject, "CN="); len(cnMatch) > 1 {
+		cn := strings.Split(cnMatch[1], ",")[0]
+		if _, domain, found := strings.Cut(cn, "@"); found {
+			return domain
+		}
+	}
+
+	// CA 证书的 O 字段
+	if len(parts) >= 3 {
+		if orgMatch := strings.Split(parts[2], "O="); len(orgMatch) > 1 {
+			return strings.Split(orgMatch[1], ",")[0]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,194 @@
+/*
+区块索引：关联区块、链码事件与私有记录
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+)
+
+// zeroAddress 链码中铸币/销毁使用的零地址
+const zeroAddress = "0x0"
+
+// transferEvent 旧版链码 Transfer 事件负载
+type transferEvent struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Value int    `json:"value"`
+}
+
+// Indexer 消费区块并写入索引库
+type Indexer struct {
+	store     *Store
+	blocks    BlockSource
+	records   RecordSource
+	chaincode string
+	orgs      map[string]string // 组织域名 -> MSP ID，从交易创建者证书中学习
+	eventKey  *EventKey         // 解密链码事件明细的私钥，可为空
+}
+
+// NewIndexer 创建索引器，只索引指定链码的交易
+func NewIndexer(store *Store, blocks BlockSource, records RecordSource, chaincode string) *Indexer {
+	return &Indexer{
+		store:     store,
+		blocks:    blocks,
+		records:   records,
+		chaincode: chaincode,
+		orgs:      map[string]string{},
+	}
+}
+
+// SetEventKey 设置解密链码事件明细的私钥
+func (idx *Indexer) SetEventKey(key *EventKey) {
+	idx.eventKey = key
+}
+
+// Run 从检查点之后的区块开始索引，直到区块源关闭或 ctx 取消
+func (idx *Indexer) Run(ctx context.Context) error {
+	orgs, err := idx.store.OrgMSPs(ctx)
+	if err != nil {
+		return err
+	}
+	idx.orgs = orgs
+
+	start, err := idx.store.NextBlock(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("indexing chaincode %s from block %d", idx.chaincode, start)
+
+	blocks, err := idx.blocks.Blocks(ctx, start)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case block, ok := <-blocks:
+			if !ok {
+				return nil
+			}
+			if err := idx.ProcessBlock(ctx, block); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ProcessBlock 解析单个区块、关联私有记录并写入索引库
+func (idx *Indexer) ProcessBlock(ctx context.Context, block *common.Block) error {
+	blockNumber := block.GetHeader().GetNumber()
+	txs, err := ParseBlock(block)
+	if err != nil {
+		return err
+	}
+
+	// 先从本区块的有效交易中学习组织映射，再解析交易双方的 MSP
+	learned := map[string]string{}
+	for _, tx := range txs {
+		if !tx.Valid || tx.CreatorID == "" || tx.CreatorMSP == "" {
+			continue
+		}
+		domain := domainFromClientID(tx.CreatorID)
+		if domain != "" && idx.orgs[domain] != tx.CreatorMSP {
+			idx.orgs[domain] = tx.CreatorMSP
+			learned[domain] = tx.CreatorMSP
+		}
+	}
+
+	var indexed []IndexedTransaction
+	for _, tx := range txs {
+		if tx.ChaincodeName != idx.chaincode {
+			continue
+		}
+		record, err := idx.enrich(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("block %d tx %s: %w", blockNumber, tx.TxID, err)
+		}
+		indexed = append(indexed, record)
+	}
+
+	if err := idx.store.SaveBlock(ctx, blockNumber, indexed, learned); err != nil {
+		return fmt.Errorf("save block %d: %w", blockNumber, err)
+	}
+	log.Printf("indexed block %d: %d transactions", blockNumber, len(indexed))
+	return nil
+}
+
+// enrich 合并区块信息、链码事件与央行私有记录
+// 只有有效交易才会在私有集合中留下记录；无效交易只保留区块信息与验证结果
+func (idx *Indexer) enrich(ctx context.Context, tx BlockTransaction) (IndexedTransaction, error) {
+	indexed := IndexedTransaction{
+		TxID:           tx.TxID,
+		BlockNumber:    tx.BlockNumber,
+		TxIndex:        tx.TxIndex,
+		ValidationCode: tx.ValidationCode,
+		Valid:          tx.Valid,
+		Timestamp:      tx.Timestamp,
+		CreatorMSP:     tx.CreatorMSP,
+		Function:       tx.Function,
+	}
+
+	if len(tx.EventPayload) > 0 {
+		from, to, amount, ok, err := bookingFromEvent(tx.EventName, tx.EventPayload, idx.eventKey)
+		if err != nil {
+			return indexed, err
+		}
+		if ok {
+			indexed.From = from
+			indexed.To = to
+			indexed.Amount = amount
+		}
+	}
+
+	if tx.Valid {
+		record, err := idx.records.Record(ctx, tx.TxID)
+		switch {
+		case errors.Is(err, errRecordNotFound):
+			// 查询类交易或未写入私有记录的交易
+		case err != nil:
+			return indexed, err
+		default:
+			indexed.HasPrivate = true
+			indexed.TransactionType = record.TransactionType
+			indexed.From = record.From
+			indexed.To = record.To
+			indexed.Spender = record.Spender
+			indexed.FromMSP = record.FromMSP
+			indexed.ToMSP = record.ToMSP
+			indexed.Amount = record.Amount
+			indexed.EndToEndID = record.EndToEndID
+			indexed.UETR = record.UETR
+			indexed.OriginalTxID = record.OriginalTxID
+			indexed.ReturnReason = record.ReturnReason
+		}
+	}
+
+	// 私有记录缺失的 MSP 按账户所属组织补全
+	if indexed.FromMSP == "" {
+		indexed.FromMSP = idx.accountMSP(indexed.From)
+	}
+	if indexed.ToMSP == "" {
+		indexed.ToMSP = idx.accountMSP(indexed.To)
+	}
+
+	return indexed, nil
+}
+
+// accountMSP 根据账户（客户端ID）所属组织域名查找 MSP ID
+func (idx *Indexer) accountMSP(account string) string {
+	if account == "" || account == zeroAddress {
+		return ""
+	}
+	return idx.orgs[domainFromClientID(account)]
+}
@@ -0,0 +1,201 @@
+/*
+CBDC 区块索引服务
+
+- 订阅通道已提交区块，解析交易位置、验证结果与链码事件
+- 以央行身份读取私有交易记录，补全区块号、交易序号与双方 MSP
+- 结果写入 SQLite，检查点保证重启后从断点继续
+- -fixtures 模式回放录制的区块与私有记录，无需运行中的网络
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// config 索引服务配置，命令行参数优先，其次环境变量
+type config struct {
+	dbPath       string
+	channel      string
+	chaincode    string
+	fixtureDir   string
+	peerEndpoint string
+	peerHost     string
+	tlsCertPath  string
+	mspID        string
+	certPath     string
+	keyPath      string
+	eventKeyPath string
+}
+
+func main() {
+	cfg := loadConfig()
+
+	if err := run(cfg); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("indexer stopped: %v", err)
+	}
+}
+
+func loadConfig() config {
+	cfg := config{}
+	flag.StringVar(&cfg.dbPath, "db", envOrDefault("INDEXER_DB", "cbdc-index.db"), "SQLite database path")
+	flag.StringVar(&cfg.channel, "channel", envOrDefault("CHANNEL_NAME", "cbdc-channel"), "channel name")
+	flag.StringVar(&cfg.chaincode, "chaincode", envOrDefault("CHAINCODE_NAME", "cbdc"), "chaincode name")
+	flag.StringVar(&cfg.fixtureDir, "fixtures", envOrDefault("INDEXER_FIXTURES", ""), "replay recorded blocks (*.block) and private records (records/<txId>.json) from this directory instead of connecting to a peer")
+	flag.StringVar(&cfg.peerEndpoint, "peer", envOrDefault("PEER_ENDPOINT", "localhost:7051"), "central bank peer gateway endpoint")
+	flag.StringVar(&cfg.peerHost, "peer-host", envOrDefault("PEER_HOST_ALIAS", ""), "TLS server name override for the peer")
+	flag.StringVar(&cfg.tlsCertPath, "tls-cert", envOrDefault("PEER_TLS_CERT", ""), "peer TLS CA certificate (PEM)")
+	flag.StringVar(&cfg.mspID, "msp", envOrDefault("MSP_ID", ""), "central bank MSP ID")
+	flag.StringVar(&cfg.certPath, "cert", envOrDefault("CERT_PATH", ""), "central bank user certificate (PEM)")
+	flag.StringVar(&cfg.keyPath, "key", envOrDefault("KEY_PATH", ""), "central bank user private key, or keystore directory")
+	flag.StringVar(&cfg.eventKeyPath, "event-key", envOrDefault("INDEXER_EVENT_KEY", ""), "P-256 private key (PEM) registered with RegisterEventKey, used to decrypt chaincode event details")
+	flag.Parse()
+	return cfg
+}
+
+func envOrDefault(key, value string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return value
+}
+
+func run(cfg config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	store, err := OpenStore(cfg.dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var eventKey *EventKey
+	if cfg.eventKeyPath != "" {
+		eventKey, err = LoadEventKey(cfg.eventKeyPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.fixtureDir != "" {
+		blocks := NewFixtureBlockSource(cfg.fixtureDir)
+		records := NewFixtureRecordSource(filepath.Join(cfg.fixtureDir, "records"))
+		indexer := NewIndexer(store, blocks, records, cfg.chaincode)
+		indexer.SetEventKey(eventKey)
+		return indexer.Run(ctx)
+	}
+
+	gw, closeGateway, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeGateway()
+
+	network := gw.GetNetwork(cfg.channel)
+	blocks := NewGatewayBlockSource(network)
+	records := NewGatewayRecordSource(network.GetContract(cfg.chaincode))
+	indexer := NewIndexer(store, blocks, records, cfg.chaincode)
+	indexer.SetEventKey(eventKey)
+	return indexer.Run(ctx)
+}
+
+// connect 以央行用户身份连接 Gateway
+func connect(cfg config) (*client.Gateway, func(), error) {
+	if cfg.mspID == "" || cfg.certPath == "" || cfg.keyPath == "" || cfg.tlsCertPath == "" {
+		return nil, nil, fmt.Errorf("-msp, -cert, -key and -tls-cert are required unless -fixtures is set")
+	}
+
+	tlsPEM, err := os.ReadFile(cfg.tlsCertPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read TLS certificate: %w", err)
+	}
+	tlsCert, err := identity.CertificateFromPEM(tlsPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse TLS certificate: %w", err)
+	}
+	certPool := x509.NewCertPool()
+	certPool.AddCert(tlsCert)
+	conn, err := grpc.NewClient(cfg.peerEndpoint, grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(certPool, cfg.peerHost)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("create gRPC connection: %w", err)
+	}
+
+	certPEM, err := os.ReadFile(cfg.certPath)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("read certificate: %w", err)
+	}
+	cert, err := identity.CertificateFromPEM(certPEM)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("parse certificate: %w", err)
+	}
+	id, err := identity.NewX509Identity(cfg.mspID, cert)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("create identity: %w", err)
+	}
+
+	keyPEM, err := readPrivateKey(cfg.keyPath)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	privateKey, err := identity.PrivateKeyFromPEM(keyPEM)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("parse private key: %w", err)
+	}
+	sign, err := identity.NewPrivateKeySign(privateKey)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("create signer: %w", err)
+	}
+
+	gw, err := client.Connect(id, client.WithSign(sign), client.WithClientConnection(conn))
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("connect gateway: %w", err)
+	}
+
+	return gw, func() {
+		gw.Close()
+		conn.Close()
+	}, nil
+}
+
+// readPrivateKey 读取私钥文件；传入目录时读取其中第一个文件（MSP keystore 布局）
+func readPrivateKey(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("read keystore: %w", err)
+		}
+		if len(entries) == 0 {
+			return nil, fmt.Errorf("keystore %s is empty", path)
+		}
+		path = filepath.Join(path, entries[0].Name())
+	}
+	return os.ReadFile(path)
+}
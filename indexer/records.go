@@ -0,0 +1,95 @@
+/*
+央行私有交易记录源：链码查询与录制记录回放
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// PrivateRecord 央行私有集合中的交易记录（链码 GetTransactionRecord 的返回）
+type PrivateRecord struct {
+	TxID            string `json:"txId"`
+	From            string `json:"from"`
+	To              string `json:"to"`
+	FromMSP         string `json:"fromMsp"`
+	ToMSP           string `json:"toMsp"`
+	Amount          int    `json:"amount"`
+	TransactionType string `json:"transactionType"`
+	Spender         string `json:"spender"`
+	EndToEndID      string `json:"endToEndId"`
+	UETR            string `json:"uetr"`
+	OriginalTxID    string `json:"originalTxId"`
+	ReturnReason    string `json:"returnReason"`
+}
+
+// errRecordNotFound 私有集合中不存在该交易记录
+var errRecordNotFound = errors.New("transaction record not found")
+
+// RecordSource 按交易ID读取私有交易记录
+type RecordSource interface {
+	Record(ctx context.Context, txID string) (*PrivateRecord, error)
+}
+
+// gatewayRecordSource 以央行身份调用链码读取私有交易记录
+type gatewayRecordSource struct {
+	contract *client.Contract
+}
+
+// NewGatewayRecordSource 创建基于 Gateway 的私有记录源，调用身份需为央行用户
+func NewGatewayRecordSource(contract *client.Contract) RecordSource {
+	return &gatewayRecordSource{contract: contract}
+}
+
+func (s *gatewayRecordSource) Record(ctx context.Context, txID string) (*PrivateRecord, error) {
+	result, err := s.contract.EvaluateWithContext(ctx, "GetTransactionRecord", client.WithArguments(txID))
+	if err != nil {
+		if strings.Contains(err.Error(), "does not exist") {
+			return nil, errRecordNotFound
+		}
+		return nil, fmt.Errorf("evaluate GetTransactionRecord %s: %w", txID, err)
+	}
+
+	var record PrivateRecord
+	if err := json.Unmarshal(result, &record); err != nil {
+		return nil, fmt.Errorf("unmarshal transaction record %s: %w", txID, err)
+	}
+	return &record, nil
+}
+
+// fixtureRecordSource 从目录读取录制的私有记录（<txID>.json）
+type fixtureRecordSource struct {
+	dir string
+}
+
+// NewFixtureRecordSource 创建基于录制文件的私有记录源
+func NewFixtureRecordSource(dir string) RecordSource {
+	return &fixtureRecordSource{dir: dir}
+}
+
+func (s *fixtureRecordSource) Record(_ context.Context, txID string) (*PrivateRecord, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, txID+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errRecordNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read fixture record %s: %w", txID, err)
+	}
+
+	var record PrivateRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("unmarshal fixture record %s: %w", txID, err)
+	}
+	return &record, nil
+}
@@ -0,0 +1,181 @@
+/*
+SQLite 索引库：交易、组织 MSP 映射与检查点
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+// IndexedTransaction 写入索引库的交易：区块位置与验证结果 + 私有记录内容
+type IndexedTransaction struct {
+	TxID            string
+	BlockNumber     uint64
+	TxIndex         uint32
+	ValidationCode  string
+	Valid           bool
+	Timestamp       int64
+	CreatorMSP      string
+	Function        string
+	TransactionType string
+	From            string
+	To              string
+	Spender         string
+	FromMSP         string
+	ToMSP           string
+	Amount          int
+	EndToEndID      string
+	UETR            string
+	OriginalTxID    string
+	ReturnReason    string
+	HasPrivate      bool // 是否已关联央行私有记录
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS transactions (
+	tx_id            TEXT PRIMARY KEY,
+	block_number     INTEGER NOT NULL,
+	tx_index         INTEGER NOT NULL,
+	validation_code  TEXT NOT NULL,
+	valid            INTEGER NOT NULL,
+	timestamp        INTEGER NOT NULL,
+	creator_msp      TEXT NOT NULL,
+	function         TEXT NOT NULL,
+	transaction_type TEXT NOT NULL,
+	from_account     TEXT NOT NULL,
+	to_account       TEXT NOT NULL,
+	spender          TEXT NOT NULL,
+	from_msp         TEXT NOT NULL,
+	to_msp           TEXT NOT NULL,
+	amount           INTEGER NOT NULL,
+	end_to_end_id    TEXT NOT NULL,
+	uetr             TEXT NOT NULL,
+	original_tx_id   TEXT NOT NULL,
+	return_reason    TEXT NOT NULL,
+	has_private      INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_transactions_position ON transactions(block_number, tx_index);
+CREATE INDEX IF NOT EXISTS idx_transactions_from ON transactions(from_account, block_number, tx_index);
+CREATE INDEX IF NOT EXISTS idx_transactions_to ON transactions(to_account, block_number, tx_index);
+CREATE INDEX IF NOT EXISTS idx_transactions_uetr ON transactions(uetr);
+
+CREATE TABLE IF NOT EXISTS org_msps (
+	domain TEXT PRIMARY KEY,
+	msp_id TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS checkpoint (
+	id           INTEGER PRIMARY KEY CHECK (id = 0),
+	block_number INTEGER NOT NULL
+);
+`
+
+// Store SQLite 索引库
+type Store struct {
+	db *sql.DB
+}
+
+// OpenStore 打开（必要时创建）索引库
+func OpenStore(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite %s: %w", path, err)
+	}
+	// SQLite 单写者
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close 关闭索引库
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// NextBlock 返回下一个待索引的区块号
+func (s *Store) NextBlock(ctx context.Context) (uint64, error) {
+	var last uint64
+	err := s.db.QueryRowContext(ctx, `SELECT block_number FROM checkpoint WHERE id = 0`).Scan(&last)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read checkpoint: %w", err)
+	}
+	return last + 1, nil
+}
+
+// OrgMSPs 返回已知的组织域名到 MSP ID 映射
+func (s *Store) OrgMSPs(ctx context.Context) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT domain, msp_id FROM org_msps`)
+	if err != nil {
+		return nil, fmt.Errorf("read org msps: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := map[string]string{}
+	for rows.Next() {
+		var domain, mspID string
+		if err := rows.Scan(&domain, &mspID); err != nil {
+			return nil, fmt.Errorf("scan org msp: %w", err)
+		}
+		orgs[domain] = mspID
+	}
+	return orgs, rows.Err()
+}
+
+// SaveBlock 在同一事务中写入区块内的交易、新发现的组织映射与检查点，保证重启后可从断点继续
+func (s *Store) SaveBlock(ctx context.Context, blockNumber uint64, txs []IndexedTransaction, orgs map[string]string) error {
+	dbTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	for domain, mspID := range orgs {
+		_, err := dbTx.ExecContext(ctx,
+			`INSERT INTO org_msps (domain, msp_id) VALUES (?, ?)
+			 ON CONFLICT(domain) DO UPDATE SET msp_id = excluded.msp_id`,
+			domain, mspID)
+		if err != nil {
+			return fmt.Errorf("save org msp %s: %w", domain, err)
+		}
+	}
+
+	for _, tx := range txs {
+		_, err := dbTx.ExecContext(ctx,
+			`INSERT OR REPLACE INTO transactions (
+				tx_id, block_number, tx_index, validation_code, valid, timestamp, creator_msp, function,
+				transaction_type, from_account, to_account, spender, from_msp, to_msp, amount,
+				end_to_end_id, uetr, original_tx_id, return_reason, has_private
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			tx.TxID, tx.BlockNumber, tx.TxIndex, tx.ValidationCode, tx.Valid, tx.Timestamp, tx.CreatorMSP, tx.Function,
+			tx.TransactionType, tx.From, tx.To, tx.Spender, tx.FromMSP, tx.ToMSP, tx.Amount,
+			tx.EndToEndID, tx.UETR, tx.OriginalTxID, tx.ReturnReason, tx.HasPrivate)
+		if err != nil {
+			return fmt.Errorf("save transaction %s: %w", tx.TxID, err)
+		}
+	}
+
+	_, err = dbTx.ExecContext(ctx,
+		`INSERT INTO checkpoint (id, block_number) VALUES (0, ?)
+		 ON CONFLICT(id) DO UPDATE SET block_number = excluded.block_number`,
+		blockNumber)
+	if err != nil {
+		return fmt.Errorf("save checkpoint: %w", err)
+	}
+
+	return dbTx.Commit()
+}